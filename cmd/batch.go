@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/joelklabo/copilot-research/internal/research"
+	"github.com/joelklabo/copilot-research/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	batchConcurrency int
+	batchStateFile   string
+	batchRestart     bool
+)
+
+// batchCmd represents the batch command
+var batchCmd = &cobra.Command{
+	Use:   "batch <manifest.yaml>",
+	Short: "Research many queries from a manifest file",
+	Long: `Research a list of queries defined in a YAML manifest, running several
+at a time. Progress is saved next to the manifest so an interrupted batch
+picks up where it left off without redoing completed queries.
+
+Manifest format:
+  concurrency: 4          # optional, default 3
+  mode: quick             # optional default for every query
+  prompt: default         # optional default for every query
+  output_dir: reports     # optional, writes <slug>.md per query
+  queries:
+    - "Evaluate spf13/cobra"
+    - query: "Evaluate urfave/cli"
+      mode: deep
+      prompt: deep-dive
+      output: reports/urfave-cli.md
+
+Examples:
+  copilot-research batch queries.yaml
+  copilot-research batch queries.yaml --concurrency 8
+  copilot-research batch queries.yaml --restart`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBatch,
+}
+
+func init() {
+	RootCmd.AddCommand(batchCmd)
+
+	batchCmd.Flags().IntVarP(&batchConcurrency, "concurrency", "c", 0, "number of queries to run at once (overrides manifest)")
+	batchCmd.Flags().StringVar(&batchStateFile, "state", "", "batch state file (default is <manifest>.state.json)")
+	batchCmd.Flags().BoolVar(&batchRestart, "restart", false, "ignore saved progress and run every query again")
+}
+
+func runBatch(cmd *cobra.Command, args []string) error {
+	manifestPath := args[0]
+
+	manifest, err := research.LoadBatchManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+	for _, item := range manifest.Queries {
+		if err := validateMode(item.Mode); err != nil {
+			return fmt.Errorf("query %q: %w", item.Query, err)
+		}
+	}
+
+	statePath := batchStateFile
+	if statePath == "" {
+		statePath = research.DefaultBatchStatePath(manifestPath)
+	}
+	if batchRestart {
+		if err := os.Remove(statePath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to reset batch state: %w", err)
+		}
+	}
+
+	state, err := research.LoadBatchState(statePath, manifestPath)
+	if err != nil {
+		return err
+	}
+
+	database, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	engine, err := newResearchEngine(database)
+	if err != nil {
+		return err
+	}
+
+	concurrency := manifest.Concurrency
+	if batchConcurrency > 0 {
+		concurrency = batchConcurrency
+	}
+	runner := research.NewBatchRunner(engine, concurrency, NoStore)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var summary *research.BatchSummary
+	if Quiet {
+		summary, err = runQuietBatch(ctx, runner, manifest, state)
+	} else {
+		summary, err = runInteractiveBatch(ctx, stop, runner, manifest, state)
+	}
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("batch interrupted, progress saved to %s", statePath)
+		}
+		return fmt.Errorf("batch failed: %w", err)
+	}
+	if summary.Failed > 0 {
+		return fmt.Errorf("%d of %d queries failed, re-run to retry them", summary.Failed, summary.Total)
+	}
+
+	return nil
+}
+
+func runQuietBatch(ctx context.Context, runner *research.BatchRunner, manifest *research.BatchManifest, state *research.BatchState) (*research.BatchSummary, error) {
+	updates := make(chan research.BatchUpdate, 10)
+	done := make(chan struct{})
+
+	// Only report item outcomes, not intermediate progress
+	go func() {
+		defer close(done)
+		for u := range updates {
+			switch u.Status {
+			case research.BatchStatusDone, research.BatchStatusSkipped, research.BatchStatusFailed:
+				fmt.Fprintf(os.Stderr, "[%d/%d] %s: %s (%s)\n", u.Index+1, len(manifest.Queries), u.Status, u.Item.Query, u.Message)
+			}
+		}
+	}()
+
+	summary, err := runner.Run(ctx, manifest, state, updates)
+	close(updates)
+	<-done
+
+	if summary != nil {
+		fmt.Fprintf(os.Stderr, "Completed: %d, Skipped: %d, Failed: %d\n", summary.Completed, summary.Skipped, summary.Failed)
+	}
+	return summary, err
+}
+
+func runInteractiveBatch(ctx context.Context, cancel context.CancelFunc, runner *research.BatchRunner, manifest *research.BatchManifest, state *research.BatchState) (*research.BatchSummary, error) {
+	p := tea.NewProgram(ui.NewBatchModel(manifest.Queries))
+
+	var (
+		summary *research.BatchSummary
+		runErr  error
+	)
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+		updates := make(chan research.BatchUpdate, 10)
+
+		go func() {
+			for u := range updates {
+				p.Send(ui.BatchUpdateMsg(u))
+			}
+		}()
+
+		summary, runErr = runner.Run(ctx, manifest, state, updates)
+		close(updates)
+		p.Send(ui.BatchDoneMsg{Summary: summary, Err: runErr})
+	}()
+
+	if _, err := p.Run(); err != nil {
+		cancel()
+		<-finished
+		return summary, fmt.Errorf("UI error: %w", err)
+	}
+
+	// Quitting the UI early stops the batch; completed items stay saved
+	cancel()
+	<-finished
+
+	return summary, runErr
+}
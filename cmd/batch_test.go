@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchCommand(t *testing.T) {
+	assert.NotNil(t, batchCmd)
+	assert.Contains(t, batchCmd.Use, "batch")
+	assert.NotEmpty(t, batchCmd.Short)
+	assert.NotNil(t, batchCmd.RunE)
+}
+
+func TestBatchCommand_Flags(t *testing.T) {
+	flags := []string{"concurrency", "state", "restart"}
+
+	for _, flagName := range flags {
+		t.Run(flagName, func(t *testing.T) {
+			flag := batchCmd.Flags().Lookup(flagName)
+			assert.NotNil(t, flag, "Flag %s should exist", flagName)
+		})
+	}
+}
+
+func TestBatchCommand_RequiresManifest(t *testing.T) {
+	assert.Error(t, batchCmd.Args(batchCmd, []string{}))
+	assert.NoError(t, batchCmd.Args(batchCmd, []string{"queries.yaml"}))
+}
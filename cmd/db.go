@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var dbMigrateStatus bool
+
+// dbCmd represents the db command
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the research history database",
+}
+
+var dbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Bring the database schema up to date",
+	Long: `Apply pending schema migrations to the research history database.
+
+Migrations also run automatically whenever the database is opened. An
+existing database is backed up next to itself before it is migrated, as
+research.db.v<version>-<timestamp>.bak.
+
+Examples:
+  copilot-research db migrate --status
+  copilot-research db migrate`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dbPath, err := databasePath()
+		if err != nil {
+			return err
+		}
+
+		if dbMigrateStatus {
+			status, err := db.ReadMigrationStatus(dbPath)
+			if err != nil {
+				return err
+			}
+			fmt.Print(formatMigrationStatus(dbPath, status))
+			return nil
+		}
+
+		result, err := db.Migrate(dbPath)
+		if err != nil {
+			return err
+		}
+		if len(result.Applied) == 0 {
+			fmt.Printf("Database is up to date (version %d)\n", result.To)
+			return nil
+		}
+		if result.Backup != "" {
+			fmt.Printf("Backed up the database to %s\n", result.Backup)
+		}
+		for _, m := range result.Applied {
+			fmt.Printf("✓ Applied %04d_%s\n", m.Version, m.Name)
+		}
+		fmt.Printf("Migrated from version %d to %d\n", result.From, result.To)
+		return nil
+	},
+}
+
+var dbVacuumCmd = &cobra.Command{
+	Use:   "vacuum",
+	Short: "Reclaim the space left by deleted sessions",
+	Long: `Rebuild the research history database to return the space left by
+deleted sessions to the file system.
+
+SQLite reuses the space of deleted rows but does not shrink the file on
+its own. Vacuuming needs free disk space of up to twice the database size
+while it runs.
+
+Examples:
+  copilot-research history delete 12 13
+  copilot-research db vacuum`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dbPath, err := databasePath()
+		if err != nil {
+			return err
+		}
+
+		database, err := openDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		before := databaseSize(dbPath)
+		if err := database.Vacuum(); err != nil {
+			return err
+		}
+		after := databaseSize(dbPath)
+
+		fmt.Printf("✓ Vacuumed %s: %s → %s\n", dbPath, formatBytes(before), formatBytes(after))
+		return nil
+	},
+}
+
+var dbDriversCmd = &cobra.Command{
+	Use:   "drivers",
+	Short: "List the storage drivers compiled into this build",
+	Long: `List the storage drivers the research history database can use in this
+build, marking the one in use. Choose a driver with database.driver in
+~/.copilot-research/config.yaml; without it the default is used.
+
+The sqlite driver needs cgo. Builds without cgo use the pure-Go
+sqlite-purego driver, compiled in with -tags purego.
+
+Examples:
+  copilot-research db drivers`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configured := ""
+		if AppConfig != nil {
+			configured = AppConfig.Database.Driver
+		}
+		fmt.Print(formatDrivers(db.Drivers(), db.DefaultDriver(), configured))
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(dbCmd)
+	dbCmd.AddCommand(dbMigrateCmd)
+	dbCmd.AddCommand(dbVacuumCmd)
+	dbCmd.AddCommand(dbDriversCmd)
+
+	dbMigrateCmd.Flags().BoolVar(&dbMigrateStatus, "status", false, "report the schema version and pending migrations without migrating")
+}
+
+// formatMigrationStatus describes a database's schema version
+func formatMigrationStatus(path string, status *db.MigrationStatus) string {
+	out := fmt.Sprintf("Database: %s\nSchema version: %d (latest: %d)\n", path, status.Version, status.Latest)
+	if len(status.Pending) == 0 {
+		return out + "Up to date\n"
+	}
+	out += fmt.Sprintf("Pending migrations (%d):\n", len(status.Pending))
+	for _, m := range status.Pending {
+		out += fmt.Sprintf("  %04d_%s\n", m.Version, m.Name)
+	}
+	return out
+}
+
+// formatDrivers lists storage drivers, marking the configured one, or the
+// default if none is configured
+func formatDrivers(drivers []string, defaultDriver, configured string) string {
+	if len(drivers) == 0 {
+		return "No storage drivers compiled in: build with cgo enabled, or with -tags purego\n"
+	}
+
+	inUse := configured
+	if inUse == "" {
+		inUse = defaultDriver
+	}
+	out := ""
+	for _, name := range drivers {
+		marker := "  "
+		if name == inUse {
+			marker = "* "
+		}
+		label := name
+		if name == defaultDriver {
+			label += " (default)"
+		}
+		out += marker + label + "\n"
+	}
+	if configured != "" && !slices.Contains(drivers, configured) {
+		out += fmt.Sprintf("Configured driver %q is not compiled into this build\n", configured)
+	}
+	return out
+}
+
+// databaseSize returns the size of a database file and its write-ahead log
+func databaseSize(path string) int64 {
+	var size int64
+	for _, p := range []string{path, path + "-wal"} {
+		if info, err := os.Stat(p); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/joelklabo/copilot-research/internal/config"
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/spf13/cobra"
+)
+
+// newPassphraseEnv holds the new passphrase for db rekey when there is no
+// terminal to prompt on
+const newPassphraseEnv = "COPILOT_RESEARCH_DB_NEW_PASSPHRASE"
+
+var (
+	rekeyKeyFile    string
+	rekeyPassphrase bool
+	rekeyDecrypt    bool
+)
+
+// errNoTerminal is returned when a passphrase is needed but stdin is not a
+// terminal to prompt on
+var errNoTerminal = errors.New("no terminal to prompt for a passphrase on")
+
+// promptPassphrase prompts for a passphrase on the terminal without echoing
+// it
+var promptPassphrase = func(prompt string) ([]byte, error) {
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		return nil, errNoTerminal
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(os.Stdin.Fd())
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return passphrase, nil
+}
+
+var dbRekeyCmd = &cobra.Command{
+	Use:   "rekey",
+	Short: "Encrypt the research database, or change or remove its key",
+	Long: `Encrypt the queries and answers in the research history with a new key,
+replacing the current key if the database is already encrypted. The
+database is unlocked with the key configured in database.encryption, and
+the configuration is updated to the new key.
+
+With --key-file the key is read from a file, which is created with a random
+key if it does not exist. Keep a copy: the history cannot be read without
+it. With --passphrase the key is derived from a passphrase, prompted for
+twice, or read from $` + newPassphraseEnv + ` without a terminal.
+Afterwards the passphrase is read from the variable in
+database.encryption.passphrase_env, or prompted for.
+
+--decrypt removes the encryption.
+
+Examples:
+  copilot-research db rekey --passphrase
+  copilot-research db rekey --key-file ~/.copilot-research/db.key
+  copilot-research db rekey --decrypt`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if AppConfig == nil {
+			return fmt.Errorf("no configuration loaded to record the new key in")
+		}
+		chosen := 0
+		for _, set := range []bool{rekeyKeyFile != "", rekeyPassphrase, rekeyDecrypt} {
+			if set {
+				chosen++
+			}
+		}
+		if chosen != 1 {
+			return fmt.Errorf("choose one of --key-file, --passphrase or --decrypt")
+		}
+
+		// Unlock with the current key before asking for the new one
+		database, err := openDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		var key *db.Key
+		switch {
+		case rekeyKeyFile != "":
+			if rekeyKeyFile, err = filepath.Abs(expandHome(rekeyKeyFile)); err != nil {
+				return fmt.Errorf("failed to resolve key file: %w", err)
+			}
+			if key, err = ensureKeyFile(rekeyKeyFile); err != nil {
+				return err
+			}
+		case rekeyPassphrase:
+			if key, err = newPassphrase(); err != nil {
+				return err
+			}
+		}
+
+		if err := database.Rekey(key); err != nil {
+			return err
+		}
+		// Scrub the old values from the free pages and the write-ahead log
+		if err := database.Vacuum(); err != nil {
+			return err
+		}
+
+		encryption := &AppConfig.Database.Encryption
+		encryption.Enabled = key != nil
+		if key != nil {
+			encryption.KeySource = key.Source
+			encryption.KeyFile = rekeyKeyFile
+		}
+		if err := config.SaveConfig(CfgFile, AppConfig); err != nil {
+			return fmt.Errorf("failed to update config: %w", err)
+		}
+
+		if key == nil {
+			fmt.Println("✓ Decrypted the research database")
+		} else {
+			fmt.Printf("✓ Encrypted the research database with a %s\n", strings.ReplaceAll(key.Source, "_", " "))
+		}
+		fmt.Printf("Updated database.encryption in %s\n", CfgFile)
+
+		if dbPath, err := databasePath(); err == nil {
+			if backups, _ := filepath.Glob(dbPath + ".v*.bak"); len(backups) > 0 && key != nil {
+				fmt.Println("Backups taken before schema migrations are not encrypted; delete them if they are no longer needed:")
+				for _, b := range backups {
+					fmt.Printf("  %s\n", b)
+				}
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	dbCmd.AddCommand(dbRekeyCmd)
+
+	dbRekeyCmd.Flags().StringVar(&rekeyKeyFile, "key-file", "", "encrypt with the key in this file, created if missing")
+	dbRekeyCmd.Flags().BoolVar(&rekeyPassphrase, "passphrase", false, "encrypt with a key derived from a passphrase")
+	dbRekeyCmd.Flags().BoolVar(&rekeyDecrypt, "decrypt", false, "remove the encryption")
+}
+
+// databaseKey returns the key of the research database configured in cfg,
+// or nil if encryption is off
+func databaseKey(cfg config.EncryptionConfig) (*db.Key, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	switch cfg.KeySource {
+	case "", db.KeyPassphrase:
+		if cfg.PassphraseEnv != "" {
+			if passphrase := os.Getenv(cfg.PassphraseEnv); passphrase != "" {
+				return &db.Key{Source: db.KeyPassphrase, Secret: []byte(passphrase)}, nil
+			}
+		}
+		passphrase, err := promptPassphrase("Database passphrase: ")
+		if errors.Is(err, errNoTerminal) {
+			if cfg.PassphraseEnv == "" {
+				return nil, fmt.Errorf("database encryption key missing: set database.encryption.passphrase_env, or run in a terminal")
+			}
+			return nil, fmt.Errorf("database encryption key missing: set $%s to the passphrase", cfg.PassphraseEnv)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &db.Key{Source: db.KeyPassphrase, Secret: passphrase}, nil
+	case db.KeyFile:
+		if cfg.KeyFile == "" {
+			return nil, fmt.Errorf("database encryption key missing: set database.encryption.key_file")
+		}
+		secret, err := readKeyFile(expandHome(cfg.KeyFile))
+		if err != nil {
+			return nil, err
+		}
+		return &db.Key{Source: db.KeyFile, Secret: secret}, nil
+	default:
+		return nil, fmt.Errorf("unknown database.encryption.key_source %q (use %s or %s)", cfg.KeySource, db.KeyPassphrase, db.KeyFile)
+	}
+}
+
+// readKeyFile reads the secret in a key file
+func readKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("database encryption key missing: key file %s not found", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	secret := bytes.TrimSpace(data)
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("database encryption key missing: key file %s is empty", path)
+	}
+	return secret, nil
+}
+
+// ensureKeyFile reads a key file, first writing a random key to it if it
+// does not exist
+func ensureKeyFile(path string) (*db.Key, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		random := make([]byte, 32)
+		rand.Read(random)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, fmt.Errorf("failed to create key file directory: %w", err)
+		}
+		if err := os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(random)+"\n"), 0600); err != nil {
+			return nil, fmt.Errorf("failed to write key file: %w", err)
+		}
+		fmt.Printf("Wrote a new key to %s\n", path)
+	}
+
+	secret, err := readKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &db.Key{Source: db.KeyFile, Secret: secret}, nil
+}
+
+// newPassphrase reads the new passphrase for db rekey
+func newPassphrase() (*db.Key, error) {
+	if passphrase := os.Getenv(newPassphraseEnv); passphrase != "" {
+		return &db.Key{Source: db.KeyPassphrase, Secret: []byte(passphrase)}, nil
+	}
+
+	passphrase, err := promptPassphrase("New database passphrase: ")
+	if errors.Is(err, errNoTerminal) {
+		return nil, fmt.Errorf("no new passphrase: set $%s or run in a terminal", newPassphraseEnv)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("the passphrase cannot be empty")
+	}
+	again, err := promptPassphrase("Repeat the passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(passphrase, again) {
+		return nil, fmt.Errorf("the passphrases do not match")
+	}
+	return &db.Key{Source: db.KeyPassphrase, Secret: passphrase}, nil
+}
+
+// expandHome expands a leading ~ to the home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
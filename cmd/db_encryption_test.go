@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/joelklabo/copilot-research/internal/config"
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubPassphrases answers passphrase prompts with the given replies in turn
+func stubPassphrases(t *testing.T, replies ...string) {
+	original := promptPassphrase
+	t.Cleanup(func() { promptPassphrase = original })
+	promptPassphrase = func(string) ([]byte, error) {
+		if len(replies) == 0 {
+			return nil, errNoTerminal
+		}
+		reply := replies[0]
+		replies = replies[1:]
+		return []byte(reply), nil
+	}
+}
+
+func TestDatabaseKey_Disabled(t *testing.T) {
+	key, err := databaseKey(config.DefaultConfig().Database.Encryption)
+	require.NoError(t, err)
+	assert.Nil(t, key)
+}
+
+func TestDatabaseKey_Passphrase(t *testing.T) {
+	cfg := config.EncryptionConfig{Enabled: true, KeySource: db.KeyPassphrase, PassphraseEnv: "TEST_DB_PASSPHRASE"}
+
+	t.Setenv("TEST_DB_PASSPHRASE", "from env")
+	key, err := databaseKey(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, &db.Key{Source: db.KeyPassphrase, Secret: []byte("from env")}, key)
+
+	t.Setenv("TEST_DB_PASSPHRASE", "")
+	stubPassphrases(t, "typed")
+	key, err = databaseKey(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("typed"), key.Secret)
+
+	_, err = databaseKey(cfg)
+	assert.ErrorContains(t, err, "database encryption key missing: set $TEST_DB_PASSPHRASE")
+}
+
+func TestDatabaseKey_KeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.key")
+	cfg := config.EncryptionConfig{Enabled: true, KeySource: db.KeyFile, KeyFile: path}
+
+	_, err := databaseKey(cfg)
+	assert.ErrorContains(t, err, "key file "+path+" not found")
+
+	require.NoError(t, os.WriteFile(path, []byte("\n"), 0600))
+	_, err = databaseKey(cfg)
+	assert.ErrorContains(t, err, "is empty")
+
+	require.NoError(t, os.WriteFile(path, []byte("s3cret\n"), 0600))
+	key, err := databaseKey(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, &db.Key{Source: db.KeyFile, Secret: []byte("s3cret")}, key)
+
+	_, err = databaseKey(config.EncryptionConfig{Enabled: true, KeySource: db.KeyFile})
+	assert.ErrorContains(t, err, "set database.encryption.key_file")
+
+	_, err = databaseKey(config.EncryptionConfig{Enabled: true, KeySource: "vault"})
+	assert.ErrorContains(t, err, `unknown database.encryption.key_source "vault"`)
+}
+
+func TestEnsureKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "db.key")
+	key, err := ensureKeyFile(path)
+	require.NoError(t, err)
+	assert.Len(t, key.Secret, 44)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// An existing key is kept
+	again, err := ensureKeyFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, key, again)
+}
+
+func TestNewPassphrase(t *testing.T) {
+	stubPassphrases(t, "one", "two")
+	_, err := newPassphrase()
+	assert.ErrorContains(t, err, "do not match")
+
+	stubPassphrases(t, "same", "same")
+	key, err := newPassphrase()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("same"), key.Secret)
+
+	stubPassphrases(t)
+	_, err = newPassphrase()
+	assert.ErrorContains(t, err, newPassphraseEnv)
+
+	t.Setenv(newPassphraseEnv, "from env")
+	key, err = newPassphrase()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("from env"), key.Secret)
+}
+
+func TestExpandHome(t *testing.T) {
+	t.Setenv("HOME", "/home/test")
+	assert.Equal(t, "/home/test/.copilot-research/db.key", expandHome("~/.copilot-research/db.key"))
+	assert.Equal(t, "/etc/db.key", expandHome("/etc/db.key"))
+}
+
+func TestDBRekey(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	oldConfig, oldCfgFile := AppConfig, CfgFile
+	AppConfig, CfgFile = config.DefaultConfig(), filepath.Join(home, "config.yaml")
+	defer func() {
+		AppConfig, CfgFile = oldConfig, oldCfgFile
+		rekeyKeyFile, rekeyPassphrase, rekeyDecrypt = "", false, false
+	}()
+
+	database, err := openDatabase()
+	require.NoError(t, err)
+	session := &db.ResearchSession{Query: "Confidential roadmap", Mode: "quick", PromptUsed: "default", Result: "Answer"}
+	require.NoError(t, database.SaveSession(session))
+	database.Close()
+
+	keyPath := filepath.Join(home, "db.key")
+	rekeyKeyFile = keyPath
+	require.NoError(t, dbRekeyCmd.RunE(dbRekeyCmd, nil))
+
+	saved, err := config.LoadConfig(CfgFile)
+	require.NoError(t, err)
+	assert.Equal(t, config.EncryptionConfig{Enabled: true, KeySource: db.KeyFile, KeyFile: keyPath, PassphraseEnv: "COPILOT_RESEARCH_DB_PASSPHRASE"}, saved.Database.Encryption)
+
+	// Readable with the configured key, and not without it
+	database, err = openDatabase()
+	require.NoError(t, err)
+	got, err := database.GetSession(session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Confidential roadmap", got.Query)
+	database.Close()
+
+	AppConfig.Database.Encryption.Enabled = false
+	_, err = openDatabase()
+	assert.ErrorIs(t, err, db.ErrKeyRequired)
+	assert.ErrorContains(t, err, "enable database.encryption in "+CfgFile)
+	AppConfig.Database.Encryption.Enabled = true
+
+	rekeyKeyFile, rekeyDecrypt = "", true
+	require.NoError(t, dbRekeyCmd.RunE(dbRekeyCmd, nil))
+	assert.False(t, AppConfig.Database.Encryption.Enabled)
+	database, err = openDatabase()
+	require.NoError(t, err)
+	database.Close()
+
+	rekeyPassphrase = true
+	assert.ErrorContains(t, dbRekeyCmd.RunE(dbRekeyCmd, nil), "choose one of")
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDBMigrateCommand(t *testing.T) {
+	assert.NotNil(t, dbMigrateCmd.RunE)
+	assert.NotNil(t, dbMigrateCmd.Flags().Lookup("status"))
+}
+
+func TestFormatMigrationStatus(t *testing.T) {
+	upToDate := formatMigrationStatus("/tmp/research.db", &db.MigrationStatus{Version: 2, Latest: 2})
+	assert.Equal(t, "Database: /tmp/research.db\nSchema version: 2 (latest: 2)\nUp to date\n", upToDate)
+
+	pending := formatMigrationStatus("/tmp/research.db", &db.MigrationStatus{
+		Version: 0,
+		Latest:  2,
+		Pending: []db.Migration{{Version: 1, Name: "baseline"}, {Version: 2, Name: "session_run_metadata"}},
+	})
+	assert.Contains(t, pending, "Schema version: 0 (latest: 2)\n")
+	assert.Contains(t, pending, "Pending migrations (2):\n  0001_baseline\n  0002_session_run_metadata\n")
+}
+
+func TestDBVacuumCommand(t *testing.T) {
+	assert.NotNil(t, dbVacuumCmd.RunE)
+	assert.Equal(t, "vacuum", dbVacuumCmd.Use)
+}
+
+func TestFormatDrivers(t *testing.T) {
+	drivers := []string{db.DriverSQLite, db.DriverSQLitePureGo}
+	assert.Equal(t, "* sqlite (default)\n  sqlite-purego\n", formatDrivers(drivers, db.DriverSQLite, ""))
+	assert.Equal(t, "  sqlite (default)\n* sqlite-purego\n", formatDrivers(drivers, db.DriverSQLite, db.DriverSQLitePureGo))
+
+	missing := formatDrivers([]string{db.DriverSQLite}, db.DriverSQLite, db.DriverSQLitePureGo)
+	assert.Contains(t, missing, `Configured driver "sqlite-purego" is not compiled into this build`)
+
+	assert.Contains(t, formatDrivers(nil, "", ""), "No storage drivers compiled in")
+}
+
+func TestDatabaseSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "research.db")
+	assert.Zero(t, databaseSize(path))
+
+	assert.NoError(t, os.WriteFile(path, make([]byte, 100), 0644))
+	assert.NoError(t, os.WriteFile(path+"-wal", make([]byte, 20), 0644))
+	assert.Equal(t, int64(120), databaseSize(path))
+}
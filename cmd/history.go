@@ -2,11 +2,15 @@ package cmd
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/charmbracelet/lipgloss"
+	"github.com/joelklabo/copilot-research/internal/config"
 	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/joelklabo/copilot-research/internal/research"
+	"github.com/joelklabo/copilot-research/internal/ui"
 	"github.com/spf13/cobra"
 )
 
@@ -16,46 +20,117 @@ var (
 	historySessionID   int64
 	historyClearAll    bool
 	historyLimitNum    int
+	historyMatrix      string
+	historyIncomplete  bool
+	historyProvider    string
+	historySince       string
+	historyUntil       string
+	historyTags        []string
+	historyStarred     bool
+	historyFailed      bool
 )
 
+// highlightStyle marks the matched terms in search excerpts
+var highlightStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("214"))
+
 // researchHistoryCmd represents the research history command
 var researchHistoryCmd = &cobra.Command{
 	Use:   "history",
 	Short: "View past research sessions",
 	Long: `View and manage your research history.
 
-List recent sessions, search by query, filter by mode, or clear history.
+List recent sessions, search them, filter by mode, provider, date, tag or
+star, or clear history. Organize sessions with the tag, star, note and rate
+subcommands.
+
+--search looks through the queries and answers of past sessions. Plain
+words must all appear; use "quoted phrases", AND, OR, NOT, parentheses
+and prefix* for more control. Results are ranked by relevance and show
+the matching excerpt.
+
+--tag can be repeated; sessions must carry every tag given.
+
+--failed lists research runs that failed instead of sessions, with their
+errors. --search then matches part of the query, and --mode, --provider,
+--since and --until apply as usual.
+
+--since and --until take a date (2026-03-01), a date and time in RFC 3339
+form, or an age such as 12h, 7d or 2w. A date given to --until includes
+that whole day.
 
 Examples:
   copilot-research history
   copilot-research history --search "Swift"
+  copilot-research history --search '"structured concurrency" AND swift NOT kotlin'
+  copilot-research history --search context --mode deep --since 30d
+  copilot-research history --provider openai --since 2026-03-01 --until 2026-03-31
   copilot-research history --mode deep
+  copilot-research history --tag golang --starred
   copilot-research history --id 123
+  copilot-research history --id 123 --matrix csv
+  copilot-research history --id 123 --template adr -o decision.md
+  copilot-research history --incomplete
+  copilot-research history --failed --since 7d
   copilot-research history --clear`,
 	RunE: runHistory,
 }
 
+var historyDeleteCmd = &cobra.Command{
+	Use:   "delete <id>...",
+	Short: "Delete research sessions",
+	Long: `Delete one or more research sessions by ID.
+
+Follow-up sessions and watches that point at a deleted session are kept
+and lose the reference. Run "copilot-research db vacuum" afterwards to
+reclaim the disk space.
+
+Examples:
+  copilot-research history delete 12
+  copilot-research history delete 12 13 14`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ids := make([]int64, len(args))
+		for i, arg := range args {
+			id, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil || id <= 0 {
+				return fmt.Errorf("invalid session ID: %s", arg)
+			}
+			ids[i] = id
+		}
+		
+		database, err := openDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+		
+		return handleDeleteSessions(database, ids)
+	},
+}
+
 func init() {
 	RootCmd.AddCommand(researchHistoryCmd)
+	researchHistoryCmd.AddCommand(historyDeleteCmd)
 	
 	researchHistoryCmd.Flags().StringVarP(&historySearchQuery, "search", "s", "", "search for query text")
 	researchHistoryCmd.Flags().StringVarP(&historyFilterMode, "mode", "m", "", "filter by mode")
 	researchHistoryCmd.Flags().Int64VarP(&historySessionID, "id", "", 0, "show specific session")
 	researchHistoryCmd.Flags().BoolVarP(&historyClearAll, "clear", "c", false, "clear all history")
 	researchHistoryCmd.Flags().IntVarP(&historyLimitNum, "limit", "n", 20, "limit number of results")
+	researchHistoryCmd.Flags().BoolVar(&historyIncomplete, "incomplete", false, "show only interrupted or cancelled sessions that can be resumed")
+	researchHistoryCmd.Flags().StringVar(&historyMatrix, "matrix", "", "with --id, print only the comparison matrix as markdown, json or csv")
+	researchHistoryCmd.Flags().StringVar(&historyProvider, "provider", "", "filter by provider")
+	researchHistoryCmd.Flags().StringVar(&historySince, "since", "", "only sessions from this date or age on (e.g. 2026-03-01, 7d)")
+	researchHistoryCmd.Flags().StringVar(&historyUntil, "until", "", "only sessions before this date or age (e.g. 2026-03-31, 1d)")
+	researchHistoryCmd.Flags().StringSliceVar(&historyTags, "tag", nil, "only sessions with this tag (repeatable)")
+	researchHistoryCmd.Flags().BoolVar(&historyStarred, "starred", false, "only starred sessions")
+	researchHistoryCmd.Flags().BoolVar(&historyFailed, "failed", false, "list failed research runs instead of sessions")
 }
 
 func runHistory(cmd *cobra.Command, args []string) error {
-	// Open database
-	home, err := os.UserHomeDir()
+	database, err := openDatabase()
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
-	}
-	
-dbPath := filepath.Join(home, ".copilot-research", "research.db")
-database, err := db.NewSQLiteDB(dbPath)
-	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
+		return err
 	}
 	defer database.Close()
 	
@@ -66,11 +141,97 @@ database, err := db.NewSQLiteDB(dbPath)
 	
 	// Handle show specific session
 	if historySessionID > 0 {
+		if historyMatrix != "" {
+			return handleShowMatrix(database, historySessionID, historyMatrix)
+		}
+		if ReportTemplate != "" || OutputFormat != "" {
+			return handleRenderSession(database, historySessionID)
+		}
 		return handleShowSession(database, historySessionID)
 	}
+	if historyMatrix != "" {
+		return fmt.Errorf("--matrix requires --id")
+	}
 	
 	// List sessions with filters
-	return handleListSessions(database, historySearchQuery, historyFilterMode, historyLimitNum)
+	filter, err := historyFilter(time.Now())
+	if err != nil {
+		return err
+	}
+	if historyFailed {
+		if len(filter.Tags) > 0 || filter.Starred || filter.Resumable {
+			return fmt.Errorf("--failed cannot be combined with --tag, --starred or --incomplete")
+		}
+		return handleListFailed(database, failedFilter(filter))
+	}
+	return handleListSessions(database, filter)
+}
+
+// historyFilter builds the session filter from the history flags
+func historyFilter(now time.Time) (db.SessionFilter, error) {
+	filter := db.SessionFilter{
+		Text:      historySearchQuery,
+		Mode:      historyFilterMode,
+		Provider:  historyProvider,
+		Resumable: historyIncomplete,
+		Tags:      historyTags,
+		Starred:   historyStarred,
+		Limit:     historyLimitNum,
+	}
+	for _, tag := range filter.Tags {
+		if _, err := db.NormalizeTag(tag); err != nil {
+			return filter, fmt.Errorf("invalid --tag: %w", err)
+		}
+	}
+	
+	var err error
+	if historySince != "" {
+		if filter.Since, err = parseHistoryTime(historySince, now, false); err != nil {
+			return filter, fmt.Errorf("invalid --since: %w", err)
+		}
+	}
+	if historyUntil != "" {
+		if filter.Until, err = parseHistoryTime(historyUntil, now, true); err != nil {
+			return filter, fmt.Errorf("invalid --until: %w", err)
+		}
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Since.Before(filter.Until) {
+		return filter, fmt.Errorf("--since must be before --until")
+	}
+	return filter, nil
+}
+
+// failedFilter selects the failed runs in the search log matching a session
+// filter
+func failedFilter(filter db.SessionFilter) db.SearchFilter {
+	return db.SearchFilter{
+		Text:     filter.Text,
+		Outcome:  db.OutcomeFailed,
+		Mode:     filter.Mode,
+		Provider: filter.Provider,
+		Since:    filter.Since,
+		Until:    filter.Until,
+		Limit:    filter.Limit,
+	}
+}
+
+// parseHistoryTime parses a date, an RFC 3339 time or an age such as 7d.
+// With endOfDay, a date means the end of that day.
+func parseHistoryTime(s string, now time.Time, endOfDay bool) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	if age, err := parseInterval(s); err == nil {
+		return now.Add(-age), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date (2006-01-02), a time (RFC 3339) or an age (e.g. 12h, 7d)", s)
 }
 
 func handleClearHistory(database db.DB) error {
@@ -87,8 +248,51 @@ func handleClearHistory(database db.DB) error {
 		return nil
 	}
 	
-	// TODO: Implement clear all sessions in database
-	fmt.Println("✓ History cleared")
+	deleted, err := database.ClearAll()
+	if err != nil {
+		return fmt.Errorf("failed to clear history: %w", err)
+	}
+	fmt.Printf("✓ History cleared (%d sessions deleted)\n", deleted)
+	fmt.Println("Reclaim the disk space with: copilot-research db vacuum")
+	return nil
+}
+
+// applyRetention deletes the sessions the history retention policy no
+// longer keeps and returns how many were deleted
+func applyRetention(database db.DB, policy config.HistoryConfig, now time.Time) (int64, error) {
+	var removed int64
+	if policy.MaxAge > 0 {
+		n, err := database.DeleteSessionsBefore(now.Add(-policy.MaxAge))
+		if err != nil {
+			return removed, fmt.Errorf("failed to apply history retention: %w", err)
+		}
+		removed += n
+	}
+	if policy.MaxSessions > 0 {
+		n, err := database.KeepLatestSessions(policy.MaxSessions)
+		if err != nil {
+			return removed, fmt.Errorf("failed to apply history retention: %w", err)
+		}
+		removed += n
+	}
+	return removed, nil
+}
+
+// handleDeleteSessions deletes sessions, continuing past IDs that do not
+// exist
+func handleDeleteSessions(database db.DB, ids []int64) error {
+	failed := 0
+	for _, id := range ids {
+		if err := database.DeleteSession(id); err != nil {
+			fmt.Printf("✗ %v\n", err)
+			failed++
+			continue
+		}
+		fmt.Printf("✓ Deleted session #%d\n", id)
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d of %d sessions", failed, len(ids))
+	}
 	return nil
 }
 
@@ -103,42 +307,126 @@ func handleShowSession(database db.DB, id int64) error {
 	fmt.Printf("Session #%d\n", session.ID)
 	fmt.Println(strings.Repeat("═", 60))
 	fmt.Printf("Query: %s\n", session.Query)
+	if session.RefinedQuery != "" {
+		fmt.Printf("Refined: %s\n", session.RefinedQuery)
+	}
 	fmt.Printf("Mode: %s\n", session.Mode)
 	fmt.Printf("Date: %s\n", session.CreatedAt.Format("2006-01-02 15:04:05"))
+	if session.Provider != "" {
+		model := ""
+		if session.Model != "" {
+			model = " (" + session.Model + ")"
+		}
+		fmt.Printf("Provider: %s%s\n", session.Provider, model)
+	}
+	if session.Tokens.Total > 0 {
+		fmt.Printf("Tokens: %d\n", session.Tokens.Total)
+	}
+	if session.ParentID != 0 {
+		fmt.Printf("Follows: session #%d\n", session.ParentID)
+	}
+	if session.QualityScore != nil {
+		fmt.Printf("Quality: %d/100\n", *session.QualityScore)
+	}
+	if session.UserRating != nil {
+		fmt.Printf("Rating: %s\n", ui.RatingStars(*session.UserRating))
+	}
+	if session.Starred {
+		fmt.Println("Starred: ★")
+	}
+	if len(session.Tags) > 0 {
+		fmt.Printf("Tags: %s\n", formatTags(session.Tags))
+	}
+	if session.InProgress() {
+		stage := "nothing"
+		if session.Checkpoint != nil {
+			stage = session.Checkpoint.Stage
+		}
+		fmt.Printf("Status: ⏸ incomplete (stopped after %s; resume with: copilot-research resume %d)\n", stage, session.ID)
+	}
+	if session.Cancelled() {
+		fmt.Printf("Status: ✕ cancelled (partial output; resume with: copilot-research resume %d)\n", session.ID)
+	}
 	fmt.Println()
 	fmt.Println("Result:")
 	fmt.Println(strings.Repeat("─", 60))
 	fmt.Println(session.Result)
 	fmt.Println()
 	
+	if session.Notes != "" {
+		fmt.Println("Notes:")
+		fmt.Println(strings.Repeat("─", 60))
+		fmt.Println(session.Notes)
+		fmt.Println()
+	}
+	
+	if session.Critique != "" {
+		fmt.Println("Critique:")
+		fmt.Println(strings.Repeat("─", 60))
+		fmt.Println(session.Critique)
+		fmt.Println()
+	}
+	
+	if len(session.Citations) > 0 {
+		fmt.Println("Sources:")
+		fmt.Println(strings.Repeat("─", 60))
+		for _, c := range session.Citations {
+			ref := c.URL
+			if ref == "" {
+				ref = c.Text
+			}
+			line := fmt.Sprintf("[%s] %s", c.Status, ref)
+			if c.Note != "" {
+				line += " - " + c.Note
+			}
+			fmt.Println(line)
+		}
+		fmt.Println()
+	}
+	
 	return nil
 }
 
-func handleListSessions(database db.DB, search, mode string, limit int) error {
-	var sessions []*db.ResearchSession
-	var err error
+func handleShowMatrix(database db.DB, id int64, format string) error {
+	session, err := database.GetSession(id)
+	if err != nil {
+		return fmt.Errorf("session not found: %w", err)
+	}
+	if session.Matrix == nil {
+		return fmt.Errorf("session #%d has no comparison matrix", id)
+	}
 	
-	if search != "" {
-		// Search sessions
-		sessions, err = database.SearchSessions(search)
-	} else {
-		// List all sessions
-		sessions, err = database.ListSessions(limit, 0)
+	output, err := research.RenderMatrix(session.Matrix, format)
+	if err != nil {
+		return err
 	}
 	
+	fmt.Println(output)
+	return nil
+}
+
+// handleRenderSession renders a stored session with --template or --format
+func handleRenderSession(database db.DB, id int64) error {
+	session, err := database.GetSession(id)
 	if err != nil {
-		return fmt.Errorf("failed to get sessions: %w", err)
+		return fmt.Errorf("session not found: %w", err)
 	}
 	
-	// Filter by mode if specified
-	if mode != "" {
-		filtered := []*db.ResearchSession{}
-		for _, s := range sessions {
-			if s.Mode == mode {
-				filtered = append(filtered, s)
-			}
-		}
-		sessions = filtered
+	output, err := renderDocument(sessionDocument(session))
+	if err != nil {
+		return err
+	}
+	
+	if err := writeOutput(OutputFile, output); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+func handleListSessions(database db.DB, filter db.SessionFilter) error {
+	sessions, err := database.FindSessions(filter)
+	if err != nil {
+		return fmt.Errorf("failed to get sessions: %w", err)
 	}
 	
 	if len(sessions) == 0 {
@@ -153,26 +441,109 @@ func handleListSessions(database db.DB, search, mode string, limit int) error {
 	fmt.Printf("% -5s % -12s % -50s % -10s\n", "ID", "Date", "Query", "Mode")
 	fmt.Println(strings.Repeat("─", 80))
 	
+	incomplete := 0
 	for _, session := range sessions {
 		dateStr := session.CreatedAt.Format("2006-01-02")
 		queryStr := truncateString(session.Query, 48)
+		modeStr := session.Mode
+		if session.InProgress() {
+			queryStr = truncateString("⏸ "+session.Query, 48)
+			modeStr += " (incomplete)"
+			incomplete++
+		}
+		if session.Cancelled() {
+			queryStr = truncateString("✕ "+session.Query, 48)
+			modeStr += " (cancelled)"
+			incomplete++
+		}
+		if session.Starred {
+			queryStr = truncateString("★ "+queryStr, 48)
+		}
 		fmt.Printf("% -5d % -12s % -50s % -10s\n",
 			session.ID,
 			dateStr,
 			queryStr,
-			session.Mode,
+			modeStr,
 		)
+		if len(session.Tags) > 0 {
+			fmt.Printf("      %s\n", formatTags(session.Tags))
+		}
+		if session.Snippet != "" {
+			fmt.Printf("      %s\n", formatSnippet(session.Snippet))
+		}
 	}
 	
 	fmt.Println(strings.Repeat("═", 80))
 	fmt.Printf("Total: %d sessions\n", len(sessions))
 	fmt.Println()
 	fmt.Println("View details: copilot-research history --id <ID>")
+	if incomplete > 0 {
+		fmt.Printf("⏸ %d unfinished; continue with: copilot-research resume <ID>\n", incomplete)
+	}
 	fmt.Println()
 	
 	return nil
 }
 
+// handleListFailed lists failed research runs with their errors
+func handleListFailed(database db.DB, filter db.SearchFilter) error {
+	entries, err := database.ListSearches(filter)
+	if err != nil {
+		return fmt.Errorf("failed to get failed runs: %w", err)
+	}
+	
+	if len(entries) == 0 {
+		fmt.Println("No failed research runs found.")
+		return nil
+	}
+	
+	fmt.Println()
+	fmt.Println("Failed Research")
+	fmt.Println(strings.Repeat("═", 80))
+	fmt.Printf("% -18s % -50s % -10s\n", "Date", "Query", "Mode")
+	fmt.Println(strings.Repeat("─", 80))
+	
+	for _, e := range entries {
+		fmt.Printf("% -18s % -50s % -10s\n",
+			e.CreatedAt.Format("2006-01-02 15:04"),
+			truncateString(oneLine(e.Query), 48),
+			e.Mode,
+		)
+		if e.Error != "" {
+			fmt.Printf("      ✗ %s\n", truncateString(oneLine(e.Error), 72))
+		}
+		if e.SessionID != 0 {
+			fmt.Printf("      progress saved as session #%d\n", e.SessionID)
+		}
+	}
+	
+	fmt.Println(strings.Repeat("═", 80))
+	fmt.Printf("Total: %d failed runs\n", len(entries))
+	fmt.Println()
+	fmt.Println("Run a query again: copilot-research history recall")
+	fmt.Println()
+	
+	return nil
+}
+
+// formatSnippet puts a search excerpt on one line with the matched terms
+// highlighted
+func formatSnippet(snippet string) string {
+	snippet = strings.Join(strings.Fields(snippet), " ")
+	var b strings.Builder
+	for {
+		before, rest, found := strings.Cut(snippet, db.HighlightStart)
+		b.WriteString(before)
+		if !found {
+			break
+		}
+		term, after, _ := strings.Cut(rest, db.HighlightEnd)
+		b.WriteString(highlightStyle.Render(term))
+		snippet = after
+	}
+	return b.String()
+}
+
 // formatSessionSummary formats a session summary for display
 func formatSessionSummary(id int64, query, mode, date string) string {
 	queryStr := truncateString(query, 48)
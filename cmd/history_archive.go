@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joelklabo/copilot-research/internal/archive"
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/spf13/cobra"
+)
+
+// historyExportCmd represents the history export command
+var historyExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export research sessions to an archive",
+	Long: `Export research sessions, to move them to another machine or share
+them with teammates.
+
+The default format is JSONL: one session per line with all its metadata,
+written to --output or stdout. With --format markdown, or when --output
+is an existing directory, each session is written to its own markdown file
+in the --output directory, with the answer as the body and the metadata
+as YAML frontmatter.
+
+The --search, --mode, --provider, --since, --until, --tag and --starred
+filters work as they do for history. Sessions are exported oldest first.
+
+Examples:
+  copilot-research history export -o history.jsonl
+  copilot-research history export --mode deep --since 30d > recent.jsonl
+  copilot-research history export --search kafka --format markdown -o kafka-notes/`,
+	Args: cobra.NoArgs,
+	RunE: runHistoryExport,
+}
+
+// historyImportCmd represents the history import command
+var historyImportCmd = &cobra.Command{
+	Use:   "import <archive>...",
+	Short: "Import research sessions from an archive",
+	Long: `Merge sessions exported with "history export" into your history. An
+archive is a JSONL file ("-" for stdin), a markdown file or a directory of
+markdown files.
+
+Sessions already in your history are skipped, matched by a hash of their
+query, mode, answer and timestamp, so importing the same archive twice
+changes nothing. Imported sessions get new IDs and keep their original
+timestamps.
+
+Examples:
+  copilot-research history import history.jsonl
+  copilot-research history import kafka-notes/
+  ssh laptop copilot-research history export | copilot-research history import -`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runHistoryImport,
+}
+
+func init() {
+	researchHistoryCmd.AddCommand(historyExportCmd)
+	researchHistoryCmd.AddCommand(historyImportCmd)
+
+	historyExportCmd.Flags().StringVarP(&historySearchQuery, "search", "s", "", "only sessions matching a search")
+	historyExportCmd.Flags().StringVarP(&historyFilterMode, "mode", "m", "", "only sessions of a mode")
+	historyExportCmd.Flags().StringVar(&historyProvider, "provider", "", "only sessions answered by a provider")
+	historyExportCmd.Flags().StringVar(&historySince, "since", "", "only sessions from this date or age on (e.g. 2026-03-01, 7d)")
+	historyExportCmd.Flags().StringVar(&historyUntil, "until", "", "only sessions before this date or age (e.g. 2026-03-31, 1d)")
+	historyExportCmd.Flags().StringSliceVar(&historyTags, "tag", nil, "only sessions with this tag (repeatable)")
+	historyExportCmd.Flags().BoolVar(&historyStarred, "starred", false, "only starred sessions")
+}
+
+func runHistoryExport(cmd *cobra.Command, args []string) error {
+	format, err := exportFormat(OutputFormat, OutputFile)
+	if err != nil {
+		return err
+	}
+	if format == archive.FormatMarkdown && OutputFile == "" {
+		return fmt.Errorf("markdown export needs a directory: use --output <dir>")
+	}
+
+	filter, err := historyFilter(time.Now())
+	if err != nil {
+		return err
+	}
+	filter.Limit = 0
+
+	database, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	sessions, err := exportSessions(database, filter)
+	if err != nil {
+		return err
+	}
+
+	if format == archive.FormatMarkdown {
+		if _, err := archive.WriteMarkdown(OutputFile, sessions); err != nil {
+			return err
+		}
+	} else if OutputFile == "" {
+		if err := archive.WriteJSONL(os.Stdout, sessions); err != nil {
+			return err
+		}
+	} else {
+		f, err := os.Create(OutputFile)
+		if err != nil {
+			return fmt.Errorf("failed to create archive: %w", err)
+		}
+		if err := archive.WriteJSONL(f, sessions); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+	}
+
+	if OutputFile != "" && !Quiet {
+		fmt.Fprintf(os.Stderr, "✓ Exported %d sessions to %s\n", len(sessions), OutputFile)
+	}
+	return nil
+}
+
+// exportFormat picks the archive format from --format and --output
+func exportFormat(format, output string) (string, error) {
+	switch strings.ToLower(format) {
+	case "jsonl":
+		return archive.FormatJSONL, nil
+	case "markdown", "md":
+		return archive.FormatMarkdown, nil
+	case "":
+		if output == "" {
+			return archive.FormatJSONL, nil
+		}
+		if info, err := os.Stat(output); (err == nil && info.IsDir()) || strings.HasSuffix(output, string(filepath.Separator)) {
+			return archive.FormatMarkdown, nil
+		}
+		return archive.FormatJSONL, nil
+	default:
+		return "", fmt.Errorf("unsupported export format: %s (use jsonl or markdown)", format)
+	}
+}
+
+// exportSessions returns the sessions matching a filter, oldest first so
+// that follow-ups come after the sessions they follow
+func exportSessions(database db.DB, filter db.SessionFilter) ([]*db.ResearchSession, error) {
+	matches, err := database.FindSessions(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	sessions := make([]*db.ResearchSession, len(matches))
+	for i, m := range matches {
+		sessions[i] = m.ResearchSession
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		if !sessions[i].CreatedAt.Equal(sessions[j].CreatedAt) {
+			return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+	return sessions, nil
+}
+
+func runHistoryImport(cmd *cobra.Command, args []string) error {
+	var sessions []*db.ResearchSession
+	for _, path := range args {
+		var (
+			found []*db.ResearchSession
+			err   error
+		)
+		if path == "-" {
+			found, err = archive.ReadJSONL(os.Stdin)
+		} else {
+			found, err = archive.Read(path)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to import %s: %w", path, err)
+		}
+		sessions = append(sessions, found...)
+	}
+
+	database, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	result, err := database.ImportSessions(sessions)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Imported %d sessions", result.Imported)
+	if result.Skipped > 0 {
+		fmt.Printf(" (%d already in your history)", result.Skipped)
+	}
+	fmt.Println()
+	return nil
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/joelklabo/copilot-research/internal/archive"
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportFormat(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		format, output, want string
+	}{
+		{"", "", archive.FormatJSONL},
+		{"", "history.jsonl", archive.FormatJSONL},
+		{"", dir, archive.FormatMarkdown},
+		{"", "notes" + string(filepath.Separator), archive.FormatMarkdown},
+		{"md", "notes", archive.FormatMarkdown},
+		{"JSONL", dir, archive.FormatJSONL},
+	}
+	for _, tt := range tests {
+		got, err := exportFormat(tt.format, tt.output)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got, "format %q, output %q", tt.format, tt.output)
+	}
+
+	_, err := exportFormat("html", "")
+	assert.ErrorContains(t, err, "unsupported export format: html")
+}
+
+func TestExportSessions_OldestFirst(t *testing.T) {
+	now := time.Now()
+	database := &db.MockDB{
+		FindSessionsFunc: func(filter db.SessionFilter) ([]*db.SessionMatch, error) {
+			return []*db.SessionMatch{
+				{ResearchSession: &db.ResearchSession{ID: 3, CreatedAt: now}},
+				{ResearchSession: &db.ResearchSession{ID: 1, CreatedAt: now.Add(-time.Hour)}},
+				{ResearchSession: &db.ResearchSession{ID: 2, CreatedAt: now}},
+			}, nil
+		},
+	}
+
+	sessions, err := exportSessions(database, db.SessionFilter{})
+	require.NoError(t, err)
+	require.Len(t, sessions, 3)
+	assert.Equal(t, []int64{1, 2, 3}, []int64{sessions[0].ID, sessions[1].ID, sessions[2].ID})
+}
+
+func TestHistoryExportImport(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	defer func() { OutputFile, OutputFormat, historyFilterMode = "", "", "" }()
+
+	database, err := openDatabase()
+	require.NoError(t, err)
+	createdAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, mode := range []string{"quick", "deep", "deep"} {
+		session := &db.ResearchSession{Query: "q", Mode: mode, PromptUsed: "default", Result: mode + string(rune('a'+i)), CreatedAt: createdAt.Add(time.Duration(i) * time.Hour)}
+		require.NoError(t, database.SaveSession(session))
+	}
+	database.Close()
+
+	jsonl := filepath.Join(t.TempDir(), "history.jsonl")
+	OutputFile = jsonl
+	historyFilterMode = "deep"
+	require.NoError(t, runHistoryExport(historyExportCmd, nil))
+	sessions, err := archive.Read(jsonl)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 2)
+
+	mdDir := filepath.Join(t.TempDir(), "md")
+	OutputFile, OutputFormat, historyFilterMode = mdDir, "markdown", ""
+	require.NoError(t, runHistoryExport(historyExportCmd, nil))
+	files, err := os.ReadDir(mdDir)
+	require.NoError(t, err)
+	assert.Len(t, files, 3)
+
+	OutputFile = ""
+	assert.ErrorContains(t, runHistoryExport(historyExportCmd, nil), "needs a directory")
+
+	// Move to a new machine: everything is imported once
+	t.Setenv("HOME", t.TempDir())
+	require.NoError(t, runHistoryImport(historyImportCmd, []string{mdDir}))
+	require.NoError(t, runHistoryImport(historyImportCmd, []string{jsonl, mdDir}))
+
+	database, err = openDatabase()
+	require.NoError(t, err)
+	defer database.Close()
+	total, err := database.GetTotalSessions()
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+	latest, err := database.GetLatestSession("q", "deep")
+	require.NoError(t, err)
+	assert.True(t, createdAt.Add(2*time.Hour).Equal(latest.CreatedAt), "timestamps are preserved")
+
+	assert.ErrorContains(t, runHistoryImport(historyImportCmd, []string{"missing.jsonl"}), "failed to import missing.jsonl")
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/joelklabo/copilot-research/internal/diff"
+	"github.com/joelklabo/copilot-research/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyDiffTUI     bool
+	historyDiffSummary bool
+)
+
+// historyDiffCmd represents the history diff command
+var historyDiffCmd = &cobra.Command{
+	Use:   "diff <id1> <id2>",
+	Short: "Compare the answers of two research sessions",
+	Long: `Compare the answers of two research sessions, typically runs of the same
+query before and after a model upgrade or a prompt change.
+
+Sections are matched by heading, ignoring numbering and emphasis, and the
+paragraphs under each heading are diffed. The output is markdown listing each
+section as added, removed, edited or unchanged.
+
+Examples:
+  copilot-research history diff 12 31
+  copilot-research history diff 12 31 --tui
+  copilot-research history diff 12 31 --summary -o changes.md`,
+	Args: cobra.ExactArgs(2),
+	RunE: runHistoryDiff,
+}
+
+func init() {
+	researchHistoryCmd.AddCommand(historyDiffCmd)
+
+	historyDiffCmd.Flags().BoolVar(&historyDiffTUI, "tui", false, "show the sections side by side in an interactive view")
+	historyDiffCmd.Flags().BoolVar(&historyDiffSummary, "summary", false, "ask the provider to summarize the changes in meaning")
+	historyDiffCmd.Flags().DurationVar(&researchTimeout, "timeout", 0, "cancel the summary if it takes longer than this, e.g. 2m (default: no limit)")
+}
+
+func runHistoryDiff(cmd *cobra.Command, args []string) error {
+	ids := make([]int64, len(args))
+	for i, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || id <= 0 {
+			return fmt.Errorf("invalid session ID: %s", arg)
+		}
+		ids[i] = id
+	}
+
+	database, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	oldSession, err := database.GetSession(ids[0])
+	if err != nil {
+		return fmt.Errorf("session not found: %w", err)
+	}
+	newSession, err := database.GetSession(ids[1])
+	if err != nil {
+		return fmt.Errorf("session not found: %w", err)
+	}
+
+	if !strings.EqualFold(strings.TrimSpace(oldSession.Query), strings.TrimSpace(newSession.Query)) {
+		fmt.Fprintf(os.Stderr, "Warning: sessions #%d and #%d answer different queries\n", oldSession.ID, newSession.ID)
+	}
+
+	result := diff.Sections(oldSession.Result, newSession.Result)
+
+	var summary string
+	if historyDiffSummary {
+		engine, err := newResearchEngine(database)
+		if err != nil {
+			return err
+		}
+		ctx, stop := researchContext()
+		defer stop()
+		summary, err = engine.SummarizeChanges(ctx, newSession.Query, oldSession.Result, newSession.Result)
+		if err != nil {
+			if ctx.Err() != nil {
+				err = interrupted(ctx, err)
+			}
+			return fmt.Errorf("failed to summarize changes: %w", err)
+		}
+	}
+
+	if historyDiffTUI {
+		model := ui.NewDiffModel(sessionLabel(oldSession), sessionLabel(newSession), result, summary)
+		if _, err := tea.NewProgram(model, tea.WithAltScreen()).Run(); err != nil {
+			return fmt.Errorf("UI error: %w", err)
+		}
+		return nil
+	}
+
+	if err := writeOutput(OutputFile, formatSessionDiff(oldSession, newSession, result, summary)); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// sessionLabel identifies a session by ID and date
+func sessionLabel(session *db.ResearchSession) string {
+	return fmt.Sprintf("session #%d (%s)", session.ID, session.CreatedAt.Format("2006-01-02"))
+}
+
+// formatSessionDiff renders a section diff of two sessions as markdown
+func formatSessionDiff(oldSession, newSession *db.ResearchSession, result *diff.SectionResult, summary string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Diff: %s → %s\n\n", sessionLabel(oldSession), sessionLabel(newSession))
+	fmt.Fprintf(&b, "Query: %s\n", newSession.Query)
+	if oldSession.Mode != newSession.Mode || oldSession.PromptUsed != newSession.PromptUsed {
+		fmt.Fprintf(&b, "Mode: %s/%s → %s/%s\n", oldSession.Mode, oldSession.PromptUsed, newSession.Mode, newSession.PromptUsed)
+	}
+	b.WriteString("\n")
+	b.WriteString(result.Markdown())
+	if summary != "" {
+		fmt.Fprintf(&b, "\n\n## Semantic Changes\n\n%s", summary)
+	}
+	return b.String()
+}
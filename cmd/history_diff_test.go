@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/joelklabo/copilot-research/internal/diff"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryDiffCommand(t *testing.T) {
+	assert.NotNil(t, historyDiffCmd)
+	assert.Contains(t, historyDiffCmd.Use, "diff")
+	assert.True(t, historyDiffCmd.HasParent())
+
+	assert.Error(t, historyDiffCmd.Args(historyDiffCmd, []string{"1"}))
+	assert.NoError(t, historyDiffCmd.Args(historyDiffCmd, []string{"1", "2"}))
+	assert.NotNil(t, historyDiffCmd.Flags().Lookup("tui"))
+	assert.NotNil(t, historyDiffCmd.Flags().Lookup("summary"))
+}
+
+func TestFormatSessionDiff(t *testing.T) {
+	date := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	oldSession := &db.ResearchSession{ID: 1, Query: "Go CLIs", Mode: "quick", PromptUsed: "default", CreatedAt: date}
+	newSession := &db.ResearchSession{ID: 2, Query: "Go CLIs", Mode: "quick", PromptUsed: "v2", CreatedAt: date}
+	result := diff.Sections("# Tools\n\nUse flag.", "# Tools\n\nUse cobra.")
+
+	out := formatSessionDiff(oldSession, newSession, result, "- Recommends cobra instead of flag")
+
+	assert.Contains(t, out, "# Diff: session #1 (2026-03-01) → session #2 (2026-03-01)")
+	assert.Contains(t, out, "Mode: quick/default → quick/v2")
+	assert.Contains(t, out, "## Tools _(edited)_")
+	assert.Contains(t, out, "- Use flag.\n+ Use cobra.")
+	assert.Contains(t, out, "## Semantic Changes\n\n- Recommends cobra instead of flag")
+}
+
+func TestRunHistoryDiff(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	err := runHistoryDiff(historyDiffCmd, []string{"1", "x"})
+	assert.ErrorContains(t, err, "invalid session ID")
+
+	database, err := openDatabase()
+	require.NoError(t, err)
+	first := &db.ResearchSession{Query: "q", Mode: "quick", PromptUsed: "default", Result: "# A\n\nold text"}
+	second := &db.ResearchSession{Query: "q", Mode: "quick", PromptUsed: "default", Result: "# A\n\nnew text"}
+	require.NoError(t, database.SaveSession(first))
+	require.NoError(t, database.SaveSession(second))
+	database.Close()
+
+	out := filepath.Join(t.TempDir(), "diff.md")
+	OutputFile = out
+	defer func() { OutputFile = "" }()
+
+	require.NoError(t, runHistoryDiff(historyDiffCmd, []string{"1", "2"}))
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "- old text\n+ new text")
+
+	err = runHistoryDiff(historyDiffCmd, []string{"1", "99"})
+	assert.ErrorContains(t, err, "session not found")
+}
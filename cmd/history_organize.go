@@ -0,0 +1,296 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/joelklabo/copilot-research/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyUnstar    bool
+	historyNoteClear bool
+)
+
+// historyTagCmd represents the history tag command
+var historyTagCmd = &cobra.Command{
+	Use:   "tag <id> [+tag|-tag]...",
+	Short: "Add or remove tags on a research session",
+	Long: `Tag a research session to find it again later with history --tag.
+
+Arguments starting with + (or without a prefix) add a tag, arguments
+starting with - remove one. Without tags, the session's current tags are
+printed. Tags are lowercase letters, digits and . _ / -.
+
+Examples:
+  copilot-research history tag 12 +golang +concurrency
+  copilot-research history tag 12 -concurrency
+  copilot-research history tag 12`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseSessionID(args[0])
+		if err != nil {
+			return err
+		}
+
+		database, err := openDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		return handleTagSession(database, id, args[1:])
+	},
+}
+
+// historyStarCmd represents the history star command
+var historyStarCmd = &cobra.Command{
+	Use:   "star <id>...",
+	Short: "Star research sessions",
+	Long: `Star research sessions you want to keep at hand, and list them with
+history --starred. Use --remove to unstar.
+
+Examples:
+  copilot-research history star 12 14
+  copilot-research history star 12 --remove`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ids := make([]int64, len(args))
+		for i, arg := range args {
+			id, err := parseSessionID(arg)
+			if err != nil {
+				return err
+			}
+			ids[i] = id
+		}
+
+		database, err := openDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		return handleStarSessions(database, ids, !historyUnstar)
+	},
+}
+
+// historyNoteCmd represents the history note command
+var historyNoteCmd = &cobra.Command{
+	Use:   "note <id> [text...]",
+	Short: "Write notes on a research session",
+	Long: `Attach free-form notes to a research session, shown with history --id.
+
+The text replaces any existing notes. Without text, the notes are opened in
+$EDITOR. Use --clear to remove them.
+
+Examples:
+  copilot-research history note 12 "Verified against the 1.22 release notes"
+  copilot-research history note 12
+  copilot-research history note 12 --clear`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseSessionID(args[0])
+		if err != nil {
+			return err
+		}
+
+		database, err := openDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		if historyNoteClear {
+			return handleSetNotes(database, id, "")
+		}
+		if len(args) > 1 {
+			return handleSetNotes(database, id, strings.Join(args[1:], " "))
+		}
+
+		session, err := database.GetSession(id)
+		if err != nil {
+			return err
+		}
+		notes, err := openEditor(session.Notes)
+		if err != nil {
+			return fmt.Errorf("failed to open editor: %w", err)
+		}
+		if strings.TrimSpace(notes) == strings.TrimSpace(session.Notes) {
+			return fmt.Errorf("no changes made, aborting")
+		}
+		return handleSetNotes(database, id, notes)
+	},
+}
+
+// historyRateCmd represents the history rate command
+var historyRateCmd = &cobra.Command{
+	Use:   "rate <id> <1-5>",
+	Short: "Rate a research session",
+	Long: `Rate how useful a research session was, from 1 (useless) to 5 (excellent).
+Rating a session again replaces its earlier rating. Completed research in
+the interactive view can also be rated with the 1 to 5 keys.
+
+Ratings are aggregated per prompt, mode and provider. "stats" shows the
+aggregates, and research suggests the best rated prompt for its mode.
+
+Examples:
+  copilot-research history rate 12 5
+  copilot-research history rate 14 2`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseSessionID(args[0])
+		if err != nil {
+			return err
+		}
+		rating, err := strconv.Atoi(args[1])
+		if err != nil || rating < db.MinRating || rating > db.MaxRating {
+			return fmt.Errorf("invalid rating: %s (use %d to %d)", args[1], db.MinRating, db.MaxRating)
+		}
+
+		database, err := openDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		return handleRateSession(database, id, rating)
+	},
+}
+
+func init() {
+	researchHistoryCmd.AddCommand(historyRateCmd)
+	researchHistoryCmd.AddCommand(historyTagCmd)
+	researchHistoryCmd.AddCommand(historyStarCmd)
+	researchHistoryCmd.AddCommand(historyNoteCmd)
+
+	// Stop parsing flags at the session ID so that -tag is an argument
+	historyTagCmd.Flags().SetInterspersed(false)
+	historyStarCmd.Flags().BoolVar(&historyUnstar, "remove", false, "unstar the sessions")
+	historyNoteCmd.Flags().BoolVar(&historyNoteClear, "clear", false, "remove the notes")
+}
+
+// parseSessionID parses a session ID argument
+func parseSessionID(arg string) (int64, error) {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid session ID: %s", arg)
+	}
+	return id, nil
+}
+
+// splitTagChanges sorts +tag and -tag arguments into tags to add and remove
+func splitTagChanges(args []string) (add, remove []string) {
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "-"):
+			remove = append(remove, arg[1:])
+		case strings.HasPrefix(arg, "+"):
+			add = append(add, arg[1:])
+		default:
+			add = append(add, arg)
+		}
+	}
+	return add, remove
+}
+
+// handleTagSession applies tag changes to a session and prints its tags
+func handleTagSession(database db.DB, id int64, args []string) error {
+	add, remove := splitTagChanges(args)
+	tags, err := database.UpdateTags(id, add, remove)
+	if err != nil {
+		return err
+	}
+
+	if len(tags) == 0 {
+		fmt.Printf("Session #%d has no tags\n", id)
+		return nil
+	}
+	if len(args) > 0 {
+		fmt.Printf("✓ Session #%d tagged: %s\n", id, formatTags(tags))
+	} else {
+		fmt.Printf("Session #%d: %s\n", id, formatTags(tags))
+	}
+	return nil
+}
+
+// handleRateSession rates a session and prints the updated aggregate for
+// its prompt, mode and provider
+func handleRateSession(database db.DB, id int64, rating int) error {
+	pattern, err := database.RateSession(id, rating)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Rated session #%d %s\n", id, ui.RatingStars(rating))
+	if pattern != nil {
+		fmt.Printf("  %s: %s\n", patternLabel(pattern), formatAverageRating(pattern))
+	}
+	return nil
+}
+
+// patternLabel describes the prompt, mode and provider a rating pattern
+// aggregates
+func patternLabel(p *db.LearnedPattern) string {
+	label := fmt.Sprintf("%s prompt in %s mode", p.PromptUsed, p.Mode)
+	if p.Provider != "" {
+		label += " via " + p.Provider
+	}
+	return label
+}
+
+// formatAverageRating formats the mean rating of a pattern
+func formatAverageRating(p *db.LearnedPattern) string {
+	noun := "ratings"
+	if p.RatingCount == 1 {
+		noun = "rating"
+	}
+	return fmt.Sprintf("%.1f/5 from %d %s", p.AverageRating(), p.RatingCount, noun)
+}
+
+// handleStarSessions stars or unstars sessions, continuing past IDs that do
+// not exist
+func handleStarSessions(database db.DB, ids []int64, starred bool) error {
+	verb := "Starred"
+	if !starred {
+		verb = "Unstarred"
+	}
+
+	failed := 0
+	for _, id := range ids {
+		if err := database.SetStarred(id, starred); err != nil {
+			fmt.Printf("✗ %v\n", err)
+			failed++
+			continue
+		}
+		fmt.Printf("✓ %s session #%d\n", verb, id)
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to update %d of %d sessions", failed, len(ids))
+	}
+	return nil
+}
+
+// handleSetNotes replaces the notes on a session
+func handleSetNotes(database db.DB, id int64, notes string) error {
+	if err := database.SetNotes(id, notes); err != nil {
+		return err
+	}
+	if strings.TrimSpace(notes) == "" {
+		fmt.Printf("✓ Removed notes from session #%d\n", id)
+	} else {
+		fmt.Printf("✓ Saved notes on session #%d\n", id)
+	}
+	return nil
+}
+
+// formatTags formats tags as #tag #other
+func formatTags(tags []string) string {
+	formatted := make([]string, len(tags))
+	for i, tag := range tags {
+		formatted[i] = "#" + tag
+	}
+	return strings.Join(formatted, " ")
+}
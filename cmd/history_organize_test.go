@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitTagChanges(t *testing.T) {
+	add, remove := splitTagChanges([]string{"+go", "-rust", "db"})
+	assert.Equal(t, []string{"go", "db"}, add)
+	assert.Equal(t, []string{"rust"}, remove)
+
+	add, remove = splitTagChanges(nil)
+	assert.Empty(t, add)
+	assert.Empty(t, remove)
+}
+
+func TestHistoryTagCommand_TakesDashArguments(t *testing.T) {
+	require.NoError(t, historyTagCmd.ParseFlags([]string{"12", "+go", "-rust"}))
+	assert.Equal(t, []string{"12", "+go", "-rust"}, historyTagCmd.Flags().Args())
+}
+
+func TestHandleTagSession(t *testing.T) {
+	var gotAdd, gotRemove []string
+	database := &db.MockDB{
+		UpdateTagsFunc: func(id int64, add, remove []string) ([]string, error) {
+			if id == 99 {
+				return nil, fmt.Errorf("session not found: %d", id)
+			}
+			gotAdd, gotRemove = add, remove
+			return []string{"go"}, nil
+		},
+	}
+
+	require.NoError(t, handleTagSession(database, 1, []string{"+go", "-rust"}))
+	assert.Equal(t, []string{"go"}, gotAdd)
+	assert.Equal(t, []string{"rust"}, gotRemove)
+
+	assert.ErrorContains(t, handleTagSession(database, 99, nil), "session not found: 99")
+}
+
+func TestHandleStarSessions(t *testing.T) {
+	starred := map[int64]bool{}
+	database := &db.MockDB{
+		SetStarredFunc: func(id int64, star bool) error {
+			if id == 99 {
+				return fmt.Errorf("session not found: %d", id)
+			}
+			starred[id] = star
+			return nil
+		},
+	}
+
+	require.NoError(t, handleStarSessions(database, []int64{1, 2}, true))
+	err := handleStarSessions(database, []int64{99, 2}, false)
+	assert.ErrorContains(t, err, "failed to update 1 of 2 sessions")
+	assert.Equal(t, map[int64]bool{1: true, 2: false}, starred)
+}
+
+func TestHandleRateSession(t *testing.T) {
+	database := &db.MockDB{
+		RateSessionFunc: func(id int64, rating int) (*db.LearnedPattern, error) {
+			if id == 99 {
+				return nil, fmt.Errorf("session not found: %d", id)
+			}
+			return &db.LearnedPattern{PromptUsed: "default", Mode: "deep", Provider: "openai", RatingCount: 2, RatingTotal: 9}, nil
+		},
+	}
+
+	require.NoError(t, handleRateSession(database, 1, 5))
+	assert.ErrorContains(t, handleRateSession(database, 99, 5), "session not found: 99")
+}
+
+func TestPatternLabel(t *testing.T) {
+	p := &db.LearnedPattern{PromptUsed: "default", Mode: "deep", Provider: "openai", RatingCount: 2, RatingTotal: 9}
+	assert.Equal(t, "default prompt in deep mode via openai", patternLabel(p))
+	assert.Equal(t, "4.5/5 from 2 ratings", formatAverageRating(p))
+
+	p = &db.LearnedPattern{PromptUsed: "default", Mode: "quick", RatingCount: 1, RatingTotal: 3}
+	assert.Equal(t, "default prompt in quick mode", patternLabel(p))
+	assert.Equal(t, "3.0/5 from 1 rating", formatAverageRating(p))
+}
+
+func TestHistoryRateCommand_ValidatesArgs(t *testing.T) {
+	assert.Error(t, historyRateCmd.Args(historyRateCmd, []string{"12"}))
+	assert.ErrorContains(t, historyRateCmd.RunE(historyRateCmd, []string{"12", "6"}), "invalid rating: 6")
+	assert.ErrorContains(t, historyRateCmd.RunE(historyRateCmd, []string{"12", "good"}), "invalid rating: good")
+	assert.ErrorContains(t, historyRateCmd.RunE(historyRateCmd, []string{"x", "3"}), "invalid session ID: x")
+}
+
+func TestHistoryOrganizeCommands(t *testing.T) {
+	for _, cmd := range []string{"tag", "star", "note", "rate"} {
+		found, _, err := researchHistoryCmd.Find([]string{cmd})
+		require.NoError(t, err)
+		assert.Equal(t, cmd, found.Name())
+	}
+	assert.NotNil(t, historyStarCmd.Flags().Lookup("remove"))
+	assert.NotNil(t, historyNoteCmd.Flags().Lookup("clear"))
+	assert.ErrorContains(t, historyNoteCmd.RunE(historyNoteCmd, []string{"abc"}), "invalid session ID: abc")
+}
+
+func TestFormatTags(t *testing.T) {
+	assert.Equal(t, "#go #db", formatTags([]string{"go", "db"}))
+	assert.Empty(t, formatTags(nil))
+}
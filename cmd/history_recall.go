@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	recallLimit int
+	recallRun   int
+)
+
+// historyRecallCmd represents the history recall command
+var historyRecallCmd = &cobra.Command{
+	Use:   "recall [text]",
+	Short: "Recall past queries, like a shell's history",
+	Long: `List the queries you researched, most recent first and each once, including
+runs that failed or were cancelled. With text, only queries containing it
+are listed.
+
+--run N researches the Nth query of the list again (1 is the most recent),
+with the mode and prompt it last ran with unless -m or -p say otherwise.
+With -q only the queries are printed, one per line, for piping into other
+tools.
+
+Examples:
+  copilot-research history recall
+  copilot-research history recall kafka
+  copilot-research history recall --run 1
+  copilot-research history recall kafka --run 2 -m deep
+  copilot-research history recall -q | fzf`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runHistoryRecall,
+}
+
+func init() {
+	researchHistoryCmd.AddCommand(historyRecallCmd)
+
+	historyRecallCmd.Flags().IntVarP(&recallLimit, "limit", "n", 20, "limit number of queries")
+	historyRecallCmd.Flags().IntVar(&recallRun, "run", 0, "research the Nth listed query again")
+}
+
+func runHistoryRecall(cmd *cobra.Command, args []string) error {
+	filter := db.SearchFilter{Distinct: true, Limit: recallLimit}
+	if len(args) > 0 {
+		filter.Text = args[0]
+	}
+	if recallRun > 0 && filter.Limit > 0 && recallRun > filter.Limit {
+		filter.Limit = recallRun
+	}
+
+	database, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	entries, err := recallQueries(database, filter)
+	database.Close()
+	if err != nil {
+		return err
+	}
+
+	if recallRun == 0 {
+		printRecall(entries)
+		return nil
+	}
+	if recallRun < 0 || recallRun > len(entries) {
+		return fmt.Errorf("no query #%d to run: %d recalled", recallRun, len(entries))
+	}
+
+	entry := entries[recallRun-1]
+	if !cmd.Flags().Changed("mode") && entry.Mode != "" {
+		Mode = entry.Mode
+	}
+	if !cmd.Flags().Changed("prompt") && entry.PromptUsed != "" {
+		PromptName = entry.PromptUsed
+	}
+	return runResearch(cmd, []string{entry.Query})
+}
+
+// recallQueries returns the latest run of each past query matching a filter
+func recallQueries(database db.DB, filter db.SearchFilter) ([]*db.SearchHistory, error) {
+	entries, err := database.ListSearches(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recall queries: %w", err)
+	}
+	return entries, nil
+}
+
+// printRecall lists recalled queries, numbered for --run
+func printRecall(entries []*db.SearchHistory) {
+	if Quiet {
+		for _, e := range entries {
+			fmt.Println(e.Query)
+		}
+		return
+	}
+	if len(entries) == 0 {
+		fmt.Println("No queries to recall.")
+		return
+	}
+
+	for i, e := range entries {
+		marker := ""
+		switch e.Outcome {
+		case db.OutcomeFailed:
+			marker = " ✗"
+		case db.OutcomeCancelled:
+			marker = " ✕"
+		}
+		fmt.Printf("%4d  %-10s %s  %s%s\n", i+1, e.Mode, e.CreatedAt.Format("2006-01-02"), oneLine(e.Query), marker)
+	}
+}
+
+// oneLine collapses whitespace, including newlines, into single spaces
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecallQueries_PassesFilter(t *testing.T) {
+	var got db.SearchFilter
+	database := &db.MockDB{
+		ListSearchesFunc: func(filter db.SearchFilter) ([]*db.SearchHistory, error) {
+			got = filter
+			return []*db.SearchHistory{{Query: "q"}}, nil
+		},
+	}
+
+	entries, err := recallQueries(database, db.SearchFilter{Text: "kafka", Distinct: true, Limit: 5})
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, db.SearchFilter{Text: "kafka", Distinct: true, Limit: 5}, got)
+}
+
+func TestHistoryRecall(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	defer func() { recallLimit, recallRun = 20, 0 }()
+
+	database, err := openDatabase()
+	require.NoError(t, err)
+	base := time.Now().Add(-time.Hour)
+	for i, q := range []string{"Go contexts", "Kafka vs NATS", "go contexts"} {
+		require.NoError(t, database.LogSearch(&db.SearchHistory{Query: q, Mode: "deep", CreatedAt: base.Add(time.Duration(i) * time.Minute)}))
+	}
+	database.Close()
+
+	require.NoError(t, runHistoryRecall(historyRecallCmd, []string{"context"}))
+
+	recallRun = 3
+	assert.ErrorContains(t, runHistoryRecall(historyRecallCmd, nil), "no query #3 to run: 2 recalled")
+}
+
+func TestOneLine(t *testing.T) {
+	assert.Equal(t, "a multi line query", oneLine("  a multi\nline\tquery "))
+}
@@ -1,9 +1,15 @@
 package cmd
 
 import (
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/joelklabo/copilot-research/internal/config"
+	"github.com/joelklabo/copilot-research/internal/db"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHistoryCommand(t *testing.T) {
@@ -13,7 +19,7 @@ func TestHistoryCommand(t *testing.T) {
 }
 
 func TestHistoryCommand_Flags(t *testing.T) {
-	flags := []string{"search", "mode", "id", "clear", "limit"}
+	flags := []string{"search", "mode", "id", "clear", "limit", "matrix", "incomplete", "provider", "since", "until", "tag", "starred", "failed"}
 	
 	for _, flagName := range flags {
 		t.Run(flagName, func(t *testing.T) {
@@ -92,3 +98,172 @@ func TestValidateClearConfirmation(t *testing.T) {
 		})
 	}
 }
+
+func TestParseHistoryTime(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	
+	got, err := parseHistoryTime("7d", now, false)
+	require.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, -7), got)
+	
+	got, err = parseHistoryTime("2026-03-01", now, false)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local), got)
+	
+	got, err = parseHistoryTime("2026-03-01", now, true)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local), got)
+	
+	got, err = parseHistoryTime("2026-03-01T08:30:00Z", now, true)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 1, 8, 30, 0, 0, time.UTC), got)
+	
+	_, err = parseHistoryTime("last tuesday", now, false)
+	assert.ErrorContains(t, err, "is not a date")
+}
+
+func TestHistoryFilter(t *testing.T) {
+	defer func() {
+		historySearchQuery, historyFilterMode, historyProvider = "", "", ""
+		historySince, historyUntil = "", ""
+		historyTags, historyStarred = nil, false
+	}()
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	
+	historySearchQuery, historyFilterMode, historyProvider = "context", "deep", "openai"
+	historySince, historyUntil = "30d", "1d"
+	filter, err := historyFilter(now)
+	require.NoError(t, err)
+	assert.Equal(t, "context", filter.Text)
+	assert.Equal(t, "deep", filter.Mode)
+	assert.Equal(t, "openai", filter.Provider)
+	assert.Equal(t, now.AddDate(0, 0, -30), filter.Since)
+	assert.Equal(t, now.AddDate(0, 0, -1), filter.Until)
+	
+	historySince, historyUntil = "1d", "30d"
+	_, err = historyFilter(now)
+	assert.ErrorContains(t, err, "--since must be before --until")
+	
+	historySince, historyUntil = "soon", ""
+	_, err = historyFilter(now)
+	assert.ErrorContains(t, err, "invalid --since")
+	
+	historySince = ""
+	historyTags, historyStarred = []string{"go", "db"}, true
+	filter, err = historyFilter(now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go", "db"}, filter.Tags)
+	assert.True(t, filter.Starred)
+	
+	historyTags = []string{"two words"}
+	_, err = historyFilter(now)
+	assert.ErrorContains(t, err, "invalid --tag")
+}
+
+func TestHandleListSessions_PassesFilter(t *testing.T) {
+	var got db.SessionFilter
+	database := &db.MockDB{
+		FindSessionsFunc: func(filter db.SessionFilter) ([]*db.SessionMatch, error) {
+			got = filter
+			return nil, nil
+		},
+	}
+	
+	filter := db.SessionFilter{Text: "nats", Mode: "compare", Resumable: true, Limit: 5}
+	require.NoError(t, handleListSessions(database, filter))
+	assert.Equal(t, filter, got)
+}
+
+func TestFailedFilter(t *testing.T) {
+	since := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	filter := failedFilter(db.SessionFilter{Text: "kafka", Mode: "deep", Provider: "openai", Since: since, Limit: 10})
+	assert.Equal(t, db.SearchFilter{Text: "kafka", Outcome: db.OutcomeFailed, Mode: "deep", Provider: "openai", Since: since, Limit: 10}, filter)
+}
+
+func TestHandleListFailed(t *testing.T) {
+	var got db.SearchFilter
+	database := &db.MockDB{
+		ListSearchesFunc: func(filter db.SearchFilter) ([]*db.SearchHistory, error) {
+			got = filter
+			return []*db.SearchHistory{
+				{Query: "Rust async", Mode: "deep", Outcome: db.OutcomeFailed, Error: "provider query failed: timeout", SessionID: 4, CreatedAt: time.Now()},
+			}, nil
+		},
+	}
+	
+	filter := db.SearchFilter{Outcome: db.OutcomeFailed, Limit: 20}
+	require.NoError(t, handleListFailed(database, filter))
+	assert.Equal(t, filter, got)
+}
+
+func TestHistory_FailedRejectsSessionFilters(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	defer func() { historyFailed, historyStarred = false, false }()
+	
+	historyFailed, historyStarred = true, true
+	assert.ErrorContains(t, runHistory(researchHistoryCmd, nil), "--failed cannot be combined")
+}
+
+func TestFormatSnippet(t *testing.T) {
+	snippet := "…the " + db.HighlightStart + "context" + db.HighlightEnd + " is\ncancelled…"
+	out := formatSnippet(snippet)
+	assert.Contains(t, out, "context")
+	assert.NotContains(t, out, db.HighlightStart)
+	assert.NotContains(t, out, db.HighlightEnd)
+	assert.False(t, strings.Contains(out, "\n"))
+	assert.Equal(t, "…the "+highlightStyle.Render("context")+" is cancelled…", out)
+}
+
+func TestHandleDeleteSessions(t *testing.T) {
+	var deleted []int64
+	database := &db.MockDB{
+		DeleteSessionFunc: func(id int64) error {
+			if id == 99 {
+				return fmt.Errorf("session not found: %d", id)
+			}
+			deleted = append(deleted, id)
+			return nil
+		},
+	}
+	
+	require.NoError(t, handleDeleteSessions(database, []int64{1, 2}))
+	assert.Equal(t, []int64{1, 2}, deleted)
+	
+	err := handleDeleteSessions(database, []int64{3, 99})
+	assert.ErrorContains(t, err, "failed to delete 1 of 2 sessions")
+	assert.Equal(t, []int64{1, 2, 3}, deleted, "later IDs are still deleted")
+}
+
+func TestApplyRetention(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	var before time.Time
+	var keep int
+	database := &db.MockDB{
+		DeleteSessionsBeforeFunc: func(t time.Time) (int64, error) {
+			before = t
+			return 2, nil
+		},
+		KeepLatestSessionsFunc: func(n int) (int64, error) {
+			keep = n
+			return 1, nil
+		},
+	}
+	
+	removed, err := applyRetention(database, config.HistoryConfig{}, now)
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+	assert.True(t, before.IsZero(), "no policy deletes nothing")
+	assert.Zero(t, keep)
+	
+	removed, err = applyRetention(database, config.HistoryConfig{MaxAge: 30 * 24 * time.Hour, MaxSessions: 100}, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), removed)
+	assert.Equal(t, now.AddDate(0, 0, -30), before)
+	assert.Equal(t, 100, keep)
+}
+
+func TestHistoryDeleteCommand(t *testing.T) {
+	assert.Equal(t, researchHistoryCmd, historyDeleteCmd.Parent())
+	assert.Error(t, historyDeleteCmd.Args(historyDeleteCmd, nil))
+	assert.ErrorContains(t, historyDeleteCmd.RunE(historyDeleteCmd, []string{"abc"}), "invalid session ID: abc")
+}
@@ -3,24 +3,44 @@ package cmd
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/joelklabo/copilot-research/internal/index"
+	"github.com/joelklabo/copilot-research/internal/knowledge"
 	"github.com/joelklabo/copilot-research/internal/prompts"
 	"github.com/joelklabo/copilot-research/internal/provider"
+	"github.com/joelklabo/copilot-research/internal/render"
 	"github.com/joelklabo/copilot-research/internal/research"
 	"github.com/joelklabo/copilot-research/internal/ui"
 	"github.com/spf13/cobra"
 )
 
 var (
-	inputFile string
+	inputFile    string
+	contextPaths []string
+	contextTopK  int
+	verifyLinks  bool
+	
+	critique          bool
+	critiqueThreshold int
+	
+	progressJSON    bool
+	refineQuery     bool
+	researchTimeout time.Duration
+	
+	compareOptions  []string
+	compareCriteria []string
+	matrixFormat    string
 )
 
 // researchCmd represents the research command
@@ -37,7 +57,16 @@ The query can be provided as:
 Examples:
   copilot-research "How do Swift actors work?"
   copilot-research "Compare React and Vue" --mode compare
+  copilot-research "Which ORM?" --mode compare --option GORM --option sqlc --option ent -o orms.csv
   copilot-research --input query.txt --output report.md
+  copilot-research "Go error handling" --quiet -o report.html
+  copilot-research "Go error handling" --quiet --format org
+  copilot-research "Swift 6 migration guide" --verify-links
+  copilot-research "Explain Go generics" --mode deep --critique
+  copilot-research "go logging" --refine
+  copilot-research "Explain Go generics" --mode deep --timeout 5m
+  copilot-research "Swift concurrency" --mode synthesis --prompt synthesis
+  copilot-research "How does our retry logic work?" --context ./docs --context "internal/**/*.go"
   echo "Explain Swift concurrency" | copilot-research --quiet`,
 	RunE: runResearch,
 }
@@ -47,6 +76,17 @@ func init() {
 	
 	// Command-specific flags
 	researchCmd.Flags().StringVarP(&inputFile, "input", "i", "", "input file containing query")
+	researchCmd.Flags().StringArrayVar(&contextPaths, "context", nil, "ground the answer in local files (path, directory or glob; repeatable)")
+	researchCmd.Flags().IntVar(&contextTopK, "context-top", 5, "number of local excerpts to include with --context")
+	researchCmd.Flags().BoolVar(&verifyLinks, "verify-links", false, "check that cited links resolve")
+	researchCmd.Flags().BoolVar(&refineQuery, "refine", false, "expand the query into a precise research brief first, asking clarifying questions in the interactive UI")
+	researchCmd.Flags().BoolVar(&critique, "critique", false, "grade the answer against a rubric and revise it once if it scores low")
+	researchCmd.Flags().BoolVar(&progressJSON, "progress-json", false, "write progress events to stderr as newline-delimited JSON (implies --quiet)")
+	researchCmd.Flags().IntVar(&critiqueThreshold, "critique-threshold", research.DefaultCritiqueThreshold, "score (0-100) below which --critique revises the answer")
+	researchCmd.Flags().StringArrayVar(&compareOptions, "option", nil, "an option to compare in compare mode (repeatable; parsed from the query if omitted)")
+	researchCmd.Flags().StringSliceVar(&compareCriteria, "criteria", nil, "comma-separated criteria to score compared options on")
+	researchCmd.Flags().DurationVar(&researchTimeout, "timeout", 0, "cancel the research if it takes longer than this, e.g. 5m (default: no limit)")
+	researchCmd.Flags().StringVar(&matrixFormat, "matrix-format", "", "comparison matrix format: markdown, json or csv (default: inferred from --output and --json)")
 }
 
 func runResearch(cmd *cobra.Command, args []string) error {
@@ -64,24 +104,214 @@ func runResearch(cmd *cobra.Command, args []string) error {
 	if err := validateMode(Mode); err != nil {
 		return err
 	}
+	if len(compareOptions) > 0 && Mode != "compare" {
+		return fmt.Errorf("--option requires --mode compare")
+	}
+	if _, err := outputRenderer(); err != nil {
+		return err
+	}
 	
 	// Initialize database
+	database, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+	
+	// Initialize research engine
+	engine, err := newResearchEngine(database)
+	if err != nil {
+		return err
+	}
+	if verifyLinks {
+		engine.SetLinkVerifier(research.NewLinkVerifier(nil, 10*time.Second))
+	}
+	if Mode == "synthesis" {
+		attachKnowledge(engine)
+	}
+	
+	opts := research.ResearchOptions{
+		Query:      query,
+		Mode:       Mode,
+		PromptName: PromptName,
+		NoStore:    NoStore,
+		
+		Critique:          critique,
+		CritiqueThreshold: critiqueThreshold,
+		
+		Options:  compareOptions,
+		Criteria: compareCriteria,
+		
+		Refine: refineQuery,
+	}
+	
+	// Retrieve local context
+	if len(contextPaths) > 0 {
+		snippets, err := loadContext(query, contextPaths, contextTopK)
+		if err != nil {
+			return err
+		}
+		opts.Context = snippets
+	}
+	
+	// Run research
+	run := func(ctx context.Context, events chan<- research.Event, clarify research.Clarifier) (*research.ResearchResult, error) {
+		opts := opts
+		opts.Clarify = clarify
+		return engine.Research(ctx, opts, events)
+	}
+	if Quiet || progressJSON {
+		return runQuietResearch(database, run)
+	}
+	
+	if !cmd.Flags().Changed("prompt") {
+		if tip := promptSuggestion(database, opts.Mode, opts.PromptName); tip != "" {
+			fmt.Fprintln(os.Stderr, tip)
+		}
+	}
+	return runInteractiveResearch(database, opts.Query, opts.Mode, run)
+}
+
+// minPromptRatings is how many ratings a prompt needs before it is
+// suggested
+const minPromptRatings = 3
+
+// promptSuggestion returns a tip naming the best rated prompt for a mode,
+// or "" if that is the prompt in use or no prompt has enough ratings
+func promptSuggestion(database db.DB, mode, current string) string {
+	best, err := database.BestPrompt(mode, minPromptRatings)
+	if err != nil || best == nil || best.PromptUsed == current {
+		return ""
+	}
+	return fmt.Sprintf("Tip: the %q prompt is rated %.1f/5 for %s research (%d ratings); try -p %s", best.PromptUsed, best.Average, mode, best.Count, best.PromptUsed)
+}
+
+// researchFunc runs or resumes research, reporting progress on events and
+// asking clarifying questions with clarify when it is not nil
+type researchFunc func(ctx context.Context, events chan<- research.Event, clarify research.Clarifier) (*research.ResearchResult, error)
+
+// attachKnowledge lets synthesis draw on the knowledge base, if there is
+// one. Problems are reported but don't stop the research.
+func attachKnowledge(engine *research.Engine) {
+	dir := GetKnowledgeDir()
+	if _, err := os.Stat(dir); err != nil {
+		return
+	}
+	
+	km, err := knowledge.NewKnowledgeManager(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: knowledge base unavailable: %v\n", err)
+		return
+	}
+	engine.SetKnowledgeSource(knowledge.NewSynthesisSource(km))
+}
+
+// loadContext updates the local document index for paths and returns the
+// excerpts most relevant to query
+func loadContext(query string, paths []string, topK int) ([]research.ContextSnippet, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	
+	indexPath := filepath.Join(home, ".copilot-research", "index.db")
+	if err := os.MkdirAll(filepath.Dir(indexPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create index directory: %w", err)
+	}
+	
+	ix, err := index.Open(indexPath)
+	if err != nil {
+		return nil, err
+	}
+	defer ix.Close()
+	
+	stats, err := ix.Update(paths)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index context: %w", err)
+	}
+	if len(stats.Files) == 0 {
+		return nil, fmt.Errorf("no indexable files (.md, .go, .txt) found in context paths")
+	}
+	
+	chunks, err := ix.Search(query, topK, stats.Files)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search context: %w", err)
+	}
+	
+	cwd, _ := os.Getwd()
+	snippets := make([]research.ContextSnippet, 0, len(chunks))
+	for _, c := range chunks {
+		path := c.Path
+		if rel, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+		snippets = append(snippets, research.ContextSnippet{
+			Path:      path,
+			StartLine: c.StartLine,
+			EndLine:   c.EndLine,
+			Content:   c.Content,
+		})
+	}
+	
+	return snippets, nil
+}
+
+// databasePath returns the history database file, creating its directory
+func databasePath() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
+		return "", fmt.Errorf("failed to get home directory: %w", err)
 	}
 	
-dbPath := filepath.Join(home, ".copilot-research", "research.db")
+	dbPath := filepath.Join(home, ".copilot-research", "research.db")
 	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
-		return fmt.Errorf("failed to create database directory: %w", err)
+		return "", fmt.Errorf("failed to create database directory: %w", err)
+	}
+	return dbPath, nil
+}
+
+// openDatabase opens (creating if needed) the research database in the
+// user's ~/.copilot-research directory with the configured storage driver
+// and key, and applies the history retention policy
+func openDatabase() (db.DB, error) {
+	dbPath, err := databasePath()
+	if err != nil {
+		return nil, err
 	}
 	
-database, err := db.NewSQLiteDB(dbPath)
+	driver := ""
+	var key *db.Key
+	if AppConfig != nil {
+		driver = AppConfig.Database.Driver
+		if key, err = databaseKey(AppConfig.Database.Encryption); err != nil {
+			return nil, err
+		}
+	}
+	database, err := db.Open(driver, dbPath, db.Options{Key: key})
+	if errors.Is(err, db.ErrKeyRequired) {
+		return nil, fmt.Errorf("failed to open database: %w: enable database.encryption in %s", err, CfgFile)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	
+	if AppConfig != nil {
+		removed, err := applyRetention(database, AppConfig.History, time.Now())
+		if err != nil {
+			database.Close()
+			return nil, err
+		}
+		if removed > 0 && !Quiet {
+			fmt.Fprintf(os.Stderr, "Retention policy removed %d old sessions\n", removed)
+		}
 	}
-	defer database.Close()
 	
+	return database, nil
+}
+
+// newResearchEngine wires a research engine to the given database, the
+// prompt templates and the authenticated providers
+func newResearchEngine(database db.DB) (*research.Engine, error) {
 	// Initialize prompt loader
 	promptsDir := filepath.Join("prompts")
 	loader := prompts.NewPromptLoader(promptsDir)
@@ -90,7 +320,7 @@ database, err := db.NewSQLiteDB(dbPath)
 	factory := provider.NewProviderFactory()
 	ghProvider := provider.NewGitHubCopilotProvider(60 * time.Second)
 	if err := factory.Register("github-copilot", ghProvider); err != nil {
-		return fmt.Errorf("failed to register provider: %w", err)
+		return nil, fmt.Errorf("failed to register provider: %w", err)
 	}
 	
 	// Updated call to NewProviderManager
@@ -108,51 +338,82 @@ database, err := db.NewSQLiteDB(dbPath)
 	// For now, keep it for ghProvider as it's the only one registered here
 	if !ghProvider.IsAuthenticated() {
 		authInfo := ghProvider.RequiresAuth()
-		return fmt.Errorf("authentication required:\n\n%s", authInfo.Instructions)
+		return nil, fmt.Errorf("authentication required:\n\n%s", authInfo.Instructions)
 	}
 	
-	// Initialize research engine
-	engine := research.NewEngine(database, loader, providerMgr)
-	
-	// Run research
-	if Quiet {
-		return runQuietResearch(engine, query)
+	return research.NewEngine(database, loader, providerMgr), nil
+}
+
+// researchContext returns a context that is cancelled by Ctrl-C or
+// SIGTERM, or when --timeout elapses
+func researchContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if researchTimeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, researchTimeout)
+	return ctx, func() {
+		cancel()
+		stop()
 	}
-	
-	return runInteractiveResearch(engine, query)
 }
 
-func runQuietResearch(engine *research.Engine, query string) error {
-	ctx := context.Background()
-	progress := make(chan string, 10)
+// interrupted explains an error from a run whose context ended
+func interrupted(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("research timed out after %s: %w", researchTimeout, err)
+	}
+	return fmt.Errorf("research cancelled: %w", err)
+}
+
+func runQuietResearch(database db.DB, run researchFunc) error {
+	ctx, stop := researchContext()
+	defer stop()
+	progress := make(chan research.Event, 10)
+	done := make(chan struct{})
 	
-	// Drain progress channel
+	// Report warnings, or every event with --progress-json
 	go func() {
-		for range progress {
-		}
+		defer close(done)
+		writeProgress(os.Stderr, progress, progressJSON)
 	}()
 	
-	opts := research.ResearchOptions{
-		Query:      query,
-		Mode:       Mode,
-		PromptName: PromptName,
-		NoStore:    NoStore,
-	}
-	
-	result, err := engine.Research(ctx, opts, progress)
+	result, err := run(ctx, progress, nil)
 	close(progress)
+	<-done
 	
 	if err != nil {
+		var checkpointed *research.CheckpointError
+		if errors.As(err, &checkpointed) {
+			fmt.Fprintf(os.Stderr, "Progress was saved. Resume with: copilot-research resume %d\n", checkpointed.SessionID)
+		}
+		if ctx.Err() != nil {
+			return interrupted(ctx, err)
+		}
 		return fmt.Errorf("research failed: %w", err)
 	}
 	
-	// Format output
-	format := "markdown"
-	if JSONOutput {
-		format = "json"
+	warnFlaggedCitations(result.Citations)
+	
+	// Format output. A comparison matrix can be written on its own, e.g.
+	// as CSV; otherwise it is part of the rendered document.
+	var output string
+	if result.Matrix != nil && (matrixFormat != "" || strings.EqualFold(filepath.Ext(OutputFile), ".csv")) {
+		output, err = research.RenderMatrix(result.Matrix, resolveMatrixFormat(matrixFormat, OutputFile, JSONOutput))
+	} else {
+		doc := newDocument(result)
+		if ReportTemplate != "" && result.SessionID != 0 {
+			// Templates may use stored session metadata
+			if session, err := database.GetSession(result.SessionID); err == nil {
+				doc.Session = session
+				doc.CreatedAt = session.CreatedAt
+			}
+		}
+		output, err = renderDocument(doc)
+	}
+	if err != nil {
+		return err
 	}
-	
-	output := formatOutput(result.Content, format)
 	
 	// Write output
 	if err := writeOutput(OutputFile, output); err != nil {
@@ -162,36 +423,84 @@ func runQuietResearch(engine *research.Engine, query string) error {
 	return nil
 }
 
-func runInteractiveResearch(engine *research.Engine, query string) error {
+// resolveMatrixFormat picks the comparison matrix format: an explicit
+// format wins, then the output file extension, then --json
+func resolveMatrixFormat(format, outputFile string, jsonOutput bool) string {
+	if format != "" {
+		return format
+	}
+	switch strings.ToLower(filepath.Ext(outputFile)) {
+	case ".csv":
+		return research.MatrixCSV
+	case ".json":
+		return research.MatrixJSON
+	}
+	if jsonOutput {
+		return research.MatrixJSON
+	}
+	return research.MatrixMarkdown
+}
+
+// writeProgress writes research events to w until events is closed. With
+// asJSON every event is written as one JSON object per line; otherwise only
+// warnings are written.
+func writeProgress(w io.Writer, events <-chan research.Event, asJSON bool) {
+	encoder := json.NewEncoder(w)
+	for event := range events {
+		if asJSON {
+			_ = encoder.Encode(event)
+		} else if event.IsWarning() {
+			fmt.Fprintln(w, event.String())
+		}
+	}
+}
+
+// warnFlaggedCitations reports dead and suspicious links on stderr so they
+// don't end up in the output
+func warnFlaggedCitations(citations []db.Citation) {
+	for _, c := range citations {
+		if c.Flagged() {
+			fmt.Fprintf(os.Stderr, "Warning: %s citation %s (%s)\n", c.Status, c.URL, c.Note)
+		}
+	}
+}
+
+func runInteractiveResearch(database db.DB, query, mode string, run researchFunc) error {
 	// Create UI model
-	model := ui.NewResearchModel(query, Mode)
+	model := ui.NewResearchModel(query, mode).WithRater(func(id int64, rating int) error {
+		_, err := database.RateSession(id, rating)
+		return err
+	})
 	
 	// Create Bubble Tea program
 	p := tea.NewProgram(model)
 	
+	ctx, stop := researchContext()
+	defer stop()
+	
 	// Start research in background
+	finished := make(chan struct{})
 	go func() {
-		ctx := context.Background()
-		progress := make(chan string, 10)
+		defer close(finished)
+		progress := make(chan research.Event, 10)
+		done := make(chan struct{})
 		
 		// Send progress updates to UI
 		go func() {
-			for msg := range progress {
-				p.Send(ui.ProgressMsg(msg))
+			defer close(done)
+			for event := range progress {
+				p.Send(ui.ProgressMsg{Event: event})
 			}
 		}()
 		
-		opts := research.ResearchOptions{
-			Query:      query,
-			Mode:       Mode,
-			PromptName: PromptName,
-			NoStore:    NoStore,
-		}
-		
-		result, err := engine.Research(ctx, opts, progress)
+		result, err := run(ctx, progress, ui.NewClarifier(p))
 		close(progress)
+		<-done
 		
 		if err != nil {
+			if ctx.Err() != nil {
+				err = interrupted(ctx, err)
+			}
 			p.Send(ui.ErrorMsg{Err: err})
 			return
 		}
@@ -200,7 +509,14 @@ func runInteractiveResearch(engine *research.Engine, query string) error {
 	}()
 	
 	// Run UI
-	if _, err := p.Run(); err != nil {
+	_, err := p.Run()
+	
+	// Quitting the UI cancels research that is still running; wait for it
+	// to record the cancelled session
+	stop()
+	<-finished
+	
+	if err != nil {
 		return fmt.Errorf("UI error: %w", err)
 	}
 	
@@ -249,18 +565,62 @@ func getQueryFromStdin() (string, error) {
 	return strings.TrimSpace(string(data)), nil
 }
 
-func formatOutput(content string, format string) string {
-	switch format {
-	case "json":
-		output := map[string]interface{}{
-			"content": content,
-			"format":  "markdown",
-		}
-		data, _ := json.MarshalIndent(output, "", "  ")
-		return string(data)
-	default:
-		return content
+// newDocument collects a research result and its metadata for rendering
+func newDocument(result *research.ResearchResult) *render.Document {
+	return &render.Document{
+		Query:        result.Query,
+		RefinedQuery: result.RefinedQuery,
+		Mode:         result.Mode,
+		Content:      result.Content,
+		Provider:     result.Provider,
+		Model:        result.Model,
+		Tokens:       result.Tokens,
+		Duration:     result.Duration,
+		SessionID:    result.SessionID,
+		Citations:    result.Citations,
+		Matrix:       result.Matrix,
+		QualityScore: result.QualityScore,
+		CreatedAt:    time.Now(),
+		Result:       result,
+	}
+}
+
+// sessionDocument collects a stored session for rendering
+func sessionDocument(session *db.ResearchSession) *render.Document {
+	return &render.Document{
+		Query:        session.Query,
+		RefinedQuery: session.RefinedQuery,
+		Mode:         session.Mode,
+		Content:      session.Result,
+		Provider:     session.Provider,
+		Model:        session.Model,
+		Tokens:       provider.TokenUsage(session.Tokens),
+		SessionID:    session.ID,
+		Citations:    session.Citations,
+		Matrix:       session.Matrix,
+		QualityScore: session.QualityScore,
+		CreatedAt:    session.CreatedAt,
+		Session:      session,
+	}
+}
+
+// outputRenderer returns the report template chosen with --template, or
+// the renderer for the format chosen with --format, inferred from the
+// --output extension, or JSON with --json; markdown otherwise
+func outputRenderer() (render.Renderer, error) {
+	if ReportTemplate != "" {
+		return render.FindTemplate(ReportTemplate, templateDirs()...)
+	}
+	return render.DefaultRegistry().Resolve(OutputFormat, OutputFile, JSONOutput)
+}
+
+// renderDocument renders doc with the output renderer
+func renderDocument(doc *render.Document) (string, error) {
+	renderer, err := outputRenderer()
+	if err != nil {
+		return "", err
 	}
+	return renderer.Render(doc)
 }
 
 func writeOutput(filename string, content string) error {
@@ -1,10 +1,18 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/joelklabo/copilot-research/internal/provider"
+	"github.com/joelklabo/copilot-research/internal/research"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -32,6 +40,91 @@ func TestResearchCommand_InputFlag(t *testing.T) {
 	assert.Equal(t, "string", flag.Value.Type())
 }
 
+func TestResearchCommand_ContextFlags(t *testing.T) {
+	flag := researchCmd.Flags().Lookup("context")
+	require.NotNil(t, flag)
+	assert.Equal(t, "stringArray", flag.Value.Type())
+	
+	flag = researchCmd.Flags().Lookup("context-top")
+	require.NotNil(t, flag)
+	assert.Equal(t, "5", flag.DefValue)
+}
+
+func TestResearchCommand_CompareFlags(t *testing.T) {
+	flag := researchCmd.Flags().Lookup("option")
+	require.NotNil(t, flag)
+	assert.Equal(t, "stringArray", flag.Value.Type())
+	
+	flag = researchCmd.Flags().Lookup("criteria")
+	require.NotNil(t, flag)
+	assert.Equal(t, "stringSlice", flag.Value.Type())
+	
+	assert.NotNil(t, researchCmd.Flags().Lookup("matrix-format"))
+}
+
+func TestResearchCommand_TimeoutFlag(t *testing.T) {
+	assert.NotNil(t, researchCmd.Flags().Lookup("timeout"))
+	assert.NotNil(t, resumeCmd.Flags().Lookup("timeout"))
+}
+
+func TestResearchCommand_RefineFlag(t *testing.T) {
+	flag := researchCmd.Flags().Lookup("refine")
+	require.NotNil(t, flag)
+	assert.Equal(t, "false", flag.DefValue)
+}
+
+func TestResolveMatrixFormat(t *testing.T) {
+	assert.Equal(t, "markdown", resolveMatrixFormat("", "", false))
+	assert.Equal(t, "markdown", resolveMatrixFormat("", "report.md", false))
+	assert.Equal(t, "csv", resolveMatrixFormat("", "matrix.CSV", false))
+	assert.Equal(t, "json", resolveMatrixFormat("", "matrix.json", false))
+	assert.Equal(t, "json", resolveMatrixFormat("", "", true))
+	assert.Equal(t, "csv", resolveMatrixFormat("", "out.csv", true), "the output extension wins over --json")
+	assert.Equal(t, "markdown", resolveMatrixFormat("markdown", "out.csv", true), "an explicit format wins")
+}
+
+func TestLoadContext(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "retry.md"), []byte("# Retry\n\nRetries use exponential backoff."), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "log.md"), []byte("# Logging\n\nStructured logs only."), 0644))
+	
+	snippets, err := loadContext("retry backoff", []string{dir}, 5)
+	require.NoError(t, err)
+	require.Len(t, snippets, 1)
+	assert.Equal(t, "retry.md", filepath.Base(snippets[0].Path))
+	assert.Equal(t, 1, snippets[0].StartLine)
+	
+	_, err = loadContext("anything", []string{filepath.Join(dir, "missing")}, 5)
+	assert.Error(t, err)
+}
+
+func TestWriteProgress(t *testing.T) {
+	send := func() <-chan research.Event {
+		events := make(chan research.Event, 3)
+		events <- research.Event{Stage: research.StageQuery, Status: research.EventStarted, Message: "Querying AI provider..."}
+		events <- research.Event{Stage: research.StageStore, Status: research.EventWarning, Message: "Failed to store session"}
+		events <- research.Event{Stage: research.StageComplete, Status: research.EventCompleted, Message: "Complete!", Percent: 100}
+		close(events)
+		return events
+	}
+	
+	var buf bytes.Buffer
+	writeProgress(&buf, send(), false)
+	assert.Equal(t, "Warning: Failed to store session\n", buf.String())
+	
+	buf.Reset()
+	writeProgress(&buf, send(), true)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	
+	var last map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[2]), &last))
+	assert.Equal(t, "complete", last["stage"])
+	assert.Equal(t, "completed", last["status"])
+	assert.Equal(t, float64(100), last["percent"])
+}
+
 func TestGetQueryFromArgs(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -115,42 +208,67 @@ func TestGetQueryFromStdin(t *testing.T) {
 	assert.Equal(t, content, query)
 }
 
-func TestFormatOutput(t *testing.T) {
-	result := "Test result content"
+func TestRenderDocument(t *testing.T) {
+	defer func() { OutputFormat, OutputFile, JSONOutput = "", "", false }()
+	
+	result := &research.ResearchResult{
+		Query:    "Test query",
+		Mode:     "quick",
+		Content:  "# Result\n\nTest result content",
+		Provider: "github-copilot",
+		Tokens:   provider.TokenUsage{Total: 42},
+	}
 	
 	tests := []struct {
 		name   string
 		format string
+		output string
+		json   bool
 		want   string
 	}{
-		{
-			name:   "markdown format",
-			format: "markdown",
-			want:   result,
-		},
-		{
-			name:   "text format",
-			format: "text",
-			want:   result,
-		},
+		{name: "markdown by default", want: "# Result\n\nTest result content"},
+		{name: "explicit format", format: "org", want: "#+TITLE: Result"},
+		{name: "inferred from extension", output: "report.html", want: "<!DOCTYPE html>"},
+		{name: "json flag", json: true, want: `"provider": "github-copilot"`},
 	}
 	
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := formatOutput(result, tt.format)
-			assert.Contains(t, got, result)
+			OutputFormat, OutputFile, JSONOutput = tt.format, tt.output, tt.json
+			got, err := renderDocument(newDocument(result))
+			require.NoError(t, err)
+			assert.Contains(t, got, tt.want)
 		})
 	}
+	
+	OutputFormat, OutputFile, JSONOutput = "rtf", "", false
+	_, err := renderDocument(newDocument(result))
+	assert.ErrorContains(t, err, "unknown format")
 }
 
-func TestFormatOutput_JSON(t *testing.T) {
-	result := "Test content"
-	output := formatOutput(result, "json")
+func TestRenderDocument_Template(t *testing.T) {
+	defer func() { ReportTemplate, OutputFormat = "", "" }()
+	
+	// The template wins over --format
+	ReportTemplate, OutputFormat = "rfc", "html"
+	output, err := renderDocument(newDocument(&research.ResearchResult{Query: "q", Mode: "deep", Content: "# Plan\n\nShip it."}))
+	require.NoError(t, err)
+	assert.Contains(t, output, "# RFC: Plan")
+	assert.Contains(t, output, "## Summary\n\nShip it.")
+}
+
+func TestRenderDocument_JSONEnvelope(t *testing.T) {
+	defer func() { JSONOutput = false }()
+	JSONOutput = true
+	
+	output, err := renderDocument(newDocument(&research.ResearchResult{Query: "q", Mode: "deep", Content: "Test content", SessionID: 3}))
+	require.NoError(t, err)
 	
-	// Should be valid JSON
-	assert.Contains(t, output, "{")
-	assert.Contains(t, output, "}")
-	assert.Contains(t, output, "content")
+	var envelope map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(output), &envelope))
+	assert.Equal(t, "Test content", envelope["content"])
+	assert.Equal(t, "deep", envelope["mode"])
+	assert.Equal(t, float64(3), envelope["session_id"])
 }
 
 func TestWriteOutput(t *testing.T) {
@@ -235,4 +353,41 @@ func TestValidateMode(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestResearchContext_Timeout(t *testing.T) {
+	defer func() { researchTimeout = 0 }()
+	
+	researchTimeout = time.Millisecond
+	ctx, stop := researchContext()
+	defer stop()
+	<-ctx.Done()
+	
+	err := interrupted(ctx, ctx.Err())
+	assert.ErrorContains(t, err, "research timed out after 1ms")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	
+	researchTimeout = 0
+	ctx, stop = researchContext()
+	stop()
+	assert.ErrorContains(t, interrupted(ctx, ctx.Err()), "research cancelled")
+}
+
+func TestPromptSuggestion(t *testing.T) {
+	var minRatings int
+	database := &db.MockDB{
+		BestPromptFunc: func(mode string, min int) (*db.PromptRating, error) {
+			minRatings = min
+			if mode != "deep" {
+				return nil, nil
+			}
+			return &db.PromptRating{PromptUsed: "thorough", Mode: "deep", Count: 8, Average: 4.5}, nil
+		},
+	}
+
+	tip := promptSuggestion(database, "deep", "default")
+	assert.Equal(t, `Tip: the "thorough" prompt is rated 4.5/5 for deep research (8 ratings); try -p thorough`, tip)
+	assert.Equal(t, minPromptRatings, minRatings)
+
+	assert.Empty(t, promptSuggestion(database, "deep", "thorough"), "already using the best prompt")
+	assert.Empty(t, promptSuggestion(database, "quick", "default"), "no prompt has enough ratings")
+}
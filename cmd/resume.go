@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/joelklabo/copilot-research/internal/research"
+	"github.com/spf13/cobra"
+)
+
+// resumeCmd represents the resume command
+var resumeCmd = &cobra.Command{
+	Use:   "resume <id>",
+	Short: "Continue an interrupted research session",
+	Long: `Continue a research session that was interrupted by an error, a crash,
+Ctrl-C or --timeout. Research is checkpointed after every completed step, so resuming
+skips the steps that already finished, such as the main query, each option
+scored in compare mode, or the critique.
+
+Incomplete and cancelled sessions are marked in the history list.
+
+Examples:
+  copilot-research history --incomplete
+  copilot-research resume 42
+  copilot-research resume 42 --quiet -o report.md`,
+	Args: cobra.ExactArgs(1),
+	RunE: runResume,
+}
+
+func init() {
+	RootCmd.AddCommand(resumeCmd)
+	
+	resumeCmd.Flags().DurationVar(&researchTimeout, "timeout", 0, "cancel the research if it takes longer than this, e.g. 5m (default: no limit)")
+}
+
+func runResume(cmd *cobra.Command, args []string) error {
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || id <= 0 {
+		return fmt.Errorf("invalid session ID: %s", args[0])
+	}
+
+	database, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	session, err := database.GetSession(id)
+	if err != nil {
+		return err
+	}
+	if !session.InProgress() && !session.Cancelled() {
+		return fmt.Errorf("session #%d is already complete", id)
+	}
+
+	engine, err := newResearchEngine(database)
+	if err != nil {
+		return err
+	}
+
+	run := func(ctx context.Context, events chan<- research.Event, _ research.Clarifier) (*research.ResearchResult, error) {
+		return engine.Resume(ctx, id, events)
+	}
+	if Quiet || progressJSON {
+		return runQuietResearch(database, run)
+	}
+
+	return runInteractiveResearch(database, session.Query, session.Mode, run)
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResumeCommand(t *testing.T) {
+	assert.NotNil(t, resumeCmd)
+	assert.Contains(t, resumeCmd.Use, "resume")
+	assert.NotEmpty(t, resumeCmd.Short)
+
+	assert.Error(t, resumeCmd.Args(resumeCmd, []string{}))
+	assert.NoError(t, resumeCmd.Args(resumeCmd, []string{"42"}))
+}
+
+func TestRunResume_Validation(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	err := runResume(resumeCmd, []string{"abc"})
+	assert.ErrorContains(t, err, "invalid session ID")
+
+	database, err := openDatabase()
+	require.NoError(t, err)
+	session := &db.ResearchSession{Query: "q", Mode: "quick", PromptUsed: "default", Result: "done"}
+	require.NoError(t, database.SaveSession(session))
+	database.Close()
+
+	err = runResume(resumeCmd, []string{fmt.Sprint(session.ID)})
+	assert.ErrorContains(t, err, "already complete")
+}
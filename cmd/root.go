@@ -16,6 +16,8 @@ var (
 	OutputFile string
 	Quiet      bool
 	JSONOutput bool
+	OutputFormat string
+	ReportTemplate string
 	Mode       string
 	PromptName string
 	NoStore    bool
@@ -52,6 +54,8 @@ cobra.OnInitialize(InitConfig)
 	RootCmd.PersistentFlags().StringVarP(&OutputFile, "output", "o", "", "output file path")
 	RootCmd.PersistentFlags().BoolVarP(&Quiet, "quiet", "q", false, "quiet mode (no UI, just output)")
 	RootCmd.PersistentFlags().BoolVar(&JSONOutput, "json", false, "output as JSON")
+	RootCmd.PersistentFlags().StringVar(&OutputFormat, "format", "", "output format: markdown, json, html, asciidoc or org (default: inferred from --output and --json)")
+	RootCmd.PersistentFlags().StringVar(&ReportTemplate, "template", "", "report template to fill in, by name or path, e.g. adr or rfc (see 'templates')")
 	RootCmd.PersistentFlags().StringVarP(&Mode, "mode", "m", "quick", "research mode (quick|deep|compare|synthesis)")
 	RootCmd.PersistentFlags().StringVarP(&PromptName, "prompt", "p", "default", "prompt template to use")
 	RootCmd.PersistentFlags().BoolVar(&NoStore, "no-store", false, "don't save to database")
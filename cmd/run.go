@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/joelklabo/copilot-research/internal/prompts"
+	"github.com/joelklabo/copilot-research/internal/render"
+	"github.com/joelklabo/copilot-research/internal/research"
+	"github.com/spf13/cobra"
+)
+
+var (
+	recipeVars  []string
+	recipeSteps bool
+)
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run <recipe>",
+	Short: "Run a multi-step research recipe",
+	Long: `Run a research recipe: an ordered list of steps defined in YAML, where
+each step sends a prompt to a provider and later steps build on the output
+of earlier ones.
+
+The recipe is looked up as a file path, then as <recipe>.yaml in ./recipes
+and ~/.copilot-research/recipes.
+
+Recipe format:
+  name: adr
+  vars:
+    topic: ""                 # empty default means required
+  steps:
+    - id: survey
+      prompt: quick           # a prompt template, or an inline template:
+      query: "Options for {{topic}}"
+      provider: openai        # optional, default is the primary provider
+      model: gpt-4o           # optional
+    - id: shortlist
+      inputs: [survey]        # makes {{survey}} available
+      template: "Pick the top 3 as a bulleted list:\n{{survey}}"
+    - id: evaluate
+      for_each: shortlist     # runs once per list item as {{item}}
+      prompt: deep-dive
+      query: "Evaluate {{item}} for {{topic}}"
+
+Examples:
+  copilot-research run adr --var topic="CLI parsing in Go"
+  copilot-research run ./team/onboarding.yaml --var team=payments --steps`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRecipe,
+}
+
+func init() {
+	RootCmd.AddCommand(runCmd)
+
+	runCmd.Flags().StringArrayVar(&recipeVars, "var", nil, "set a recipe variable as name=value (repeatable)")
+	runCmd.Flags().BoolVar(&recipeSteps, "steps", false, "include the output of every step, not just the last")
+	runCmd.Flags().DurationVar(&researchTimeout, "timeout", 0, "cancel the recipe if it takes longer than this, e.g. 5m (default: no limit)")
+}
+
+func runRecipe(cmd *cobra.Command, args []string) error {
+	vars, err := parseVars(recipeVars)
+	if err != nil {
+		return err
+	}
+	if _, err := outputRenderer(); err != nil {
+		return err
+	}
+
+	path, err := research.FindRecipe(args[0], recipeDirs()...)
+	if err != nil {
+		return err
+	}
+
+	recipe, err := research.LoadRecipe(path)
+	if err != nil {
+		return err
+	}
+
+	database, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	runner := research.NewRecipeRunner(database, prompts.NewPromptLoader("prompts"), AppProviderManager)
+
+	ctx, stop := researchContext()
+	defer stop()
+
+	events := make(chan research.Event, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event := range events {
+			if event.IsWarning() || (!Quiet && event.Status == research.EventStarted) {
+				fmt.Fprintln(os.Stderr, event.String())
+			}
+		}
+	}()
+
+	result, err := runner.Run(ctx, recipe, research.RecipeOptions{Vars: vars, NoStore: NoStore}, events)
+	close(events)
+	<-done
+
+	if err != nil {
+		if ctx.Err() != nil {
+			err = interrupted(ctx, err)
+		}
+		return fmt.Errorf("recipe %s failed: %w", recipe.Name, err)
+	}
+
+	content := result.Output
+	if recipeSteps {
+		content = formatRecipeSteps(result)
+	}
+
+	doc := &render.Document{
+		Query:     recipe.Name,
+		Mode:      "recipe",
+		Content:   content,
+		Duration:  result.Duration,
+		Tokens:    result.Tokens,
+		SessionID: result.SessionID,
+		CreatedAt: time.Now(),
+	}
+	if len(result.Steps) > 0 {
+		final := result.Steps[len(result.Steps)-1]
+		doc.Provider = final.Provider
+		doc.Model = final.Model
+	}
+
+	output, err := renderDocument(doc)
+	if err != nil {
+		return err
+	}
+
+	if err := writeOutput(OutputFile, output); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
+
+// recipeDirs lists the directories searched for recipes by name
+func recipeDirs() []string {
+	dirs := []string{"recipes"}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".copilot-research", "recipes"))
+	}
+	return dirs
+}
+
+// parseVars parses name=value pairs from --var flags
+func parseVars(pairs []string) (map[string]string, error) {
+	vars := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --var %q (expected name=value)", pair)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// formatRecipeSteps renders the output of every step under its own heading
+func formatRecipeSteps(result *research.RecipeResult) string {
+	var b strings.Builder
+	for i, step := range result.Steps {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "# Step %d: %s\n\n%s", i+1, step.ID, strings.TrimSpace(step.Output))
+	}
+	return b.String()
+}
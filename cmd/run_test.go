@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/joelklabo/copilot-research/internal/research"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunCommand(t *testing.T) {
+	assert.NotNil(t, runCmd)
+	assert.Contains(t, runCmd.Use, "run")
+	assert.NotEmpty(t, runCmd.Short)
+	assert.NotNil(t, runCmd.RunE)
+
+	assert.NotNil(t, runCmd.Flags().Lookup("var"))
+	assert.NotNil(t, runCmd.Flags().Lookup("steps"))
+
+	assert.Error(t, runCmd.Args(runCmd, []string{}))
+	assert.NoError(t, runCmd.Args(runCmd, []string{"adr"}))
+}
+
+func TestParseVars(t *testing.T) {
+	vars, err := parseVars([]string{"topic=CLI parsing", "query=a=b", "empty="})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"topic": "CLI parsing", "query": "a=b", "empty": ""}, vars)
+
+	_, err = parseVars([]string{"novalue"})
+	assert.Error(t, err)
+
+	_, err = parseVars([]string{"=value"})
+	assert.Error(t, err)
+}
+
+func TestFormatRecipeSteps(t *testing.T) {
+	result := &research.RecipeResult{Steps: []research.StepResult{
+		{ID: "survey", Output: "Options\n"},
+		{ID: "adr", Output: "Decision"},
+	}}
+
+	assert.Equal(t, "# Step 1: survey\n\nOptions\n\n# Step 2: adr\n\nDecision", formatRecipeSteps(result))
+}
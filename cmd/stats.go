@@ -3,32 +3,47 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/joelklabo/copilot-research/internal/db"
 	"github.com/joelklabo/copilot-research/internal/ui"
 	"github.com/spf13/cobra"
 )
 
+// statsSince is the start of the usage report window
+var statsSince string
+
+// statsNow is the clock the usage report window ends at, replaced in tests
+var statsNow = time.Now
+
 // statsCmd represents the stats command
 var statsCmd = &cobra.Command{
 	Use:   "stats",
 	Short: "Show research statistics",
 	Long: `Display statistics about your research usage, patterns, and database size.
 
+With --since, also chart the activity since a date or age: daily and weekly
+sessions, tokens and estimated cost, and the runs, average latency, failure
+rate and fallbacks of each provider. Costs are estimates from built-in list
+prices, not billed amounts; GitHub Copilot usage counts as free.
+
+With --json, print that usage report as JSON instead, over the last 30 days
+unless --since is given.
+
 Examples:
-  copilot-research stats`,
+  copilot-research stats
+  copilot-research stats --since 30d
+  copilot-research stats --since 2026-01-01 --json`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		home, err := os.UserHomeDir()
+		dbPath, err := databasePath()
 		if err != nil {
-			return fmt.Errorf("failed to get home directory: %w", err)
+			return err
 		}
-		dbPath := filepath.Join(home, ".copilot-research", "research.db")
-		database, err := db.NewSQLiteDB(dbPath)
+		database, err := openDatabase()
 		if err != nil {
-			return fmt.Errorf("failed to open database: %w", err)
+			return err
 		}
 		return _runStats(database, dbPath)
 	},
@@ -39,6 +54,27 @@ func _runStats(database db.DB, dbPath string) error {
 
 	defer database.Close()
 
+	now := statsNow()
+	since := now.Add(-statsDefaultWindow)
+	if statsSince != "" {
+		var err error
+		if since, err = parseHistoryTime(statsSince, now, false); err != nil {
+			return err
+		}
+	}
+	primary := ""
+	if AppConfig != nil {
+		primary = AppConfig.Providers.Primary
+	}
+
+	if JSONOutput {
+		report, err := buildUsageReport(database, since, now, primary)
+		if err != nil {
+			return err
+		}
+		return printUsageReportJSON(report)
+	}
+
 	// Get total sessions
 	totalSessions, err := database.GetTotalSessions()
 	if err != nil {
@@ -96,6 +132,64 @@ func _runStats(database db.DB, dbPath string) error {
 		w.Flush()
 	}
 
+	// Get rating aggregates
+	patterns, err := database.GetRatingPatterns()
+	if err != nil {
+		return fmt.Errorf("failed to get ratings: %w", err)
+	}
+
+	if len(patterns) > 0 {
+		fmt.Println()
+		fmt.Println(styles.HeaderStyle.Render("Ratings:"))
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		var modes []string
+		for _, p := range patterns {
+			providerName := p.Provider
+			if providerName == "" {
+				providerName = "-"
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%.1f/5 (%d)\n", p.Mode, p.PromptUsed, providerName, p.AverageRating(), p.RatingCount)
+			if len(modes) == 0 || modes[len(modes)-1] != p.Mode {
+				modes = append(modes, p.Mode)
+			}
+		}
+		w.Flush()
+
+		for _, mode := range modes {
+			best, err := database.BestPrompt(mode, minPromptRatings)
+			if err != nil {
+				return fmt.Errorf("failed to get best prompt: %w", err)
+			}
+			if best != nil {
+				fmt.Printf("  Best prompt for %s: %s (%.1f/5 from %d ratings)\n", mode, best.PromptUsed, best.Average, best.Count)
+			}
+		}
+	}
+
+	// Get tag counts
+	tagCounts, err := database.GetTagCounts()
+	if err != nil {
+		return fmt.Errorf("failed to get tag counts: %w", err)
+	}
+
+	if len(tagCounts) > 0 {
+		fmt.Println()
+		fmt.Println(styles.HeaderStyle.Render("Tags:"))
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		for _, tc := range tagCounts {
+			fmt.Fprintf(w, "  #%s\t%d\n", tc.Tag, tc.Count)
+		}
+		w.Flush()
+	}
+
+	if statsSince != "" {
+		report, err := buildUsageReport(database, since, now, primary)
+		if err != nil {
+			return err
+		}
+		printUsageReport(report)
+	}
+
 	return nil
 }
 
@@ -115,15 +209,15 @@ func formatBytes(b int64) string {
 
 func init() {
 	RootCmd.AddCommand(statsCmd)
+	statsCmd.Flags().StringVar(&statsSince, "since", "", "chart activity from this date or age on (e.g. 2026-03-01, 30d)")
 	statsCmd.RunE = func(cmd *cobra.Command, args []string) error {
-		home, err := os.UserHomeDir()
+		dbPath, err := databasePath()
 		if err != nil {
-			return fmt.Errorf("failed to get home directory: %w", err)
+			return err
 		}
-		dbPath := filepath.Join(home, ".copilot-research", "research.db")
-		database, err := db.NewSQLiteDB(dbPath)
+		database, err := openDatabase()
 		if err != nil {
-			return fmt.Errorf("failed to open database: %w", err)
+			return err
 		}
 		return _runStats(database, dbPath)
 	}
@@ -40,6 +40,21 @@ func TestRunStats(t *testing.T) {
 				{Query: "Go routines", Count: 1},
 			}, nil
 		},
+		GetRatingPatternsFunc: func() ([]*db.LearnedPattern, error) {
+			return []*db.LearnedPattern{
+				{PromptUsed: "thorough", Mode: "deep", Provider: "openai", RatingCount: 4, RatingTotal: 18},
+				{PromptUsed: "default", Mode: "deep", RatingCount: 2, RatingTotal: 6},
+			}, nil
+		},
+		BestPromptFunc: func(mode string, minRatings int) (*db.PromptRating, error) {
+			return &db.PromptRating{PromptUsed: "thorough", Mode: mode, Count: 4, Average: 4.5}, nil
+		},
+		GetTagCountsFunc: func() ([]db.TagCount, error) {
+			return []db.TagCount{
+				{Tag: "go", Count: 3},
+				{Tag: "api", Count: 1},
+			}, nil
+		},
 		CloseFunc: func() error {
 			return nil
 		},
@@ -78,6 +93,13 @@ func TestRunStats(t *testing.T) {
 	assert.Contains(t, output, "Top Queries:")
 	assert.Contains(t, output, "1. Swift concurrency (2 times)")
 	assert.Contains(t, output, "2. Go routines (1 times)")
+	assert.Contains(t, output, "Ratings:")
+	assert.Contains(t, output, "  deep   thorough   openai   4.5/5 (4)")
+	assert.Contains(t, output, "  deep   default    -        3.0/5 (2)")
+	assert.Contains(t, output, "Best prompt for deep: thorough (4.5/5 from 4 ratings)")
+	assert.Contains(t, output, "Tags:")
+	assert.Contains(t, output, "  #go    3")
+	assert.Contains(t, output, "  #api   1")
 }
 
 func TestFormatBytes(t *testing.T) {
@@ -0,0 +1,367 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/joelklabo/copilot-research/internal/ui"
+)
+
+// statsDefaultWindow is the window of stats --json without --since
+const statsDefaultWindow = 30 * 24 * time.Hour
+
+// maxSparkline is the most days drawn in a sparkline; longer windows are
+// drawn by week
+const maxSparkline = 90
+
+// modelPrice is the list price of a model in US dollars per million tokens
+type modelPrice struct {
+	Prompt     float64
+	Completion float64
+}
+
+// modelPrices are the list prices costs are estimated with, by model name
+// prefix. The longest matching prefix wins. They are a snapshot of the
+// published prices, not updated with them, so costs are only estimates and
+// can differ from what a provider bills.
+var modelPrices = map[string]modelPrice{
+	"gpt-4o":            {Prompt: 2.50, Completion: 10.00},
+	"gpt-4o-mini":       {Prompt: 0.15, Completion: 0.60},
+	"gpt-4.1":           {Prompt: 2.00, Completion: 8.00},
+	"gpt-4.1-mini":      {Prompt: 0.40, Completion: 1.60},
+	"gpt-4.1-nano":      {Prompt: 0.10, Completion: 0.40},
+	"gpt-4-turbo":       {Prompt: 10.00, Completion: 30.00},
+	"gpt-3.5-turbo":     {Prompt: 0.50, Completion: 1.50},
+	"o1":                {Prompt: 15.00, Completion: 60.00},
+	"o3-mini":           {Prompt: 1.10, Completion: 4.40},
+	"claude-3-haiku":    {Prompt: 0.25, Completion: 1.25},
+	"claude-3-5-haiku":  {Prompt: 0.80, Completion: 4.00},
+	"claude-3-5-sonnet": {Prompt: 3.00, Completion: 15.00},
+	"claude-3-7-sonnet": {Prompt: 3.00, Completion: 15.00},
+	"claude-sonnet-4":   {Prompt: 3.00, Completion: 15.00},
+	"claude-3-opus":     {Prompt: 15.00, Completion: 75.00},
+	"claude-opus-4":     {Prompt: 15.00, Completion: 75.00},
+}
+
+// estimateCost returns the list price of the tokens a provider's model
+// used, and false if the model has no known price. GitHub Copilot usage is
+// covered by its subscription.
+func estimateCost(provider, model string, tokens db.TokenCount) (float64, bool) {
+	if provider == "github-copilot" {
+		return 0, true
+	}
+
+	match := ""
+	for prefix := range modelPrices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(match) {
+			match = prefix
+		}
+	}
+	if match == "" {
+		return 0, false
+	}
+	price := modelPrices[match]
+	return (float64(tokens.Prompt)*price.Prompt + float64(tokens.Completion)*price.Completion) / 1e6, true
+}
+
+// usageTotals sums sessions and logged runs
+type usageTotals struct {
+	Sessions  int           `json:"sessions"`
+	Tokens    db.TokenCount `json:"tokens"`
+	Cost      float64       `json:"estimated_cost_usd"` // Estimated from modelPrices
+	Runs      int           `json:"runs"`
+	Failed    int           `json:"failed"`
+	Cancelled int           `json:"cancelled"`
+	Answered  int           `json:"answered"`  // Runs a provider answered
+	Fallbacks int           `json:"fallbacks"` // Answered runs not answered by the primary provider
+}
+
+// addSessions adds the sessions of a usage row, costing cost
+func (t *usageTotals) addSessions(u db.SessionUsage, cost float64) {
+	t.Sessions += u.Sessions
+	t.Tokens.Prompt += u.Tokens.Prompt
+	t.Tokens.Completion += u.Tokens.Completion
+	t.Tokens.Total += u.Tokens.Total
+	t.Cost += cost
+}
+
+// addRuns adds the runs of a usage row
+func (t *usageTotals) addRuns(u db.RunUsage, primary string) {
+	t.Runs += u.Runs
+	t.Failed += u.Failed
+	t.Cancelled += u.Cancelled
+	if u.Provider != "" {
+		t.Answered += u.Runs
+		if primary != "" && u.Provider != primary {
+			t.Fallbacks += u.Runs
+		}
+	}
+}
+
+// usagePoint is the usage in the day or week starting at Start
+type usagePoint struct {
+	Start time.Time `json:"start"`
+	usageTotals
+}
+
+// providerUsage is the usage of a provider over the whole window
+type providerUsage struct {
+	Provider string `json:"provider"` // "" for runs no provider answered
+	usageTotals
+	AverageLatencyMS int64   `json:"average_latency_ms"`
+	FailureRate      float64 `json:"failure_rate"`
+}
+
+// usageReport is the usage of the research history in a window
+type usageReport struct {
+	Since           time.Time `json:"since"`
+	Until           time.Time `json:"until"`
+	PrimaryProvider string    `json:"primary_provider"`
+	usageTotals
+	FailureRate    float64         `json:"failure_rate"`  // Share of runs that failed
+	FallbackRate   float64         `json:"fallback_rate"` // Share of answered runs that fell back
+	Daily          []usagePoint    `json:"daily"`
+	Weekly         []usagePoint    `json:"weekly"`
+	Providers      []providerUsage `json:"providers"`
+	UnpricedModels []string        `json:"unpriced_models,omitempty"` // Models left out of the cost
+}
+
+// buildUsageReport aggregates the usage between since and until, bucketed
+// by local days and weeks
+func buildUsageReport(database db.DB, since, until time.Time, primary string) (*usageReport, error) {
+	report := &usageReport{Since: since, Until: until, PrimaryProvider: primary}
+	unpriced := make(map[string]bool)
+
+	// sessionCost costs a session usage row, noting unpriced models
+	sessionCost := func(u db.SessionUsage) float64 {
+		cost, ok := estimateCost(u.Provider, u.Model, u.Tokens)
+		if !ok && u.Tokens.Total > 0 {
+			name := u.Model
+			if name == "" {
+				name = u.Provider + " (model not recorded)"
+			}
+			unpriced[name] = true
+		}
+		return cost
+	}
+
+	for _, period := range []string{db.PeriodDay, db.PeriodWeek} {
+		filter := db.UsageFilter{Since: since, Until: until, Period: period, Location: time.Local}
+		points := emptyPeriods(since, until, period)
+		index := make(map[string]*usagePoint, len(points))
+		for i := range points {
+			index[points[i].Start.Format("2006-01-02")] = &points[i]
+		}
+
+		sessions, err := database.GetSessionUsage(filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get session usage: %w", err)
+		}
+		for _, u := range sessions {
+			if p := index[u.Period.Format("2006-01-02")]; p != nil {
+				p.addSessions(u, sessionCost(u))
+			}
+		}
+		runs, err := database.GetRunUsage(filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get run usage: %w", err)
+		}
+		for _, u := range runs {
+			if p := index[u.Period.Format("2006-01-02")]; p != nil {
+				p.addRuns(u, primary)
+			}
+		}
+
+		if period == db.PeriodDay {
+			report.Daily = points
+		} else {
+			report.Weekly = points
+		}
+	}
+
+	// Totals per provider, over the whole window
+	filter := db.UsageFilter{Since: since, Until: until}
+	providers := make(map[string]*providerUsage)
+	provider := func(name string) *providerUsage {
+		if providers[name] == nil {
+			providers[name] = &providerUsage{Provider: name}
+		}
+		return providers[name]
+	}
+	sessions, err := database.GetSessionUsage(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session usage: %w", err)
+	}
+	for _, u := range sessions {
+		cost := sessionCost(u)
+		provider(u.Provider).addSessions(u, cost)
+		report.addSessions(u, cost)
+	}
+	runs, err := database.GetRunUsage(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get run usage: %w", err)
+	}
+	for _, u := range runs {
+		p := provider(u.Provider)
+		p.addRuns(u, primary)
+		p.AverageLatencyMS = u.AverageLatency.Milliseconds()
+		p.FailureRate = rate(p.Failed, p.Runs)
+		report.addRuns(u, primary)
+	}
+
+	for _, p := range providers {
+		report.Providers = append(report.Providers, *p)
+	}
+	sort.Slice(report.Providers, func(i, j int) bool {
+		a, b := report.Providers[i], report.Providers[j]
+		if a.Runs != b.Runs {
+			return a.Runs > b.Runs
+		}
+		return a.Provider < b.Provider
+	})
+	for name := range unpriced {
+		report.UnpricedModels = append(report.UnpricedModels, name)
+	}
+	sort.Strings(report.UnpricedModels)
+
+	report.FailureRate = rate(report.Failed, report.Runs)
+	report.FallbackRate = rate(report.Fallbacks, report.Answered)
+	return report, nil
+}
+
+// emptyPeriods returns a point for every local day or week from the one
+// containing since to the one containing until
+func emptyPeriods(since, until time.Time, period string) []usagePoint {
+	start := startOfDay(since)
+	last := startOfDay(until)
+	step := 1
+	if period == db.PeriodWeek {
+		// Weeks start on Monday
+		start = start.AddDate(0, 0, -(int(start.Weekday())+6)%7)
+		step = 7
+	}
+
+	var points []usagePoint
+	for t := start; !t.After(last); t = t.AddDate(0, 0, step) {
+		points = append(points, usagePoint{Start: t})
+	}
+	return points
+}
+
+// startOfDay returns the local midnight starting the day of t
+func startOfDay(t time.Time) time.Time {
+	t = t.In(time.Local)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// rate returns n as a share of total, or 0 if total is 0
+func rate(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+// printUsageReportJSON prints a usage report as indented JSON
+func printUsageReportJSON(report *usageReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage report: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// printUsageReport prints a usage report with sparklines of the daily
+// values, or weekly ones for long windows, and bar charts of the weekly
+// sessions and the runs per provider
+func printUsageReport(report *usageReport) {
+	styles := ui.DefaultStyles()
+
+	series, unit := report.Daily, "daily"
+	if len(series) > maxSparkline {
+		series, unit = report.Weekly, "weekly"
+	}
+	spark := func(value func(usagePoint) float64) string {
+		values := make([]float64, len(series))
+		for i, p := range series {
+			values[i] = value(p)
+		}
+		return styles.ChartStyle.Render(ui.Sparkline(values))
+	}
+
+	fmt.Println()
+	fmt.Println(styles.HeaderStyle.Render(fmt.Sprintf("Activity since %s (%s):", report.Since.Format("2006-01-02"), unit)))
+	rows := []struct {
+		label string
+		value func(usagePoint) float64
+		total string
+	}{
+		{"Sessions", func(p usagePoint) float64 { return float64(p.Sessions) }, fmt.Sprintf("%d", report.Sessions)},
+		{"Tokens", func(p usagePoint) float64 { return float64(p.Tokens.Total) }, formatTokens(report.Tokens.Total)},
+		{"Cost", func(p usagePoint) float64 { return p.Cost }, fmt.Sprintf("$%.2f estimated", report.Cost)},
+		{"Runs", func(p usagePoint) float64 { return float64(p.Runs) }, fmt.Sprintf("%d", report.Runs)},
+		{"Failures", func(p usagePoint) float64 { return float64(p.Failed) }, fmt.Sprintf("%d (%.0f%% of runs)", report.Failed, report.FailureRate*100)},
+		{"Fallbacks", func(p usagePoint) float64 { return float64(p.Fallbacks) }, fmt.Sprintf("%d (%.0f%% of answered runs)", report.Fallbacks, report.FallbackRate*100)},
+	}
+	for _, row := range rows {
+		fmt.Printf("  %-10s %s  %s\n", row.label, spark(row.value), row.total)
+	}
+	fmt.Println("  Costs are estimates from built-in list prices, not billed amounts")
+	if len(report.UnpricedModels) > 0 {
+		fmt.Printf("  Cost leaves out models without a known price: %s\n", strings.Join(report.UnpricedModels, ", "))
+	}
+	if report.PrimaryProvider != "" {
+		fmt.Printf("  Fallbacks are runs answered by a provider other than %s\n", report.PrimaryProvider)
+	}
+
+	fmt.Println()
+	fmt.Println(styles.HeaderStyle.Render("Weekly Sessions:"))
+	var weeks []ui.Bar
+	for _, p := range report.Weekly {
+		weeks = append(weeks, ui.Bar{
+			Label: "  " + p.Start.Format("2006-01-02"),
+			Value: float64(p.Sessions),
+			Text:  fmt.Sprintf("%d (%s tokens, $%.2f)", p.Sessions, formatTokens(p.Tokens.Total), p.Cost),
+		})
+	}
+	fmt.Print(ui.BarChart(weeks, 30, styles.ChartStyle))
+
+	if len(report.Providers) > 0 {
+		fmt.Println()
+		fmt.Println(styles.HeaderStyle.Render("Providers:"))
+		var bars []ui.Bar
+		for _, p := range report.Providers {
+			name := p.Provider
+			if name == "" {
+				name = "(none)"
+			}
+			latency := "-"
+			if p.AverageLatencyMS > 0 {
+				latency = (time.Duration(p.AverageLatencyMS) * time.Millisecond).Round(100 * time.Millisecond).String()
+			}
+			bars = append(bars, ui.Bar{
+				Label: "  " + name,
+				Value: float64(p.Runs),
+				Text:  fmt.Sprintf("%d runs, %s avg, %.0f%% failed, $%.2f", p.Runs, latency, p.FailureRate*100, p.Cost),
+			})
+		}
+		fmt.Print(ui.BarChart(bars, 30, styles.ChartStyle))
+	}
+}
+
+// formatTokens abbreviates a token count, e.g. 12.3k or 1.2M
+func formatTokens(n int) string {
+	switch {
+	case n >= 1000000:
+		return fmt.Sprintf("%.1fM", float64(n)/1000000)
+	case n >= 1000:
+		return fmt.Sprintf("%.1fk", float64(n)/1000)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
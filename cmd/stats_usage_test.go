@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/joelklabo/copilot-research/internal/config"
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// usageMockDB returns canned usage for two days, 2 and 9 March 2026, a
+// week apart
+func usageMockDB() *db.MockDB {
+	march2 := time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local)
+	march9 := march2.AddDate(0, 0, 7)
+	period := func(filter db.UsageFilter, t time.Time) time.Time {
+		if filter.Period == "" {
+			return time.Time{}
+		}
+		return t
+	}
+
+	return &db.MockDB{
+		GetSessionUsageFunc: func(filter db.UsageFilter) ([]db.SessionUsage, error) {
+			return []db.SessionUsage{
+				{Period: period(filter, march2), Provider: "openai", Model: "gpt-4o-2024-08-06", Sessions: 2, Tokens: db.TokenCount{Prompt: 1000000, Completion: 100000, Total: 1100000}},
+				{Period: period(filter, march9), Provider: "github-copilot", Model: "gpt-4o", Sessions: 3, Tokens: db.TokenCount{Prompt: 3000, Completion: 1000, Total: 4000}},
+				{Period: period(filter, march9), Provider: "anthropic", Model: "claude-next", Sessions: 1, Tokens: db.TokenCount{Total: 500}},
+			}, nil
+		},
+		GetRunUsageFunc: func(filter db.UsageFilter) ([]db.RunUsage, error) {
+			return []db.RunUsage{
+				{Period: period(filter, march2), Provider: "openai", Runs: 2, AverageLatency: 2400 * time.Millisecond},
+				{Period: period(filter, march9), Provider: "", Runs: 1, Failed: 1},
+				{Period: period(filter, march9), Provider: "github-copilot", Runs: 4, Failed: 1, AverageLatency: 1500 * time.Millisecond},
+			}, nil
+		},
+		CloseFunc: func() error { return nil },
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	tokens := db.TokenCount{Prompt: 1000000, Completion: 1000000}
+
+	cost, ok := estimateCost("openai", "gpt-4o-mini-2024-07-18", tokens)
+	assert.True(t, ok)
+	assert.InDelta(t, 0.75, cost, 1e-9, "the longest prefix wins over gpt-4o")
+
+	cost, ok = estimateCost("github-copilot", "gpt-4o", tokens)
+	assert.True(t, ok)
+	assert.Zero(t, cost)
+
+	_, ok = estimateCost("anthropic", "claude-next", tokens)
+	assert.False(t, ok)
+}
+
+func TestEmptyPeriods(t *testing.T) {
+	// Wednesday 4 March to Tuesday 10 March 2026
+	since := time.Date(2026, 3, 4, 15, 0, 0, 0, time.Local)
+	until := time.Date(2026, 3, 10, 9, 0, 0, 0, time.Local)
+
+	days := emptyPeriods(since, until, db.PeriodDay)
+	require.Len(t, days, 7)
+	assert.Equal(t, time.Date(2026, 3, 4, 0, 0, 0, 0, time.Local), days[0].Start)
+	assert.Equal(t, time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local), days[6].Start)
+
+	weeks := emptyPeriods(since, until, db.PeriodWeek)
+	require.Len(t, weeks, 2)
+	assert.Equal(t, time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local), weeks[0].Start)
+	assert.Equal(t, time.Date(2026, 3, 9, 0, 0, 0, 0, time.Local), weeks[1].Start)
+}
+
+func TestBuildUsageReport(t *testing.T) {
+	since := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	until := time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local)
+
+	report, err := buildUsageReport(usageMockDB(), since, until, "github-copilot")
+	require.NoError(t, err)
+
+	assert.Equal(t, 6, report.Sessions)
+	assert.Equal(t, 1104500, report.Tokens.Total)
+	assert.InDelta(t, 3.5, report.Cost, 1e-9)
+	assert.Equal(t, []string{"claude-next"}, report.UnpricedModels)
+	assert.Equal(t, 7, report.Runs)
+	assert.Equal(t, 2, report.Failed)
+	assert.InDelta(t, 2.0/7, report.FailureRate, 1e-9)
+	assert.Equal(t, 6, report.Answered)
+	assert.Equal(t, 2, report.Fallbacks, "openai answered instead of github-copilot")
+
+	require.Len(t, report.Daily, 10)
+	assert.Equal(t, 2, report.Daily[1].Sessions)
+	assert.InDelta(t, 3.5, report.Daily[1].Cost, 1e-9)
+	assert.Equal(t, 4, report.Daily[8].Sessions)
+
+	// 1 March is a Sunday, so the window starts in the week of 23 February
+	require.Len(t, report.Weekly, 3)
+	assert.Equal(t, time.Date(2026, 2, 23, 0, 0, 0, 0, time.Local), report.Weekly[0].Start)
+	assert.Equal(t, 2, report.Weekly[1].Sessions)
+	assert.Equal(t, 5, report.Weekly[2].Runs)
+
+	require.Len(t, report.Providers, 4)
+	copilot := report.Providers[0]
+	assert.Equal(t, "github-copilot", copilot.Provider)
+	assert.Equal(t, 4, copilot.Runs)
+	assert.Equal(t, int64(1500), copilot.AverageLatencyMS)
+	assert.InDelta(t, 0.25, copilot.FailureRate, 1e-9)
+	assert.Equal(t, "openai", report.Providers[1].Provider)
+	assert.Equal(t, "", report.Providers[2].Provider)
+	assert.Equal(t, "anthropic", report.Providers[3].Provider)
+}
+
+// captureStats runs _runStats and returns its output
+func captureStats(t *testing.T, database db.DB) string {
+	t.Helper()
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := _runStats(database, "")
+
+	w.Close()
+	out, _ := io.ReadAll(r)
+	os.Stdout = oldStdout
+	require.NoError(t, err)
+	return string(out)
+}
+
+// statsTestNow is noon on 10 March 2026
+func statsTestNow() time.Time {
+	return time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local)
+}
+
+func TestRunStats_Since(t *testing.T) {
+	oldAppConfig, oldSince, oldNow := AppConfig, statsSince, statsNow
+	defer func() { AppConfig, statsSince, statsNow = oldAppConfig, oldSince, oldNow }()
+	AppConfig = config.DefaultConfig()
+	statsNow = statsTestNow
+	statsSince = "2026-03-01"
+
+	output := captureStats(t, usageMockDB())
+
+	assert.Contains(t, output, "Research Statistics")
+	assert.Contains(t, output, "Activity since 2026-03-01 (daily):")
+	assert.Contains(t, output, "$3.50 estimated")
+	assert.Contains(t, output, "Costs are estimates from built-in list prices")
+	assert.Contains(t, output, "2 (29% of runs)")
+	assert.Contains(t, output, "2 (33% of answered runs)")
+	assert.Contains(t, output, "Cost leaves out models without a known price: claude-next")
+	assert.Contains(t, output, "Fallbacks are runs answered by a provider other than github-copilot")
+	assert.Contains(t, output, "Weekly Sessions:")
+	assert.Contains(t, output, "2026-03-02")
+	assert.Contains(t, output, "2 (1.1M tokens, $3.50)")
+	assert.Contains(t, output, "Providers:")
+	assert.Contains(t, output, "4 runs, 1.5s avg, 25% failed, $0.00")
+	assert.Contains(t, output, "(none)")
+
+	statsSince = "soon"
+	assert.Error(t, _runStats(usageMockDB(), ""))
+}
+
+func TestRunStats_JSON(t *testing.T) {
+	oldAppConfig, oldSince, oldJSON, oldNow := AppConfig, statsSince, JSONOutput, statsNow
+	defer func() { AppConfig, statsSince, JSONOutput, statsNow = oldAppConfig, oldSince, oldJSON, oldNow }()
+	AppConfig = config.DefaultConfig()
+	statsNow = statsTestNow
+	statsSince = "2026-03-01"
+	JSONOutput = true
+
+	output := captureStats(t, usageMockDB())
+	assert.NotContains(t, output, "Research Statistics")
+
+	var report map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(output), &report))
+	assert.Equal(t, "github-copilot", report["primary_provider"])
+	assert.Equal(t, float64(6), report["sessions"])
+	assert.InDelta(t, 3.5, report["estimated_cost_usd"], 1e-9)
+	assert.Len(t, report["weekly"], 3)
+
+	providers := report["providers"].([]interface{})
+	copilot := providers[0].(map[string]interface{})
+	assert.Equal(t, "github-copilot", copilot["provider"])
+	assert.Equal(t, float64(1500), copilot["average_latency_ms"])
+}
+
+func TestFormatTokens(t *testing.T) {
+	assert.Equal(t, "999", formatTokens(999))
+	assert.Equal(t, "12.3k", formatTokens(12345))
+	assert.Equal(t, "1.2M", formatTokens(1234567))
+}
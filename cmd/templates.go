@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joelklabo/copilot-research/internal/render"
+	"github.com/spf13/cobra"
+)
+
+// templatesCmd represents the templates command
+var templatesCmd = &cobra.Command{
+	Use:   "templates",
+	Short: "List report templates",
+	Long: `List the report templates that --template fills in with a research
+result, such as an ADR or RFC skeleton.
+
+Templates use Go text/template syntax. Put your own in
+~/.copilot-research/templates/<name>.tmpl; a template there overrides a
+built-in template of the same name.
+
+Examples:
+  copilot-research templates
+  copilot-research templates show adr > ~/.copilot-research/templates/adr.tmpl
+  copilot-research "Kafka vs NATS for our event bus" --mode compare --template adr
+  copilot-research history --id 42 --template rfc`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		templates := render.ListTemplates(templateDirs()...)
+
+		fmt.Println(titleStyle.Render(fmt.Sprintf("Report Templates (%d)", len(templates))))
+		fmt.Println(strings.Repeat("━", 60))
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintf(w, "%s\t%s\n", headerStyle.Render("Name"), headerStyle.Render("Source"))
+		for _, t := range templates {
+			fmt.Fprintf(w, "%s\t%s\n", t.Name, t.Source)
+		}
+		return w.Flush()
+	},
+}
+
+var templatesShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Print a report template",
+	Long: `Print the source of a report template, e.g. to copy a built-in
+template into ~/.copilot-research/templates/ and customize it.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tmpl, err := render.FindTemplate(args[0], templateDirs()...)
+		if err != nil {
+			return err
+		}
+		fmt.Print(tmpl.Text())
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(templatesCmd)
+	templatesCmd.AddCommand(templatesShowCmd)
+}
+
+// templateDirs lists the directories searched for report templates by name
+func templateDirs() []string {
+	var dirs []string
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".copilot-research", "templates"))
+	}
+	return dirs
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplatesCommand(t *testing.T) {
+	assert.NotNil(t, templatesCmd)
+	assert.Equal(t, "templates", templatesCmd.Use)
+	assert.NotNil(t, templatesCmd.RunE)
+
+	assert.Error(t, templatesShowCmd.Args(templatesShowCmd, []string{}))
+	assert.NoError(t, templatesShowCmd.Args(templatesShowCmd, []string{"adr"}))
+
+	assert.NotNil(t, RootCmd.PersistentFlags().Lookup("template"))
+}
+
+func TestHandleRenderSession(t *testing.T) {
+	defer func() { ReportTemplate, OutputFile = "", "" }()
+
+	mockDB := &db.MockDB{
+		GetSessionFunc: func(id int64) (*db.ResearchSession, error) {
+			return &db.ResearchSession{
+				ID:        id,
+				Query:     "Kafka or NATS?",
+				Mode:      "compare",
+				Result:    "# Event Bus\n\n## Recommendation\n\nUse NATS.",
+				CreatedAt: time.Date(2026, 5, 4, 0, 0, 0, 0, time.UTC),
+			}, nil
+		},
+	}
+
+	ReportTemplate = "adr"
+	OutputFile = filepath.Join(t.TempDir(), "adr.md")
+	require.NoError(t, handleRenderSession(mockDB, 12))
+
+	data, err := os.ReadFile(OutputFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# ADR: Event Bus")
+	assert.Contains(t, string(data), "- Date: 2026-05-04")
+	assert.Contains(t, string(data), "- Research: session #12 (compare)")
+	assert.Contains(t, string(data), "## Decision\n\nUse NATS.")
+
+	ReportTemplate = "nope"
+	assert.ErrorContains(t, handleRenderSession(mockDB, 12), "template not found: nope")
+}
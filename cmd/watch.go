@@ -0,0 +1,343 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/joelklabo/copilot-research/internal/watch"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchEvery     string
+	watchForce     bool
+	watchThreshold float64
+)
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Re-research topics on a schedule and report changes",
+	Long: `Register queries to be re-researched at an interval and detect when the
+answer changes. 'watch run' executes the queries that are due, so it can be
+driven by cron:
+
+  0 * * * * copilot-research watch run --quiet
+
+Examples:
+  copilot-research watch add "Stripe API breaking changes" --every 7d
+  copilot-research watch list
+  copilot-research watch run
+  copilot-research watch remove 3`,
+}
+
+var watchAddCmd = &cobra.Command{
+	Use:   "add <query>",
+	Short: "Watch a query",
+	Long: `Register a query to re-research at an interval. The global --mode and
+--prompt flags select how it is researched.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateMode(Mode); err != nil {
+			return err
+		}
+
+		interval, err := parseInterval(watchEvery)
+		if err != nil {
+			return err
+		}
+
+		database, err := openDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		w := &db.WatchedQuery{
+			Query:      strings.Join(args, " "),
+			Mode:       Mode,
+			PromptUsed: PromptName,
+			Interval:   interval,
+		}
+		if err := database.SaveWatch(w); err != nil {
+			return err
+		}
+
+		fmt.Println(successStyle.Render("✓") + fmt.Sprintf(" Watching #%d every %s: %s", w.ID, formatInterval(interval), w.Query))
+		return nil
+	},
+}
+
+var watchListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List watched queries",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		database, err := openDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		watches, err := database.ListWatches()
+		if err != nil {
+			return err
+		}
+
+		if len(watches) == 0 {
+			fmt.Println("No watched queries.")
+			fmt.Println("\nAdd one with:")
+			fmt.Println("  copilot-research watch add <query> --every 24h")
+			return nil
+		}
+
+		fmt.Println(titleStyle.Render(fmt.Sprintf("Watched Queries (%d)", len(watches))))
+		fmt.Println(strings.Repeat("━", 80))
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			headerStyle.Render("ID"),
+			headerStyle.Render("Query"),
+			headerStyle.Render("Mode"),
+			headerStyle.Render("Every"),
+			headerStyle.Render("Last Run"))
+
+		now := time.Now()
+		for _, watch := range watches {
+			lastRun := "never"
+			if watch.LastRunAt != nil {
+				lastRun = formatTimeAgo(*watch.LastRunAt)
+			}
+			if watch.IsDue(now) {
+				lastRun += " (due)"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n",
+				watch.ID,
+				truncateString(watch.Query, 40),
+				watch.Mode,
+				formatInterval(watch.Interval),
+				infoStyle.Render(lastRun))
+		}
+
+		w.Flush()
+		return nil
+	},
+}
+
+var watchRemoveCmd = &cobra.Command{
+	Use:   "remove <id>",
+	Short: "Stop watching a query",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid watch id: %s", args[0])
+		}
+
+		database, err := openDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		if err := database.DeleteWatch(id); err != nil {
+			return err
+		}
+
+		fmt.Println(successStyle.Render("✓") + fmt.Sprintf(" Removed watch #%d", id))
+		return nil
+	},
+}
+
+var watchRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Re-research watched queries that are due",
+	Long: `Re-research every watched query whose interval has elapsed, diff the
+answer against the last stored session and store it if it changed
+materially. Use --force to run all watches regardless of schedule.`,
+	RunE: runWatch,
+}
+
+func init() {
+	RootCmd.AddCommand(watchCmd)
+
+	watchCmd.AddCommand(watchAddCmd)
+	watchCmd.AddCommand(watchListCmd)
+	watchCmd.AddCommand(watchRemoveCmd)
+	watchCmd.AddCommand(watchRunCmd)
+
+	watchAddCmd.Flags().StringVar(&watchEvery, "every", "24h", "re-research interval (e.g. 6h, 1d, 2w)")
+	watchRunCmd.Flags().BoolVar(&watchForce, "force", false, "run all watches, not only those that are due")
+	watchRunCmd.Flags().Float64Var(&watchThreshold, "threshold", watch.DefaultThreshold, "similarity below which an answer counts as changed (0-1)")
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	database, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	engine, err := newResearchEngine(database)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	reports, err := watch.NewRunner(database, engine, watchThreshold).RunDue(ctx, watchForce)
+	if err != nil {
+		return fmt.Errorf("watch run failed: %w", err)
+	}
+
+	if JSONOutput {
+		data, err := json.MarshalIndent(watchReportsJSON(reports), "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal reports: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		printWatchReports(reports)
+	}
+
+	failed := 0
+	for _, r := range reports {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d watches failed", failed, len(reports))
+	}
+
+	return nil
+}
+
+// printWatchReports writes a human readable change summary. In quiet mode
+// only changed and failed watches are printed, which keeps cron mail short.
+func printWatchReports(reports []*watch.Report) {
+	if len(reports) == 0 {
+		if !Quiet {
+			fmt.Println("No watches are due.")
+		}
+		return
+	}
+
+	for _, r := range reports {
+		label := fmt.Sprintf("#%d %s (%s)", r.Watch.ID, r.Watch.Query, r.Watch.Mode)
+		switch {
+		case r.Err != nil:
+			fmt.Printf("✗ %s: %v\n", label, r.Err)
+		case r.FirstRun:
+			fmt.Printf("● %s: first answer stored as session #%d\n", label, r.SessionID)
+		case r.Changed:
+			fmt.Printf("↻ %s: changed, stored as session #%d (was #%d)\n", label, r.SessionID, r.PreviousSessionID)
+			fmt.Println(indent(r.Diff.Summary(5), "  "))
+		default:
+			if !Quiet {
+				fmt.Printf("= %s: no material change (%.0f%% similar)\n", label, r.Diff.Similarity*100)
+			}
+		}
+	}
+}
+
+// watchReportJSON is the machine readable form of a watch report
+type watchReportJSON struct {
+	WatchID           int64    `json:"watch_id"`
+	Query             string   `json:"query"`
+	Mode              string   `json:"mode"`
+	Changed           bool     `json:"changed"`
+	FirstRun          bool     `json:"first_run"`
+	Similarity        *float64 `json:"similarity,omitempty"`
+	Added             []string `json:"added,omitempty"`
+	Removed           []string `json:"removed,omitempty"`
+	SessionID         int64    `json:"session_id,omitempty"`
+	PreviousSessionID int64    `json:"previous_session_id,omitempty"`
+	Error             string   `json:"error,omitempty"`
+}
+
+func watchReportsJSON(reports []*watch.Report) []watchReportJSON {
+	out := make([]watchReportJSON, 0, len(reports))
+	for _, r := range reports {
+		j := watchReportJSON{
+			WatchID:           r.Watch.ID,
+			Query:             r.Watch.Query,
+			Mode:              r.Watch.Mode,
+			Changed:           r.Changed,
+			FirstRun:          r.FirstRun,
+			SessionID:         r.SessionID,
+			PreviousSessionID: r.PreviousSessionID,
+		}
+		if r.Diff != nil {
+			similarity := r.Diff.Similarity
+			j.Similarity = &similarity
+			j.Added = r.Diff.Added
+			j.Removed = r.Diff.Removed
+		}
+		if r.Err != nil {
+			j.Error = r.Err.Error()
+		}
+		out = append(out, j)
+	}
+	return out
+}
+
+// parseInterval parses a duration, additionally accepting d (days) and
+// w (weeks) suffixes
+func parseInterval(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if strings.HasSuffix(s, suffix) {
+			n, err := strconv.ParseFloat(strings.TrimSuffix(s, suffix), 64)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid interval: %s", s)
+			}
+			return time.Duration(n * float64(unit)), nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid interval: %s (use e.g. 30m, 6h, 1d, 2w)", s)
+	}
+	return d, nil
+}
+
+// formatInterval renders an interval using the largest whole unit
+func formatInterval(d time.Duration) string {
+	day := 24 * time.Hour
+	switch {
+	case d%(7*day) == 0:
+		return fmt.Sprintf("%dw", d/(7*day))
+	case d%day == 0:
+		return fmt.Sprintf("%dd", d/day)
+	default:
+		s := d.String()
+		if strings.HasSuffix(s, "m0s") {
+			s = strings.TrimSuffix(s, "0s")
+		}
+		if strings.HasSuffix(s, "h0m") {
+			s = strings.TrimSuffix(s, "0m")
+		}
+		return s
+	}
+}
+
+// indent prefixes every non-empty line of s
+func indent(s, prefix string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
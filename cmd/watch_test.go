@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/joelklabo/copilot-research/internal/diff"
+	"github.com/joelklabo/copilot-research/internal/watch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchCommand(t *testing.T) {
+	assert.NotNil(t, watchCmd)
+	assert.Equal(t, "watch", watchCmd.Use)
+
+	subcommands := []string{"add", "list", "remove", "run"}
+	for _, name := range subcommands {
+		t.Run(name, func(t *testing.T) {
+			sub, _, err := watchCmd.Find([]string{name})
+			require.NoError(t, err)
+			assert.Equal(t, name, sub.Name())
+		})
+	}
+
+	assert.NotNil(t, watchAddCmd.Flags().Lookup("every"))
+	assert.NotNil(t, watchRunCmd.Flags().Lookup("force"))
+	assert.NotNil(t, watchRunCmd.Flags().Lookup("threshold"))
+}
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"30m", 30 * time.Minute, false},
+		{"6h", 6 * time.Hour, false},
+		{"1d", 24 * time.Hour, false},
+		{"1.5d", 36 * time.Hour, false},
+		{"2w", 14 * 24 * time.Hour, false},
+		{"", 0, true},
+		{"xd", 0, true},
+		{"-1h", 0, true},
+		{"weekly", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseInterval(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFormatInterval(t *testing.T) {
+	assert.Equal(t, "2w", formatInterval(14*24*time.Hour))
+	assert.Equal(t, "3d", formatInterval(72*time.Hour))
+	assert.Equal(t, "6h", formatInterval(6*time.Hour))
+	assert.Equal(t, "1h30m", formatInterval(90*time.Minute))
+}
+
+func TestWatchReportsJSON(t *testing.T) {
+	reports := []*watch.Report{
+		{
+			Watch:     &db.WatchedQuery{ID: 1, Query: "q", Mode: "quick"},
+			Changed:   true,
+			Diff:      &diff.Result{Similarity: 0.5, Added: []string{"new"}},
+			SessionID: 9,
+		},
+		{
+			Watch: &db.WatchedQuery{ID: 2, Query: "r", Mode: "deep"},
+			Err:   fmt.Errorf("boom"),
+		},
+	}
+
+	out := watchReportsJSON(reports)
+	require.Len(t, out, 2)
+	assert.True(t, out[0].Changed)
+	require.NotNil(t, out[0].Similarity)
+	assert.Equal(t, 0.5, *out[0].Similarity)
+	assert.Equal(t, []string{"new"}, out[0].Added)
+	assert.Equal(t, "boom", out[1].Error)
+	assert.Nil(t, out[1].Similarity)
+}
+
+func TestIndent(t *testing.T) {
+	assert.Equal(t, "  a\n\n  b", indent("a\n\nb\n", "  "))
+}
@@ -0,0 +1,231 @@
+// Package archive reads and writes research history archives, to move
+// sessions between machines or share them: JSONL with one session per line,
+// or a directory of markdown files with YAML frontmatter.
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"gopkg.in/yaml.v3"
+)
+
+// Archive formats
+const (
+	FormatJSONL    = "jsonl"
+	FormatMarkdown = "markdown"
+)
+
+// WriteJSONL writes sessions as JSON Lines, one session with all its
+// metadata per line
+func WriteJSONL(w io.Writer, sessions []*db.ResearchSession) error {
+	enc := json.NewEncoder(w)
+	for _, session := range sessions {
+		if err := enc.Encode(session); err != nil {
+			return fmt.Errorf("failed to write session %d: %w", session.ID, err)
+		}
+	}
+	return nil
+}
+
+// ReadJSONL reads sessions written by WriteJSONL. Blank lines are skipped.
+func ReadJSONL(r io.Reader) ([]*db.ResearchSession, error) {
+	var sessions []*db.ResearchSession
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		session := &db.ResearchSession{}
+		if err := json.Unmarshal(text, session); err != nil {
+			return nil, fmt.Errorf("line %d: invalid session: %w", line, err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	return sessions, nil
+}
+
+// WriteMarkdown writes each session to <dir>/<id>-<query>.md, the answer as
+// the body and everything else as frontmatter, and returns the files
+// written
+func WriteMarkdown(dir string, sessions []*db.ResearchSession) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	var files []string
+	for _, session := range sessions {
+		data, err := MarshalMarkdown(session)
+		if err != nil {
+			return files, err
+		}
+		path := filepath.Join(dir, fmt.Sprintf("%04d-%s.md", session.ID, slugify(session.Query)))
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return files, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+// MarshalMarkdown renders a session as markdown with YAML frontmatter. The
+// frontmatter uses the session's JSON field names, in the same order.
+func MarshalMarkdown(session *db.ResearchSession) ([]byte, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode session %d: %w", session.ID, err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to encode session %d: %w", session.ID, err)
+	}
+
+	frontmatter := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range sessionFields {
+		value, ok := fields[key]
+		if !ok || key == "result" {
+			continue
+		}
+		valueNode := &yaml.Node{}
+		if err := valueNode.Encode(value); err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", key, err)
+		}
+		frontmatter.Content = append(frontmatter.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, valueNode)
+	}
+
+	var fm bytes.Buffer
+	enc := yaml.NewEncoder(&fm)
+	enc.SetIndent(2)
+	if err := enc.Encode(frontmatter); err != nil {
+		return nil, fmt.Errorf("failed to marshal frontmatter: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to marshal frontmatter: %w", err)
+	}
+	return []byte(fmt.Sprintf("---\n%s---\n\n%s\n", fm.String(), strings.TrimRight(session.Result, "\n"))), nil
+}
+
+// ParseMarkdown reads a session written by MarshalMarkdown
+func ParseMarkdown(data []byte) (*db.ResearchSession, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if !strings.HasPrefix(text, "---\n") {
+		return nil, fmt.Errorf("missing frontmatter")
+	}
+	fm, body, found := strings.Cut(text[len("---\n"):], "\n---\n")
+	if !found {
+		return nil, fmt.Errorf("unterminated frontmatter")
+	}
+
+	var fields map[string]interface{}
+	if err := yaml.Unmarshal([]byte(fm), &fields); err != nil {
+		return nil, fmt.Errorf("failed to parse frontmatter: %w", err)
+	}
+	if fields == nil {
+		fields = make(map[string]interface{})
+	}
+	fields["result"] = strings.TrimSpace(body)
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse frontmatter: %w", err)
+	}
+	session := &db.ResearchSession{}
+	if err := json.Unmarshal(data, session); err != nil {
+		return nil, fmt.Errorf("failed to parse frontmatter: %w", err)
+	}
+	return session, nil
+}
+
+// ReadMarkdownDir reads the sessions in a directory written by
+// WriteMarkdown, in file name order
+func ReadMarkdownDir(dir string) ([]*db.ResearchSession, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.md"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archive: %w", err)
+	}
+	sort.Strings(paths)
+
+	var sessions []*db.ResearchSession
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		session, err := ParseMarkdown(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+// Read reads an archive of either format: a directory of markdown files, a
+// single markdown file or a JSONL file
+func Read(path string) ([]*db.ResearchSession, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	if info.IsDir() {
+		return ReadMarkdownDir(path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".md") {
+		session, err := ParseMarkdown(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return []*db.ResearchSession{session}, nil
+	}
+	return ReadJSONL(bytes.NewReader(data))
+}
+
+// sessionFields lists the JSON field names of db.ResearchSession in
+// declaration order
+var sessionFields = func() []string {
+	var names []string
+	t := reflect.TypeOf(db.ResearchSession{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}()
+
+var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slugify turns a query into a short file-name friendly string
+func slugify(s string) string {
+	slug := strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(s), "-"), "-")
+	if len(slug) > 60 {
+		slug = strings.TrimRight(slug[:60], "-")
+	}
+	if slug == "" {
+		slug = "session"
+	}
+	return slug
+}
@@ -0,0 +1,137 @@
+package archive
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSessions() []*db.ResearchSession {
+	score, rating := 82, 4
+	return []*db.ResearchSession{
+		{
+			ID:           3,
+			Query:        "How do Go contexts work?",
+			RefinedQuery: "Explain context cancellation",
+			Mode:         "deep",
+			PromptUsed:   "default",
+			Result:       "# Go Contexts\n\n---\n\nContexts carry deadlines.",
+			QualityScore: &score,
+			Citations:    []db.Citation{{Text: "docs", URL: "https://pkg.go.dev/context", Status: db.CitationOK, StatusCode: 200}},
+			Status:       db.SessionComplete,
+			Provider:     "openai",
+			Model:        "gpt-4o",
+			Tokens:       db.TokenCount{Prompt: 10, Completion: 20, Total: 30},
+			UserRating:   &rating,
+			Starred:      true,
+			Tags:         []string{"concurrency", "go"},
+			Notes:        "Matches the\nstdlib docs",
+			CreatedAt:    time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			ID:         4,
+			Query:      "Kafka vs NATS",
+			Mode:       "compare",
+			PromptUsed: "default",
+			Result:     "NATS.",
+			Matrix:     &db.ComparisonMatrix{Criteria: []string{"Speed"}, Options: []db.MatrixOption{{Name: "NATS", Scores: []db.MatrixScore{{Criterion: "Speed", Score: 5}}}}},
+			Status:     db.SessionCancelled,
+			Checkpoint: &db.Checkpoint{Stage: "compare_matrix", Options: []string{"Kafka", "NATS"}},
+			ParentID:   3,
+			CreatedAt:  time.Date(2026, 3, 2, 8, 15, 30, 0, time.FixedZone("CET", 3600)),
+		},
+	}
+}
+
+// assertSameSessions compares sessions field by field, with times compared
+// as instants
+func assertSameSessions(t *testing.T, want, got []*db.ResearchSession) {
+	t.Helper()
+	require.Len(t, got, len(want))
+	for i := range want {
+		assert.True(t, want[i].CreatedAt.Equal(got[i].CreatedAt), "created_at of session %d", want[i].ID)
+		w, g := *want[i], *got[i]
+		w.CreatedAt, g.CreatedAt = time.Time{}, time.Time{}
+		assert.Equal(t, w, g)
+	}
+}
+
+func TestJSONL_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteJSONL(&buf, testSessions()))
+	assert.Equal(t, 2, strings.Count(buf.String(), "\n"), "one session per line")
+
+	sessions, err := ReadJSONL(strings.NewReader(buf.String() + "\n\n"))
+	require.NoError(t, err)
+	assertSameSessions(t, testSessions(), sessions)
+
+	_, err = ReadJSONL(strings.NewReader("{\"id\": 1}\nnot json\n"))
+	assert.ErrorContains(t, err, "line 2: invalid session")
+}
+
+func TestMarkdown_RoundTrip(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "export")
+	files, err := WriteMarkdown(dir, testSessions())
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "0003-how-do-go-contexts-work.md"),
+		filepath.Join(dir, "0004-kafka-vs-nats.md"),
+	}, files)
+
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	text := string(data)
+	assert.True(t, strings.HasPrefix(text, "---\nid: 3\nquery: How do Go contexts work?\nrefined_query: Explain context cancellation\nmode: deep\n"))
+	assert.Contains(t, text, "\nprovider: openai\n")
+	assert.Contains(t, text, "\ntags:\n  - concurrency\n  - go\n")
+	assert.True(t, strings.HasSuffix(text, "---\n\n# Go Contexts\n\n---\n\nContexts carry deadlines.\n"), "the answer is the body")
+	assert.NotContains(t, text, "result:")
+
+	sessions, err := ReadMarkdownDir(dir)
+	require.NoError(t, err)
+	assertSameSessions(t, testSessions(), sessions)
+
+	sessions, err = Read(files[1])
+	require.NoError(t, err)
+	assertSameSessions(t, testSessions()[1:], sessions)
+}
+
+func TestParseMarkdown_Errors(t *testing.T) {
+	_, err := ParseMarkdown([]byte("# No frontmatter"))
+	assert.ErrorContains(t, err, "missing frontmatter")
+
+	_, err = ParseMarkdown([]byte("---\nid: 1\n"))
+	assert.ErrorContains(t, err, "unterminated frontmatter")
+
+	_, err = ParseMarkdown([]byte("---\nid: [\n---\nbody"))
+	assert.ErrorContains(t, err, "failed to parse frontmatter")
+}
+
+func TestRead_DetectsFormat(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "history.jsonl")
+	var buf bytes.Buffer
+	require.NoError(t, WriteJSONL(&buf, testSessions()))
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+
+	sessions, err := Read(path)
+	require.NoError(t, err)
+	assertSameSessions(t, testSessions(), sessions)
+
+	mdDir := filepath.Join(dir, "md")
+	_, err = WriteMarkdown(mdDir, testSessions())
+	require.NoError(t, err)
+	sessions, err = Read(mdDir)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 2)
+
+	_, err = Read(filepath.Join(dir, "missing.jsonl"))
+	assert.ErrorContains(t, err, "failed to read archive")
+}
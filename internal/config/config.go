@@ -12,6 +12,31 @@ import (
 // Config holds the entire application configuration
 type Config struct {
 	Providers ProviderConfig `yaml:"providers"`
+	History   HistoryConfig  `yaml:"history"`
+	Database  DatabaseConfig `yaml:"database"`
+}
+
+// DatabaseConfig selects the storage backend of the research history
+type DatabaseConfig struct {
+	Driver     string           `yaml:"driver"` // Storage driver, e.g. sqlite or sqlite-purego; empty picks the default
+	Encryption EncryptionConfig `yaml:"encryption"`
+}
+
+// EncryptionConfig encrypts the queries and answers in the research history
+// at rest, with a key derived from a passphrase or read from a key file
+type EncryptionConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	KeySource     string `yaml:"key_source"`     // passphrase or key_file
+	KeyFile       string `yaml:"key_file"`       // Path of the key file, with key_source key_file
+	PassphraseEnv string `yaml:"passphrase_env"` // Variable holding the passphrase; without it, the passphrase is prompted for
+}
+
+// HistoryConfig holds the retention policy for the research history,
+// applied whenever the database is opened. Zero values keep everything, and
+// starred sessions are always kept.
+type HistoryConfig struct {
+	MaxAge      time.Duration `yaml:"max_age"`      // Delete sessions older than this, e.g. 720h
+	MaxSessions int           `yaml:"max_sessions"` // Keep only this many of the most recent sessions
 }
 
 // ProviderConfig holds configuration for AI providers
@@ -85,6 +110,12 @@ func DefaultConfig() *Config {
 			AutoFallback:   true,
 			NotifyFallback: true,
 		},
+		Database: DatabaseConfig{
+			Encryption: EncryptionConfig{
+				KeySource:     "passphrase",
+				PassphraseEnv: "COPILOT_RESEARCH_DB_PASSPHRASE",
+			},
+		},
 	}
 }
 
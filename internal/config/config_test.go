@@ -66,6 +66,49 @@ providers:
 	assert.Equal(t, 60*time.Second, cfg.Providers.GitHubCopilot.Timeout)
 }
 
+func TestLoadConfig_HistoryRetention(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(cfgPath, []byte("history:\n  max_age: 720h\n  max_sessions: 500\n"), 0644)
+	require.NoError(t, err)
+
+	cfg, err := LoadConfig(cfgPath)
+	require.NoError(t, err)
+	assert.Equal(t, 30*24*time.Hour, cfg.History.MaxAge)
+	assert.Equal(t, 500, cfg.History.MaxSessions)
+
+	// Retention is off by default
+	assert.Zero(t, DefaultConfig().History)
+}
+
+func TestLoadConfig_DatabaseDriver(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(cfgPath, []byte("database:\n  driver: sqlite-purego\n"), 0644)
+	require.NoError(t, err)
+
+	cfg, err := LoadConfig(cfgPath)
+	require.NoError(t, err)
+	assert.Equal(t, "sqlite-purego", cfg.Database.Driver)
+
+	// The driver is picked at run time by default
+	assert.Empty(t, DefaultConfig().Database.Driver)
+}
+
+func TestLoadConfig_DatabaseEncryption(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(cfgPath, []byte("database:\n  encryption:\n    enabled: true\n    key_source: key_file\n    key_file: ~/.copilot-research/db.key\n"), 0644)
+	require.NoError(t, err)
+
+	cfg, err := LoadConfig(cfgPath)
+	require.NoError(t, err)
+	assert.True(t, cfg.Database.Encryption.Enabled)
+	assert.Equal(t, "key_file", cfg.Database.Encryption.KeySource)
+	assert.Equal(t, "~/.copilot-research/db.key", cfg.Database.Encryption.KeyFile)
+	assert.Equal(t, "COPILOT_RESEARCH_DB_PASSPHRASE", cfg.Database.Encryption.PassphraseEnv)
+
+	// Encryption is off by default
+	assert.False(t, DefaultConfig().Database.Encryption.Enabled)
+}
+
 func TestLoadConfig_InvalidFile(t *testing.T) {
 	tmpDir := t.TempDir()
 	cfgPath := filepath.Join(tmpDir, "config.yaml")
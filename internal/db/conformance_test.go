@@ -0,0 +1,34 @@
+package db_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/joelklabo/copilot-research/internal/db/dbtest"
+	"github.com/stretchr/testify/require"
+)
+
+// openDriver opens a fresh database with a registered driver
+func openDriver(name string, opts db.Options) dbtest.Opener {
+	return func(t *testing.T) db.DB {
+		d, err := db.Open(name, filepath.Join(t.TempDir(), "research.db"), opts)
+		require.NoError(t, err)
+		t.Cleanup(func() { d.Close() })
+		return d
+	}
+}
+
+func TestConformance_Drivers(t *testing.T) {
+	require.NotEmpty(t, db.Drivers())
+	for _, name := range db.Drivers() {
+		t.Run(name, func(t *testing.T) {
+			dbtest.RunConformance(t, openDriver(name, db.Options{}))
+		})
+	}
+}
+
+func TestConformance_Encrypted(t *testing.T) {
+	key := &db.Key{Source: db.KeyFile, Secret: []byte("0123456789abcdef0123456789abcdef")}
+	dbtest.RunConformance(t, openDriver(db.DefaultDriver(), db.Options{Key: key}))
+}
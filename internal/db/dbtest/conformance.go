@@ -0,0 +1,503 @@
+// Package dbtest holds the conformance suite every db.DB backend must pass
+package dbtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Opener returns a new, empty database for one test. It should close the
+// database with t.Cleanup.
+type Opener func(t *testing.T) db.DB
+
+// RunConformance checks that the databases returned by open behave as the
+// db.DB interface documents
+func RunConformance(t *testing.T, open Opener) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, d db.DB)
+	}{
+		{"Sessions", testSessions},
+		{"SessionSearch", testSessionSearch},
+		{"Delete", testDelete},
+		{"RetentionSparesStarred", testRetentionSparesStarred},
+		{"Import", testImport},
+		{"SearchLog", testSearchLog},
+		{"Organization", testOrganization},
+		{"Patterns", testPatterns},
+		{"Ratings", testRatings},
+		{"Watches", testWatches},
+		{"Stats", testStats},
+		{"Usage", testUsage},
+		{"Vacuum", testVacuum},
+		{"Rekey", testRekey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, open(t))
+		})
+	}
+}
+
+// base is the creation time of the first session saved by a test
+var base = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// saveSessions saves complete sessions for the queries, one minute apart in
+// order, and returns them with their IDs set
+func saveSessions(t *testing.T, d db.DB, mode string, queries ...string) []*db.ResearchSession {
+	t.Helper()
+
+	sessions := make([]*db.ResearchSession, len(queries))
+	for i, q := range queries {
+		sessions[i] = &db.ResearchSession{
+			Query:      q,
+			Mode:       mode,
+			PromptUsed: "default",
+			Result:     "Findings on " + q,
+			Status:     db.SessionComplete,
+			Provider:   "openai",
+			CreatedAt:  base.Add(time.Duration(i) * time.Minute),
+		}
+		require.NoError(t, d.SaveSession(sessions[i]))
+		require.NotZero(t, sessions[i].ID)
+	}
+	return sessions
+}
+
+// queries returns the queries of sessions, in order
+func queries(sessions []*db.ResearchSession) []string {
+	out := make([]string, len(sessions))
+	for i, s := range sessions {
+		out[i] = s.Query
+	}
+	return out
+}
+
+func testSessions(t *testing.T, d db.DB) {
+	score := 82
+	session := &db.ResearchSession{
+		Query:        "Go generics",
+		RefinedQuery: "How do Go generics compare to Java's?",
+		Mode:         "deep",
+		PromptUsed:   "default",
+		Result:       "Generics landed in Go 1.18",
+		QualityScore: &score,
+		Citations:    []db.Citation{{URL: "https://go.dev/doc/tutorial/generics"}},
+		Status:       db.SessionInProgress,
+		Checkpoint:   &db.Checkpoint{Stage: "main", Content: "Generics landed"},
+		Provider:     "openai",
+		Model:        "gpt-4o",
+		Tokens:       db.TokenCount{Prompt: 10, Completion: 20, Total: 30},
+		CreatedAt:    base.Add(-time.Hour),
+	}
+	require.NoError(t, d.SaveSession(session))
+	require.NotZero(t, session.ID)
+
+	got, err := d.GetSession(session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, session.Query, got.Query)
+	assert.Equal(t, session.RefinedQuery, got.RefinedQuery)
+	assert.Equal(t, session.Result, got.Result)
+	assert.Equal(t, &score, got.QualityScore)
+	assert.Equal(t, session.Citations[0].URL, got.Citations[0].URL)
+	assert.Equal(t, session.Checkpoint, got.Checkpoint)
+	assert.Equal(t, session.Tokens, got.Tokens)
+	assert.True(t, got.CreatedAt.Equal(session.CreatedAt))
+	assert.True(t, got.InProgress())
+
+	session.Result = "Generics landed in Go 1.18, with type sets"
+	session.Status = db.SessionComplete
+	session.Checkpoint = nil
+	require.NoError(t, d.UpdateSession(session))
+	got, err = d.GetSession(session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, session.Result, got.Result)
+	assert.Equal(t, db.SessionComplete, got.Status)
+	assert.Nil(t, got.Checkpoint)
+
+	_, err = d.GetSession(session.ID + 100)
+	assert.Error(t, err)
+	assert.Error(t, d.UpdateSession(&db.ResearchSession{ID: session.ID + 100, Query: "missing"}))
+
+	// Newest first, paged
+	saveSessions(t, d, "quick", "first", "second", "third")
+	page, err := d.ListSessions(2, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"third", "second"}, queries(page))
+	page, err = d.ListSessions(2, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "Go generics"}, queries(page))
+
+	latest, err := d.GetLatestSession("Go generics", "deep")
+	require.NoError(t, err)
+	require.NotNil(t, latest)
+	assert.Equal(t, session.ID, latest.ID)
+	latest, err = d.GetLatestSession("Go generics", "quick")
+	require.NoError(t, err)
+	assert.Nil(t, latest)
+}
+
+func testSessionSearch(t *testing.T, d db.DB) {
+	saveSessions(t, d, "quick", "Kafka partitions", "NATS streams")
+	saveSessions(t, d, "deep", "Kafka vs NATS")
+
+	found, err := d.SearchSessions("Kafka")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"Kafka partitions", "Kafka vs NATS"}, queries(found))
+
+	matches, err := d.FindSessions(db.SessionFilter{Text: "kafka"})
+	require.NoError(t, err)
+	assert.Len(t, matches, 2)
+
+	matches, err = d.FindSessions(db.SessionFilter{Text: "kafka", Mode: "deep"})
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "Kafka vs NATS", matches[0].Query)
+
+	matches, err = d.FindSessions(db.SessionFilter{Since: base.Add(time.Minute), Limit: 1})
+	require.NoError(t, err)
+	assert.Len(t, matches, 1)
+}
+
+func testDelete(t *testing.T, d db.DB) {
+	sessions := saveSessions(t, d, "quick", "one", "two", "three", "four")
+
+	require.NoError(t, d.DeleteSession(sessions[0].ID))
+	_, err := d.GetSession(sessions[0].ID)
+	assert.Error(t, err)
+	assert.Error(t, d.DeleteSession(sessions[0].ID))
+
+	removed, err := d.DeleteSessionsBefore(base.Add(90 * time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	removed, err = d.KeepLatestSessions(1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+	remaining, err := d.ListSessions(10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"four"}, queries(remaining))
+
+	removed, err = d.ClearAll()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+	total, err := d.GetTotalSessions()
+	require.NoError(t, err)
+	assert.Zero(t, total)
+}
+
+func testRetentionSparesStarred(t *testing.T, d db.DB) {
+	sessions := saveSessions(t, d, "quick", "one", "two", "three", "four")
+	require.NoError(t, d.SetStarred(sessions[0].ID, true))
+	require.NoError(t, d.SetStarred(sessions[2].ID, true))
+
+	removed, err := d.DeleteSessionsBefore(base.Add(150 * time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	removed, err = d.KeepLatestSessions(1)
+	require.NoError(t, err)
+	assert.Zero(t, removed, "the only unstarred session left is the latest")
+
+	remaining, err := d.ListSessions(10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"four", "three", "one"}, queries(remaining))
+}
+
+func testImport(t *testing.T, d db.DB) {
+	existing := saveSessions(t, d, "quick", "already here")[0]
+	imported := &db.ResearchSession{
+		Query:     "from another machine",
+		Mode:      "deep",
+		Result:    "Imported findings",
+		Status:    db.SessionComplete,
+		Starred:   true,
+		Tags:      []string{"imported"},
+		CreatedAt: base.Add(-time.Hour),
+	}
+
+	result, err := d.ImportSessions([]*db.ResearchSession{
+		{Query: existing.Query, Mode: existing.Mode, Result: existing.Result, Status: db.SessionComplete, CreatedAt: existing.CreatedAt},
+		imported,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &db.ImportResult{Imported: 1, Skipped: 1}, result)
+
+	matches, err := d.FindSessions(db.SessionFilter{Tags: []string{"imported"}, Starred: true})
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.True(t, matches[0].CreatedAt.Equal(imported.CreatedAt))
+
+	// Importing the same sessions again changes nothing
+	result, err = d.ImportSessions([]*db.ResearchSession{imported})
+	require.NoError(t, err)
+	assert.Equal(t, &db.ImportResult{Skipped: 1}, result)
+}
+
+func testSearchLog(t *testing.T, d db.DB) {
+	entries := []*db.SearchHistory{
+		{Query: "Kafka partitions", Mode: "quick", Latency: 2 * time.Second, CreatedAt: base},
+		{Query: "Rust async", Mode: "deep", Outcome: db.OutcomeFailed, Error: "timeout", CreatedAt: base.Add(time.Minute)},
+		{Query: "kafka partitions", Mode: "quick", CreatedAt: base.Add(2 * time.Minute)},
+	}
+	for _, e := range entries {
+		require.NoError(t, d.LogSearch(e))
+		assert.NotZero(t, e.ID)
+	}
+	assert.Equal(t, db.OutcomeSuccess, entries[0].Outcome)
+
+	all, err := d.ListSearches(db.SearchFilter{})
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	assert.Equal(t, entries[2].ID, all[0].ID)
+	assert.Equal(t, 2*time.Second, all[2].Latency)
+
+	failed, err := d.ListSearches(db.SearchFilter{Outcome: db.OutcomeFailed})
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	assert.Equal(t, "timeout", failed[0].Error)
+
+	distinct, err := d.ListSearches(db.SearchFilter{Text: "KAFKA", Distinct: true})
+	require.NoError(t, err)
+	require.Len(t, distinct, 1)
+	assert.Equal(t, entries[2].ID, distinct[0].ID)
+}
+
+func testOrganization(t *testing.T, d db.DB) {
+	sessions := saveSessions(t, d, "quick", "tagged", "plain")
+	id := sessions[0].ID
+
+	tags, err := d.UpdateTags(id, []string{"Go", "concurrency"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"concurrency", "go"}, tags)
+	tags, err = d.UpdateTags(id, nil, []string{"concurrency"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go"}, tags)
+	_, err = d.UpdateTags(id+100, []string{"go"}, nil)
+	assert.Error(t, err)
+
+	require.NoError(t, d.SetStarred(id, true))
+	require.NoError(t, d.SetNotes(id, "Checked against the spec"))
+	assert.Error(t, d.SetStarred(id+100, true))
+
+	got, err := d.GetSession(id)
+	require.NoError(t, err)
+	assert.True(t, got.Starred)
+	assert.Equal(t, []string{"go"}, got.Tags)
+	assert.Equal(t, "Checked against the spec", got.Notes)
+
+	// Checkpoint updates leave the user's organization alone
+	require.NoError(t, d.UpdateSession(got))
+	got, err = d.GetSession(id)
+	require.NoError(t, err)
+	assert.True(t, got.Starred)
+
+	matches, err := d.FindSessions(db.SessionFilter{Starred: true})
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, id, matches[0].ID)
+
+	counts, err := d.GetTagCounts()
+	require.NoError(t, err)
+	assert.Equal(t, []db.TagCount{{Tag: "go", Count: 1}}, counts)
+}
+
+func testPatterns(t *testing.T, d db.DB) {
+	pattern := &db.LearnedPattern{PatternName: "compare-first", Description: "Compare before deep dives", SuccessCount: 1}
+	require.NoError(t, d.SavePattern(pattern))
+
+	require.NoError(t, d.IncrementPattern("compare-first"))
+	got, err := d.GetPattern("compare-first")
+	require.NoError(t, err)
+	assert.Equal(t, "Compare before deep dives", got.Description)
+	assert.Equal(t, 2, got.SuccessCount)
+
+	_, err = d.GetPattern("missing")
+	assert.Error(t, err)
+	assert.Error(t, d.IncrementPattern("missing"))
+}
+
+func testRatings(t *testing.T, d db.DB) {
+	sessions := saveSessions(t, d, "deep", "one", "two")
+
+	pattern, err := d.RateSession(sessions[0].ID, 4)
+	require.NoError(t, err)
+	require.NotNil(t, pattern)
+	assert.Equal(t, 1, pattern.RatingCount)
+
+	pattern, err = d.RateSession(sessions[1].ID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 3.0, pattern.AverageRating())
+
+	// Rating again replaces the earlier rating
+	pattern, err = d.RateSession(sessions[1].ID, 5)
+	require.NoError(t, err)
+	assert.Equal(t, 2, pattern.RatingCount)
+	assert.Equal(t, 4.5, pattern.AverageRating())
+
+	_, err = d.RateSession(sessions[0].ID, 6)
+	assert.Error(t, err)
+	_, err = d.RateSession(sessions[1].ID+100, 3)
+	assert.Error(t, err)
+
+	patterns, err := d.GetRatingPatterns()
+	require.NoError(t, err)
+	assert.Len(t, patterns, 1)
+
+	best, err := d.BestPrompt("deep", 2)
+	require.NoError(t, err)
+	require.NotNil(t, best)
+	assert.Equal(t, "default", best.PromptUsed)
+	best, err = d.BestPrompt("deep", 3)
+	require.NoError(t, err)
+	assert.Nil(t, best)
+}
+
+func testWatches(t *testing.T, d db.DB) {
+	watch := &db.WatchedQuery{Query: "Go release notes", Mode: "quick", PromptUsed: "default", Interval: 24 * time.Hour}
+	require.NoError(t, d.SaveWatch(watch))
+
+	watches, err := d.ListWatches()
+	require.NoError(t, err)
+	require.Len(t, watches, 1)
+	assert.Equal(t, 24*time.Hour, watches[0].Interval)
+	assert.Nil(t, watches[0].LastRunAt)
+
+	id := watches[0].ID
+	session := saveSessions(t, d, "quick", "Go release notes")[0]
+	require.NoError(t, d.UpdateWatchRun(id, base, session.ID))
+	watches, err = d.ListWatches()
+	require.NoError(t, err)
+	require.NotNil(t, watches[0].LastRunAt)
+	assert.True(t, watches[0].LastRunAt.Equal(base))
+	assert.Equal(t, session.ID, *watches[0].LastSessionID)
+
+	require.NoError(t, d.DeleteWatch(id))
+	assert.Error(t, d.DeleteWatch(id))
+	assert.Error(t, d.UpdateWatchRun(id, base, session.ID))
+}
+
+func testStats(t *testing.T, d db.DB) {
+	saveSessions(t, d, "quick", "one", "two")
+	saveSessions(t, d, "deep", "three")
+	for _, q := range []string{"Kafka", "Kafka", "NATS"} {
+		require.NoError(t, d.LogSearch(&db.SearchHistory{Query: q, Mode: "quick"}))
+	}
+
+	total, err := d.GetTotalSessions()
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+
+	modes, err := d.GetModeStats()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"quick": 2, "deep": 1}, modes)
+
+	top, err := d.GetTopQueries(1)
+	require.NoError(t, err)
+	assert.Equal(t, []db.QueryCount{{Query: "Kafka", Count: 2}}, top)
+}
+
+func testUsage(t *testing.T, d db.DB) {
+	day := 24 * time.Hour
+	for _, s := range []*db.ResearchSession{
+		{Provider: "openai", Model: "gpt-4o", Tokens: db.TokenCount{Prompt: 100, Completion: 50, Total: 150}, CreatedAt: base},
+		{Provider: "openai", Model: "gpt-4o", Tokens: db.TokenCount{Prompt: 200, Completion: 100, Total: 300}, CreatedAt: base.Add(day)},
+		{Provider: "anthropic", Model: "claude-3-5-sonnet", Tokens: db.TokenCount{Prompt: 10, Completion: 5, Total: 15}, CreatedAt: base.Add(day + time.Hour)},
+		{Provider: "openai", Model: "gpt-4o", Tokens: db.TokenCount{Prompt: 1, Completion: 1, Total: 2}, CreatedAt: base.Add(-30 * day)},
+	} {
+		s.Query, s.Mode, s.PromptUsed, s.Result, s.Status = "q", "quick", "default", "r", db.SessionComplete
+		require.NoError(t, d.SaveSession(s))
+	}
+	for _, e := range []*db.SearchHistory{
+		{Provider: "openai", Latency: time.Second, CreatedAt: base},
+		{Provider: "openai", Outcome: db.OutcomeFailed, CreatedAt: base.Add(time.Minute)},
+		{Provider: "openai", Latency: 3 * time.Second, CreatedAt: base.Add(day)},
+		{Outcome: db.OutcomeFailed, CreatedAt: base.Add(day)},
+		{Provider: "github-copilot", Outcome: db.OutcomeCancelled, Latency: 500 * time.Millisecond, CreatedAt: base.Add(day)},
+		{Provider: "openai", CreatedAt: base.Add(-30 * day)},
+	} {
+		e.Query = "q"
+		require.NoError(t, d.LogSearch(e))
+	}
+
+	march1 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	march2 := march1.Add(day)
+	filter := db.UsageFilter{Since: base.Add(-time.Hour), Period: db.PeriodDay}
+
+	sessions, err := d.GetSessionUsage(filter)
+	require.NoError(t, err)
+	assert.Equal(t, []db.SessionUsage{
+		{Period: march1, Provider: "openai", Model: "gpt-4o", Sessions: 1, Tokens: db.TokenCount{Prompt: 100, Completion: 50, Total: 150}},
+		{Period: march2, Provider: "anthropic", Model: "claude-3-5-sonnet", Sessions: 1, Tokens: db.TokenCount{Prompt: 10, Completion: 5, Total: 15}},
+		{Period: march2, Provider: "openai", Model: "gpt-4o", Sessions: 1, Tokens: db.TokenCount{Prompt: 200, Completion: 100, Total: 300}},
+	}, sessions)
+
+	runs, err := d.GetRunUsage(filter)
+	require.NoError(t, err)
+	assert.Equal(t, []db.RunUsage{
+		{Period: march1, Provider: "openai", Runs: 2, Failed: 1, AverageLatency: time.Second},
+		{Period: march2, Provider: "", Runs: 1, Failed: 1},
+		{Period: march2, Provider: "github-copilot", Runs: 1, Cancelled: 1, AverageLatency: 500 * time.Millisecond},
+		{Period: march2, Provider: "openai", Runs: 1, AverageLatency: 3 * time.Second},
+	}, runs)
+
+	// 1 March 2026 is a Sunday, the last day of the week before
+	filter.Period = db.PeriodWeek
+	sessions, err = d.GetSessionUsage(filter)
+	require.NoError(t, err)
+	require.Len(t, sessions, 3)
+	assert.Equal(t, march1.AddDate(0, 0, -6), sessions[0].Period)
+	assert.Equal(t, march2, sessions[1].Period)
+
+	// Without a period, each provider has a single bucket over the window
+	filter.Period = ""
+	runs, err = d.GetRunUsage(filter)
+	require.NoError(t, err)
+	require.Len(t, runs, 3)
+	assert.Equal(t, db.RunUsage{Provider: "openai", Runs: 3, Failed: 1, AverageLatency: 2 * time.Second}, runs[2])
+
+	filter.Until = base.Add(time.Hour)
+	sessions, err = d.GetSessionUsage(filter)
+	require.NoError(t, err)
+	assert.Equal(t, []db.SessionUsage{{Provider: "openai", Model: "gpt-4o", Sessions: 1, Tokens: db.TokenCount{Prompt: 100, Completion: 50, Total: 150}}}, sessions)
+}
+
+func testVacuum(t *testing.T, d db.DB) {
+	saveSessions(t, d, "quick", "one")
+	_, err := d.ClearAll()
+	require.NoError(t, err)
+	assert.NoError(t, d.Vacuum())
+}
+
+func testRekey(t *testing.T, d db.DB) {
+	session := saveSessions(t, d, "quick", "Kafka partitions")[0]
+	require.NoError(t, d.LogSearch(&db.SearchHistory{Query: "Kafka partitions", SessionID: session.ID}))
+
+	// Sessions stay readable and searchable under a new key and after
+	// removing it
+	for _, key := range []*db.Key{{Source: db.KeyFile, Secret: []byte("conformance key")}, nil} {
+		require.NoError(t, d.Rekey(key))
+
+		got, err := d.GetSession(session.ID)
+		require.NoError(t, err)
+		assert.Equal(t, session.Query, got.Query)
+		assert.Equal(t, session.Result, got.Result)
+
+		matches, err := d.FindSessions(db.SessionFilter{Text: "partitions"})
+		require.NoError(t, err)
+		require.Len(t, matches, 1)
+		assert.Equal(t, session.ID, matches[0].ID)
+
+		searches, err := d.ListSearches(db.SearchFilter{Text: "kafka"})
+		require.NoError(t, err)
+		require.Len(t, searches, 1)
+		assert.Equal(t, "Kafka partitions", searches[0].Query)
+	}
+
+	assert.Error(t, d.Rekey(&db.Key{Source: db.KeyFile}))
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Driver opens a storage backend for the research database at path
+type Driver func(path string, opts Options) (DB, error)
+
+// Options configure how a driver opens the research database
+type Options struct {
+	Key *Key // Encrypts the database at rest; nil if it is not encrypted
+}
+
+// Storage drivers registered by this package, depending on build
+const (
+	DriverSQLite       = "sqlite"        // SQLite through mattn/go-sqlite3, needs cgo
+	DriverSQLitePureGo = "sqlite-purego" // SQLite through modernc.org/sqlite, built with -tags purego
+)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]Driver)
+)
+
+// Register makes a storage driver available by name. It panics if the name
+// is already registered, like database/sql.Register.
+func Register(name string, driver Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	if driver == nil {
+		panic("db: Register driver is nil")
+	}
+	if _, dup := drivers[name]; dup {
+		panic("db: Register called twice for driver " + name)
+	}
+	drivers[name] = driver
+}
+
+// Drivers returns the names of the registered storage drivers, sorted
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultDriver returns the driver used when none is configured: cgo SQLite
+// when it is compiled in, then pure-Go SQLite, then any registered driver.
+// It returns "" if none is.
+func DefaultDriver() string {
+	names := Drivers()
+	for _, preferred := range []string{DriverSQLite, DriverSQLitePureGo} {
+		for _, name := range names {
+			if name == preferred {
+				return name
+			}
+		}
+	}
+	if len(names) > 0 {
+		return names[0]
+	}
+	return ""
+}
+
+// Open opens the research database at path with the named storage driver,
+// or with DefaultDriver if name is empty
+func Open(name, path string, opts Options) (DB, error) {
+	if name == "" {
+		name = DefaultDriver()
+	}
+
+	driversMu.RLock()
+	driver, ok := drivers[name]
+	driversMu.RUnlock()
+
+	if !ok {
+		available := strings.Join(Drivers(), ", ")
+		if available == "" {
+			return nil, fmt.Errorf("no storage driver is compiled in: build with cgo enabled, or with -tags purego")
+		}
+		return nil, fmt.Errorf("unknown storage driver %q (available: %s)", name, available)
+	}
+	return driver(path, opts)
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegister(t *testing.T) {
+	opened := ""
+	key := &Key{Source: KeyPassphrase, Secret: []byte("secret")}
+	var gotOpts Options
+	Register("test-driver", func(path string, opts Options) (DB, error) {
+		opened = path
+		gotOpts = opts
+		return &MockDB{}, nil
+	})
+	defer func() {
+		driversMu.Lock()
+		delete(drivers, "test-driver")
+		driversMu.Unlock()
+	}()
+
+	assert.Contains(t, Drivers(), "test-driver")
+	d, err := Open("test-driver", "/tmp/research.db", Options{Key: key})
+	require.NoError(t, err)
+	assert.IsType(t, &MockDB{}, d)
+	assert.Equal(t, "/tmp/research.db", opened)
+	assert.Same(t, key, gotOpts.Key)
+
+	assert.Panics(t, func() { Register("test-driver", func(string, Options) (DB, error) { return nil, nil }) })
+	assert.Panics(t, func() { Register("nil-driver", nil) })
+}
+
+func TestOpen_UnknownDriver(t *testing.T) {
+	_, err := Open("postgres", filepath.Join(t.TempDir(), "research.db"), Options{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown storage driver "postgres"`)
+	assert.Contains(t, err.Error(), DriverSQLite)
+}
+
+func TestOpen_DefaultDriver(t *testing.T) {
+	// The cgo driver is preferred; a build with CGO_ENABLED=0 -tags purego
+	// only has the pure-Go one
+	want := DriverSQLite
+	if _, ok := sqliteEngines[DriverSQLite]; !ok {
+		want = DriverSQLitePureGo
+	}
+	assert.Equal(t, want, DefaultDriver())
+
+	d, err := Open("", filepath.Join(t.TempDir(), "research.db"), Options{})
+	require.NoError(t, err)
+	defer d.Close()
+	assert.IsType(t, &SQLiteDB{}, d)
+}
@@ -0,0 +1,338 @@
+package db
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sources of the key of an encrypted database
+const (
+	KeyPassphrase = "passphrase"
+	KeyFile       = "key_file"
+)
+
+// Key is the secret the encryption key of a database is derived from: a
+// passphrase, stretched with PBKDF2, or the contents of a key file
+type Key struct {
+	Source string // KeyPassphrase or KeyFile
+	Secret []byte
+}
+
+var (
+	// ErrKeyRequired is returned when opening an encrypted database without a key
+	ErrKeyRequired = errors.New("research database is encrypted, but no key was given")
+	// ErrWrongKey is returned when a key does not decrypt the database
+	ErrWrongKey = errors.New("wrong key for the research database")
+)
+
+// pbkdf2Iterations is the PBKDF2-SHA256 work factor for new passphrases
+var pbkdf2Iterations = 600000
+
+const (
+	sealedPrefix = "enc:v1:"          // Marks a value sealed by fieldCipher
+	keyCheck     = "copilot-research" // Sealed into the encryption table to verify keys
+	keyInfo      = "copilot-research database key"
+)
+
+// fieldCipher seals and opens the encrypted columns with AES-256-GCM. A nil
+// fieldCipher leaves values in plaintext.
+type fieldCipher struct {
+	aead cipher.AEAD
+}
+
+// keyParams are the stored parameters a key is derived with
+type keyParams struct {
+	source     string
+	salt       []byte
+	iterations int
+	check      string
+}
+
+// newFieldCipher derives the encryption key from key with params
+func newFieldCipher(key *Key, params keyParams) (*fieldCipher, error) {
+	var derived []byte
+	var err error
+	switch key.Source {
+	case KeyPassphrase:
+		derived, err = pbkdf2.Key(sha256.New, string(key.Secret), params.salt, params.iterations, 32)
+	case KeyFile:
+		derived, err = hkdf.Key(sha256.New, key.Secret, params.salt, keyInfo, 32)
+	default:
+		return nil, fmt.Errorf("unknown key source %q (use %s or %s)", key.Source, KeyPassphrase, KeyFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return &fieldCipher{aead: aead}, nil
+}
+
+// seal encrypts a value. Empty values stay empty.
+func (c *fieldCipher) seal(value string) string {
+	if c == nil || value == "" {
+		return value
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	rand.Read(nonce)
+	sealed := c.aead.Seal(nonce, nonce, []byte(value), nil)
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed)
+}
+
+// open decrypts a sealed value. Values stored before the database was
+// encrypted are returned as they are.
+func (c *fieldCipher) open(value string) (string, error) {
+	if !strings.HasPrefix(value, sealedPrefix) {
+		return value, nil
+	}
+	if c == nil {
+		return "", ErrKeyRequired
+	}
+	data, err := base64.StdEncoding.DecodeString(value[len(sealedPrefix):])
+	if err != nil || len(data) < c.aead.NonceSize() {
+		return "", fmt.Errorf("failed to decrypt value: malformed ciphertext")
+	}
+	plain, err := c.aead.Open(nil, data[:c.aead.NonceSize()], data[c.aead.NonceSize():], nil)
+	if err != nil {
+		return "", ErrWrongKey
+	}
+	return string(plain), nil
+}
+
+// openAll decrypts values in place
+func (c *fieldCipher) openAll(values ...*string) error {
+	for _, v := range values {
+		plain, err := c.open(*v)
+		if err != nil {
+			return err
+		}
+		*v = plain
+	}
+	return nil
+}
+
+// readKeyParams returns the key parameters of an encrypted database, or nil
+// if it is not encrypted
+func readKeyParams(db *sql.DB) (*keyParams, error) {
+	params := &keyParams{}
+	err := db.QueryRow("SELECT key_source, salt, iterations, key_check FROM encryption WHERE id = 1").
+		Scan(&params.source, &params.salt, &params.iterations, &params.check)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encryption parameters: %w", err)
+	}
+	return params, nil
+}
+
+// unlock returns the cipher of the database with the stored params, checking
+// that key is the key it was encrypted with
+func unlock(key *Key, params *keyParams) (*fieldCipher, error) {
+	if params == nil {
+		return nil, nil
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%w (it was encrypted with a %s)", ErrKeyRequired, sourceName(params.source))
+	}
+	if key.Source != params.source {
+		return nil, fmt.Errorf("research database is encrypted with a %s, not a %s", sourceName(params.source), sourceName(key.Source))
+	}
+
+	c, err := newFieldCipher(key, *params)
+	if err != nil {
+		return nil, err
+	}
+	if check, err := c.open(params.check); err != nil || check != keyCheck {
+		return nil, ErrWrongKey
+	}
+	return c, nil
+}
+
+// sourceName describes a key source in messages
+func sourceName(source string) string {
+	if source == KeyFile {
+		return "key file"
+	}
+	return source
+}
+
+// newKeyParams creates fresh parameters for encrypting with key, and the
+// cipher they give
+func newKeyParams(key *Key) (*keyParams, *fieldCipher, error) {
+	params := keyParams{source: key.Source, salt: make([]byte, 16)}
+	rand.Read(params.salt)
+	if key.Source == KeyPassphrase {
+		params.iterations = pbkdf2Iterations
+	}
+
+	c, err := newFieldCipher(key, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	params.check = c.seal(keyCheck)
+	return &params, c, nil
+}
+
+// Rekey re-encrypts the database with key, encrypting it if it was not, or
+// decrypts it if key is nil. Space freed by the old values is reclaimed
+// with Vacuum.
+func (s *SQLiteDB) Rekey(key *Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next, err := s.rekey(key)
+	if err != nil {
+		return err
+	}
+	s.cipher = next
+	return nil
+}
+
+// rekey re-encrypts the database with key in a transaction and returns the
+// new cipher
+func (s *SQLiteDB) rekey(key *Key) (*fieldCipher, error) {
+	if key != nil && len(key.Secret) == 0 {
+		return nil, fmt.Errorf("encryption key is empty")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var next *fieldCipher
+	if _, err := tx.Exec("DELETE FROM encryption"); err != nil {
+		return nil, fmt.Errorf("failed to update encryption parameters: %w", err)
+	}
+	if key != nil {
+		var params *keyParams
+		if params, next, err = newKeyParams(key); err != nil {
+			return nil, err
+		}
+		_, err := tx.Exec(
+			"INSERT INTO encryption (id, key_source, salt, iterations, key_check) VALUES (1, ?, ?, ?, ?)",
+			params.source, params.salt, params.iterations, params.check,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update encryption parameters: %w", err)
+		}
+	}
+
+	if err := s.reseal(tx, next); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return next, nil
+}
+
+// sealedSessionColumns are the columns of research_sessions stored encrypted
+var sealedSessionColumns = []string{"query", "refined_query", "result", "critique", "citations", "matrix", "checkpoint", "notes"}
+
+// reseal rewrites the encrypted columns of every row with next
+func (s *SQLiteDB) reseal(tx *sql.Tx, next *fieldCipher) error {
+	rows, err := tx.Query("SELECT id, " + strings.Join(sealedSessionColumns, ", ") + " FROM research_sessions")
+	if err != nil {
+		return fmt.Errorf("failed to read sessions: %w", err)
+	}
+	type sealedSession struct {
+		id     int64
+		values []sql.NullString
+	}
+	var sessions []sealedSession
+	for rows.Next() {
+		r := sealedSession{values: make([]sql.NullString, len(sealedSessionColumns))}
+		dest := []interface{}{&r.id}
+		for i := range r.values {
+			dest = append(dest, &r.values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan session: %w", err)
+		}
+		for i := range r.values {
+			if r.values[i].String, err = s.cipher.open(r.values[i].String); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to decrypt session %d: %w", r.id, err)
+			}
+		}
+		sessions = append(sessions, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read sessions: %w", err)
+	}
+
+	update := "UPDATE research_sessions SET " + strings.Join(sealedSessionColumns, " = ?, ") + " = ? WHERE id = ?"
+	for _, r := range sessions {
+		var args []interface{}
+		for _, v := range r.values {
+			if v.Valid {
+				args = append(args, next.seal(v.String))
+			} else {
+				args = append(args, nil)
+			}
+		}
+		if _, err := tx.Exec(update, append(args, r.id)...); err != nil {
+			return fmt.Errorf("failed to re-encrypt session %d: %w", r.id, err)
+		}
+	}
+
+	if err := s.resealQueries(tx, next, "search_history", "search"); err != nil {
+		return err
+	}
+	return s.resealQueries(tx, next, "watched_queries", "watch")
+}
+
+// resealQueries re-encrypts the query column of table with next. noun names
+// a row in errors.
+func (s *SQLiteDB) resealQueries(tx *sql.Tx, next *fieldCipher, table, noun string) error {
+	rows, err := tx.Query("SELECT id, query FROM " + table)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", table, err)
+	}
+	queries := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var query string
+		if err := rows.Scan(&id, &query); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan %s: %w", noun, err)
+		}
+		if query, err = s.cipher.open(query); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to decrypt %s %d: %w", noun, id, err)
+		}
+		queries[id] = query
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", table, err)
+	}
+
+	for id, query := range queries {
+		if _, err := tx.Exec("UPDATE "+table+" SET query = ? WHERE id = ?", next.seal(query), id); err != nil {
+			return fmt.Errorf("failed to re-encrypt %s %d: %w", noun, id, err)
+		}
+	}
+	return nil
+}
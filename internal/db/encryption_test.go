@@ -0,0 +1,321 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testKey = &Key{Source: KeyFile, Secret: []byte("0123456789abcdef0123456789abcdef")}
+
+// openEncrypted opens the database at path with key
+func openEncrypted(t *testing.T, path string, key *Key) (*SQLiteDB, error) {
+	t.Helper()
+	engine, err := defaultSQLiteEngine()
+	require.NoError(t, err)
+	d, err := newSQLiteDB(engine, path, Options{Key: key})
+	if err != nil {
+		return nil, err
+	}
+	return d.(*SQLiteDB), nil
+}
+
+func TestEncryption_StoresCiphertext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "research.db")
+	d, err := openEncrypted(t, path, testKey)
+	require.NoError(t, err)
+	defer d.Close()
+
+	session := &ResearchSession{
+		Query:        "Acquisition targets in fintech",
+		RefinedQuery: "Which fintech startups could we acquire?",
+		Mode:         "deep",
+		PromptUsed:   "default",
+		Result:       "Confidential shortlist",
+		Status:       SessionInProgress,
+		Checkpoint:   &Checkpoint{Stage: "main", Content: "Confidential shortlist"},
+	}
+	require.NoError(t, d.SaveSession(session))
+	require.NoError(t, d.LogSearch(&SearchHistory{Query: session.Query, SessionID: session.ID}))
+
+	var query, refined, result, checkpoint, logged string
+	require.NoError(t, d.db.QueryRow("SELECT query, refined_query, result, checkpoint FROM research_sessions").Scan(&query, &refined, &result, &checkpoint))
+	require.NoError(t, d.db.QueryRow("SELECT query FROM search_history").Scan(&logged))
+	for _, stored := range []string{query, refined, result, checkpoint, logged} {
+		assert.True(t, strings.HasPrefix(stored, sealedPrefix), stored)
+		assert.NotContains(t, stored, "ontidential")
+		assert.NotContains(t, stored, "fintech")
+	}
+
+	got, err := d.GetSession(session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, session.Query, got.Query)
+	assert.Equal(t, session.RefinedQuery, got.RefinedQuery)
+	assert.Equal(t, session.Checkpoint, got.Checkpoint)
+}
+
+// confidentialSession returns a session with "Confidential" in every
+// column that is stored encrypted
+func confidentialSession(query string) *ResearchSession {
+	return &ResearchSession{
+		Query:        query,
+		RefinedQuery: "Confidential refined query",
+		Mode:         "compare",
+		PromptUsed:   "default",
+		Result:       "Confidential shortlist",
+		Critique:     "Confidential critique",
+		Citations:    []Citation{{Text: "Confidential memo", URL: "https://intranet.example/confidential", Status: CitationOK}},
+		Matrix:       &ComparisonMatrix{Criteria: []string{"Confidential fit"}, Options: []MatrixOption{{Name: "Confidential target"}}},
+		Status:       SessionInProgress,
+		Checkpoint:   &Checkpoint{Stage: "main", Content: "Confidential shortlist"},
+		Notes:        "Confidential notes",
+	}
+}
+
+// assertSealedColumns checks that no encrypted column of any session is
+// stored in plaintext
+func assertSealedColumns(t *testing.T, d *SQLiteDB) {
+	t.Helper()
+	rows, err := d.db.Query("SELECT " + strings.Join(sealedSessionColumns, ", ") + " FROM research_sessions")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	for rows.Next() {
+		values := make([]sql.NullString, len(sealedSessionColumns))
+		dest := make([]interface{}, len(values))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		require.NoError(t, rows.Scan(dest...))
+		for i, v := range values {
+			require.True(t, v.Valid, sealedSessionColumns[i])
+			assert.True(t, strings.HasPrefix(v.String, sealedPrefix), "%s: %s", sealedSessionColumns[i], v.String)
+			assert.NotContains(t, strings.ToLower(v.String), "confidential", sealedSessionColumns[i])
+		}
+	}
+	require.NoError(t, rows.Err())
+}
+
+func TestEncryption_SealsEverySessionColumn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "research.db")
+	d, err := openEncrypted(t, path, nil)
+	require.NoError(t, err)
+	before := confidentialSession("Confidential query")
+	require.NoError(t, d.SaveSession(before))
+	require.NoError(t, d.SetNotes(before.ID, before.Notes))
+	require.NoError(t, d.Close())
+
+	// Encrypting an existing database reseals every column
+	d, err = openEncrypted(t, path, testKey)
+	require.NoError(t, err)
+	defer d.Close()
+	assertSealedColumns(t, d)
+
+	saved := confidentialSession("Another confidential query")
+	require.NoError(t, d.SaveSession(saved))
+	require.NoError(t, d.SetNotes(saved.ID, saved.Notes))
+	_, err = d.ImportSessions([]*ResearchSession{confidentialSession("Imported confidential query")})
+	require.NoError(t, err)
+	assertSealedColumns(t, d)
+
+	got, err := d.GetSession(before.ID)
+	require.NoError(t, err)
+	assert.Equal(t, before.Critique, got.Critique)
+	assert.Equal(t, before.Citations, got.Citations)
+	assert.Equal(t, before.Matrix, got.Matrix)
+	assert.Equal(t, before.Notes, got.Notes)
+
+	// Rekeying again keeps them readable
+	require.NoError(t, d.Rekey(&Key{Source: KeyFile, Secret: []byte("another key")}))
+	assertSealedColumns(t, d)
+	got, err = d.GetSession(saved.ID)
+	require.NoError(t, err)
+	assert.Equal(t, saved.Matrix, got.Matrix)
+	assert.Equal(t, saved.Notes, got.Notes)
+}
+
+// rawWatchQueries returns the query column of every watch as stored
+func rawWatchQueries(t *testing.T, d *SQLiteDB) []string {
+	t.Helper()
+	rows, err := d.db.Query("SELECT query FROM watched_queries ORDER BY id")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var queries []string
+	for rows.Next() {
+		var query string
+		require.NoError(t, rows.Scan(&query))
+		queries = append(queries, query)
+	}
+	require.NoError(t, rows.Err())
+	return queries
+}
+
+func TestEncryption_SealsWatchQueries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "research.db")
+	d, err := openEncrypted(t, path, nil)
+	require.NoError(t, err)
+	require.NoError(t, d.SaveWatch(&WatchedQuery{Query: "Confidential acquisition", Mode: "quick", PromptUsed: "default", Interval: time.Hour}))
+	require.NoError(t, d.Close())
+
+	// Encrypting an existing database seals its watches
+	d, err = openEncrypted(t, path, testKey)
+	require.NoError(t, err)
+	defer d.Close()
+
+	// Saving the same watch again updates it rather than adding another
+	again := &WatchedQuery{Query: "Confidential acquisition", Mode: "quick", PromptUsed: "default", Interval: 2 * time.Hour}
+	require.NoError(t, d.SaveWatch(again))
+	require.NoError(t, d.SaveWatch(&WatchedQuery{Query: "Confidential merger", Mode: "quick", PromptUsed: "default", Interval: time.Hour}))
+
+	raw := rawWatchQueries(t, d)
+	require.Len(t, raw, 2)
+	for _, stored := range raw {
+		assert.True(t, strings.HasPrefix(stored, sealedPrefix), stored)
+		assert.NotContains(t, strings.ToLower(stored), "confidential")
+	}
+
+	watches, err := d.ListWatches()
+	require.NoError(t, err)
+	require.Len(t, watches, 2)
+	assert.Equal(t, again.ID, watches[0].ID)
+	assert.Equal(t, "Confidential acquisition", watches[0].Query)
+	assert.Equal(t, 2*time.Hour, watches[0].Interval)
+	assert.Equal(t, "Confidential merger", watches[1].Query)
+
+	// Rekeying reseals them, and decrypting restores the plaintext
+	require.NoError(t, d.Rekey(&Key{Source: KeyFile, Secret: []byte("another key")}))
+	for _, stored := range rawWatchQueries(t, d) {
+		assert.True(t, strings.HasPrefix(stored, sealedPrefix), stored)
+	}
+	require.NoError(t, d.Rekey(nil))
+	assert.Equal(t, []string{"Confidential acquisition", "Confidential merger"}, rawWatchQueries(t, d))
+}
+
+func TestEncryption_KeyErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "research.db")
+	d, err := openEncrypted(t, path, testKey)
+	require.NoError(t, err)
+	require.NoError(t, d.Close())
+
+	_, err = openEncrypted(t, path, nil)
+	assert.True(t, errors.Is(err, ErrKeyRequired))
+	assert.Contains(t, err.Error(), "encrypted with a key file")
+
+	_, err = openEncrypted(t, path, &Key{Source: KeyFile, Secret: []byte("another key")})
+	assert.True(t, errors.Is(err, ErrWrongKey))
+
+	_, err = openEncrypted(t, path, &Key{Source: KeyPassphrase, Secret: []byte("hunter2")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "encrypted with a key file, not a passphrase")
+
+	d, err = openEncrypted(t, path, testKey)
+	require.NoError(t, err)
+	d.Close()
+}
+
+func TestEncryption_EncryptsExistingDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "research.db")
+	d, err := openEncrypted(t, path, nil)
+	require.NoError(t, err)
+	session := &ResearchSession{Query: "stored in plaintext", Mode: "quick", PromptUsed: "default", Result: "plain answer"}
+	require.NoError(t, d.SaveSession(session))
+	require.NoError(t, d.Close())
+
+	d, err = openEncrypted(t, path, testKey)
+	require.NoError(t, err)
+	defer d.Close()
+
+	var query string
+	require.NoError(t, d.db.QueryRow("SELECT query FROM research_sessions").Scan(&query))
+	assert.True(t, strings.HasPrefix(query, sealedPrefix))
+
+	got, err := d.GetSession(session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "stored in plaintext", got.Query)
+}
+
+func TestEncryption_Passphrase(t *testing.T) {
+	defer func(n int) { pbkdf2Iterations = n }(pbkdf2Iterations)
+	pbkdf2Iterations = 1000
+
+	path := filepath.Join(t.TempDir(), "research.db")
+	passphrase := &Key{Source: KeyPassphrase, Secret: []byte("correct horse battery staple")}
+	d, err := openEncrypted(t, path, passphrase)
+	require.NoError(t, err)
+	require.NoError(t, d.SaveSession(&ResearchSession{Query: "q", Mode: "quick", PromptUsed: "default", Result: "r"}))
+
+	// The work factor is stored, so changing the default later keeps old
+	// databases readable
+	params, err := readKeyParams(d.db)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, params.iterations)
+	require.NoError(t, d.Close())
+	pbkdf2Iterations = 2000
+
+	d, err = openEncrypted(t, path, passphrase)
+	require.NoError(t, err)
+	defer d.Close()
+
+	// Rekeying to a key file, then removing encryption
+	require.NoError(t, d.Rekey(testKey))
+	params, err = readKeyParams(d.db)
+	require.NoError(t, err)
+	assert.Equal(t, KeyFile, params.source)
+
+	require.NoError(t, d.Rekey(nil))
+	params, err = readKeyParams(d.db)
+	require.NoError(t, err)
+	assert.Nil(t, params)
+	var query string
+	require.NoError(t, d.db.QueryRow("SELECT query FROM research_sessions").Scan(&query))
+	assert.Equal(t, "q", query)
+}
+
+func TestFieldCipher(t *testing.T) {
+	params, _, err := newKeyParams(testKey)
+	require.NoError(t, err)
+	_, other, err := newKeyParams(testKey)
+	require.NoError(t, err)
+
+	sealed := other.seal("secret")
+	assert.NotEqual(t, sealed, other.seal("secret"), "nonces are random")
+	opened, err := other.open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", opened)
+
+	assert.Empty(t, other.seal(""))
+	opened, err = other.open("written before encryption")
+	require.NoError(t, err)
+	assert.Equal(t, "written before encryption", opened)
+
+	// A different salt derives a different key
+	wrong, err := newFieldCipher(testKey, *params)
+	require.NoError(t, err)
+	_, err = wrong.open(sealed)
+	assert.ErrorIs(t, err, ErrWrongKey)
+
+	var none *fieldCipher
+	assert.Equal(t, "plain", none.seal("plain"))
+	_, err = none.open(sealed)
+	assert.ErrorIs(t, err, ErrKeyRequired)
+}
+
+func TestSearchTerms(t *testing.T) {
+	assert.Equal(t, []string{"kafka", "nats", "stream"}, searchTerms(`"Kafka" OR (NATS) stream*`))
+	assert.Empty(t, searchTerms("  "))
+}
+
+func TestTermSnippet(t *testing.T) {
+	text := "one two three four five six seven eight nine ten eleven twelve thirteen fourteen fifteen sixteen seventeen Kafka nineteen twenty"
+	snippet := termSnippet(text, []string{"kafka"})
+	assert.Equal(t, "…fourteen fifteen sixteen seventeen "+HighlightStart+"Kafka"+HighlightEnd+" nineteen twenty", snippet)
+
+	assert.Empty(t, termSnippet(text, []string{"rust"}))
+}
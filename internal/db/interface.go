@@ -1,22 +1,59 @@
 package db
 
+import "time"
+
 // DB defines the interface for database operations
 type DB interface {
 	// Sessions
 	SaveSession(session *ResearchSession) error
+	UpdateSession(session *ResearchSession) error
 	GetSession(id int64) (*ResearchSession, error)
 	ListSessions(limit, offset int) ([]*ResearchSession, error)
 	SearchSessions(query string) ([]*ResearchSession, error)
+	FindSessions(filter SessionFilter) ([]*SessionMatch, error)
+	GetLatestSession(query, mode string) (*ResearchSession, error)
+	DeleteSession(id int64) error
+	DeleteSessionsBefore(before time.Time) (int64, error)
+	KeepLatestSessions(n int) (int64, error)
+	ClearAll() (int64, error)
+	ImportSessions(sessions []*ResearchSession) (*ImportResult, error)
+
+	// Search log
+	LogSearch(entry *SearchHistory) error
+	ListSearches(filter SearchFilter) ([]*SearchHistory, error)
+
+	// Organization
+	UpdateTags(id int64, add, remove []string) ([]string, error)
+	SetStarred(id int64, starred bool) error
+	SetNotes(id int64, notes string) error
 
 	// Patterns
 	SavePattern(pattern *LearnedPattern) error
 	GetPattern(name string) (*LearnedPattern, error)
 	IncrementPattern(name string) error
 
+	// Ratings
+	RateSession(id int64, rating int) (*LearnedPattern, error)
+	GetRatingPatterns() ([]*LearnedPattern, error)
+	BestPrompt(mode string, minRatings int) (*PromptRating, error)
+
+	// Watches
+	SaveWatch(watch *WatchedQuery) error
+	ListWatches() ([]*WatchedQuery, error)
+	DeleteWatch(id int64) error
+	UpdateWatchRun(id int64, runAt time.Time, sessionID int64) error
+
 	// Stats
 	GetTotalSessions() (int, error)
 	GetModeStats() (map[string]int, error)
 	GetTopQueries(limit int) ([]QueryCount, error)
+	GetTagCounts() ([]TagCount, error)
+	GetSessionUsage(filter UsageFilter) ([]SessionUsage, error)
+	GetRunUsage(filter UsageFilter) ([]RunUsage, error)
+
+	// Maintenance
+	Vacuum() error
+	Rekey(key *Key) error
 
 	// Cleanup
 	Close() error
@@ -25,18 +62,86 @@ type DB interface {
 // MockDB is a mock implementation of the DB interface for testing
 type MockDB struct {
 	SaveSessionFunc    func(session *ResearchSession) error
+	UpdateSessionFunc  func(session *ResearchSession) error
 	GetSessionFunc     func(id int64) (*ResearchSession, error)
 	ListSessionsFunc   func(limit, offset int) ([]*ResearchSession, error)
 	SearchSessionsFunc func(query string) ([]*ResearchSession, error)
+	FindSessionsFunc   func(filter SessionFilter) ([]*SessionMatch, error)
+	GetLatestSessionFunc func(query, mode string) (*ResearchSession, error)
+	DeleteSessionFunc  func(id int64) error
+	DeleteSessionsBeforeFunc func(before time.Time) (int64, error)
+	KeepLatestSessionsFunc func(n int) (int64, error)
+	ClearAllFunc       func() (int64, error)
+	ImportSessionsFunc func(sessions []*ResearchSession) (*ImportResult, error)
+	LogSearchFunc      func(entry *SearchHistory) error
+	ListSearchesFunc   func(filter SearchFilter) ([]*SearchHistory, error)
+	UpdateTagsFunc     func(id int64, add, remove []string) ([]string, error)
+	SetStarredFunc     func(id int64, starred bool) error
+	SetNotesFunc       func(id int64, notes string) error
 	SavePatternFunc    func(pattern *LearnedPattern) error
 	GetPatternFunc     func(name string) (*LearnedPattern, error)
 	IncrementPatternFunc func(name string) error
+	RateSessionFunc      func(id int64, rating int) (*LearnedPattern, error)
+	GetRatingPatternsFunc func() ([]*LearnedPattern, error)
+	BestPromptFunc       func(mode string, minRatings int) (*PromptRating, error)
+	SaveWatchFunc      func(watch *WatchedQuery) error
+	ListWatchesFunc    func() ([]*WatchedQuery, error)
+	DeleteWatchFunc    func(id int64) error
+	UpdateWatchRunFunc func(id int64, runAt time.Time, sessionID int64) error
 	GetTotalSessionsFunc func() (int, error)
 	GetModeStatsFunc   func() (map[string]int, error)
 	GetTopQueriesFunc  func(limit int) ([]QueryCount, error)
+	GetTagCountsFunc   func() ([]TagCount, error)
+	GetSessionUsageFunc func(filter UsageFilter) ([]SessionUsage, error)
+	GetRunUsageFunc    func(filter UsageFilter) ([]RunUsage, error)
+	VacuumFunc         func() error
+	RekeyFunc          func(key *Key) error
 	CloseFunc          func() error
 }
 
+// NewMockDB returns a MockDB that forwards every call to backend, for tests
+// that override a few methods of a working database
+func NewMockDB(backend DB) *MockDB {
+	return &MockDB{
+		SaveSessionFunc:          backend.SaveSession,
+		UpdateSessionFunc:        backend.UpdateSession,
+		GetSessionFunc:           backend.GetSession,
+		ListSessionsFunc:         backend.ListSessions,
+		SearchSessionsFunc:       backend.SearchSessions,
+		FindSessionsFunc:         backend.FindSessions,
+		GetLatestSessionFunc:     backend.GetLatestSession,
+		DeleteSessionFunc:        backend.DeleteSession,
+		DeleteSessionsBeforeFunc: backend.DeleteSessionsBefore,
+		KeepLatestSessionsFunc:   backend.KeepLatestSessions,
+		ClearAllFunc:             backend.ClearAll,
+		ImportSessionsFunc:       backend.ImportSessions,
+		LogSearchFunc:            backend.LogSearch,
+		ListSearchesFunc:         backend.ListSearches,
+		UpdateTagsFunc:           backend.UpdateTags,
+		SetStarredFunc:           backend.SetStarred,
+		SetNotesFunc:             backend.SetNotes,
+		SavePatternFunc:          backend.SavePattern,
+		GetPatternFunc:           backend.GetPattern,
+		IncrementPatternFunc:     backend.IncrementPattern,
+		RateSessionFunc:          backend.RateSession,
+		GetRatingPatternsFunc:    backend.GetRatingPatterns,
+		BestPromptFunc:           backend.BestPrompt,
+		SaveWatchFunc:            backend.SaveWatch,
+		ListWatchesFunc:          backend.ListWatches,
+		DeleteWatchFunc:          backend.DeleteWatch,
+		UpdateWatchRunFunc:       backend.UpdateWatchRun,
+		GetTotalSessionsFunc:     backend.GetTotalSessions,
+		GetModeStatsFunc:         backend.GetModeStats,
+		GetTopQueriesFunc:        backend.GetTopQueries,
+		GetTagCountsFunc:         backend.GetTagCounts,
+		GetSessionUsageFunc:      backend.GetSessionUsage,
+		GetRunUsageFunc:          backend.GetRunUsage,
+		VacuumFunc:               backend.Vacuum,
+		RekeyFunc:                backend.Rekey,
+		CloseFunc:                backend.Close,
+	}
+}
+
 // SaveSession calls SaveSessionFunc
 func (m *MockDB) SaveSession(session *ResearchSession) error {
 	if m.SaveSessionFunc != nil {
@@ -45,6 +150,14 @@ func (m *MockDB) SaveSession(session *ResearchSession) error {
 	return nil
 }
 
+// UpdateSession calls UpdateSessionFunc
+func (m *MockDB) UpdateSession(session *ResearchSession) error {
+	if m.UpdateSessionFunc != nil {
+		return m.UpdateSessionFunc(session)
+	}
+	return nil
+}
+
 // GetSession calls GetSessionFunc
 func (m *MockDB) GetSession(id int64) (*ResearchSession, error) {
 	if m.GetSessionFunc != nil {
@@ -69,6 +182,102 @@ func (m *MockDB) SearchSessions(query string) ([]*ResearchSession, error) {
 	return nil, nil
 }
 
+// FindSessions calls FindSessionsFunc
+func (m *MockDB) FindSessions(filter SessionFilter) ([]*SessionMatch, error) {
+	if m.FindSessionsFunc != nil {
+		return m.FindSessionsFunc(filter)
+	}
+	return nil, nil
+}
+
+// GetLatestSession calls GetLatestSessionFunc
+func (m *MockDB) GetLatestSession(query, mode string) (*ResearchSession, error) {
+	if m.GetLatestSessionFunc != nil {
+		return m.GetLatestSessionFunc(query, mode)
+	}
+	return nil, nil
+}
+
+// DeleteSession calls DeleteSessionFunc
+func (m *MockDB) DeleteSession(id int64) error {
+	if m.DeleteSessionFunc != nil {
+		return m.DeleteSessionFunc(id)
+	}
+	return nil
+}
+
+// DeleteSessionsBefore calls DeleteSessionsBeforeFunc
+func (m *MockDB) DeleteSessionsBefore(before time.Time) (int64, error) {
+	if m.DeleteSessionsBeforeFunc != nil {
+		return m.DeleteSessionsBeforeFunc(before)
+	}
+	return 0, nil
+}
+
+// KeepLatestSessions calls KeepLatestSessionsFunc
+func (m *MockDB) KeepLatestSessions(n int) (int64, error) {
+	if m.KeepLatestSessionsFunc != nil {
+		return m.KeepLatestSessionsFunc(n)
+	}
+	return 0, nil
+}
+
+// ClearAll calls ClearAllFunc
+func (m *MockDB) ClearAll() (int64, error) {
+	if m.ClearAllFunc != nil {
+		return m.ClearAllFunc()
+	}
+	return 0, nil
+}
+
+// ImportSessions calls ImportSessionsFunc
+func (m *MockDB) ImportSessions(sessions []*ResearchSession) (*ImportResult, error) {
+	if m.ImportSessionsFunc != nil {
+		return m.ImportSessionsFunc(sessions)
+	}
+	return &ImportResult{}, nil
+}
+
+// LogSearch calls LogSearchFunc
+func (m *MockDB) LogSearch(entry *SearchHistory) error {
+	if m.LogSearchFunc != nil {
+		return m.LogSearchFunc(entry)
+	}
+	return nil
+}
+
+// ListSearches calls ListSearchesFunc
+func (m *MockDB) ListSearches(filter SearchFilter) ([]*SearchHistory, error) {
+	if m.ListSearchesFunc != nil {
+		return m.ListSearchesFunc(filter)
+	}
+	return nil, nil
+}
+
+// UpdateTags calls UpdateTagsFunc
+func (m *MockDB) UpdateTags(id int64, add, remove []string) ([]string, error) {
+	if m.UpdateTagsFunc != nil {
+		return m.UpdateTagsFunc(id, add, remove)
+	}
+	return nil, nil
+}
+
+// SetStarred calls SetStarredFunc
+func (m *MockDB) SetStarred(id int64, starred bool) error {
+	if m.SetStarredFunc != nil {
+		return m.SetStarredFunc(id, starred)
+	}
+	return nil
+}
+
+// SetNotes calls SetNotesFunc
+func (m *MockDB) SetNotes(id int64, notes string) error {
+	if m.SetNotesFunc != nil {
+		return m.SetNotesFunc(id, notes)
+	}
+	return nil
+}
+
 // SavePattern calls SavePatternFunc
 func (m *MockDB) SavePattern(pattern *LearnedPattern) error {
 	if m.SavePatternFunc != nil {
@@ -93,6 +302,62 @@ func (m *MockDB) IncrementPattern(name string) error {
 	return nil
 }
 
+// RateSession calls RateSessionFunc
+func (m *MockDB) RateSession(id int64, rating int) (*LearnedPattern, error) {
+	if m.RateSessionFunc != nil {
+		return m.RateSessionFunc(id, rating)
+	}
+	return nil, nil
+}
+
+// GetRatingPatterns calls GetRatingPatternsFunc
+func (m *MockDB) GetRatingPatterns() ([]*LearnedPattern, error) {
+	if m.GetRatingPatternsFunc != nil {
+		return m.GetRatingPatternsFunc()
+	}
+	return nil, nil
+}
+
+// BestPrompt calls BestPromptFunc
+func (m *MockDB) BestPrompt(mode string, minRatings int) (*PromptRating, error) {
+	if m.BestPromptFunc != nil {
+		return m.BestPromptFunc(mode, minRatings)
+	}
+	return nil, nil
+}
+
+// SaveWatch calls SaveWatchFunc
+func (m *MockDB) SaveWatch(watch *WatchedQuery) error {
+	if m.SaveWatchFunc != nil {
+		return m.SaveWatchFunc(watch)
+	}
+	return nil
+}
+
+// ListWatches calls ListWatchesFunc
+func (m *MockDB) ListWatches() ([]*WatchedQuery, error) {
+	if m.ListWatchesFunc != nil {
+		return m.ListWatchesFunc()
+	}
+	return nil, nil
+}
+
+// DeleteWatch calls DeleteWatchFunc
+func (m *MockDB) DeleteWatch(id int64) error {
+	if m.DeleteWatchFunc != nil {
+		return m.DeleteWatchFunc(id)
+	}
+	return nil
+}
+
+// UpdateWatchRun calls UpdateWatchRunFunc
+func (m *MockDB) UpdateWatchRun(id int64, runAt time.Time, sessionID int64) error {
+	if m.UpdateWatchRunFunc != nil {
+		return m.UpdateWatchRunFunc(id, runAt, sessionID)
+	}
+	return nil
+}
+
 // GetTotalSessions calls GetTotalSessionsFunc
 func (m *MockDB) GetTotalSessions() (int, error) {
 	if m.GetTotalSessionsFunc != nil {
@@ -117,6 +382,46 @@ func (m *MockDB) GetTopQueries(limit int) ([]QueryCount, error) {
 	return nil, nil
 }
 
+// GetTagCounts calls GetTagCountsFunc
+func (m *MockDB) GetTagCounts() ([]TagCount, error) {
+	if m.GetTagCountsFunc != nil {
+		return m.GetTagCountsFunc()
+	}
+	return nil, nil
+}
+
+// GetSessionUsage calls GetSessionUsageFunc
+func (m *MockDB) GetSessionUsage(filter UsageFilter) ([]SessionUsage, error) {
+	if m.GetSessionUsageFunc != nil {
+		return m.GetSessionUsageFunc(filter)
+	}
+	return nil, nil
+}
+
+// GetRunUsage calls GetRunUsageFunc
+func (m *MockDB) GetRunUsage(filter UsageFilter) ([]RunUsage, error) {
+	if m.GetRunUsageFunc != nil {
+		return m.GetRunUsageFunc(filter)
+	}
+	return nil, nil
+}
+
+// Vacuum calls VacuumFunc
+func (m *MockDB) Vacuum() error {
+	if m.VacuumFunc != nil {
+		return m.VacuumFunc()
+	}
+	return nil
+}
+
+// Rekey calls RekeyFunc
+func (m *MockDB) Rekey(key *Key) error {
+	if m.RekeyFunc != nil {
+		return m.RekeyFunc(key)
+	}
+	return nil
+}
+
 // Close calls CloseFunc
 func (m *MockDB) Close() error {
 	if m.CloseFunc != nil {
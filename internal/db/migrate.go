@@ -0,0 +1,296 @@
+package db
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql migrations/fallback/*.sql
+var migrationFiles embed.FS
+
+// Migration is one embedded schema change. The schema version of a database
+// is kept in PRAGMA user_version and migrations are applied in order of
+// Version, each in its own transaction.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+	// Fallback is applied instead when SQL needs an SQLite module this
+	// build lacks, such as FTS5. It lives in migrations/fallback/ under the
+	// same file name.
+	Fallback string
+}
+
+// MigrationStatus describes the schema version of a database
+type MigrationStatus struct {
+	Version int         // Current version; 0 for a new database or one created before versioning
+	Latest  int         // Version this build migrates to
+	Pending []Migration // Migrations that opening the database will apply
+}
+
+// MigrationResult describes what bringing a database up to date did
+type MigrationResult struct {
+	From    int
+	To      int
+	Applied []Migration
+	Backup  string // Copy of the database taken before migrating, if any
+}
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.sql$`)
+
+// Migrations returns the embedded migrations in order
+func Migrations() ([]Migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	var migrations []Migration
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		m := migrationFilePattern.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", e.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		data, err := migrationFiles.ReadFile("migrations/" + e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", e.Name(), err)
+		}
+		migration := Migration{Version: version, Name: m[2], SQL: string(data)}
+		if fallback, err := migrationFiles.ReadFile("migrations/fallback/" + e.Name()); err == nil {
+			migration.Fallback = string(fallback)
+		}
+		migrations = append(migrations, migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migrations must be numbered 1, 2, 3, ...: found %d at position %d", m.Version, i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// ReadMigrationStatus reports the schema version of the database at path
+// without migrating it
+func ReadMigrationStatus(path string) (*MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	version := 0
+	if _, err := os.Stat(path); err == nil {
+		db, err := OpenSQLite(path)
+		if err != nil {
+			return nil, err
+		}
+		defer db.Close()
+		if version, err = userVersion(db); err != nil {
+			return nil, err
+		}
+	}
+
+	status := &MigrationStatus{Version: version, Latest: len(migrations)}
+	for _, m := range migrations {
+		if m.Version > version {
+			status.Pending = append(status.Pending, m)
+		}
+	}
+	return status, nil
+}
+
+// Migrate brings the database at path up to date, creating it if needed
+func Migrate(path string) (*MigrationResult, error) {
+	db, err := OpenSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return migrate(db, path)
+}
+
+// migrate applies the pending migrations. An existing database file is
+// backed up first, next to it.
+func migrate(db *sql.DB, path string) (*MigrationResult, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := userVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	result := &MigrationResult{From: version, To: version}
+
+	if version > len(migrations) {
+		return nil, fmt.Errorf("database schema version %d is newer than this build supports (%d)", version, len(migrations))
+	}
+	if version == len(migrations) {
+		return result, nil
+	}
+
+	if hasData, err := hasTables(db); err != nil {
+		return nil, err
+	} else if hasData && path != ":memory:" {
+		result.Backup = fmt.Sprintf("%s.v%d-%s.bak", path, version, time.Now().Format("20060102-150405"))
+		if _, err := db.Exec("VACUUM INTO ?", result.Backup); err != nil {
+			return nil, fmt.Errorf("failed to back up database before migrating: %w", err)
+		}
+	}
+
+	for _, m := range migrations[version:] {
+		applied, err := applyMigration(db, m)
+		if err != nil {
+			return nil, err
+		}
+		if applied {
+			result.Applied = append(result.Applied, m)
+		}
+		result.To = m.Version
+	}
+	return result, nil
+}
+
+// applyMigration runs one migration and records its version in the same
+// transaction. It reports false if another process applied it first.
+func applyMigration(db *sql.DB, m Migration) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin migration %d: %w", m.Version, err)
+	}
+	defer tx.Rollback()
+
+	var current int
+	if err := tx.QueryRow("PRAGMA user_version").Scan(&current); err != nil {
+		return false, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if current >= m.Version {
+		return false, nil
+	}
+
+	if err := execMigrationSQL(tx, m); err != nil {
+		return false, fmt.Errorf("failed to apply migration %d (%s): %w", m.Version, m.Name, err)
+	}
+	if m.Version == 1 {
+		if err := addLegacyColumns(tx); err != nil {
+			return false, err
+		}
+	}
+	// PRAGMA does not take bound parameters
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", m.Version)); err != nil {
+		return false, fmt.Errorf("failed to record schema version %d: %w", m.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit migration %d: %w", m.Version, err)
+	}
+	return true, nil
+}
+
+// execMigrationSQL runs a migration's SQL, or its fallback if the SQL needs
+// a module this SQLite build does not have
+func execMigrationSQL(tx *sql.Tx, m Migration) error {
+	if m.Fallback == "" {
+		_, err := tx.Exec(m.SQL)
+		return err
+	}
+
+	// A savepoint undoes any statements that ran before the failing one
+	if _, err := tx.Exec("SAVEPOINT migration"); err != nil {
+		return err
+	}
+	_, err := tx.Exec(m.SQL)
+	if err == nil || !strings.Contains(err.Error(), "no such module") {
+		return err
+	}
+	if _, err := tx.Exec("ROLLBACK TO migration"); err != nil {
+		return err
+	}
+	_, err = tx.Exec(m.Fallback)
+	return err
+}
+
+// userVersion reads the schema version of a database
+func userVersion(db *sql.DB) (int, error) {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// hasTables reports whether the database has any tables yet
+func hasTables(db *sql.DB) (bool, error) {
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'").Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to inspect schema: %w", err)
+	}
+	return count > 0, nil
+}
+
+// legacyColumns lists research_sessions columns that versions before schema
+// versioning added when opening a database, so such a database may have any
+// subset of them
+var legacyColumns = []struct {
+	name       string
+	definition string
+}{
+	{"citations", "TEXT"},
+	{"critique", "TEXT"},
+	{"matrix", "TEXT"},
+	{"status", "TEXT NOT NULL DEFAULT 'complete'"},
+	{"checkpoint", "TEXT"},
+	{"refined_query", "TEXT"},
+}
+
+// addLegacyColumns adds the legacy columns a database created before schema
+// versioning does not have yet
+func addLegacyColumns(tx *sql.Tx) error {
+	rows, err := tx.Query("PRAGMA table_info(research_sessions)")
+	if err != nil {
+		return fmt.Errorf("failed to inspect schema: %w", err)
+	}
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to inspect schema: %w", err)
+		}
+		existing[name] = true
+	}
+	rows.Close()
+
+	for _, col := range legacyColumns {
+		if existing[col.name] {
+			continue
+		}
+		stmt := fmt.Sprintf("ALTER TABLE research_sessions ADD COLUMN %s %s", col.name, col.definition)
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to add column %s: %w", col.name, err)
+		}
+	}
+
+	return nil
+}
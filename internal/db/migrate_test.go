@@ -0,0 +1,169 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createLegacyDB creates a database as versions before schema versioning
+// left it: tables present, user_version 0
+func createLegacyDB(t *testing.T, path string) {
+	t.Helper()
+	raw, err := OpenSQLite(path)
+	require.NoError(t, err)
+	defer raw.Close()
+
+	_, err = raw.Exec(`CREATE TABLE research_sessions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		query TEXT NOT NULL,
+		mode TEXT NOT NULL,
+		prompt_used TEXT NOT NULL,
+		result TEXT NOT NULL,
+		quality_score INTEGER,
+		citations TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`)
+	require.NoError(t, err)
+	_, err = raw.Exec(`INSERT INTO research_sessions (query, mode, prompt_used, result) VALUES ('old', 'quick', 'default', 'r')`)
+	require.NoError(t, err)
+}
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(migrations), 2)
+
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.Version)
+		assert.NotEmpty(t, m.Name)
+		assert.NotEmpty(t, m.SQL)
+	}
+	assert.Equal(t, "baseline", migrations[0].Name)
+	assert.Contains(t, migrations[2].SQL, "fts5")
+	assert.Contains(t, migrations[2].Fallback, "fts4")
+}
+
+func TestMigrate_NewDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "new.db")
+
+	status, err := ReadMigrationStatus(dbPath)
+	require.NoError(t, err)
+	assert.Equal(t, 0, status.Version)
+	assert.Len(t, status.Pending, status.Latest)
+	_, err = os.Stat(dbPath)
+	assert.True(t, os.IsNotExist(err), "reading the status does not create the database")
+
+	result, err := Migrate(dbPath)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.From)
+	assert.Equal(t, status.Latest, result.To)
+	assert.Len(t, result.Applied, status.Latest)
+	assert.Empty(t, result.Backup, "an empty database is not backed up")
+
+	status, err = ReadMigrationStatus(dbPath)
+	require.NoError(t, err)
+	assert.Equal(t, status.Latest, status.Version)
+	assert.Empty(t, status.Pending)
+
+	// Already up to date
+	result, err = Migrate(dbPath)
+	require.NoError(t, err)
+	assert.Equal(t, result.From, result.To)
+	assert.Empty(t, result.Applied)
+	assert.Empty(t, result.Backup)
+}
+
+func TestMigrate_LegacyDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "old.db")
+	createLegacyDB(t, dbPath)
+
+	status, err := ReadMigrationStatus(dbPath)
+	require.NoError(t, err)
+	assert.Equal(t, 0, status.Version)
+
+	result, err := Migrate(dbPath)
+	require.NoError(t, err)
+	assert.Equal(t, status.Latest, result.To)
+	require.NotEmpty(t, result.Backup)
+
+	// The backup is the database as it was
+	backup, err := OpenSQLite(result.Backup)
+	require.NoError(t, err)
+	defer backup.Close()
+	version, err := userVersion(backup)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+	var query string
+	require.NoError(t, backup.QueryRow("SELECT query FROM research_sessions").Scan(&query))
+	assert.Equal(t, "old", query)
+
+	database, err := NewSQLiteDB(dbPath)
+	require.NoError(t, err)
+	defer database.Close()
+
+	session, err := database.GetSession(1)
+	require.NoError(t, err)
+	assert.Equal(t, "old", session.Query)
+	assert.Equal(t, SessionComplete, session.Status)
+	assert.Empty(t, session.Provider)
+	assert.Zero(t, session.ParentID)
+}
+
+func TestMigrate_NewerDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "future.db")
+	raw, err := OpenSQLite(dbPath)
+	require.NoError(t, err)
+	_, err = raw.Exec("PRAGMA user_version = 999")
+	require.NoError(t, err)
+	require.NoError(t, raw.Close())
+
+	_, err = NewSQLiteDB(dbPath)
+	assert.ErrorContains(t, err, "newer than this build supports")
+}
+
+func TestMigrate_FailedMigrationRollsBack(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "broken.db")
+	raw, err := OpenSQLite(dbPath)
+	require.NoError(t, err)
+	defer raw.Close()
+
+	_, err = applyMigration(raw, Migration{Version: 1, Name: "broken", SQL: "CREATE TABLE a (id INTEGER); CREATE TABLE nope ("})
+	require.Error(t, err)
+
+	version, err := userVersion(raw)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+	has, err := hasTables(raw)
+	require.NoError(t, err)
+	assert.False(t, has, "the partial migration is rolled back")
+}
+
+func TestMigrate_FallbackForMissingModule(t *testing.T) {
+	raw, err := OpenSQLite(filepath.Join(t.TempDir(), "fallback.db"))
+	require.NoError(t, err)
+	defer raw.Close()
+
+	applied, err := applyMigration(raw, Migration{
+		Version:  2,
+		Name:     "search",
+		SQL:      "CREATE TABLE a (id INTEGER); CREATE VIRTUAL TABLE b USING no_such_module(x);",
+		Fallback: "CREATE TABLE c (id INTEGER);",
+	})
+	require.NoError(t, err)
+	assert.True(t, applied)
+
+	var names []string
+	rows, err := raw.Query("SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name")
+	require.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name))
+		names = append(names, name)
+	}
+	assert.Equal(t, []string{"c"}, names, "statements before the failing one are undone")
+}
@@ -1,12 +1,21 @@
+-- Version 1: the schema as of the first versioned release. Databases created
+-- before then already have these tables; missing columns are added in code.
+
 -- Research Sessions Table
 -- Stores all research queries and their results
 CREATE TABLE IF NOT EXISTS research_sessions (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     query TEXT NOT NULL,
+    refined_query TEXT, -- Research brief the query was expanded into before dispatch
     mode TEXT NOT NULL,
     prompt_used TEXT NOT NULL,
     result TEXT NOT NULL,
-    quality_score INTEGER,
+    quality_score INTEGER, -- Rubric score from 0 to 100
+    critique TEXT, -- Reviewer feedback behind quality_score
+    citations TEXT, -- JSON array of extracted citations
+    matrix TEXT, -- JSON comparison matrix from compare mode
+    status TEXT NOT NULL DEFAULT 'complete', -- 'complete', 'in_progress' or 'cancelled'
+    checkpoint TEXT, -- JSON state of an unfinished run, used to resume it
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -51,3 +60,19 @@ CREATE INDEX IF NOT EXISTS idx_history_session ON search_history(session_id);
 
 -- Index for temporal queries
 CREATE INDEX IF NOT EXISTS idx_history_created ON search_history(created_at DESC);
+
+
+-- Watched Queries Table
+-- Queries that are re-researched on a schedule to detect changes
+CREATE TABLE IF NOT EXISTS watched_queries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    query TEXT NOT NULL,
+    mode TEXT NOT NULL,
+    prompt_used TEXT NOT NULL,
+    interval_seconds INTEGER NOT NULL,
+    last_run_at DATETIME,
+    last_session_id INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(query, mode, prompt_used),
+    FOREIGN KEY (last_session_id) REFERENCES research_sessions(id) ON DELETE SET NULL
+);
@@ -0,0 +1,10 @@
+-- Version 2: record which provider and model produced a session, the tokens
+-- it used, and the session it follows up on
+ALTER TABLE research_sessions ADD COLUMN provider TEXT;
+ALTER TABLE research_sessions ADD COLUMN model TEXT;
+ALTER TABLE research_sessions ADD COLUMN prompt_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE research_sessions ADD COLUMN completion_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE research_sessions ADD COLUMN total_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE research_sessions ADD COLUMN parent_id INTEGER REFERENCES research_sessions(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_sessions_parent ON research_sessions(parent_id);
@@ -0,0 +1,30 @@
+-- Version 3: full-text search over session queries and results. The index
+-- reads from research_sessions and is kept in sync by triggers.
+CREATE VIRTUAL TABLE sessions_fts USING fts5(
+    query,
+    refined_query,
+    result,
+    content = 'research_sessions',
+    content_rowid = 'id',
+    tokenize = 'porter unicode61'
+);
+
+CREATE TRIGGER sessions_fts_insert AFTER INSERT ON research_sessions BEGIN
+    INSERT INTO sessions_fts(rowid, query, refined_query, result)
+    VALUES (new.id, new.query, new.refined_query, new.result);
+END;
+
+CREATE TRIGGER sessions_fts_delete AFTER DELETE ON research_sessions BEGIN
+    INSERT INTO sessions_fts(sessions_fts, rowid, query, refined_query, result)
+    VALUES ('delete', old.id, old.query, old.refined_query, old.result);
+END;
+
+CREATE TRIGGER sessions_fts_update AFTER UPDATE OF query, refined_query, result ON research_sessions BEGIN
+    INSERT INTO sessions_fts(sessions_fts, rowid, query, refined_query, result)
+    VALUES ('delete', old.id, old.query, old.refined_query, old.result);
+    INSERT INTO sessions_fts(rowid, query, refined_query, result)
+    VALUES (new.id, new.query, new.refined_query, new.result);
+END;
+
+-- Index the sessions stored so far
+INSERT INTO sessions_fts(sessions_fts) VALUES ('rebuild');
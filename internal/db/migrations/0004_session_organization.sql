@@ -0,0 +1,18 @@
+-- Version 4: organize sessions with tags, a starred flag and free-form notes
+ALTER TABLE research_sessions ADD COLUMN starred INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE research_sessions ADD COLUMN notes TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_sessions_starred ON research_sessions(starred) WHERE starred = 1;
+
+-- Session Tags Table
+-- Labels attached to sessions, stored lowercase
+CREATE TABLE IF NOT EXISTS session_tags (
+    session_id INTEGER NOT NULL,
+    tag TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (session_id, tag),
+    FOREIGN KEY (session_id) REFERENCES research_sessions(id) ON DELETE CASCADE
+);
+
+-- Index for filtering and counting by tag
+CREATE INDEX IF NOT EXISTS idx_session_tags_tag ON session_tags(tag);
@@ -0,0 +1,12 @@
+-- Version 5: let users rate sessions, and aggregate the ratings per prompt,
+-- mode and provider into learned_patterns
+ALTER TABLE research_sessions ADD COLUMN user_rating INTEGER CHECK (user_rating BETWEEN 1 AND 5);
+
+ALTER TABLE learned_patterns ADD COLUMN prompt_used TEXT;
+ALTER TABLE learned_patterns ADD COLUMN mode TEXT;
+ALTER TABLE learned_patterns ADD COLUMN provider TEXT;
+ALTER TABLE learned_patterns ADD COLUMN rating_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE learned_patterns ADD COLUMN rating_total INTEGER NOT NULL DEFAULT 0;
+
+-- Index for finding the best rated prompts of a mode
+CREATE INDEX IF NOT EXISTS idx_patterns_mode_prompt ON learned_patterns(mode, prompt_used) WHERE rating_count > 0;
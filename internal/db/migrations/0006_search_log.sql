@@ -0,0 +1,20 @@
+-- Version 6: log every research run in search_history, with its outcome,
+-- provider and latency, whether or not it stored a session
+ALTER TABLE search_history ADD COLUMN mode TEXT;
+ALTER TABLE search_history ADD COLUMN prompt_used TEXT;
+ALTER TABLE search_history ADD COLUMN provider TEXT;
+ALTER TABLE search_history ADD COLUMN outcome TEXT NOT NULL DEFAULT 'success';
+ALTER TABLE search_history ADD COLUMN error TEXT;
+ALTER TABLE search_history ADD COLUMN latency_ms INTEGER NOT NULL DEFAULT 0;
+
+-- Index for filtering by outcome; recall uses idx_history_created from
+-- version 1
+CREATE INDEX IF NOT EXISTS idx_search_history_outcome ON search_history(outcome);
+
+-- Runs from before the log was written, reconstructed from their sessions
+INSERT INTO search_history (session_id, query, mode, prompt_used, provider, outcome, created_at)
+SELECT id, query, mode, prompt_used, provider,
+    CASE status WHEN 'cancelled' THEN 'cancelled' WHEN 'in_progress' THEN 'failed' ELSE 'success' END,
+    created_at
+FROM research_sessions
+WHERE id NOT IN (SELECT session_id FROM search_history WHERE session_id IS NOT NULL);
@@ -0,0 +1,10 @@
+-- Version 7: encryption at rest. A database is encrypted once this table
+-- holds its key parameters; the key itself is never stored.
+CREATE TABLE IF NOT EXISTS encryption (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    key_source TEXT NOT NULL,               -- passphrase or key_file
+    salt BLOB NOT NULL,
+    iterations INTEGER NOT NULL DEFAULT 0,  -- PBKDF2 rounds for a passphrase
+    key_check TEXT NOT NULL,                -- A known value sealed with the key, to detect a wrong key
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
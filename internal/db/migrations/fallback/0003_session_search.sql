@@ -0,0 +1,32 @@
+-- Version 3 for SQLite builds without FTS5 (built without the sqlite_fts5
+-- tag): the same index with FTS4, which has no ranking function
+CREATE VIRTUAL TABLE sessions_fts USING fts4(
+    content="research_sessions",
+    query,
+    refined_query,
+    result,
+    tokenize=porter
+);
+
+-- FTS4 reads the old values from the content table, so they are removed
+-- before the row changes
+CREATE TRIGGER sessions_fts_before_update BEFORE UPDATE OF query, refined_query, result ON research_sessions BEGIN
+    DELETE FROM sessions_fts WHERE docid = old.id;
+END;
+
+CREATE TRIGGER sessions_fts_before_delete BEFORE DELETE ON research_sessions BEGIN
+    DELETE FROM sessions_fts WHERE docid = old.id;
+END;
+
+CREATE TRIGGER sessions_fts_insert AFTER INSERT ON research_sessions BEGIN
+    INSERT INTO sessions_fts(docid, query, refined_query, result)
+    VALUES (new.id, new.query, new.refined_query, new.result);
+END;
+
+CREATE TRIGGER sessions_fts_after_update AFTER UPDATE OF query, refined_query, result ON research_sessions BEGIN
+    INSERT INTO sessions_fts(docid, query, refined_query, result)
+    VALUES (new.id, new.query, new.refined_query, new.result);
+END;
+
+-- Index the sessions stored so far
+INSERT INTO sessions_fts(sessions_fts) VALUES ('rebuild');
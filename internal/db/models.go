@@ -1,16 +1,167 @@
 package db
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
 
 // ResearchSession represents a single research query and its result
 type ResearchSession struct {
-	ID           int64     `json:"id"`
-	Query        string    `json:"query"`
-	Mode         string    `json:"mode"`
-	PromptUsed   string    `json:"prompt_used"`
-	Result       string    `json:"result"`
-	QualityScore *int      `json:"quality_score,omitempty"` // Optional user rating
-	CreatedAt    time.Time `json:"created_at"`
+	ID           int64             `json:"id"`
+	Query        string            `json:"query"`
+	RefinedQuery string            `json:"refined_query,omitempty"` // Research brief the query was expanded into
+	Mode         string            `json:"mode"`
+	PromptUsed   string            `json:"prompt_used"`
+	Result       string            `json:"result"`
+	QualityScore *int              `json:"quality_score,omitempty"` // Rubric score from 0 to 100
+	Critique     string            `json:"critique,omitempty"`      // Reviewer feedback behind QualityScore
+	Citations    []Citation        `json:"citations,omitempty"`
+	Matrix       *ComparisonMatrix `json:"matrix,omitempty"`     // Set by compare mode
+	Status       string            `json:"status"`               // SessionComplete, SessionInProgress or SessionCancelled
+	Checkpoint   *Checkpoint       `json:"checkpoint,omitempty"` // Saved state of an unfinished run
+	Provider     string            `json:"provider,omitempty"`   // Provider that answered
+	Model        string            `json:"model,omitempty"`
+	Tokens       TokenCount        `json:"tokens"`
+	ParentID     int64             `json:"parent_id,omitempty"` // Session this one follows up on; 0 if none
+	UserRating   *int              `json:"user_rating,omitempty"` // The user's rating from 1 to 5
+	Starred      bool              `json:"starred,omitempty"`
+	Tags         []string          `json:"tags,omitempty"`  // Sorted, lowercase
+	Notes        string            `json:"notes,omitempty"` // The user's own notes
+	CreatedAt    time.Time         `json:"created_at"`
+}
+
+// TokenCount is the number of tokens a session's provider calls used
+type TokenCount struct {
+	Prompt     int `json:"prompt"`
+	Completion int `json:"completion"`
+	Total      int `json:"total"`
+}
+
+// Session statuses
+const (
+	SessionComplete   = "complete"
+	SessionInProgress = "in_progress"
+	SessionCancelled  = "cancelled" // Stopped by the user or a timeout; Result holds the partial output
+)
+
+// InProgress reports whether the session was interrupted before finishing
+func (s *ResearchSession) InProgress() bool {
+	return s.Status == SessionInProgress
+}
+
+// Cancelled reports whether the run was cancelled before finishing
+func (s *ResearchSession) Cancelled() bool {
+	return s.Status == SessionCancelled
+}
+
+// Resumable reports whether the session can be continued from its
+// checkpoint: it was interrupted, or cancelled after a checkpoint
+func (s *ResearchSession) Resumable() bool {
+	return s.InProgress() || (s.Cancelled() && s.Checkpoint != nil)
+}
+
+// ContentHash identifies a session by its query, mode, answer and creation
+// time to the second, so the same session imported twice, from any
+// machine or archive format, is recognized. Surrounding whitespace is
+// ignored.
+func (s *ResearchSession) ContentHash() string {
+	h := sha256.New()
+	for _, part := range []string{strings.TrimSpace(s.Query), s.Mode, strings.TrimSpace(s.Result), s.CreatedAt.UTC().Format(time.RFC3339)} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ImportResult reports what importing sessions did
+type ImportResult struct {
+	Imported int // Sessions added
+	Skipped  int // Sessions already present, by ContentHash
+}
+
+// Checkpoint is the state of an unfinished research run, saved after each
+// completed step so the run can be resumed
+type Checkpoint struct {
+	Stage             string            `json:"stage"`                        // Last completed stage
+	Prompt            string            `json:"prompt,omitempty"`             // Rendered prompt for the main query
+	Context           string            `json:"context,omitempty"`            // Rendered local context
+	Snippets          []ContextSnippet  `json:"snippets,omitempty"`           // Local file excerpts the run is grounded in
+	Sources           string            `json:"sources,omitempty"`            // Prior research section appended to synthesis reports
+	Content           string            `json:"content,omitempty"`            // Answer so far
+	Critique          bool              `json:"critique,omitempty"`           // Whether the run grades its answer
+	CritiqueThreshold int               `json:"critique_threshold,omitempty"` // Score below which the answer is revised
+	Options           []string          `json:"options,omitempty"`            // Options being compared
+	Criteria          []string          `json:"criteria,omitempty"`           // Criteria the options are scored on
+	Matrix            *ComparisonMatrix `json:"matrix,omitempty"`             // Options scored so far
+}
+
+// ContextSnippet is a checkpointed excerpt of a local file given as context
+type ContextSnippet struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Content   string `json:"content"`
+}
+
+// ComparisonMatrix scores a set of options against shared criteria
+type ComparisonMatrix struct {
+	Criteria []string       `json:"criteria"`
+	Options  []MatrixOption `json:"options"`
+}
+
+// MatrixOption is one compared option with a score per criterion
+type MatrixOption struct {
+	Name    string        `json:"name"`
+	Summary string        `json:"summary,omitempty"`
+	Scores  []MatrixScore `json:"scores"` // In the same order as the matrix criteria
+}
+
+// MatrixScore rates an option on one criterion from 1 to 5; 0 means unknown
+type MatrixScore struct {
+	Criterion string `json:"criterion"`
+	Score     int    `json:"score"`
+	Note      string `json:"note,omitempty"`
+}
+
+// Average returns the mean of the known scores, or 0 if none are known
+func (o MatrixOption) Average() float64 {
+	total, count := 0, 0
+	for _, s := range o.Scores {
+		if s.Score > 0 {
+			total += s.Score
+			count++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return float64(total) / float64(count)
+}
+
+// Citation statuses
+const (
+	CitationUnchecked  = "unchecked"
+	CitationOK         = "ok"
+	CitationDead       = "dead"
+	CitationSuspicious = "suspicious"
+)
+
+// Citation is a source referenced by a research result
+type Citation struct {
+	Text       string `json:"text,omitempty"` // Link text or reference label
+	URL        string `json:"url,omitempty"`  // Empty for non-URL references such as local files
+	Status     string `json:"status"`
+	StatusCode int    `json:"status_code,omitempty"` // HTTP status when verified
+	Note       string `json:"note,omitempty"`        // Why the citation was flagged
+}
+
+// Flagged reports whether the citation is dead or suspicious
+func (c Citation) Flagged() bool {
+	return c.Status == CitationDead || c.Status == CitationSuspicious
 }
 
 // LearnedPattern tracks successful research patterns and strategies
@@ -21,14 +172,95 @@ type LearnedPattern struct {
 	SuccessCount int       `json:"success_count"`
 	LastUsed     time.Time `json:"last_used"`
 	CreatedAt    time.Time `json:"created_at"`
+
+	// Set on the patterns that aggregate user ratings
+	PromptUsed  string `json:"prompt_used,omitempty"`
+	Mode        string `json:"mode,omitempty"`
+	Provider    string `json:"provider,omitempty"`
+	RatingCount int    `json:"rating_count,omitempty"`
+	RatingTotal int    `json:"rating_total,omitempty"`
+}
+
+// AverageRating returns the mean user rating the pattern aggregates, or 0
+// if it has none
+func (p *LearnedPattern) AverageRating() float64 {
+	if p.RatingCount == 0 {
+		return 0
+	}
+	return float64(p.RatingTotal) / float64(p.RatingCount)
+}
+
+// Bounds of a user rating
+const (
+	MinRating = 1
+	MaxRating = 5
+)
+
+// ratingPatternName names the pattern aggregating the ratings of sessions
+// with a prompt, mode and provider
+func ratingPatternName(prompt, mode, provider string) string {
+	return fmt.Sprintf("rating:%s:%s:%s", mode, prompt, provider)
+}
+
+// PromptRating is the mean user rating of the sessions run with a prompt in
+// a mode, across providers
+type PromptRating struct {
+	PromptUsed string  `json:"prompt_used"`
+	Mode       string  `json:"mode"`
+	Count      int     `json:"count"`
+	Average    float64 `json:"average"`
 }
 
 // SearchHistory maintains a log of all search queries
 type SearchHistory struct {
-	ID        int64     `json:"id"`
-	SessionID int64     `json:"session_id"`
-	Query     string    `json:"query"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         int64         `json:"id"`
+	SessionID  int64         `json:"session_id"` // Session the run stored; 0 if none
+	Query      string        `json:"query"`
+	Mode       string        `json:"mode,omitempty"`
+	PromptUsed string        `json:"prompt_used,omitempty"`
+	Provider   string        `json:"provider,omitempty"` // Provider of the last answer, if any
+	Outcome    string        `json:"outcome"`            // OutcomeSuccess, OutcomeFailed or OutcomeCancelled
+	Error      string        `json:"error,omitempty"`
+	Latency    time.Duration `json:"latency"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+// Outcomes of a logged research run
+const (
+	OutcomeSuccess   = "success"
+	OutcomeFailed    = "failed"
+	OutcomeCancelled = "cancelled"
+)
+
+// SearchFilter selects entries of the search log. Zero fields don't filter.
+type SearchFilter struct {
+	Text     string // Substring of the query, case-insensitive
+	Prefix   string // Start of the query, case-insensitive
+	Outcome  string
+	Mode     string
+	Provider string
+	Since    time.Time
+	Until    time.Time
+	Distinct bool // Only the most recent run of each query
+	Limit    int
+}
+
+// TagCount is a tag and the number of sessions carrying it
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._/-]*$`)
+
+// NormalizeTag lowercases a tag and checks that it is a single word of
+// letters, digits and . _ / -
+func NormalizeTag(tag string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(tag))
+	if len(normalized) > 64 || !tagPattern.MatchString(normalized) {
+		return "", fmt.Errorf("invalid tag %q: use letters, digits and . _ / -", tag)
+	}
+	return normalized, nil
 }
 
 // QueryCount represents a query string and its count
@@ -36,3 +268,58 @@ type QueryCount struct {
 	Query string `json:"query"`
 	Count int    `json:"count"`
 }
+
+// Periods usage statistics can be bucketed by
+const (
+	PeriodDay  = "day"
+	PeriodWeek = "week" // Weeks start on Monday
+)
+
+// UsageFilter selects the window of usage statistics and how it is
+// bucketed. Zero Since and Until don't filter.
+type UsageFilter struct {
+	Since    time.Time
+	Until    time.Time
+	Period   string         // PeriodDay, PeriodWeek, or "" for a single bucket
+	Location *time.Location // Zone periods start in; UTC if nil
+}
+
+// SessionUsage counts the sessions a provider and model answered in a
+// period, and the tokens they used
+type SessionUsage struct {
+	Period   time.Time  `json:"period"` // Start of the period; zero without a period
+	Provider string     `json:"provider"`
+	Model    string     `json:"model"`
+	Sessions int        `json:"sessions"`
+	Tokens   TokenCount `json:"tokens"`
+}
+
+// RunUsage counts the logged runs of a provider in a period by outcome
+type RunUsage struct {
+	Period         time.Time     `json:"period"`   // Start of the period; zero without a period
+	Provider       string        `json:"provider"` // "" for runs no provider answered
+	Runs           int           `json:"runs"`
+	Failed         int           `json:"failed"`
+	Cancelled      int           `json:"cancelled"`
+	AverageLatency time.Duration `json:"average_latency"` // Mean over the runs that recorded a latency
+}
+
+// WatchedQuery is a query that is re-researched on a schedule
+type WatchedQuery struct {
+	ID            int64         `json:"id"`
+	Query         string        `json:"query"`
+	Mode          string        `json:"mode"`
+	PromptUsed    string        `json:"prompt_used"`
+	Interval      time.Duration `json:"interval"`
+	LastRunAt     *time.Time    `json:"last_run_at,omitempty"`
+	LastSessionID *int64        `json:"last_session_id,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+}
+
+// IsDue reports whether the watch should run again at the given time
+func (w *WatchedQuery) IsDue(now time.Time) bool {
+	if w.LastRunAt == nil {
+		return true
+	}
+	return !now.Before(w.LastRunAt.Add(w.Interval))
+}
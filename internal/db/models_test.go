@@ -2,6 +2,7 @@ package db
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 
@@ -180,3 +181,58 @@ func TestQualityScoreOptional(t *testing.T) {
 	assert.NotNil(t, session.QualityScore)
 	assert.Equal(t, 3, *session.QualityScore)
 }
+
+func TestWatchedQuery_IsDue(t *testing.T) {
+	now := time.Now()
+
+	never := &WatchedQuery{Interval: time.Hour}
+	assert.True(t, never.IsDue(now), "a watch that never ran is due")
+
+	recent := now.Add(-30 * time.Minute)
+	notDue := &WatchedQuery{Interval: time.Hour, LastRunAt: &recent}
+	assert.False(t, notDue.IsDue(now))
+
+	old := now.Add(-2 * time.Hour)
+	due := &WatchedQuery{Interval: time.Hour, LastRunAt: &old}
+	assert.True(t, due.IsDue(now))
+}
+
+func TestResearchSession_Resumable(t *testing.T) {
+	assert.False(t, (&ResearchSession{Status: SessionComplete}).Resumable())
+	assert.True(t, (&ResearchSession{Status: SessionInProgress}).Resumable())
+	assert.False(t, (&ResearchSession{Status: SessionCancelled}).Resumable(), "nothing to resume from")
+
+	cancelled := &ResearchSession{Status: SessionCancelled, Checkpoint: &Checkpoint{Stage: "query"}}
+	assert.True(t, cancelled.Cancelled())
+	assert.False(t, cancelled.InProgress())
+	assert.True(t, cancelled.Resumable())
+}
+
+func TestResearchSession_ContentHash(t *testing.T) {
+	createdAt := time.Date(2026, 3, 1, 12, 0, 0, 500, time.UTC)
+	a := &ResearchSession{ID: 1, Query: "q", Mode: "quick", Result: "r", CreatedAt: createdAt}
+	b := &ResearchSession{ID: 2, Query: "q", Mode: "quick", Result: "r", Provider: "openai", CreatedAt: createdAt.In(time.FixedZone("PST", -8*3600))}
+	assert.Equal(t, a.ContentHash(), b.ContentHash(), "IDs, metadata and zones do not matter")
+	assert.Len(t, a.ContentHash(), 64)
+
+	b.Result = "r\n"
+	assert.Equal(t, a.ContentHash(), b.ContentHash(), "surrounding whitespace does not matter")
+
+	b.Result = "r2"
+	assert.NotEqual(t, a.ContentHash(), b.ContentHash())
+	b.Result = "r"
+	b.CreatedAt = createdAt.Add(time.Second)
+	assert.NotEqual(t, a.ContentHash(), b.ContentHash())
+}
+
+func TestNormalizeTag(t *testing.T) {
+	for in, want := range map[string]string{"Golang": "golang", " k8s ": "k8s", "lang/go": "lang/go", "v1.2_beta-x": "v1.2_beta-x"} {
+		got, err := NormalizeTag(in)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	for _, in := range []string{"", "-leading", "two words", "#hash", strings.Repeat("a", 65)} {
+		_, err := NormalizeTag(in)
+		assert.ErrorContains(t, err, "invalid tag", in)
+	}
+}
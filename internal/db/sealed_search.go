@@ -0,0 +1,213 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The queries and answers of an encrypted database are opaque to SQLite, so
+// searches over them load the candidate rows and match the decrypted text
+// here instead. Other filters still run in SQL.
+
+// searchSealedSessions is SearchSessions for an encrypted database
+func (s *SQLiteDB) searchSealedSessions(query string) ([]*ResearchSession, error) {
+	rows, err := s.db.Query("SELECT " + sessionColumns + " FROM research_sessions ORDER BY created_at DESC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to search sessions: %w", err)
+	}
+	defer rows.Close()
+
+	needle := strings.ToLower(query)
+	var sessions []*ResearchSession
+	for rows.Next() {
+		session, err := s.scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		if strings.Contains(strings.ToLower(session.Query), needle) || strings.Contains(strings.ToLower(session.RefinedQuery), needle) {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, rows.Err()
+}
+
+// latestSealedSession is GetLatestSession for an encrypted database
+func (s *SQLiteDB) latestSealedSession(query, mode string) (*ResearchSession, error) {
+	rows, err := s.db.Query(`
+		SELECT `+sessionColumns+`
+		FROM research_sessions
+		WHERE mode = ? AND status = 'complete'
+		ORDER BY created_at DESC, id DESC
+	`, mode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest session: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		session, err := s.scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get latest session: %w", err)
+		}
+		if session.Query == query {
+			return session, nil
+		}
+	}
+	return nil, rows.Err()
+}
+
+// searchTerms splits search input into lowercase words, dropping the
+// full-text query syntax an encrypted database cannot evaluate
+func searchTerms(text string) []string {
+	var terms []string
+	for _, word := range strings.Fields(text) {
+		switch word {
+		case "AND", "OR", "NOT", "NEAR":
+			continue
+		}
+		word = strings.ToLower(strings.Trim(word, `"()*`))
+		if word != "" {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// matchSealedSessions keeps the matches whose decrypted query, refined
+// query or answer contain every term, with a snippet of the answer around
+// them, up to limit if it is positive
+func matchSealedSessions(matches []*SessionMatch, text string, limit int) []*SessionMatch {
+	terms := searchTerms(text)
+	var kept []*SessionMatch
+	for _, m := range matches {
+		content := strings.ToLower(m.Query + "\n" + m.RefinedQuery + "\n" + m.Result)
+		matched := true
+		for _, term := range terms {
+			if !strings.Contains(content, term) {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		m.Snippet = termSnippet(m.Result, terms)
+		if m.Snippet == "" {
+			m.Snippet = termSnippet(m.Query, terms)
+		}
+		kept = append(kept, m)
+		if limit > 0 && len(kept) == limit {
+			break
+		}
+	}
+	return kept
+}
+
+// termSnippet excerpts about 16 words of text around the first word
+// containing a term, highlighting such words like the full-text index does.
+// It returns "" if no word contains a term.
+func termSnippet(text string, terms []string) string {
+	words := strings.Fields(text)
+	first := -1
+	for i, word := range words {
+		lower := strings.ToLower(word)
+		for _, term := range terms {
+			if strings.Contains(lower, term) {
+				if first < 0 {
+					first = i
+				}
+				words[i] = HighlightStart + word + HighlightEnd
+				break
+			}
+		}
+	}
+	if first < 0 {
+		return ""
+	}
+
+	start := max(0, first-4)
+	end := min(len(words), start+16)
+	snippet := strings.Join(words[start:end], " ")
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(words) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// matchSealedSearches applies the text, prefix, distinct and limit parts of
+// a filter to decrypted search log entries, most recent first
+func matchSealedSearches(entries []*SearchHistory, filter SearchFilter) []*SearchHistory {
+	text := strings.ToLower(strings.TrimSpace(filter.Text))
+	prefix := strings.ToLower(filter.Prefix)
+	seen := make(map[string]bool)
+
+	var kept []*SearchHistory
+	for _, e := range entries {
+		query := strings.ToLower(e.Query)
+		if !strings.Contains(query, text) || !strings.HasPrefix(query, prefix) {
+			continue
+		}
+		if filter.Distinct {
+			key := strings.TrimSpace(query)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		kept = append(kept, e)
+		if filter.Limit > 0 && len(kept) == filter.Limit {
+			break
+		}
+	}
+	return kept
+}
+
+// topSealedQueries is GetTopQueries for an encrypted database
+func (s *SQLiteDB) topSealedQueries(limit int) ([]QueryCount, error) {
+	rows, err := s.db.Query("SELECT id, query FROM search_history ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top queries: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]*QueryCount)
+	latest := make(map[string]int64)
+	for rows.Next() {
+		var id int64
+		var query string
+		if err := rows.Scan(&id, &query); err != nil {
+			return nil, fmt.Errorf("failed to scan top query: %w", err)
+		}
+		if query, err = s.cipher.open(query); err != nil {
+			return nil, err
+		}
+		if counts[query] == nil {
+			counts[query] = &QueryCount{Query: query}
+		}
+		counts[query].Count++
+		latest[query] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get top queries: %w", err)
+	}
+
+	topQueries := make([]QueryCount, 0, len(counts))
+	for _, qc := range counts {
+		topQueries = append(topQueries, *qc)
+	}
+	sort.Slice(topQueries, func(i, j int) bool {
+		if topQueries[i].Count != topQueries[j].Count {
+			return topQueries[i].Count > topQueries[j].Count
+		}
+		return latest[topQueries[i].Query] > latest[topQueries[j].Query]
+	})
+	if limit >= 0 && len(topQueries) > limit {
+		topQueries = topQueries[:limit]
+	}
+	return topQueries, nil
+}
@@ -0,0 +1,191 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Markers around matched terms in SessionMatch.Snippet. They are control
+// characters so they cannot collide with markdown in the stored answers.
+const (
+	HighlightStart = "\x02"
+	HighlightEnd   = "\x03"
+)
+
+// SessionFilter selects sessions for FindSessions. Zero fields match
+// everything.
+type SessionFilter struct {
+	// Text is a full-text query over the query, refined query and result.
+	// Plain words must all appear; "quoted phrases", AND, OR, NOT,
+	// parentheses and prefix* use the full-text query syntax.
+	Text      string
+	Mode      string
+	Provider  string
+	Since     time.Time // Sessions created at or after
+	Until     time.Time // Sessions created before
+	Resumable bool      // Only sessions that can be resumed
+	Tags      []string  // Only sessions carrying all of these tags
+	Starred   bool      // Only starred sessions
+	Limit     int
+}
+
+// SessionMatch is a session found by FindSessions
+type SessionMatch struct {
+	*ResearchSession
+	Snippet string  // Excerpt around the matched terms; empty without Text
+	Rank    float64 // Relevance, lower is better; 0 when results are ordered by date
+}
+
+// searchOperator matches input that uses the full-text query syntax rather
+// than plain words
+var searchOperator = regexp.MustCompile(`["*()]|\b(AND|OR|NOT|NEAR)\b`)
+
+// matchExpression turns search input into a MATCH expression. Plain words
+// are quoted, so punctuation such as "c++" or "go-sqlite3" is searched for
+// rather than parsed as syntax.
+func matchExpression(text string) string {
+	if searchOperator.MatchString(text) {
+		return text
+	}
+	words := strings.Fields(text)
+	for i, w := range words {
+		words[i] = `"` + strings.ReplaceAll(w, `"`, `""`) + `"`
+	}
+	return strings.Join(words, " ")
+}
+
+// FindSessions returns the sessions matching a filter. With Text, results
+// are ranked by relevance when SQLite has FTS5 and newest first otherwise;
+// without it they are newest first.
+func (s *SQLiteDB) FindSessions(filter SessionFilter) ([]*SessionMatch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var (
+		where []string
+		args  []interface{}
+		query string
+	)
+	text := strings.TrimSpace(filter.Text)
+	// The index of an encrypted database holds ciphertext, so its text is
+	// matched after decrypting instead
+	sealedText := ""
+	if s.cipher != nil {
+		sealedText, text = text, ""
+	}
+	columns := "s." + strings.ReplaceAll(sessionColumns, ", ", ", s.")
+
+	switch {
+	case text == "":
+		query = "SELECT " + columns + ", '', 0 FROM research_sessions s"
+	case s.fts5:
+		query = `SELECT ` + columns + `,
+			snippet(sessions_fts, -1, ?, ?, '…', 16), bm25(sessions_fts, 10.0, 5.0, 1.0)
+			FROM sessions_fts JOIN research_sessions s ON s.id = sessions_fts.rowid`
+		args = append(args, HighlightStart, HighlightEnd)
+	default:
+		query = `SELECT ` + columns + `,
+			snippet(sessions_fts, ?, ?, '…', -1, 16), 0
+			FROM sessions_fts JOIN research_sessions s ON s.id = sessions_fts.docid`
+		args = append(args, HighlightStart, HighlightEnd)
+	}
+
+	if text != "" {
+		where = append(where, "sessions_fts MATCH ?")
+		args = append(args, matchExpression(text))
+	}
+	if filter.Mode != "" {
+		where = append(where, "s.mode = ?")
+		args = append(args, filter.Mode)
+	}
+	if filter.Provider != "" {
+		where = append(where, "s.provider = ?")
+		args = append(args, filter.Provider)
+	}
+	// created_at is stored with a zone offset, which julianday normalizes
+	if !filter.Since.IsZero() {
+		where = append(where, "julianday(s.created_at) >= julianday(?)")
+		args = append(args, filter.Since.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if !filter.Until.IsZero() {
+		where = append(where, "julianday(s.created_at) < julianday(?)")
+		args = append(args, filter.Until.UTC().Format("2006-01-02 15:04:05"))
+	}
+	for _, tag := range filter.Tags {
+		tag, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, "EXISTS (SELECT 1 FROM session_tags t WHERE t.session_id = s.id AND t.tag = ?)")
+		args = append(args, tag)
+	}
+	if filter.Starred {
+		where = append(where, "s.starred = 1")
+	}
+	if filter.Resumable {
+		where = append(where, "(s.status = 'in_progress' OR (s.status = 'cancelled' AND s.checkpoint IS NOT NULL))")
+	}
+
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	if text != "" && s.fts5 {
+		query += " ORDER BY bm25(sessions_fts, 10.0, 5.0, 1.0), s.created_at DESC"
+	} else {
+		query += " ORDER BY s.created_at DESC, s.id DESC"
+	}
+	if filter.Limit > 0 && sealedText == "" {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		if text != "" && isSearchSyntaxError(err) {
+			return nil, fmt.Errorf("invalid search syntax %q: %w", text, err)
+		}
+		return nil, fmt.Errorf("failed to search sessions: %w", err)
+	}
+
+	var matches []*SessionMatch
+	for rows.Next() {
+		match := &SessionMatch{}
+		session, err := s.scanSession(rows, &match.Snippet, &match.Rank)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		match.ResearchSession = session
+		matches = append(matches, match)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		if text != "" && isSearchSyntaxError(err) {
+			return nil, fmt.Errorf("invalid search syntax %q: %w", text, err)
+		}
+		return nil, fmt.Errorf("failed to search sessions: %w", err)
+	}
+
+	if sealedText != "" {
+		matches = matchSealedSessions(matches, sealedText, filter.Limit)
+	}
+	sessions := make([]*ResearchSession, len(matches))
+	for i, m := range matches {
+		sessions[i] = m.ResearchSession
+	}
+	if err := s.loadTags(sessions...); err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// isSearchSyntaxError reports whether SQLite rejected a MATCH expression
+func isSearchSyntaxError(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "fts5: syntax error") ||
+		strings.Contains(msg, "malformed MATCH") ||
+		strings.Contains(msg, "unterminated string") ||
+		strings.Contains(msg, "no such column")
+}
@@ -0,0 +1,175 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// searchLogColumns lists the search_history columns read by scanSearch
+const searchLogColumns = "id, session_id, query, mode, prompt_used, provider, outcome, error, latency_ms, created_at"
+
+// LogSearch records a research run in the search log
+func (s *SQLiteDB) LogSearch(entry *SearchHistory) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	if entry.Outcome == "" {
+		entry.Outcome = OutcomeSuccess
+	}
+
+	query := `
+		INSERT INTO search_history (session_id, query, mode, prompt_used, provider, outcome, error, latency_ms, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	var sessionID interface{}
+	if entry.SessionID != 0 {
+		sessionID = entry.SessionID
+	}
+	result, err := s.db.Exec(
+		query,
+		sessionID,
+		s.cipher.seal(entry.Query),
+		nullIfEmpty(entry.Mode),
+		nullIfEmpty(entry.PromptUsed),
+		nullIfEmpty(entry.Provider),
+		entry.Outcome,
+		nullIfEmpty(entry.Error),
+		entry.Latency.Milliseconds(),
+		entry.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to log search: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get insert ID: %w", err)
+	}
+
+	entry.ID = id
+	return nil
+}
+
+// ListSearches returns the entries of the search log matching a filter,
+// most recent first
+func (s *SQLiteDB) ListSearches(filter SearchFilter) ([]*SearchHistory, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// The queries of an encrypted database are matched after decrypting
+	sealed := s.cipher != nil && (strings.TrimSpace(filter.Text) != "" || filter.Prefix != "" || filter.Distinct)
+
+	var (
+		where []string
+		args  []interface{}
+	)
+	if text := strings.TrimSpace(filter.Text); text != "" && !sealed {
+		where = append(where, "query LIKE ? ESCAPE '\\'")
+		args = append(args, "%"+escapeLike(text)+"%")
+	}
+	if filter.Prefix != "" && !sealed {
+		where = append(where, "query LIKE ? ESCAPE '\\'")
+		args = append(args, escapeLike(filter.Prefix)+"%")
+	}
+	if filter.Outcome != "" {
+		where = append(where, "outcome = ?")
+		args = append(args, filter.Outcome)
+	}
+	if filter.Mode != "" {
+		where = append(where, "mode = ?")
+		args = append(args, filter.Mode)
+	}
+	if filter.Provider != "" {
+		where = append(where, "provider = ?")
+		args = append(args, filter.Provider)
+	}
+	// created_at is stored with a zone offset, which julianday normalizes
+	if !filter.Since.IsZero() {
+		where = append(where, "julianday(created_at) >= julianday(?)")
+		args = append(args, filter.Since.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if !filter.Until.IsZero() {
+		where = append(where, "julianday(created_at) < julianday(?)")
+		args = append(args, filter.Until.UTC().Format("2006-01-02 15:04:05"))
+	}
+
+	conditions := ""
+	if len(where) > 0 {
+		conditions = " WHERE " + strings.Join(where, " AND ")
+	}
+	query := "SELECT " + searchLogColumns + " FROM search_history" + conditions
+	if filter.Distinct && !sealed {
+		query = "SELECT " + searchLogColumns + " FROM search_history WHERE id IN (SELECT MAX(id) FROM search_history" + conditions + " GROUP BY lower(trim(query)))"
+	}
+	query += " ORDER BY julianday(created_at) DESC, id DESC"
+	if filter.Limit > 0 && !sealed {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list searches: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*SearchHistory
+	for rows.Next() {
+		entry, err := s.scanSearch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan search: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if sealed {
+		entries = matchSealedSearches(entries, filter)
+	}
+	return entries, nil
+}
+
+// scanSearch reads a search log entry selected with searchLogColumns,
+// decrypting it
+func (s *SQLiteDB) scanSearch(row rowScanner) (*SearchHistory, error) {
+	entry := &SearchHistory{}
+	var sessionID sql.NullInt64
+	var mode, promptUsed, providerName, errText sql.NullString
+	var latency int64
+	err := row.Scan(
+		&entry.ID,
+		&sessionID,
+		&entry.Query,
+		&mode,
+		&promptUsed,
+		&providerName,
+		&entry.Outcome,
+		&errText,
+		&latency,
+		&entry.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Query, err = s.cipher.open(entry.Query); err != nil {
+		return nil, err
+	}
+
+	entry.SessionID = sessionID.Int64
+	entry.Mode = mode.String
+	entry.PromptUsed = promptUsed.String
+	entry.Provider = providerName.String
+	entry.Error = errText.String
+	entry.Latency = time.Duration(latency) * time.Millisecond
+	return entry, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s, for use with ESCAPE '\'
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,153 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedSearchLog logs runs for the search log tests, oldest first
+func seedSearchLog(t *testing.T, db *SQLiteDB) []*SearchHistory {
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	entries := []*SearchHistory{
+		{Query: "Go contexts", Mode: "quick", Provider: "openai", Outcome: OutcomeSuccess, Latency: 1500 * time.Millisecond, CreatedAt: base},
+		{Query: "Rust async", Mode: "deep", Outcome: OutcomeFailed, Error: "provider query failed: timeout", CreatedAt: base.Add(time.Hour)},
+		{Query: "go contexts ", Mode: "deep", Provider: "github-copilot", Outcome: OutcomeCancelled, CreatedAt: base.Add(2 * time.Hour)},
+		{Query: "Kafka 100%", Mode: "quick", Outcome: OutcomeFailed, Error: "no providers available", CreatedAt: base.Add(3 * time.Hour)},
+	}
+	for _, e := range entries {
+		require.NoError(t, db.LogSearch(e))
+	}
+	return entries
+}
+
+// searchQueries returns the queries of log entries, in order
+func searchQueries(entries []*SearchHistory) []string {
+	queries := []string{}
+	for _, e := range entries {
+		queries = append(queries, e.Query)
+	}
+	return queries
+}
+
+func TestLogSearch(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	session := &ResearchSession{Query: "q", Mode: "quick", PromptUsed: "default", Result: "r"}
+	require.NoError(t, db.SaveSession(session))
+
+	entry := &SearchHistory{SessionID: session.ID, Query: "q", Mode: "quick", PromptUsed: "default", Provider: "openai", Latency: 2345 * time.Millisecond}
+	require.NoError(t, db.LogSearch(entry))
+	assert.NotZero(t, entry.ID)
+	assert.Equal(t, OutcomeSuccess, entry.Outcome, "success is the default outcome")
+
+	entries, err := db.ListSearches(SearchFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	got := entries[0]
+	assert.Equal(t, session.ID, got.SessionID)
+	assert.Equal(t, "default", got.PromptUsed)
+	assert.Equal(t, "openai", got.Provider)
+	assert.Equal(t, 2345*time.Millisecond, got.Latency)
+	assert.False(t, got.CreatedAt.IsZero())
+}
+
+func TestListSearches_Filters(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+	e := seedSearchLog(t, db)
+
+	entries, err := db.ListSearches(SearchFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Kafka 100%", "go contexts ", "Rust async", "Go contexts"}, searchQueries(entries))
+
+	entries, err = db.ListSearches(SearchFilter{Outcome: OutcomeFailed})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Kafka 100%", "Rust async"}, searchQueries(entries))
+	assert.Equal(t, "no providers available", entries[0].Error)
+	assert.Zero(t, entries[0].SessionID)
+
+	entries, err = db.ListSearches(SearchFilter{Text: "CONTEXT", Mode: "quick"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Go contexts"}, searchQueries(entries))
+
+	entries, err = db.ListSearches(SearchFilter{Prefix: "go"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go contexts ", "Go contexts"}, searchQueries(entries))
+
+	entries, err = db.ListSearches(SearchFilter{Text: "0%"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Kafka 100%"}, searchQueries(entries), "wildcards are matched literally")
+
+	entries, err = db.ListSearches(SearchFilter{Since: e[1].CreatedAt, Until: e[3].CreatedAt, Provider: "github-copilot"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go contexts "}, searchQueries(entries))
+
+	entries, err = db.ListSearches(SearchFilter{Limit: 1})
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestListSearches_Distinct(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+	seedSearchLog(t, db)
+
+	entries, err := db.ListSearches(SearchFilter{Distinct: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Kafka 100%", "go contexts ", "Rust async"}, searchQueries(entries), "only the latest run of a query")
+
+	entries, err = db.ListSearches(SearchFilter{Distinct: true, Mode: "quick"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Kafka 100%", "Go contexts"}, searchQueries(entries), "filters apply before picking the latest run")
+}
+
+func TestGetTopQueries_CountsEveryRun(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	for _, outcome := range []string{OutcomeSuccess, OutcomeFailed, OutcomeCancelled} {
+		require.NoError(t, db.LogSearch(&SearchHistory{Query: "flaky", Outcome: outcome}))
+	}
+	require.NoError(t, db.LogSearch(&SearchHistory{Query: "once"}))
+
+	top, err := db.GetTopQueries(5)
+	require.NoError(t, err)
+	assert.Equal(t, []QueryCount{{Query: "flaky", Count: 3}, {Query: "once", Count: 1}}, top)
+}
+
+func TestDeleteSessionsBefore_PrunesSearchLog(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	require.NoError(t, db.LogSearch(&SearchHistory{Query: "old", CreatedAt: now.AddDate(0, 0, -40)}))
+	require.NoError(t, db.LogSearch(&SearchHistory{Query: "new", CreatedAt: now}))
+
+	_, err := db.DeleteSessionsBefore(now.AddDate(0, 0, -30))
+	require.NoError(t, err)
+
+	entries, err := db.ListSearches(SearchFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"new"}, searchQueries(entries))
+}
+
+func TestMigrate_BackfillsSearchLog(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "old.db")
+	createLegacyDB(t, dbPath)
+
+	db, err := NewSQLiteDB(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	entries, err := db.ListSearches(SearchFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "old", entries[0].Query)
+	assert.Equal(t, int64(1), entries[0].SessionID)
+	assert.Equal(t, OutcomeSuccess, entries[0].Outcome)
+}
@@ -0,0 +1,221 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedSearchSessions saves sessions for the search tests, oldest first
+func seedSearchSessions(t *testing.T, db *SQLiteDB) []*ResearchSession {
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	sessions := []*ResearchSession{
+		{Query: "Go contexts", Mode: "quick", Provider: "github-copilot", Result: "Cancellation propagates through context trees.", CreatedAt: base},
+		{Query: "Rust async runtimes", Mode: "deep", Provider: "openai", Result: "Tokio is the common choice; cancellation is cooperative.", CreatedAt: base.AddDate(0, 0, 1)},
+		{Query: "SQLite full-text search", Mode: "deep", Provider: "github-copilot", Result: "FTS5 ranks matches with bm25 and can highlight snippets.", CreatedAt: base.AddDate(0, 0, 2)},
+		{Query: "Kafka vs NATS", Mode: "compare", Provider: "openai", Result: "NATS is simpler to run.", Status: SessionInProgress, CreatedAt: base.AddDate(0, 0, 3)},
+	}
+	for _, s := range sessions {
+		require.NoError(t, db.SaveSession(s))
+	}
+	return sessions
+}
+
+// matchIDs returns the session IDs of matches, in order
+func matchIDs(matches []*SessionMatch) []int64 {
+	ids := []int64{}
+	for _, m := range matches {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
+
+func TestMatchExpression(t *testing.T) {
+	assert.Equal(t, `"go" "contexts"`, matchExpression("go contexts"))
+	assert.Equal(t, `"c++" "go-sqlite3"`, matchExpression("c++ go-sqlite3"))
+	assert.Equal(t, `"context cancel"`, matchExpression(`"context cancel"`))
+	assert.Equal(t, "tokio OR nats", matchExpression("tokio OR nats"))
+	assert.Equal(t, "cancel*", matchExpression("cancel*"))
+}
+
+func TestFindSessions_Text(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+	s := seedSearchSessions(t, db)
+
+	// Matches the result as well as the query, with porter stemming
+	matches, err := db.FindSessions(SessionFilter{Text: "cancel"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int64{s[0].ID, s[1].ID}, matchIDs(matches))
+
+	matches, err = db.FindSessions(SessionFilter{Text: "highlight snippets"})
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, s[2].ID, matches[0].ID)
+	assert.Contains(t, matches[0].Snippet, HighlightStart+"highlight"+HighlightEnd)
+	assert.Equal(t, "SQLite full-text search", matches[0].Query)
+
+	matches, err = db.FindSessions(SessionFilter{Text: `"cooperative cancellation"`})
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+
+	matches, err = db.FindSessions(SessionFilter{Text: "tokio OR nats"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int64{s[1].ID, s[3].ID}, matchIDs(matches))
+
+	matches, err = db.FindSessions(SessionFilter{Text: "cancellation NOT tokio"})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{s[0].ID}, matchIDs(matches))
+
+	_, err = db.FindSessions(SessionFilter{Text: `"unbalanced`})
+	assert.ErrorContains(t, err, "invalid search syntax")
+}
+
+func TestFindSessions_Ranking(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+	if !db.fts5 {
+		t.Skip("ranking needs FTS5; run with -tags sqlite_fts5")
+	}
+
+	inResult := &ResearchSession{Query: "Message queues", Mode: "quick", Result: "Kafka and NATS both work.", CreatedAt: time.Now()}
+	inQuery := &ResearchSession{Query: "NATS clustering", Mode: "quick", Result: "Use JetStream.", CreatedAt: time.Now().Add(-time.Hour)}
+	require.NoError(t, db.SaveSession(inResult))
+	require.NoError(t, db.SaveSession(inQuery))
+
+	matches, err := db.FindSessions(SessionFilter{Text: "nats"})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{inQuery.ID, inResult.ID}, matchIDs(matches))
+	assert.Less(t, matches[0].Rank, 0.0)
+}
+
+func TestFindSessions_Filters(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+	s := seedSearchSessions(t, db)
+
+	matches, err := db.FindSessions(SessionFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{s[3].ID, s[2].ID, s[1].ID, s[0].ID}, matchIDs(matches))
+	assert.Empty(t, matches[0].Snippet)
+
+	matches, err = db.FindSessions(SessionFilter{Mode: "deep", Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{s[2].ID}, matchIDs(matches))
+
+	matches, err = db.FindSessions(SessionFilter{Provider: "openai", Text: "nats"})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{s[3].ID}, matchIDs(matches))
+
+	// Since is inclusive and Until exclusive, whatever the zone
+	est := time.FixedZone("EST", -5*60*60)
+	matches, err = db.FindSessions(SessionFilter{
+		Since: s[1].CreatedAt.In(est),
+		Until: s[3].CreatedAt,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{s[2].ID, s[1].ID}, matchIDs(matches))
+
+	matches, err = db.FindSessions(SessionFilter{Resumable: true})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{s[3].ID}, matchIDs(matches))
+}
+
+func TestFindSessions_TagsAndStarred(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+	s := seedSearchSessions(t, db)
+
+	_, err := db.UpdateTags(s[0].ID, []string{"go", "concurrency"}, nil)
+	require.NoError(t, err)
+	_, err = db.UpdateTags(s[1].ID, []string{"concurrency"}, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.SetStarred(s[1].ID, true))
+	require.NoError(t, db.SetStarred(s[2].ID, true))
+
+	matches, err := db.FindSessions(SessionFilter{Tags: []string{"Concurrency"}})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{s[1].ID, s[0].ID}, matchIDs(matches))
+	assert.Equal(t, []string{"concurrency"}, matches[0].Tags)
+	assert.Equal(t, []string{"concurrency", "go"}, matches[1].Tags)
+
+	matches, err = db.FindSessions(SessionFilter{Tags: []string{"concurrency", "go"}})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{s[0].ID}, matchIDs(matches), "every tag is required")
+
+	matches, err = db.FindSessions(SessionFilter{Starred: true})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{s[2].ID, s[1].ID}, matchIDs(matches))
+
+	matches, err = db.FindSessions(SessionFilter{Starred: true, Tags: []string{"concurrency"}, Text: "cancellation"})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{s[1].ID}, matchIDs(matches))
+
+	_, err = db.FindSessions(SessionFilter{Tags: []string{"not a tag"}})
+	assert.ErrorContains(t, err, "invalid tag")
+}
+
+func TestFindSessions_IndexFollowsChanges(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+	s := seedSearchSessions(t, db)
+
+	s[3].Result = "JetStream adds persistence."
+	require.NoError(t, db.UpdateSession(s[3]))
+
+	matches, err := db.FindSessions(SessionFilter{Text: "simpler"})
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+
+	matches, err = db.FindSessions(SessionFilter{Text: "jetstream"})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{s[3].ID}, matchIDs(matches))
+
+	_, err = db.db.Exec("DELETE FROM research_sessions WHERE id = ?", s[3].ID)
+	require.NoError(t, err)
+	matches, err = db.FindSessions(SessionFilter{Text: "jetstream"})
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+}
+
+func TestFindSessions_IndexesExistingSessions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	createLegacyDB(t, path)
+
+	database, err := NewSQLiteDB(path)
+	require.NoError(t, err)
+	defer database.Close()
+
+	matches, err := database.FindSessions(SessionFilter{Text: "old"})
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "old", matches[0].Query)
+}
+
+func TestCheckSearchIndex_FTS5(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "research.db")
+	raw, err := OpenSQLite(path)
+	require.NoError(t, err)
+	defer raw.Close()
+
+	var enabled bool
+	require.NoError(t, raw.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled))
+
+	// Write the schema entry of an FTS5 index directly, as a build with
+	// FTS5 would have created it
+	_, err = raw.Exec(`PRAGMA writable_schema = ON;
+		INSERT INTO sqlite_master (type, name, tbl_name, rootpage, sql)
+		VALUES ('table', 'sessions_fts', 'sessions_fts', 0, 'CREATE VIRTUAL TABLE sessions_fts USING fts5(query, refined_query, result)');
+		PRAGMA writable_schema = OFF;`)
+	require.NoError(t, err)
+
+	err = checkSearchIndex(raw)
+	if enabled {
+		assert.NoError(t, err)
+	} else {
+		assert.ErrorIs(t, err, ErrFTS5Unavailable)
+	}
+}
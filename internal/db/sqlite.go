@@ -2,270 +2,1330 @@ package db
 
 import (
 	"database/sql"
-	_ "embed"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
+// ErrFTS5Unavailable is returned when opening a database whose search index
+// uses FTS5 with a SQLite build that lacks it, which could not write to it
+var ErrFTS5Unavailable = errors.New("research database has an FTS5 search index, but this build of copilot-research lacks FTS5; rebuild it with -tags sqlite_fts5")
+
 // Compile-time check that SQLiteDB implements the DB interface
 var _ DB = (*SQLiteDB)(nil)
 
-//go:embed schema.sql
-var schemaSQL string
-
 // SQLiteDB implements database operations for SQLite
 type SQLiteDB struct {
-	db *sql.DB
-	mu sync.RWMutex
+	db     *sql.DB
+	mu     sync.RWMutex
+	fts5   bool         // Whether the search index uses FTS5 rather than the FTS4 fallback
+	cipher *fieldCipher // Encrypts queries and answers at rest; nil if the database is not
+}
+
+// sqliteEngine is a database/sql SQLite driver the SQLite backend can run on
+type sqliteEngine struct {
+	driver string                   // database/sql driver name
+	dsn    func(path string) string // Data source name enabling WAL and a busy timeout
+}
+
+// sqliteEngines holds the compiled-in engines by storage driver name
+var sqliteEngines = make(map[string]sqliteEngine)
+
+// registerSQLite registers the SQLite backend running on engine as a
+// storage driver
+func registerSQLite(name string, engine sqliteEngine) {
+	sqliteEngines[name] = engine
+	Register(name, func(path string, opts Options) (DB, error) {
+		return newSQLiteDB(engine, path, opts)
+	})
 }
 
-// NewSQLiteDB creates a new SQLite database connection
+// defaultSQLiteEngine returns the engine of the default storage driver, or
+// else of any compiled-in SQLite driver
+func defaultSQLiteEngine() (sqliteEngine, error) {
+	if engine, ok := sqliteEngines[DefaultDriver()]; ok {
+		return engine, nil
+	}
+	for _, name := range []string{DriverSQLite, DriverSQLitePureGo} {
+		if engine, ok := sqliteEngines[name]; ok {
+			return engine, nil
+		}
+	}
+	return sqliteEngine{}, fmt.Errorf("no SQLite driver is compiled in: build with cgo enabled, or with -tags purego")
+}
+
+// NewSQLiteDB creates a new SQLite database connection with the default
+// SQLite driver, migrating the schema to the latest version
 func NewSQLiteDB(path string) (DB, error) {
-	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_timeout=5000")
+	engine, err := defaultSQLiteEngine()
+	if err != nil {
+		return nil, err
+	}
+	return newSQLiteDB(engine, path, Options{})
+}
+
+// newSQLiteDB opens and migrates the database at path on engine, unlocking
+// or encrypting it with opts.Key
+func newSQLiteDB(engine sqliteEngine, path string, opts Options) (DB, error) {
+	db, err := engine.open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkSearchIndex(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, err := migrate(db, path); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	var index string
+	if err := db.QueryRow("SELECT sql FROM sqlite_master WHERE name = 'sessions_fts'").Scan(&index); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to inspect search index: %w", err)
+	}
+
+	s := &SQLiteDB{db: db, fts5: strings.Contains(strings.ToLower(index), "fts5")}
+
+	params, err := readKeyParams(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if params == nil && opts.Key != nil {
+		// Encrypt the sessions stored so far, and scrub their plaintext
+		if s.cipher, err = s.rekey(opts.Key); err == nil {
+			err = s.Vacuum()
+		}
+	} else {
+		s.cipher, err = unlock(opts.Key, params)
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// checkSearchIndex fails with ErrFTS5Unavailable if the search index uses
+// FTS5 and the SQLite library does not have it. Without the check, every
+// write to the sessions fails with "no such module: fts5".
+func checkSearchIndex(db *sql.DB) error {
+	var index string
+	err := db.QueryRow("SELECT sql FROM sqlite_master WHERE name = 'sessions_fts'").Scan(&index)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to inspect search index: %w", err)
+	}
+	if !strings.Contains(strings.ToLower(index), "fts5") {
+		return nil
+	}
+
+	var enabled bool
+	if err := db.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled); err != nil {
+		return fmt.Errorf("failed to inspect SQLite build: %w", err)
+	}
+	if !enabled {
+		return ErrFTS5Unavailable
+	}
+	return nil
+}
+
+// OpenSQLite opens a connection pool with the default SQLite driver without
+// touching the schema, for other databases such as the context index
+func OpenSQLite(path string) (*sql.DB, error) {
+	engine, err := defaultSQLiteEngine()
+	if err != nil {
+		return nil, err
+	}
+	return engine.open(path)
+}
+
+// open opens a connection pool without touching the schema
+func (e sqliteEngine) open(path string) (*sql.DB, error) {
+	db, err := sql.Open(e.driver, e.dsn(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// Set connection pool settings
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(5 * time.Minute)
+
+	return db, nil
+}
+
+// sessionColumns lists the research_sessions columns read by scanSession
+const sessionColumns = "id, query, refined_query, mode, prompt_used, result, quality_score, critique, citations, matrix, status, checkpoint, provider, model, prompt_tokens, completion_tokens, total_tokens, parent_id, user_rating, starred, notes, created_at"
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanSession reads a research session selected with sessionColumns, and
+// any extra columns selected after them into extra, decrypting it
+func (s *SQLiteDB) scanSession(row rowScanner, extra ...interface{}) (*ResearchSession, error) {
+	session := &ResearchSession{}
+	var refinedQuery, critique, citations, matrix, status, checkpoint, providerName, model, notes sql.NullString
+	var parentID sql.NullInt64
+	err := row.Scan(append([]interface{}{
+		&session.ID,
+		&session.Query,
+		&refinedQuery,
+		&session.Mode,
+		&session.PromptUsed,
+		&session.Result,
+		&session.QualityScore,
+		&critique,
+		&citations,
+		&matrix,
+		&status,
+		&checkpoint,
+		&providerName,
+		&model,
+		&session.Tokens.Prompt,
+		&session.Tokens.Completion,
+		&session.Tokens.Total,
+		&parentID,
+		&session.UserRating,
+		&session.Starred,
+		&notes,
+		&session.CreatedAt,
+	}, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.cipher.openAll(&session.Query, &refinedQuery.String, &session.Result, &critique.String,
+		&citations.String, &matrix.String, &checkpoint.String, &notes.String); err != nil {
+		return nil, err
+	}
+
+	session.RefinedQuery = refinedQuery.String
+	session.Critique = critique.String
+	session.Provider = providerName.String
+	session.Model = model.String
+	session.ParentID = parentID.Int64
+	session.Notes = notes.String
+
+	if citations.Valid && citations.String != "" {
+		if err := json.Unmarshal([]byte(citations.String), &session.Citations); err != nil {
+			return nil, fmt.Errorf("failed to decode citations: %w", err)
+		}
+	}
+
+	if matrix.Valid && matrix.String != "" {
+		session.Matrix = &ComparisonMatrix{}
+		if err := json.Unmarshal([]byte(matrix.String), session.Matrix); err != nil {
+			return nil, fmt.Errorf("failed to decode comparison matrix: %w", err)
+		}
+	}
+
+	session.Status = status.String
+	if session.Status == "" {
+		session.Status = SessionComplete
+	}
+
+	if checkpoint.Valid && checkpoint.String != "" {
+		session.Checkpoint = &Checkpoint{}
+		if err := json.Unmarshal([]byte(checkpoint.String), session.Checkpoint); err != nil {
+			return nil, fmt.Errorf("failed to decode checkpoint: %w", err)
+		}
+	}
+
+	return session, nil
+}
+
+// nullIfEmpty stores empty strings as NULL
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// nullIfZero stores zero IDs as NULL
+func nullIfZero(id int64) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// encodeCitations serializes citations for storage, storing NULL when empty
+func encodeCitations(citations []Citation) (interface{}, error) {
+	if len(citations) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(citations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode citations: %w", err)
+	}
+	return string(data), nil
+}
+
+// encodeJSON serializes a value for storage in a JSON column
+func encodeJSON(v interface{}, what string) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", what, err)
+	}
+	return string(data), nil
+}
+
+// sessionValues returns the stored values of a session's mutable columns,
+// encrypted, in the order used by SaveSession and UpdateSession
+func (s *SQLiteDB) sessionValues(session *ResearchSession) ([]interface{}, error) {
+	citations, err := encodeCitations(session.Citations)
+	if err != nil {
+		return nil, err
+	}
+	if citations != nil {
+		citations = s.cipher.seal(citations.(string))
+	}
+
+	var matrix, checkpoint interface{}
+	if session.Matrix != nil {
+		if matrix, err = encodeJSON(session.Matrix, "comparison matrix"); err != nil {
+			return nil, err
+		}
+		matrix = s.cipher.seal(matrix.(string))
+	}
+	if session.Checkpoint != nil {
+		if checkpoint, err = encodeJSON(session.Checkpoint, "checkpoint"); err != nil {
+			return nil, err
+		}
+		checkpoint = s.cipher.seal(checkpoint.(string))
+	}
+
+	status := session.Status
+	if status == "" {
+		status = SessionComplete
+	}
+
+	return []interface{}{
+		s.cipher.seal(session.Query),
+		nullIfEmpty(s.cipher.seal(session.RefinedQuery)),
+		session.Mode,
+		session.PromptUsed,
+		s.cipher.seal(session.Result),
+		session.QualityScore,
+		nullIfEmpty(s.cipher.seal(session.Critique)),
+		citations,
+		matrix,
+		status,
+		checkpoint,
+		nullIfEmpty(session.Provider),
+		nullIfEmpty(session.Model),
+		session.Tokens.Prompt,
+		session.Tokens.Completion,
+		session.Tokens.Total,
+		nullIfZero(session.ParentID),
+	}, nil
+}
+
+// insertSessionSQL inserts a session from sessionValues and its creation time
+const insertSessionSQL = `
+	INSERT INTO research_sessions (query, refined_query, mode, prompt_used, result, quality_score, critique, citations, matrix, status, checkpoint,
+		provider, model, prompt_tokens, completion_tokens, total_tokens, parent_id, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+// SaveSession saves a research session to the database
+func (s *SQLiteDB) SaveSession(session *ResearchSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.sessionValues(session)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.Exec(insertSessionSQL, append(values, session.CreatedAt)...)
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get insert ID: %w", err)
+	}
+
+	session.ID = id
+	if session.Status == "" {
+		session.Status = SessionComplete
+	}
+	return nil
+}
+
+// UpdateSession overwrites a saved session, e.g. to checkpoint or finish it
+func (s *SQLiteDB) UpdateSession(session *ResearchSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := `
+		UPDATE research_sessions
+		SET query = ?, refined_query = ?, mode = ?, prompt_used = ?, result = ?, quality_score = ?,
+			critique = ?, citations = ?, matrix = ?, status = ?, checkpoint = ?,
+			provider = ?, model = ?, prompt_tokens = ?, completion_tokens = ?, total_tokens = ?, parent_id = ?
+		WHERE id = ?
+	`
+
+	values, err := s.sessionValues(session)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.Exec(query, append(values, session.ID)...)
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("session not found: %d", session.ID)
+	}
+
+	return nil
+}
+
+// GetSession retrieves a session by ID
+func (s *SQLiteDB) GetSession(id int64) (*ResearchSession, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM research_sessions
+		WHERE id = ?
+	`
+
+	session, err := s.scanSession(s.db.QueryRow(query, id))
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("session not found: %d", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	if err := s.loadTags(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// loadTags fills in the tags of sessions
+func (s *SQLiteDB) loadTags(sessions ...*ResearchSession) error {
+	// Stay well below SQLite's limit on bound parameters
+	const batch = 500
+	for len(sessions) > batch {
+		if err := s.loadTags(sessions[:batch]...); err != nil {
+			return err
+		}
+		sessions = sessions[batch:]
+	}
+	if len(sessions) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*ResearchSession, len(sessions))
+	args := make([]interface{}, len(sessions))
+	for i, session := range sessions {
+		byID[session.ID] = session
+		args[i] = session.ID
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(sessions)), ", ")
+	rows, err := s.db.Query("SELECT session_id, tag FROM session_tags WHERE session_id IN ("+placeholders+") ORDER BY tag", args...)
+	if err != nil {
+		return fmt.Errorf("failed to get tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return fmt.Errorf("failed to scan tag: %w", err)
+		}
+		byID[id].Tags = append(byID[id].Tags, tag)
+	}
+	return rows.Err()
+}
+
+// ListSessions retrieves sessions with pagination
+func (s *SQLiteDB) ListSessions(limit, offset int) ([]*ResearchSession, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM research_sessions
+		ORDER BY created_at DESC
+		LIMIT ? OFFSET ?
+	`
+
+	rows, err := s.db.Query(query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	var sessions []*ResearchSession
+	for rows.Next() {
+		session, err := s.scanSession(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	rows.Close()
+
+	if err := s.loadTags(sessions...); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// SearchSessions finds sessions whose query or refined query matches a
+// query string
+func (s *SQLiteDB) SearchSessions(query string) ([]*ResearchSession, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.cipher != nil {
+		return s.searchSealedSessions(query)
+	}
+
+	sql := `
+		SELECT ` + sessionColumns + `
+		FROM research_sessions
+		WHERE query LIKE ? OR refined_query LIKE ?
+		ORDER BY created_at DESC
+	`
+
+	pattern := "%" + query + "%"
+	rows, err := s.db.Query(sql, pattern, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*ResearchSession
+	for rows.Next() {
+		session, err := s.scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+// GetLatestSession returns the most recent complete session for a query and
+// mode, or nil if there is none
+func (s *SQLiteDB) GetLatestSession(query, mode string) (*ResearchSession, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.cipher != nil {
+		return s.latestSealedSession(query, mode)
+	}
+
+	sqlQuery := `
+		SELECT ` + sessionColumns + `
+		FROM research_sessions
+		WHERE query = ? AND mode = ? AND status = 'complete'
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
+	`
+
+	session, err := s.scanSession(s.db.QueryRow(sqlQuery, query, mode))
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest session: %w", err)
+	}
+
+	return session, nil
+}
+
+// UpdateTags adds and removes tags on a session and returns its tags
+// afterwards
+func (s *SQLiteDB) UpdateTags(id int64, add, remove []string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := sessionExists(tx, id); err != nil {
+		return nil, err
+	}
+	if err := addTags(tx, id, add); err != nil {
+		return nil, err
+	}
+	for _, tag := range remove {
+		tag, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec("DELETE FROM session_tags WHERE session_id = ? AND tag = ?", id, tag); err != nil {
+			return nil, fmt.Errorf("failed to remove tag: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	session := &ResearchSession{ID: id}
+	if err := s.loadTags(session); err != nil {
+		return nil, err
+	}
+	return session.Tags, nil
+}
+
+// addTags attaches tags to a session, ignoring ones it already has
+func addTags(tx *sql.Tx, id int64, tags []string) error {
+	for _, tag := range tags {
+		tag, err := NormalizeTag(tag)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("INSERT OR IGNORE INTO session_tags (session_id, tag) VALUES (?, ?)", id, tag); err != nil {
+			return fmt.Errorf("failed to add tag: %w", err)
+		}
+	}
+	return nil
+}
+
+// sessionExists returns an error if there is no session with the ID
+func sessionExists(tx *sql.Tx, id int64) error {
+	var found int
+	err := tx.QueryRow("SELECT 1 FROM research_sessions WHERE id = ?", id).Scan(&found)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("session not found: %d", id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+	return nil
+}
+
+// SetStarred stars or unstars a session
+func (s *SQLiteDB) SetStarred(id int64, starred bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.updateSessionField(id, "starred", starred)
+}
+
+// SetNotes replaces the user's notes on a session; empty notes remove them
+func (s *SQLiteDB) SetNotes(id int64, notes string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.updateSessionField(id, "notes", nullIfEmpty(s.cipher.seal(strings.TrimSpace(notes))))
+}
+
+// updateSessionField sets one column of a session
+func (s *SQLiteDB) updateSessionField(id int64, column string, value interface{}) error {
+	result, err := s.db.Exec("UPDATE research_sessions SET "+column+" = ? WHERE id = ?", value, id)
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("session not found: %d", id)
+	}
+	return nil
+}
+
+// ImportSessions adds sessions from another database, e.g. an exported
+// archive, in one transaction. Sessions already present by ContentHash are
+// skipped, so importing the same archive again changes nothing. Imported
+// sessions get new IDs and keep their creation times; parent references
+// are carried over when the parent is part of the same import.
+func (s *SQLiteDB) ImportSessions(sessions []*ResearchSession) (*ImportResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	existing, err := sessionHashes(tx, s.cipher)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{}
+	newIDs := make(map[int64]int64) // Archive ID to ID in this database
+	var imported []*ResearchSession
+	for _, session := range sessions {
+		hash := session.ContentHash()
+		if id, ok := existing[hash]; ok {
+			newIDs[session.ID] = id
+			result.Skipped++
+			continue
+		}
+
+		stored := *session
+		stored.ParentID = 0
+		if stored.CreatedAt.IsZero() {
+			stored.CreatedAt = time.Now()
+		}
+		values, err := s.sessionValues(&stored)
+		if err != nil {
+			return nil, err
+		}
+		res, err := tx.Exec(insertSessionSQL, append(values, stored.CreatedAt)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import session: %w", err)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get insert ID: %w", err)
+		}
+
+		if session.Starred || session.Notes != "" {
+			if _, err := tx.Exec("UPDATE research_sessions SET starred = ?, notes = ? WHERE id = ?", session.Starred, nullIfEmpty(s.cipher.seal(session.Notes)), id); err != nil {
+				return nil, fmt.Errorf("failed to import session: %w", err)
+			}
+		}
+		if err := addTags(tx, id, session.Tags); err != nil {
+			return nil, err
+		}
+		if session.UserRating != nil {
+			if err := rateSession(tx, id, *session.UserRating); err != nil {
+				return nil, err
+			}
+		}
+
+		existing[hash] = id
+		newIDs[session.ID] = id
+		imported = append(imported, session)
+		result.Imported++
+	}
+
+	for _, session := range imported {
+		parentID, ok := newIDs[session.ParentID]
+		if session.ParentID == 0 || !ok {
+			continue
+		}
+		if _, err := tx.Exec("UPDATE research_sessions SET parent_id = ? WHERE id = ?", parentID, newIDs[session.ID]); err != nil {
+			return nil, fmt.Errorf("failed to link imported session: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// sessionHashes maps the ContentHash of every stored session to its ID
+func sessionHashes(tx *sql.Tx, c *fieldCipher) (map[string]int64, error) {
+	rows, err := tx.Query("SELECT id, query, mode, result, created_at FROM research_sessions")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sessions: %w", err)
+	}
+	defer rows.Close()
+
+	hashes := make(map[string]int64)
+	for rows.Next() {
+		var session ResearchSession
+		if err := rows.Scan(&session.ID, &session.Query, &session.Mode, &session.Result, &session.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		if err := c.openAll(&session.Query, &session.Result); err != nil {
+			return nil, err
+		}
+		hashes[session.ContentHash()] = session.ID
+	}
+	return hashes, rows.Err()
+}
+
+// DeleteSession removes a session
+func (s *SQLiteDB) DeleteSession(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.deleteSessions("id = ?", id)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("session not found: %d", id)
+	}
+	return nil
+}
+
+// DeleteSessionsBefore removes the unstarred sessions created before a time
+// and returns how many were removed
+func (s *SQLiteDB) DeleteSessionsBefore(before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// created_at is stored with a zone offset, which julianday normalizes
+	cutoff := before.UTC().Format("2006-01-02 15:04:05")
+	if _, err := s.db.Exec("DELETE FROM search_history WHERE julianday(created_at) < julianday(?)", cutoff); err != nil {
+		return 0, fmt.Errorf("failed to prune search history: %w", err)
+	}
+	return s.deleteSessions("julianday(created_at) < julianday(?) AND starred = 0", cutoff)
+}
+
+// KeepLatestSessions removes the unstarred sessions that are not among the n
+// most recent and returns how many were removed
+func (s *SQLiteDB) KeepLatestSessions(n int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.deleteSessions("id NOT IN (SELECT id FROM research_sessions ORDER BY created_at DESC, id DESC LIMIT ?) AND starred = 0", n)
+}
+
+// ClearAll removes every session and the search log, and returns how many
+// sessions were removed
+func (s *SQLiteDB) ClearAll() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.db.Exec("DELETE FROM search_history"); err != nil {
+		return 0, fmt.Errorf("failed to clear search history: %w", err)
+	}
+	return s.deleteSessions("1 = 1")
+}
+
+// deleteSessions removes the sessions matching a condition in one
+// transaction. Foreign keys are not enforced on the connection, so
+// references to the sessions are cleared here; the search log keeps the
+// runs that stored them.
+func (s *SQLiteDB) deleteSessions(where string, args ...interface{}) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	matching := "(SELECT id FROM research_sessions WHERE " + where + ")"
+	for _, stmt := range []string{
+		"UPDATE search_history SET session_id = NULL WHERE session_id IN " + matching,
+		"DELETE FROM session_tags WHERE session_id IN " + matching,
+		"UPDATE watched_queries SET last_session_id = NULL WHERE last_session_id IN " + matching,
+		"UPDATE research_sessions SET parent_id = NULL WHERE parent_id IN " + matching,
+	} {
+		if _, err := tx.Exec(stmt, args...); err != nil {
+			return 0, fmt.Errorf("failed to delete sessions: %w", err)
+		}
+	}
+
+	result, err := tx.Exec("DELETE FROM research_sessions WHERE "+where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return rows, nil
+}
+
+// SavePattern saves a learned pattern to the database
+func (s *SQLiteDB) SavePattern(pattern *LearnedPattern) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := `
+		INSERT INTO learned_patterns (pattern_name, description, success_count, last_used, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(pattern_name) DO UPDATE SET
+			description = excluded.description,
+			success_count = excluded.success_count,
+			last_used = excluded.last_used
+	`
+
+	result, err := s.db.Exec(
+		query,
+		pattern.PatternName,
+		pattern.Description,
+		pattern.SuccessCount,
+		pattern.LastUsed,
+		pattern.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save pattern: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get insert ID: %w", err)
+	}
+
+	pattern.ID = id
+	return nil
+}
+
+// GetPattern retrieves a pattern by name
+func (s *SQLiteDB) GetPattern(name string) (*LearnedPattern, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `
+		SELECT ` + patternColumns + `
+		FROM learned_patterns
+		WHERE pattern_name = ?
+	`
+
+	pattern, err := scanPattern(s.db.QueryRow(query, name))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("pattern not found: %s", name)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, fmt.Errorf("failed to get pattern: %w", err)
 	}
 
-	// Set connection pool settings
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	return pattern, nil
+}
 
-	// Initialize schema
-	if _, err := db.Exec(schemaSQL); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to initialize schema: %w", err)
-	}
+// patternColumns lists the learned_patterns columns read by scanPattern
+const patternColumns = "id, pattern_name, description, success_count, last_used, created_at, prompt_used, mode, provider, rating_count, rating_total"
 
-	return &SQLiteDB{db: db}, nil
+// scanPattern reads a pattern selected with patternColumns
+func scanPattern(row rowScanner) (*LearnedPattern, error) {
+	pattern := &LearnedPattern{}
+	var promptUsed, mode, providerName sql.NullString
+	err := row.Scan(
+		&pattern.ID,
+		&pattern.PatternName,
+		&pattern.Description,
+		&pattern.SuccessCount,
+		&pattern.LastUsed,
+		&pattern.CreatedAt,
+		&promptUsed,
+		&mode,
+		&providerName,
+		&pattern.RatingCount,
+		&pattern.RatingTotal,
+	)
+	if err != nil {
+		return nil, err
+	}
+	pattern.PromptUsed = promptUsed.String
+	pattern.Mode = mode.String
+	pattern.Provider = providerName.String
+	return pattern, nil
 }
 
-// SaveSession saves a research session to the database
-func (s *SQLiteDB) SaveSession(session *ResearchSession) error {
+// IncrementPattern increments the success count for a pattern
+func (s *SQLiteDB) IncrementPattern(name string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	query := `
-		INSERT INTO research_sessions (query, mode, prompt_used, result, quality_score, created_at)
-		VALUES (?, ?, ?, ?, ?, ?)
+		UPDATE learned_patterns
+		SET success_count = success_count + 1,
+		    last_used = ?
+		WHERE pattern_name = ?
 	`
 
-	result, err := s.db.Exec(
-		query,
-		session.Query,
-		session.Mode,
-		session.PromptUsed,
-		session.Result,
-		session.QualityScore,
-		session.CreatedAt,
-	)
+	result, err := s.db.Exec(query, time.Now(), name)
 	if err != nil {
-		return fmt.Errorf("failed to save session: %w", err)
+		return fmt.Errorf("failed to increment pattern: %w", err)
 	}
 
-	id, err := result.LastInsertId()
+	rows, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to get insert ID: %w", err)
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("pattern not found: %s", name)
 	}
 
-	session.ID = id
 	return nil
 }
 
-// GetSession retrieves a session by ID
-func (s *SQLiteDB) GetSession(id int64) (*ResearchSession, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// RateSession records the user's rating of a session, from 1 to 5, and
+// returns the updated aggregate for the session's prompt, mode and provider
+func (s *SQLiteDB) RateSession(id int64, rating int) (*LearnedPattern, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	query := `
-		SELECT id, query, mode, prompt_used, result, quality_score, created_at
-		FROM research_sessions
-		WHERE id = ?
-	`
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	session := &ResearchSession{}
-	err := s.db.QueryRow(query, id).Scan(
-		&session.ID,
-		&session.Query,
-		&session.Mode,
-		&session.PromptUsed,
-		&session.Result,
-		&session.QualityScore,
-		&session.CreatedAt,
-	)
+	if err := rateSession(tx, id, rating); err != nil {
+		return nil, err
+	}
+
+	pattern, err := scanPattern(tx.QueryRow(`
+		SELECT `+patternColumns+`
+		FROM learned_patterns
+		WHERE pattern_name = (
+			SELECT 'rating:' || mode || ':' || prompt_used || ':' || COALESCE(provider, '')
+			FROM research_sessions WHERE id = ?
+		)`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rating pattern: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return pattern, nil
+}
+
+// rateSession sets a session's rating and adds it to the rating pattern of
+// the session's prompt, mode and provider, replacing the session's earlier
+// rating. Patterns keep counting the ratings of sessions deleted later.
+func rateSession(tx *sql.Tx, id int64, rating int) error {
+	if rating < MinRating || rating > MaxRating {
+		return fmt.Errorf("invalid rating %d: use %d to %d", rating, MinRating, MaxRating)
+	}
 
+	var prompt, mode string
+	var providerName sql.NullString
+	var previous sql.NullInt64
+	err := tx.QueryRow("SELECT prompt_used, mode, provider, user_rating FROM research_sessions WHERE id = ?", id).Scan(&prompt, &mode, &providerName, &previous)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("session not found: %d", id)
+		return fmt.Errorf("session not found: %d", id)
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get session: %w", err)
+		return fmt.Errorf("failed to get session: %w", err)
 	}
 
-	return session, nil
+	if _, err := tx.Exec("UPDATE research_sessions SET user_rating = ? WHERE id = ?", rating, id); err != nil {
+		return fmt.Errorf("failed to rate session: %w", err)
+	}
+
+	// Changes to the pattern's counts
+	count, total, successes := 1, rating, 0
+	if rating >= 4 {
+		successes++
+	}
+	if previous.Valid {
+		count--
+		total -= int(previous.Int64)
+		if previous.Int64 >= 4 {
+			successes--
+		}
+	}
+
+	query := `
+		INSERT INTO learned_patterns (pattern_name, description, success_count, last_used, created_at,
+			prompt_used, mode, provider, rating_count, rating_total)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(pattern_name) DO UPDATE SET
+			success_count = success_count + excluded.success_count,
+			last_used = excluded.last_used,
+			rating_count = rating_count + excluded.rating_count,
+			rating_total = rating_total + excluded.rating_total
+	`
+	now := time.Now()
+	description := fmt.Sprintf("User ratings of %s research with the %s prompt", mode, prompt)
+	if providerName.String != "" {
+		description += " via " + providerName.String
+	}
+	_, err = tx.Exec(query,
+		ratingPatternName(prompt, mode, providerName.String), description, successes, now, now,
+		prompt, mode, providerName.String, count, total,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update rating pattern: %w", err)
+	}
+	return nil
 }
 
-// ListSessions retrieves sessions with pagination
-func (s *SQLiteDB) ListSessions(limit, offset int) ([]*ResearchSession, error) {
+// GetRatingPatterns returns the patterns aggregating user ratings, by mode
+// and best rated first
+func (s *SQLiteDB) GetRatingPatterns() ([]*LearnedPattern, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	query := `
-		SELECT id, query, mode, prompt_used, result, quality_score, created_at
-		FROM research_sessions
-		ORDER BY created_at DESC
-		LIMIT ? OFFSET ?
+		SELECT ` + patternColumns + `
+		FROM learned_patterns
+		WHERE rating_count > 0
+		ORDER BY mode, CAST(rating_total AS REAL) / rating_count DESC, rating_count DESC, pattern_name
 	`
 
-	rows, err := s.db.Query(query, limit, offset)
+	rows, err := s.db.Query(query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list sessions: %w", err)
+		return nil, fmt.Errorf("failed to get rating patterns: %w", err)
 	}
 	defer rows.Close()
 
-	var sessions []*ResearchSession
+	var patterns []*LearnedPattern
 	for rows.Next() {
-		session := &ResearchSession{}
-		err := rows.Scan(
-			&session.ID,
-			&session.Query,
-			&session.Mode,
-			&session.PromptUsed,
-			&session.Result,
-			&session.QualityScore,
-			&session.CreatedAt,
-		)
+		pattern, err := scanPattern(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan session: %w", err)
+			return nil, fmt.Errorf("failed to scan pattern: %w", err)
 		}
-		sessions = append(sessions, session)
+		patterns = append(patterns, pattern)
 	}
 
-	return sessions, nil
+	return patterns, nil
 }
 
-// SearchSessions finds sessions matching a query string
-func (s *SQLiteDB) SearchSessions(query string) ([]*ResearchSession, error) {
+// BestPrompt returns the prompt with the highest mean user rating for a
+// mode, across providers, among prompts rated at least minRatings times. It
+// returns nil if no prompt qualifies.
+func (s *SQLiteDB) BestPrompt(mode string, minRatings int) (*PromptRating, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	sql := `
-		SELECT id, query, mode, prompt_used, result, quality_score, created_at
-		FROM research_sessions
-		WHERE query LIKE ?
-		ORDER BY created_at DESC
+	query := `
+		SELECT prompt_used, mode, SUM(rating_count) as count, CAST(SUM(rating_total) AS REAL) / SUM(rating_count) as average
+		FROM learned_patterns
+		WHERE mode = ? AND rating_count > 0
+		GROUP BY prompt_used
+		HAVING count >= ?
+		ORDER BY average DESC, count DESC, prompt_used
+		LIMIT 1
 	`
 
-	rows, err := s.db.Query(sql, "%"+query+"%")
-	if err != nil {
-		return nil, fmt.Errorf("failed to search sessions: %w", err)
+	best := &PromptRating{}
+	err := s.db.QueryRow(query, mode, minRatings).Scan(&best.PromptUsed, &best.Mode, &best.Count, &best.Average)
+	if err == sql.ErrNoRows {
+		return nil, nil
 	}
-	defer rows.Close()
-
-	var sessions []*ResearchSession
-	for rows.Next() {
-		session := &ResearchSession{}
-		err := rows.Scan(
-			&session.ID,
-			&session.Query,
-			&session.Mode,
-			&session.PromptUsed,
-			&session.Result,
-			&session.QualityScore,
-			&session.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan session: %w", err)
-		}
-		sessions = append(sessions, session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get best prompt: %w", err)
 	}
-
-	return sessions, nil
+	return best, nil
 }
 
-// SavePattern saves a learned pattern to the database
-func (s *SQLiteDB) SavePattern(pattern *LearnedPattern) error {
+// SaveWatch adds a watched query, or updates the interval of an existing
+// watch for the same query, mode and prompt
+func (s *SQLiteDB) SaveWatch(watch *WatchedQuery) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	query := `
-		INSERT INTO learned_patterns (pattern_name, description, success_count, last_used, created_at)
+		INSERT INTO watched_queries (query, mode, prompt_used, interval_seconds, created_at)
 		VALUES (?, ?, ?, ?, ?)
-		ON CONFLICT(pattern_name) DO UPDATE SET
-			description = excluded.description,
-			success_count = excluded.success_count,
-			last_used = excluded.last_used
+		ON CONFLICT(query, mode, prompt_used) DO UPDATE SET
+			interval_seconds = excluded.interval_seconds
 	`
 
-	result, err := s.db.Exec(
+	if watch.CreatedAt.IsZero() {
+		watch.CreatedAt = time.Now()
+	}
+
+	// Sealed queries never match each other, so an encrypted database finds
+	// the existing watch by decrypting them instead of relying on the upsert
+	if s.cipher != nil {
+		return s.saveSealedWatch(watch)
+	}
+
+	_, err := s.db.Exec(
 		query,
-		pattern.PatternName,
-		pattern.Description,
-		pattern.SuccessCount,
-		pattern.LastUsed,
-		pattern.CreatedAt,
+		watch.Query,
+		watch.Mode,
+		watch.PromptUsed,
+		int64(watch.Interval/time.Second),
+		watch.CreatedAt,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to save pattern: %w", err)
+		return fmt.Errorf("failed to save watch: %w", err)
 	}
 
-	id, err := result.LastInsertId()
+	// LastInsertId is not reliable for upserts, so look the row up
+	err = s.db.QueryRow(
+		"SELECT id FROM watched_queries WHERE query = ? AND mode = ? AND prompt_used = ?",
+		watch.Query, watch.Mode, watch.PromptUsed,
+	).Scan(&watch.ID)
 	if err != nil {
-		return fmt.Errorf("failed to get insert ID: %w", err)
+		return fmt.Errorf("failed to get watch ID: %w", err)
 	}
 
-	pattern.ID = id
 	return nil
 }
 
-// GetPattern retrieves a pattern by name
-func (s *SQLiteDB) GetPattern(name string) (*LearnedPattern, error) {
+// ListWatches returns all watched queries, oldest first
+func (s *SQLiteDB) ListWatches() ([]*WatchedQuery, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	query := `
-		SELECT id, pattern_name, description, success_count, last_used, created_at
-		FROM learned_patterns
-		WHERE pattern_name = ?
+		SELECT id, query, mode, prompt_used, interval_seconds, last_run_at, last_session_id, created_at
+		FROM watched_queries
+		ORDER BY id
 	`
 
-	pattern := &LearnedPattern{}
-	err := s.db.QueryRow(query, name).Scan(
-		&pattern.ID,
-		&pattern.PatternName,
-		&pattern.Description,
-		&pattern.SuccessCount,
-		&pattern.LastUsed,
-		&pattern.CreatedAt,
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list watches: %w", err)
+	}
+	defer rows.Close()
+
+	var watches []*WatchedQuery
+	for rows.Next() {
+		watch := &WatchedQuery{}
+		var intervalSeconds int64
+		var lastRun sql.NullTime
+		var lastSession sql.NullInt64
+		err := rows.Scan(
+			&watch.ID,
+			&watch.Query,
+			&watch.Mode,
+			&watch.PromptUsed,
+			&intervalSeconds,
+			&lastRun,
+			&lastSession,
+			&watch.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan watch: %w", err)
+		}
+		if watch.Query, err = s.cipher.open(watch.Query); err != nil {
+			return nil, fmt.Errorf("failed to decrypt watch %d: %w", watch.ID, err)
+		}
+		watch.Interval = time.Duration(intervalSeconds) * time.Second
+		if lastRun.Valid {
+			watch.LastRunAt = &lastRun.Time
+		}
+		if lastSession.Valid {
+			watch.LastSessionID = &lastSession.Int64
+		}
+		watches = append(watches, watch)
+	}
+
+	return watches, rows.Err()
+}
+
+// saveSealedWatch is SaveWatch for an encrypted database
+func (s *SQLiteDB) saveSealedWatch(watch *WatchedQuery) error {
+	rows, err := s.db.Query(
+		"SELECT id, query FROM watched_queries WHERE mode = ? AND prompt_used = ?",
+		watch.Mode, watch.PromptUsed,
 	)
+	if err != nil {
+		return fmt.Errorf("failed to save watch: %w", err)
+	}
+	var existing int64
+	for rows.Next() {
+		var id int64
+		var query string
+		if err := rows.Scan(&id, &query); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan watch: %w", err)
+		}
+		if query, err = s.cipher.open(query); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to decrypt watch %d: %w", id, err)
+		}
+		if query == watch.Query {
+			existing = id
+			break
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to save watch: %w", err)
+	}
 
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("pattern not found: %s", name)
+	if existing > 0 {
+		if _, err := s.db.Exec("UPDATE watched_queries SET interval_seconds = ? WHERE id = ?",
+			int64(watch.Interval/time.Second), existing); err != nil {
+			return fmt.Errorf("failed to save watch: %w", err)
+		}
+		watch.ID = existing
+		return nil
 	}
+
+	result, err := s.db.Exec(
+		"INSERT INTO watched_queries (query, mode, prompt_used, interval_seconds, created_at) VALUES (?, ?, ?, ?, ?)",
+		s.cipher.seal(watch.Query),
+		watch.Mode,
+		watch.PromptUsed,
+		int64(watch.Interval/time.Second),
+		watch.CreatedAt,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get pattern: %w", err)
+		return fmt.Errorf("failed to save watch: %w", err)
+	}
+	if watch.ID, err = result.LastInsertId(); err != nil {
+		return fmt.Errorf("failed to get watch ID: %w", err)
+	}
+	return nil
+}
+
+// DeleteWatch removes a watched query
+func (s *SQLiteDB) DeleteWatch(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.db.Exec("DELETE FROM watched_queries WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete watch: %w", err)
 	}
 
-	return pattern, nil
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("watch not found: %d", id)
+	}
+
+	return nil
 }
 
-// IncrementPattern increments the success count for a pattern
-func (s *SQLiteDB) IncrementPattern(name string) error {
+// UpdateWatchRun records when a watch last ran and the session holding its
+// latest stored answer
+func (s *SQLiteDB) UpdateWatchRun(id int64, runAt time.Time, sessionID int64) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	query := `
-		UPDATE learned_patterns
-		SET success_count = success_count + 1,
-		    last_used = ?
-		WHERE pattern_name = ?
+		UPDATE watched_queries
+		SET last_run_at = ?,
+		    last_session_id = ?
+		WHERE id = ?
 	`
 
-	result, err := s.db.Exec(query, time.Now(), name)
+	var session interface{}
+	if sessionID > 0 {
+		session = sessionID
+	}
+
+	result, err := s.db.Exec(query, runAt, session, id)
 	if err != nil {
-		return fmt.Errorf("failed to increment pattern: %w", err)
+		return fmt.Errorf("failed to update watch: %w", err)
 	}
 
 	rows, err := result.RowsAffected()
@@ -274,7 +1334,7 @@ func (s *SQLiteDB) IncrementPattern(name string) error {
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("pattern not found: %s", name)
+		return fmt.Errorf("watch not found: %d", id)
 	}
 
 	return nil
@@ -324,16 +1384,21 @@ func (s *SQLiteDB) GetModeStats() (map[string]int, error) {
 	return stats, nil
 }
 
-// GetTopQueries returns the most common queries
+// GetTopQueries returns the most often researched queries, counting every
+// logged run whatever its outcome
 func (s *SQLiteDB) GetTopQueries(limit int) ([]QueryCount, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	if s.cipher != nil {
+		return s.topSealedQueries(limit)
+	}
+
 	query := `
 		SELECT query, COUNT(*) as count
-		FROM research_sessions
+		FROM search_history
 		GROUP BY query
-		ORDER BY count DESC
+		ORDER BY count DESC, MAX(id) DESC
 		LIMIT ?
 	`
 
@@ -355,6 +1420,53 @@ func (s *SQLiteDB) GetTopQueries(limit int) ([]QueryCount, error) {
 	return topQueries, nil
 }
 
+// GetTagCounts returns every tag with the number of sessions carrying it,
+// most used first
+func (s *SQLiteDB) GetTagCounts() ([]TagCount, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `
+		SELECT tag, COUNT(*) as count
+		FROM session_tags
+		GROUP BY tag
+		ORDER BY count DESC, tag
+	`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tag counts: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []TagCount
+	for rows.Next() {
+		var tc TagCount
+		if err := rows.Scan(&tc.Tag, &tc.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan tag count: %w", err)
+		}
+		counts = append(counts, tc)
+	}
+
+	return counts, nil
+}
+
+// Vacuum rebuilds the database file to reclaim the space left by deleted
+// rows
+func (s *SQLiteDB) Vacuum() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.db.Exec("VACUUM"); err != nil {
+		return fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	// Copy the rebuilt pages back from the write-ahead log and empty it
+	if _, err := s.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("failed to checkpoint database: %w", err)
+	}
+	return nil
+}
+
 // Close closes the database connection
 func (s *SQLiteDB) Close() error {
 	s.mu.Lock()
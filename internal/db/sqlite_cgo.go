@@ -0,0 +1,16 @@
+//go:build cgo
+
+package db
+
+import (
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func init() {
+	registerSQLite(DriverSQLite, sqliteEngine{
+		driver: "sqlite3",
+		dsn: func(path string) string {
+			return path + "?_journal_mode=WAL&_timeout=5000"
+		},
+	})
+}
@@ -0,0 +1,19 @@
+//go:build purego
+
+package db
+
+import (
+	_ "modernc.org/sqlite"
+)
+
+// The pure-Go driver lets CGO_ENABLED=0 go build -tags purego build without
+// a C toolchain
+func init() {
+	registerSQLite(DriverSQLitePureGo, sqliteEngine{
+		driver: "sqlite",
+		dsn: func(path string) string {
+			// Store times as go-sqlite3 does, in a form julianday() parses
+			return path + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_time_format=sqlite"
+		},
+	})
+}
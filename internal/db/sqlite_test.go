@@ -1,6 +1,7 @@
 package db
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
@@ -18,7 +19,7 @@ func setupTestDB(t *testing.T) (*SQLiteDB, string) {
 	db, err := NewSQLiteDB(dbPath)
 	require.NoError(t, err, "should create database successfully")
 	
-	return db, dbPath
+	return db.(*SQLiteDB), dbPath
 }
 
 func TestNewSQLiteDB(t *testing.T) {
@@ -69,6 +70,7 @@ func TestSaveSessionWithQualityScore(t *testing.T) {
 		PromptUsed:   "default",
 		Result:       "Result",
 		QualityScore: &score,
+		Critique:     "OVERALL: 5",
 		CreatedAt:    time.Now(),
 	}
 	
@@ -79,6 +81,99 @@ func TestSaveSessionWithQualityScore(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, retrieved.QualityScore)
 	assert.Equal(t, 5, *retrieved.QualityScore)
+	assert.Equal(t, "OVERALL: 5", retrieved.Critique)
+}
+
+func TestSaveSessionWithCitations(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+	
+	session := &ResearchSession{
+		Query:      "Test with citations",
+		Mode:       "quick",
+		PromptUsed: "default",
+		Result:     "See [Go](https://go.dev)",
+		Citations: []Citation{
+			{Text: "Go", URL: "https://go.dev", Status: CitationOK, StatusCode: 200},
+			{URL: "https://example.com/x", Status: CitationSuspicious, Note: "placeholder domain"},
+		},
+		CreatedAt: time.Now(),
+	}
+	
+	require.NoError(t, db.SaveSession(session))
+	
+	retrieved, err := db.GetSession(session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, session.Citations, retrieved.Citations)
+	
+	// Sessions without citations read back as nil
+	plain := &ResearchSession{Query: "plain", Mode: "quick", PromptUsed: "default", Result: "r", CreatedAt: time.Now()}
+	require.NoError(t, db.SaveSession(plain))
+	retrieved, err = db.GetSession(plain.ID)
+	require.NoError(t, err)
+	assert.Nil(t, retrieved.Citations)
+}
+
+func TestSaveSessionWithMatrix(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+	
+	matrix := &ComparisonMatrix{
+		Criteria: []string{"Performance", "Maturity"},
+		Options: []MatrixOption{
+			{Name: "React", Summary: "UI library", Scores: []MatrixScore{{Criterion: "Performance", Score: 4, Note: "virtual DOM"}, {Criterion: "Maturity", Score: 5}}},
+			{Name: "Vue", Scores: []MatrixScore{{Criterion: "Performance", Score: 4}, {Criterion: "Maturity"}}},
+		},
+	}
+	session := &ResearchSession{
+		Query:      "React vs Vue",
+		Mode:       "compare",
+		PromptUsed: "compare",
+		Result:     "table",
+		Matrix:     matrix,
+		CreatedAt:  time.Now(),
+	}
+	require.NoError(t, db.SaveSession(session))
+	
+	retrieved, err := db.GetSession(session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, matrix, retrieved.Matrix)
+}
+
+func TestMatrixOption_Average(t *testing.T) {
+	option := MatrixOption{Scores: []MatrixScore{{Score: 4}, {Score: 0}, {Score: 5}}}
+	assert.Equal(t, 4.5, option.Average(), "unknown scores are ignored")
+	assert.Equal(t, 0.0, MatrixOption{}.Average())
+}
+
+func TestNewSQLiteDB_AddsMissingColumns(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "old.db")
+	
+	// Create a database with the original research_sessions schema
+	raw, err := OpenSQLite(dbPath)
+	require.NoError(t, err)
+	_, err = raw.Exec(`CREATE TABLE research_sessions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		query TEXT NOT NULL,
+		mode TEXT NOT NULL,
+		prompt_used TEXT NOT NULL,
+		result TEXT NOT NULL,
+		quality_score INTEGER,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`)
+	require.NoError(t, err)
+	_, err = raw.Exec(`INSERT INTO research_sessions (query, mode, prompt_used, result) VALUES ('old', 'quick', 'default', 'r')`)
+	require.NoError(t, err)
+	require.NoError(t, raw.Close())
+	
+	database, err := NewSQLiteDB(dbPath)
+	require.NoError(t, err)
+	defer database.Close()
+	
+	session, err := database.GetSession(1)
+	require.NoError(t, err)
+	assert.Equal(t, "old", session.Query)
+	assert.Nil(t, session.Citations)
 }
 
 func TestListSessions(t *testing.T) {
@@ -302,3 +397,560 @@ func TestGetPatternNotFound(t *testing.T) {
 	_, err := db.GetPattern("non-existent")
 	assert.Error(t, err, "should return error for non-existent pattern")
 }
+
+func TestGetLatestSession(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	latest, err := db.GetLatestSession("Go generics", "quick")
+	require.NoError(t, err)
+	assert.Nil(t, latest, "should return nil when there is no session")
+
+	base := time.Now().Add(-time.Hour)
+	for i, result := range []string{"first", "second"} {
+		err := db.SaveSession(&ResearchSession{
+			Query:      "Go generics",
+			Mode:       "quick",
+			PromptUsed: "default",
+			Result:     result,
+			CreatedAt:  base.Add(time.Duration(i) * time.Minute),
+		})
+		require.NoError(t, err)
+	}
+	require.NoError(t, db.SaveSession(&ResearchSession{
+		Query: "Go generics", Mode: "deep", PromptUsed: "default", Result: "deep", CreatedAt: time.Now(),
+	}))
+
+	latest, err = db.GetLatestSession("Go generics", "quick")
+	require.NoError(t, err)
+	require.NotNil(t, latest)
+	assert.Equal(t, "second", latest.Result)
+}
+
+func TestWatches(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	watch := &WatchedQuery{
+		Query:      "Stripe API changes",
+		Mode:       "quick",
+		PromptUsed: "default",
+		Interval:   24 * time.Hour,
+	}
+	require.NoError(t, db.SaveWatch(watch))
+	assert.Greater(t, watch.ID, int64(0))
+
+	// Saving the same query again updates the interval instead of duplicating
+	again := &WatchedQuery{Query: watch.Query, Mode: watch.Mode, PromptUsed: watch.PromptUsed, Interval: time.Hour}
+	require.NoError(t, db.SaveWatch(again))
+	assert.Equal(t, watch.ID, again.ID)
+
+	watches, err := db.ListWatches()
+	require.NoError(t, err)
+	require.Len(t, watches, 1)
+	assert.Equal(t, time.Hour, watches[0].Interval)
+	assert.Nil(t, watches[0].LastRunAt)
+	assert.Nil(t, watches[0].LastSessionID)
+
+	session := &ResearchSession{Query: watch.Query, Mode: "quick", PromptUsed: "default", Result: "r", CreatedAt: time.Now()}
+	require.NoError(t, db.SaveSession(session))
+
+	runAt := time.Now()
+	require.NoError(t, db.UpdateWatchRun(watch.ID, runAt, session.ID))
+
+	watches, err = db.ListWatches()
+	require.NoError(t, err)
+	require.NotNil(t, watches[0].LastRunAt)
+	require.NotNil(t, watches[0].LastSessionID)
+	assert.Equal(t, session.ID, *watches[0].LastSessionID)
+	assert.WithinDuration(t, runAt, *watches[0].LastRunAt, time.Second)
+
+	assert.Error(t, db.UpdateWatchRun(9999, runAt, 0))
+
+	require.NoError(t, db.DeleteWatch(watch.ID))
+	assert.Error(t, db.DeleteWatch(watch.ID), "deleting twice should fail")
+
+	watches, err = db.ListWatches()
+	require.NoError(t, err)
+	assert.Empty(t, watches)
+}
+
+func TestUpdateSession(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	session := &ResearchSession{
+		Query:      "Long query",
+		Mode:       "deep",
+		PromptUsed: "default",
+		Status:     SessionInProgress,
+		Checkpoint: &Checkpoint{Stage: "load_prompt", Prompt: "rendered"},
+		CreatedAt:  time.Now(),
+	}
+	require.NoError(t, db.SaveSession(session))
+
+	retrieved, err := db.GetSession(session.ID)
+	require.NoError(t, err)
+	assert.True(t, retrieved.InProgress())
+	assert.Equal(t, session.Checkpoint, retrieved.Checkpoint)
+
+	session.Result = "Done"
+	session.Status = SessionComplete
+	session.Checkpoint = nil
+	require.NoError(t, db.UpdateSession(session))
+
+	retrieved, err = db.GetSession(session.ID)
+	require.NoError(t, err)
+	assert.False(t, retrieved.InProgress())
+	assert.Nil(t, retrieved.Checkpoint)
+	assert.Equal(t, "Done", retrieved.Result)
+
+	assert.Error(t, db.UpdateSession(&ResearchSession{ID: 999, Query: "x", Mode: "quick", PromptUsed: "default"}))
+}
+
+func TestSaveSessionWithRefinedQuery(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	session := &ResearchSession{
+		Query:        "go logging",
+		RefinedQuery: "Compare structured logging libraries for Go services: slog, zap and zerolog",
+		Mode:         "quick",
+		PromptUsed:   "default",
+		Result:       "r",
+		CreatedAt:    time.Now(),
+	}
+	require.NoError(t, db.SaveSession(session))
+
+	retrieved, err := db.GetSession(session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "go logging", retrieved.Query)
+	assert.Equal(t, session.RefinedQuery, retrieved.RefinedQuery)
+
+	// The refined query is searchable too
+	found, err := db.SearchSessions("zerolog")
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, session.ID, found[0].ID)
+}
+
+func TestSaveSessionWithRunMetadata(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	first := &ResearchSession{Query: "q", Mode: "quick", PromptUsed: "default", Result: "v1", CreatedAt: time.Now()}
+	require.NoError(t, db.SaveSession(first))
+
+	session := &ResearchSession{
+		Query:      "q",
+		Mode:       "quick",
+		PromptUsed: "default",
+		Result:     "v2",
+		Provider:   "openai",
+		Model:      "gpt-4o",
+		Tokens:     TokenCount{Prompt: 10, Completion: 20, Total: 30},
+		ParentID:   first.ID,
+		CreatedAt:  time.Now(),
+	}
+	require.NoError(t, db.SaveSession(session))
+
+	retrieved, err := db.GetSession(session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "openai", retrieved.Provider)
+	assert.Equal(t, "gpt-4o", retrieved.Model)
+	assert.Equal(t, TokenCount{Prompt: 10, Completion: 20, Total: 30}, retrieved.Tokens)
+	assert.Equal(t, first.ID, retrieved.ParentID)
+
+	retrieved, err = db.GetSession(first.ID)
+	require.NoError(t, err)
+	assert.Zero(t, retrieved.ParentID)
+	assert.Zero(t, retrieved.Tokens.Total)
+}
+
+func TestSaveSession_DefaultsToComplete(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	session := &ResearchSession{Query: "q", Mode: "quick", PromptUsed: "default", Result: "r", CreatedAt: time.Now()}
+	require.NoError(t, db.SaveSession(session))
+	assert.Equal(t, SessionComplete, session.Status)
+
+	retrieved, err := db.GetSession(session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, SessionComplete, retrieved.Status)
+}
+
+func TestGetLatestSession_SkipsIncomplete(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	done := &ResearchSession{Query: "q", Mode: "quick", PromptUsed: "default", Result: "full", CreatedAt: time.Now().Add(-time.Hour)}
+	require.NoError(t, db.SaveSession(done))
+	partial := &ResearchSession{Query: "q", Mode: "quick", PromptUsed: "default", Status: SessionInProgress, CreatedAt: time.Now()}
+	require.NoError(t, db.SaveSession(partial))
+
+	latest, err := db.GetLatestSession("q", "quick")
+	require.NoError(t, err)
+	assert.Equal(t, done.ID, latest.ID)
+}
+
+// saveSessionsAt saves one session per creation time, oldest first
+func saveSessionsAt(t *testing.T, db *SQLiteDB, times ...time.Time) []*ResearchSession {
+	var sessions []*ResearchSession
+	for i, createdAt := range times {
+		session := &ResearchSession{Query: fmt.Sprintf("query %d", i), Mode: "quick", PromptUsed: "default", Result: "r", CreatedAt: createdAt}
+		require.NoError(t, db.SaveSession(session))
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+func TestDeleteSession(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	sessions := saveSessionsAt(t, db, now.Add(-time.Hour), now)
+	followUp := sessions[1]
+	followUp.ParentID = sessions[0].ID
+	require.NoError(t, db.UpdateSession(followUp))
+
+	watch := &WatchedQuery{Query: "q", Mode: "quick", PromptUsed: "default", Interval: time.Hour}
+	require.NoError(t, db.SaveWatch(watch))
+	require.NoError(t, db.UpdateWatchRun(watch.ID, now, sessions[0].ID))
+	_, err := db.db.Exec("INSERT INTO search_history (session_id, query) VALUES (?, 'q')", sessions[0].ID)
+	require.NoError(t, err)
+
+	require.NoError(t, db.DeleteSession(sessions[0].ID))
+	_, err = db.GetSession(sessions[0].ID)
+	assert.Error(t, err)
+	assert.ErrorContains(t, db.DeleteSession(sessions[0].ID), "session not found")
+
+	// References to the deleted session are cleared
+	retrieved, err := db.GetSession(followUp.ID)
+	require.NoError(t, err)
+	assert.Zero(t, retrieved.ParentID)
+	watches, err := db.ListWatches()
+	require.NoError(t, err)
+	assert.Nil(t, watches[0].LastSessionID)
+	var logged int
+	require.NoError(t, db.db.QueryRow("SELECT COUNT(*) FROM search_history WHERE session_id IS NOT NULL").Scan(&logged))
+	assert.Zero(t, logged, "the search log keeps the run but not the session")
+	require.NoError(t, db.db.QueryRow("SELECT COUNT(*) FROM search_history").Scan(&logged))
+	assert.Equal(t, 1, logged)
+}
+
+func TestDeleteSessionsBefore(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	sessions := saveSessionsAt(t, db, now.AddDate(0, 0, -40), now.AddDate(0, 0, -31), now.AddDate(0, 0, -1))
+
+	deleted, err := db.DeleteSessionsBefore(now.AddDate(0, 0, -30).In(time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	remaining, err := db.ListSessions(10, 0)
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	assert.Equal(t, sessions[2].ID, remaining[0].ID)
+}
+
+func TestKeepLatestSessions(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	sessions := saveSessionsAt(t, db, now.Add(-3*time.Hour), now.Add(-2*time.Hour), now.Add(-time.Hour))
+
+	deleted, err := db.KeepLatestSessions(2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	_, err = db.GetSession(sessions[0].ID)
+	assert.Error(t, err)
+
+	deleted, err = db.KeepLatestSessions(5)
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+}
+
+func TestClearAllAndVacuum(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	saveSessionsAt(t, db, now, now, now)
+
+	deleted, err := db.ClearAll()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+
+	total, err := db.GetTotalSessions()
+	require.NoError(t, err)
+	assert.Zero(t, total)
+
+	matches, err := db.FindSessions(SessionFilter{Text: "query"})
+	require.NoError(t, err)
+	assert.Empty(t, matches, "the search index follows deletions")
+
+	require.NoError(t, db.Vacuum())
+}
+
+func TestImportSessions(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	createdAt := time.Date(2025, 6, 1, 9, 30, 0, 0, time.UTC)
+	local := &ResearchSession{Query: "already here", Mode: "quick", PromptUsed: "default", Result: "r", CreatedAt: createdAt}
+	require.NoError(t, db.SaveSession(local))
+
+	archive := []*ResearchSession{
+		{ID: 7, Query: "already here", Mode: "quick", PromptUsed: "default", Result: "r", CreatedAt: createdAt.In(time.FixedZone("CET", 3600))},
+		{ID: 8, Query: "parent", Mode: "deep", PromptUsed: "default", Result: "first", Provider: "openai", Tokens: TokenCount{Total: 42}, CreatedAt: createdAt.Add(time.Hour)},
+		{ID: 9, Query: "parent", Mode: "deep", PromptUsed: "default", Result: "second", ParentID: 8, CreatedAt: createdAt.Add(2 * time.Hour)},
+		{ID: 10, Query: "orphan", Mode: "quick", PromptUsed: "default", Result: "x", ParentID: 99, CreatedAt: createdAt.Add(3 * time.Hour)},
+	}
+
+	result, err := db.ImportSessions(archive)
+	require.NoError(t, err)
+	assert.Equal(t, &ImportResult{Imported: 3, Skipped: 1}, result)
+
+	sessions, err := db.ListSessions(10, 0)
+	require.NoError(t, err)
+	require.Len(t, sessions, 4)
+	byResult := make(map[string]*ResearchSession)
+	for _, s := range sessions {
+		byResult[s.Result] = s
+	}
+	assert.True(t, createdAt.Add(time.Hour).Equal(byResult["first"].CreatedAt), "the original timestamp is kept")
+	assert.Equal(t, "openai", byResult["first"].Provider)
+	assert.Equal(t, 42, byResult["first"].Tokens.Total)
+	assert.Equal(t, byResult["first"].ID, byResult["second"].ParentID, "the parent is remapped to its new ID")
+	assert.Zero(t, byResult["x"].ParentID, "a parent outside the archive is dropped")
+
+	// Importing again is a no-op
+	result, err = db.ImportSessions(archive)
+	require.NoError(t, err)
+	assert.Equal(t, &ImportResult{Imported: 0, Skipped: 4}, result)
+	total, err := db.GetTotalSessions()
+	require.NoError(t, err)
+	assert.Equal(t, 4, total)
+}
+
+func TestUpdateTags(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	sessions := saveSessionsAt(t, db, time.Now())
+	id := sessions[0].ID
+
+	tags, err := db.UpdateTags(id, []string{"Golang", "concurrency", "golang"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"concurrency", "golang"}, tags)
+
+	tags, err = db.UpdateTags(id, []string{"rust"}, []string{"concurrency", "never-added"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"golang", "rust"}, tags)
+
+	session, err := db.GetSession(id)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"golang", "rust"}, session.Tags)
+
+	_, err = db.UpdateTags(id, []string{"not valid"}, nil)
+	assert.ErrorContains(t, err, "invalid tag")
+	tags, err = db.UpdateTags(id, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"golang", "rust"}, tags, "a failed update changes nothing")
+
+	_, err = db.UpdateTags(999, []string{"go"}, nil)
+	assert.ErrorContains(t, err, "session not found: 999")
+}
+
+func TestSetStarredAndNotes(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	sessions := saveSessionsAt(t, db, time.Now())
+	id := sessions[0].ID
+
+	require.NoError(t, db.SetStarred(id, true))
+	require.NoError(t, db.SetNotes(id, "  checked against the spec\n"))
+
+	session, err := db.GetSession(id)
+	require.NoError(t, err)
+	assert.True(t, session.Starred)
+	assert.Equal(t, "checked against the spec", session.Notes)
+
+	// Engine updates do not touch what the user set
+	session.Result = "updated"
+	require.NoError(t, db.UpdateSession(session))
+	session, err = db.GetSession(id)
+	require.NoError(t, err)
+	assert.True(t, session.Starred)
+	assert.Equal(t, "checked against the spec", session.Notes)
+
+	require.NoError(t, db.SetStarred(id, false))
+	require.NoError(t, db.SetNotes(id, ""))
+	session, err = db.GetSession(id)
+	require.NoError(t, err)
+	assert.False(t, session.Starred)
+	assert.Empty(t, session.Notes)
+
+	assert.ErrorContains(t, db.SetStarred(999, true), "session not found: 999")
+	assert.ErrorContains(t, db.SetNotes(999, "x"), "session not found: 999")
+}
+
+func TestGetTagCounts(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	sessions := saveSessionsAt(t, db, now, now, now)
+	_, err := db.UpdateTags(sessions[0].ID, []string{"go", "db"}, nil)
+	require.NoError(t, err)
+	_, err = db.UpdateTags(sessions[1].ID, []string{"go"}, nil)
+	require.NoError(t, err)
+	_, err = db.UpdateTags(sessions[2].ID, []string{"go", "api"}, nil)
+	require.NoError(t, err)
+
+	counts, err := db.GetTagCounts()
+	require.NoError(t, err)
+	assert.Equal(t, []TagCount{{Tag: "go", Count: 3}, {Tag: "api", Count: 1}, {Tag: "db", Count: 1}}, counts)
+
+	// Tags go with their session
+	require.NoError(t, db.DeleteSession(sessions[2].ID))
+	counts, err = db.GetTagCounts()
+	require.NoError(t, err)
+	assert.Equal(t, []TagCount{{Tag: "go", Count: 2}, {Tag: "db", Count: 1}}, counts)
+}
+
+func TestImportSessions_KeepsTagsStarsAndNotes(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	archive := []*ResearchSession{
+		{ID: 3, Query: "q", Mode: "quick", PromptUsed: "default", Result: "r", Starred: true, Tags: []string{"Go", "db"}, Notes: "keep", CreatedAt: time.Now()},
+	}
+	_, err := db.ImportSessions(archive)
+	require.NoError(t, err)
+
+	sessions, err := db.ListSessions(1, 0)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.True(t, sessions[0].Starred)
+	assert.Equal(t, []string{"db", "go"}, sessions[0].Tags)
+	assert.Equal(t, "keep", sessions[0].Notes)
+}
+
+func TestRateSession(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	sessions := []*ResearchSession{
+		{Query: "a", Mode: "deep", PromptUsed: "default", Result: "r", Provider: "openai", CreatedAt: now},
+		{Query: "b", Mode: "deep", PromptUsed: "default", Result: "r", Provider: "openai", CreatedAt: now},
+		{Query: "c", Mode: "deep", PromptUsed: "default", Result: "r", CreatedAt: now},
+	}
+	for _, s := range sessions {
+		require.NoError(t, db.SaveSession(s))
+	}
+
+	pattern, err := db.RateSession(sessions[0].ID, 5)
+	require.NoError(t, err)
+	assert.Equal(t, "rating:deep:default:openai", pattern.PatternName)
+	assert.Equal(t, "default", pattern.PromptUsed)
+	assert.Equal(t, "deep", pattern.Mode)
+	assert.Equal(t, "openai", pattern.Provider)
+	assert.Equal(t, 1, pattern.RatingCount)
+	assert.Equal(t, 1, pattern.SuccessCount)
+
+	pattern, err = db.RateSession(sessions[1].ID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, pattern.RatingCount)
+	assert.Equal(t, 3.5, pattern.AverageRating())
+
+	// Rating again replaces the earlier rating
+	pattern, err = db.RateSession(sessions[0].ID, 3)
+	require.NoError(t, err)
+	assert.Equal(t, 2, pattern.RatingCount)
+	assert.Equal(t, 2.5, pattern.AverageRating())
+	assert.Zero(t, pattern.SuccessCount)
+
+	session, err := db.GetSession(sessions[0].ID)
+	require.NoError(t, err)
+	require.NotNil(t, session.UserRating)
+	assert.Equal(t, 3, *session.UserRating)
+
+	// Sessions without a provider have their own pattern
+	pattern, err = db.RateSession(sessions[2].ID, 4)
+	require.NoError(t, err)
+	assert.Equal(t, "rating:deep:default:", pattern.PatternName)
+	assert.Empty(t, pattern.Provider)
+
+	same, err := db.GetPattern(pattern.PatternName)
+	require.NoError(t, err)
+	assert.Equal(t, pattern.RatingTotal, same.RatingTotal)
+
+	_, err = db.RateSession(sessions[0].ID, 6)
+	assert.ErrorContains(t, err, "invalid rating 6")
+	_, err = db.RateSession(999, 3)
+	assert.ErrorContains(t, err, "session not found: 999")
+}
+
+func TestBestPromptAndRatingPatterns(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	rate := func(prompt, mode, provider string, rating int) {
+		session := &ResearchSession{Query: "q", Mode: mode, PromptUsed: prompt, Result: "r", Provider: provider, CreatedAt: time.Now()}
+		require.NoError(t, db.SaveSession(session))
+		_, err := db.RateSession(session.ID, rating)
+		require.NoError(t, err)
+	}
+	rate("default", "deep", "openai", 3)
+	rate("default", "deep", "github-copilot", 4)
+	rate("default", "deep", "openai", 2)
+	rate("thorough", "deep", "openai", 5)
+	rate("thorough", "deep", "github-copilot", 4)
+	rate("terse", "quick", "openai", 5)
+
+	best, err := db.BestPrompt("deep", 2)
+	require.NoError(t, err)
+	require.NotNil(t, best)
+	assert.Equal(t, &PromptRating{PromptUsed: "thorough", Mode: "deep", Count: 2, Average: 4.5}, best)
+
+	best, err = db.BestPrompt("deep", 3)
+	require.NoError(t, err)
+	require.NotNil(t, best)
+	assert.Equal(t, "default", best.PromptUsed, "only prompts with enough ratings qualify")
+	assert.Equal(t, 3, best.Count)
+
+	best, err = db.BestPrompt("compare", 1)
+	require.NoError(t, err)
+	assert.Nil(t, best)
+
+	patterns, err := db.GetRatingPatterns()
+	require.NoError(t, err)
+	require.Len(t, patterns, 5)
+	assert.Equal(t, "thorough", patterns[0].PromptUsed)
+	assert.Equal(t, "quick", patterns[4].Mode)
+}
+
+func TestImportSessions_KeepsRatings(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	rating := 4
+	_, err := db.ImportSessions([]*ResearchSession{
+		{ID: 1, Query: "q", Mode: "quick", PromptUsed: "default", Result: "r", UserRating: &rating, CreatedAt: time.Now()},
+	})
+	require.NoError(t, err)
+
+	best, err := db.BestPrompt("quick", 1)
+	require.NoError(t, err)
+	require.NotNil(t, best)
+	assert.Equal(t, 4.0, best.Average)
+}
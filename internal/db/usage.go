@@ -0,0 +1,211 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// checkPeriod returns an error if the period of a filter is unknown
+func checkPeriod(filter UsageFilter) error {
+	switch filter.Period {
+	case "", PeriodDay, PeriodWeek:
+		return nil
+	default:
+		return fmt.Errorf("unknown usage period %q (use %s or %s)", filter.Period, PeriodDay, PeriodWeek)
+	}
+}
+
+// periodStart returns the start of the period of a filter that t falls in.
+// Each time is converted to the filter's location on its own, so days
+// follow the offset in effect at that time rather than the current one.
+func periodStart(t time.Time, filter UsageFilter) time.Time {
+	if filter.Period == "" {
+		return time.Time{}
+	}
+	loc := filter.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	if filter.Period == PeriodWeek {
+		start = start.AddDate(0, 0, -(int(start.Weekday())+6)%7)
+	}
+	return start
+}
+
+// usageWindow returns the WHERE clause limiting a usage query to the window
+// of a filter, and its arguments
+func usageWindow(filter UsageFilter) (string, []interface{}) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	// created_at is stored with a zone offset, which julianday normalizes
+	if !filter.Since.IsZero() {
+		where = append(where, "julianday(created_at) >= julianday(?)")
+		args = append(args, filter.Since.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if !filter.Until.IsZero() {
+		where = append(where, "julianday(created_at) < julianday(?)")
+		args = append(args, filter.Until.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if len(where) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(where, " AND "), args
+}
+
+// GetSessionUsage returns the sessions and tokens of each provider and model
+// per period, oldest period first
+func (s *SQLiteDB) GetSessionUsage(filter UsageFilter) ([]SessionUsage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := checkPeriod(filter); err != nil {
+		return nil, err
+	}
+	where, args := usageWindow(filter)
+
+	// Periods depend on the zone offset of each session, so they are
+	// bucketed here rather than in SQL
+	query := `
+		SELECT created_at, COALESCE(provider, ''), COALESCE(model, ''),
+			prompt_tokens, completion_tokens, total_tokens
+		FROM research_sessions` + where
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session usage: %w", err)
+	}
+	defer rows.Close()
+
+	type key struct {
+		period          int64
+		provider, model string
+	}
+	buckets := make(map[key]*SessionUsage)
+	var usage []*SessionUsage
+	for rows.Next() {
+		var createdAt time.Time
+		var provider, model string
+		var tokens TokenCount
+		if err := rows.Scan(&createdAt, &provider, &model, &tokens.Prompt, &tokens.Completion, &tokens.Total); err != nil {
+			return nil, fmt.Errorf("failed to scan session usage: %w", err)
+		}
+		period := periodStart(createdAt, filter)
+		k := key{period.Unix(), provider, model}
+		u := buckets[k]
+		if u == nil {
+			u = &SessionUsage{Period: period, Provider: provider, Model: model}
+			buckets[k] = u
+			usage = append(usage, u)
+		}
+		u.Sessions++
+		u.Tokens.Prompt += tokens.Prompt
+		u.Tokens.Completion += tokens.Completion
+		u.Tokens.Total += tokens.Total
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get session usage: %w", err)
+	}
+
+	sort.Slice(usage, func(i, j int) bool {
+		a, b := usage[i], usage[j]
+		if !a.Period.Equal(b.Period) {
+			return a.Period.Before(b.Period)
+		}
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		return a.Model < b.Model
+	})
+	result := make([]SessionUsage, len(usage))
+	for i, u := range usage {
+		result[i] = *u
+	}
+	return result, nil
+}
+
+// GetRunUsage returns the logged runs of each provider per period, by
+// outcome, oldest period first
+func (s *SQLiteDB) GetRunUsage(filter UsageFilter) ([]RunUsage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := checkPeriod(filter); err != nil {
+		return nil, err
+	}
+	where, args := usageWindow(filter)
+
+	query := `
+		SELECT created_at, COALESCE(provider, ''), outcome, latency_ms
+		FROM search_history` + where
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get run usage: %w", err)
+	}
+	defer rows.Close()
+
+	type key struct {
+		period   int64
+		provider string
+	}
+	type bucket struct {
+		RunUsage
+		latency time.Duration
+		timed   int
+	}
+	buckets := make(map[key]*bucket)
+	var usage []*bucket
+	for rows.Next() {
+		var createdAt time.Time
+		var provider, outcome string
+		var latencyMS int64
+		if err := rows.Scan(&createdAt, &provider, &outcome, &latencyMS); err != nil {
+			return nil, fmt.Errorf("failed to scan run usage: %w", err)
+		}
+		period := periodStart(createdAt, filter)
+		k := key{period.Unix(), provider}
+		b := buckets[k]
+		if b == nil {
+			b = &bucket{RunUsage: RunUsage{Period: period, Provider: provider}}
+			buckets[k] = b
+			usage = append(usage, b)
+		}
+		b.Runs++
+		switch outcome {
+		case OutcomeFailed:
+			b.Failed++
+		case OutcomeCancelled:
+			b.Cancelled++
+		}
+		// Runs logged before latencies were recorded have a latency of 0
+		if latencyMS > 0 {
+			b.latency += time.Duration(latencyMS) * time.Millisecond
+			b.timed++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get run usage: %w", err)
+	}
+
+	sort.Slice(usage, func(i, j int) bool {
+		a, b := usage[i], usage[j]
+		if !a.Period.Equal(b.Period) {
+			return a.Period.Before(b.Period)
+		}
+		return a.Provider < b.Provider
+	})
+	result := make([]RunUsage, len(usage))
+	for i, b := range usage {
+		result[i] = b.RunUsage
+		if b.timed > 0 {
+			result[i].AverageLatency = (b.latency / time.Duration(b.timed)).Round(time.Millisecond)
+		}
+	}
+	return result, nil
+}
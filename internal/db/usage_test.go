@@ -0,0 +1,68 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSessionUsage_Location(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	for _, at := range []time.Time{
+		time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		time.Date(2026, 3, 1, 23, 30, 0, 0, time.UTC),
+	} {
+		require.NoError(t, db.SaveSession(&ResearchSession{Query: "q", Mode: "quick", PromptUsed: "default", Result: "r", CreatedAt: at}))
+	}
+
+	// Two hours east of UTC, the second session is on the next day
+	east := time.FixedZone("UTC+2", 2*60*60)
+	usage, err := db.GetSessionUsage(UsageFilter{Period: PeriodDay, Location: east})
+	require.NoError(t, err)
+	require.Len(t, usage, 2)
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, east), usage[0].Period)
+	assert.Equal(t, time.Date(2026, 3, 2, 0, 0, 0, 0, east), usage[1].Period)
+
+	usage, err = db.GetSessionUsage(UsageFilter{Period: PeriodDay})
+	require.NoError(t, err)
+	require.Len(t, usage, 1)
+	assert.Equal(t, 2, usage[0].Sessions)
+}
+
+func TestGetRunUsage_DaylightSaving(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+
+	// 23:30 local on both days: EST (UTC-5) in January, EDT (UTC-4) in July
+	for _, at := range []time.Time{
+		time.Date(2026, 1, 15, 4, 30, 0, 0, time.UTC),
+		time.Date(2026, 7, 15, 3, 30, 0, 0, time.UTC),
+	} {
+		require.NoError(t, db.LogSearch(&SearchHistory{Query: "q", Mode: "quick", Provider: "openai", CreatedAt: at}))
+	}
+
+	usage, err := db.GetRunUsage(UsageFilter{Period: PeriodDay, Location: newYork})
+	require.NoError(t, err)
+	require.Len(t, usage, 2)
+	assert.Equal(t, time.Date(2026, 1, 14, 0, 0, 0, 0, newYork), usage[0].Period)
+	assert.Equal(t, time.Date(2026, 7, 14, 0, 0, 0, 0, newYork), usage[1].Period)
+}
+
+func TestUsage_UnknownPeriod(t *testing.T) {
+	db, _ := setupTestDB(t)
+	defer db.Close()
+
+	_, err := db.GetSessionUsage(UsageFilter{Period: "month"})
+	assert.ErrorContains(t, err, `unknown usage period "month"`)
+	_, err = db.GetRunUsage(UsageFilter{Period: "month"})
+	assert.Error(t, err)
+}
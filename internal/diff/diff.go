@@ -0,0 +1,209 @@
+package diff
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// OpKind identifies the kind of a diff operation
+type OpKind int
+
+const (
+	// Equal marks a paragraph present in both documents
+	Equal OpKind = iota
+	// Insert marks a paragraph only present in the new document
+	Insert
+	// Delete marks a paragraph only present in the old document
+	Delete
+)
+
+// String returns the diff marker for the operation kind
+func (k OpKind) String() string {
+	switch k {
+	case Insert:
+		return "+"
+	case Delete:
+		return "-"
+	default:
+		return " "
+	}
+}
+
+// Op is a single step in a paragraph diff
+type Op struct {
+	Kind OpKind
+	Old  string
+	New  string
+}
+
+// Result is the outcome of diffing two documents paragraph by paragraph
+type Result struct {
+	Ops        []Op
+	Added      []string
+	Removed    []string
+	Unchanged  int
+	Similarity float64 // 0.0 (nothing shared) to 1.0 (same words)
+}
+
+// ParagraphMatchThreshold is the word similarity at which two paragraphs are
+// treated as the same paragraph. Model output is rarely byte-identical
+// between runs, so exact matching would report everything as changed.
+const ParagraphMatchThreshold = 0.8
+
+// Paragraphs diffs two markdown documents paragraph by paragraph
+func Paragraphs(oldText, newText string) *Result {
+	oldParas := SplitParagraphs(oldText)
+	newParas := SplitParagraphs(newText)
+
+	result := &Result{
+		Ops:        diffParagraphs(oldParas, newParas),
+		Similarity: Similarity(oldText, newText),
+	}
+
+	for _, op := range result.Ops {
+		switch op.Kind {
+		case Insert:
+			result.Added = append(result.Added, op.New)
+		case Delete:
+			result.Removed = append(result.Removed, op.Old)
+		default:
+			result.Unchanged++
+		}
+	}
+
+	return result
+}
+
+// Material reports whether the documents differ enough to matter, i.e. their
+// similarity has dropped below threshold
+func (r *Result) Material(threshold float64) bool {
+	return r.Similarity < threshold
+}
+
+// Summary renders a short human readable description of the changes,
+// listing at most maxItems added and removed paragraphs
+func (r *Result) Summary(maxItems int) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Similarity: %.0f%% (%d added, %d removed, %d unchanged paragraphs)\n",
+		r.Similarity*100, len(r.Added), len(r.Removed), r.Unchanged)
+
+	writeList := func(title string, paras []string) {
+		if len(paras) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n%s:\n", title)
+		for i, p := range paras {
+			if i == maxItems {
+				fmt.Fprintf(&b, "  … and %d more\n", len(paras)-maxItems)
+				break
+			}
+			fmt.Fprintf(&b, "  • %s\n", firstLine(p, 100))
+		}
+	}
+
+	writeList("Added", r.Added)
+	writeList("Removed", r.Removed)
+
+	return b.String()
+}
+
+// SplitParagraphs splits text on blank lines, keeping fenced code blocks
+// intact and dropping empty paragraphs
+func SplitParagraphs(text string) []string {
+	var (
+		paras   []string
+		current []string
+		inFence bool
+	)
+
+	flush := func() {
+		if p := strings.TrimSpace(strings.Join(current, "\n")); p != "" {
+			paras = append(paras, p)
+		}
+		current = current[:0]
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+		if !inFence && strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		current = append(current, line)
+	}
+	flush()
+
+	return paras
+}
+
+var wordPattern = regexp.MustCompile(`[\p{L}\p{N}_]+`)
+
+// words returns the lower-cased word frequencies of text
+func words(text string) map[string]int {
+	counts := make(map[string]int)
+	for _, w := range wordPattern.FindAllString(strings.ToLower(text), -1) {
+		counts[w]++
+	}
+	return counts
+}
+
+// Similarity returns the cosine similarity of the word frequencies of a and b
+func Similarity(a, b string) float64 {
+	wa, wb := words(a), words(b)
+	if len(wa) == 0 && len(wb) == 0 {
+		return 1
+	}
+
+	var dot, na, nb float64
+	for w, ca := range wa {
+		na += float64(ca * ca)
+		dot += float64(ca * wb[w])
+	}
+	for _, cb := range wb {
+		nb += float64(cb * cb)
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// diffParagraphs computes a longest-common-subsequence diff where paragraphs
+// match if they are similar enough
+func diffParagraphs(a, b []string) []Op {
+	pairs := align(len(a), len(b), func(i, j int) bool {
+		return a[i] == b[j] || Similarity(a[i], b[j]) >= ParagraphMatchThreshold
+	})
+
+	ops := make([]Op, 0, len(pairs))
+	for _, p := range pairs {
+		switch {
+		case p.old < 0:
+			ops = append(ops, Op{Kind: Insert, New: b[p.new]})
+		case p.new < 0:
+			ops = append(ops, Op{Kind: Delete, Old: a[p.old]})
+		default:
+			ops = append(ops, Op{Kind: Equal, Old: a[p.old], New: b[p.new]})
+		}
+	}
+
+	return ops
+}
+
+// firstLine returns the first line of s, truncated to maxLen runes
+func firstLine(s string, maxLen int) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	runes := []rune(s)
+	if len(runes) > maxLen {
+		return string(runes[:maxLen-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,91 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitParagraphs(t *testing.T) {
+	text := "# Title\n\nFirst paragraph\ncontinues here.\n\n\n```go\nfunc main() {\n\n}\n```\n\nLast"
+
+	paras := SplitParagraphs(text)
+	require.Len(t, paras, 4)
+	assert.Equal(t, "# Title", paras[0])
+	assert.Equal(t, "First paragraph\ncontinues here.", paras[1])
+	assert.Equal(t, "```go\nfunc main() {\n\n}\n```", paras[2])
+	assert.Equal(t, "Last", paras[3])
+}
+
+func TestSimilarity(t *testing.T) {
+	assert.InDelta(t, 1.0, Similarity("Go is fast", "go IS fast!"), 0.0001)
+	assert.InDelta(t, 0.0, Similarity("alpha beta", "gamma delta"), 0.0001)
+	assert.Equal(t, 1.0, Similarity("", ""))
+	assert.Equal(t, 0.0, Similarity("words", ""))
+
+	partial := Similarity("the quick brown fox", "the quick red fox")
+	assert.Greater(t, partial, 0.5)
+	assert.Less(t, partial, 1.0)
+}
+
+func TestParagraphs_Identical(t *testing.T) {
+	text := "# Heading\n\nSome content.\n\nMore content."
+	result := Paragraphs(text, text)
+
+	assert.Empty(t, result.Added)
+	assert.Empty(t, result.Removed)
+	assert.Equal(t, 3, result.Unchanged)
+	assert.False(t, result.Material(0.9))
+}
+
+func TestParagraphs_Changes(t *testing.T) {
+	oldText := "# API\n\nUse v1 endpoints for all calls.\n\nAuthentication uses API keys."
+	newText := "# API\n\nUse v2 endpoints, v1 is deprecated and removed in March.\n\nAuthentication uses API keys.\n\nRate limits are now 100 requests per minute."
+
+	result := Paragraphs(oldText, newText)
+
+	assert.Equal(t, 2, result.Unchanged)
+	assert.Len(t, result.Added, 2)
+	assert.Len(t, result.Removed, 1)
+	assert.Contains(t, result.Removed[0], "v1 endpoints")
+	assert.Contains(t, result.Added[len(result.Added)-1], "Rate limits")
+
+	// Ops keep document order
+	assert.Equal(t, Equal, result.Ops[0].Kind)
+	assert.Equal(t, "# API", result.Ops[0].New)
+}
+
+func TestParagraphs_FuzzyMatch(t *testing.T) {
+	oldText := "Actors isolate mutable state so that only one task accesses it at a time."
+	newText := "Actors isolate their mutable state so only one task accesses it at a time."
+
+	result := Paragraphs(oldText, newText)
+	assert.Equal(t, 1, result.Unchanged)
+	assert.Empty(t, result.Added)
+	assert.Empty(t, result.Removed)
+}
+
+func TestResult_Summary(t *testing.T) {
+	result := &Result{
+		Added:      []string{"one\nmore", "two", "three"},
+		Removed:    []string{"gone"},
+		Unchanged:  4,
+		Similarity: 0.5,
+	}
+
+	summary := result.Summary(2)
+	assert.Contains(t, summary, "Similarity: 50%")
+	assert.Contains(t, summary, "3 added, 1 removed, 4 unchanged")
+	assert.Contains(t, summary, "• one\n")
+	assert.Contains(t, summary, "… and 1 more")
+	assert.Contains(t, summary, "Removed:\n  • gone")
+	assert.False(t, strings.Contains(summary, "three"))
+}
+
+func TestOpKind_String(t *testing.T) {
+	assert.Equal(t, "+", Insert.String())
+	assert.Equal(t, "-", Delete.String())
+	assert.Equal(t, " ", Equal.String())
+}
@@ -0,0 +1,269 @@
+package diff
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Section is a markdown heading and the text under it. Text before the first
+// heading forms a section with an empty heading.
+type Section struct {
+	Heading string // Heading text without the leading #s
+	Level   int    // 1 for #, 2 for ##, ...; 0 for text before the first heading
+	Body    string
+}
+
+var (
+	headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+	// Numbering and decoration that varies between runs: "1.", "2)", "**", emoji
+	headingNoisePattern = regexp.MustCompile(`^[\d.)\s]+|[*_` + "`" + `:]|[^\p{L}\p{N}\s&/+#-]`)
+)
+
+// SplitSections splits a markdown document at its headings, ignoring
+// heading-like lines inside fenced code blocks
+func SplitSections(text string) []Section {
+	var sections []Section
+	current := Section{}
+	var body []string
+	inFence := false
+
+	flush := func() {
+		current.Body = strings.TrimSpace(strings.Join(body, "\n"))
+		if current.Heading != "" || current.Body != "" {
+			sections = append(sections, current)
+		}
+		body = body[:0]
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+		if !inFence {
+			if m := headingPattern.FindStringSubmatch(line); m != nil {
+				flush()
+				current = Section{Heading: m[2], Level: len(m[1])}
+				continue
+			}
+		}
+		body = append(body, line)
+	}
+	flush()
+
+	return sections
+}
+
+// headingKey normalizes a heading for matching, so "2. **Trade-offs**" and
+// "Trade-offs" are the same section
+func headingKey(heading string) string {
+	return strings.Join(strings.Fields(strings.ToLower(headingNoisePattern.ReplaceAllString(heading, ""))), " ")
+}
+
+// SectionDiff is the diff of one section. Kind is Equal when the heading is
+// in both documents, even if the text under it changed.
+type SectionDiff struct {
+	Kind    OpKind
+	Heading string // The new heading, or the old one if the section was removed
+	Level   int
+	Ops     []Op // Paragraph diff of the section bodies
+}
+
+// Changed reports whether the section was added, removed or edited
+func (s SectionDiff) Changed() bool {
+	if s.Kind != Equal {
+		return true
+	}
+	for _, op := range s.Ops {
+		if op.Kind != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+// SectionResult is the outcome of diffing two documents section by section
+type SectionResult struct {
+	Sections   []SectionDiff
+	Similarity float64 // Similarity of the whole documents
+}
+
+// Sections diffs two markdown documents by matching their headings and then
+// diffing the paragraphs under each heading
+func Sections(oldText, newText string) *SectionResult {
+	oldSections := SplitSections(oldText)
+	newSections := SplitSections(newText)
+
+	pairs := align(len(oldSections), len(newSections), func(i, j int) bool {
+		return headingKey(oldSections[i].Heading) == headingKey(newSections[j].Heading)
+	})
+
+	result := &SectionResult{Similarity: Similarity(oldText, newText)}
+	for _, p := range pairs {
+		switch {
+		case p.old < 0:
+			s := newSections[p.new]
+			result.Sections = append(result.Sections, SectionDiff{
+				Kind: Insert, Heading: s.Heading, Level: s.Level,
+				Ops: diffParagraphs(nil, SplitParagraphs(s.Body)),
+			})
+		case p.new < 0:
+			s := oldSections[p.old]
+			result.Sections = append(result.Sections, SectionDiff{
+				Kind: Delete, Heading: s.Heading, Level: s.Level,
+				Ops: diffParagraphs(SplitParagraphs(s.Body), nil),
+			})
+		default:
+			o, n := oldSections[p.old], newSections[p.new]
+			result.Sections = append(result.Sections, SectionDiff{
+				Kind: Equal, Heading: n.Heading, Level: n.Level,
+				Ops: diffParagraphs(SplitParagraphs(o.Body), SplitParagraphs(n.Body)),
+			})
+		}
+	}
+
+	return result
+}
+
+// Counts returns how many sections were added, removed, edited and left
+// unchanged
+func (r *SectionResult) Counts() (added, removed, edited, unchanged int) {
+	for _, s := range r.Sections {
+		switch {
+		case s.Kind == Insert:
+			added++
+		case s.Kind == Delete:
+			removed++
+		case s.Changed():
+			edited++
+		default:
+			unchanged++
+		}
+	}
+	return added, removed, edited, unchanged
+}
+
+// Markdown renders the diff as markdown: one heading per section, marked as
+// added, removed, edited or unchanged, with a diff block of the paragraphs
+// that changed
+func (r *SectionResult) Markdown() string {
+	var b strings.Builder
+
+	added, removed, edited, unchanged := r.Counts()
+	fmt.Fprintf(&b, "Similarity: %.0f%% (%d sections added, %d removed, %d edited, %d unchanged)\n",
+		r.Similarity*100, added, removed, edited, unchanged)
+
+	for _, s := range r.Sections {
+		heading := s.Heading
+		if heading == "" {
+			heading = "(introduction)"
+		}
+
+		status := "unchanged"
+		switch {
+		case s.Kind == Insert:
+			status = "added"
+		case s.Kind == Delete:
+			status = "removed"
+		case s.Changed():
+			status = "edited"
+		}
+
+		fmt.Fprintf(&b, "\n## %s _(%s)_\n", heading, status)
+		if !s.Changed() {
+			continue
+		}
+
+		b.WriteString("\n```diff\n")
+		kept := 0
+		flushKept := func() {
+			if kept > 0 {
+				fmt.Fprintf(&b, "  … %d unchanged paragraph(s)\n", kept)
+				kept = 0
+			}
+		}
+		for _, op := range s.Ops {
+			switch op.Kind {
+			case Equal:
+				kept++
+			case Delete:
+				flushKept()
+				writeMarked(&b, "-", op.Old)
+			case Insert:
+				flushKept()
+				writeMarked(&b, "+", op.New)
+			}
+		}
+		flushKept()
+		b.WriteString("```\n")
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// writeMarked writes every line of a paragraph with a diff marker
+func writeMarked(b *strings.Builder, marker, para string) {
+	for _, line := range strings.Split(para, "\n") {
+		fmt.Fprintf(b, "%s %s\n", marker, line)
+	}
+}
+
+// pair links an item of the old sequence to one of the new sequence; -1
+// means the item has no counterpart
+type pair struct {
+	old, new int
+}
+
+// align matches two sequences with a longest-common-subsequence over match,
+// returning the items of both in order
+func align(n, m int, match func(i, j int) bool) []pair {
+	matched := make([][]bool, n)
+	for i := 0; i < n; i++ {
+		matched[i] = make([]bool, m)
+		for j := 0; j < m; j++ {
+			matched[i][j] = match(i, j)
+		}
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if matched[i][j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var pairs []pair
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case matched[i][j]:
+			pairs = append(pairs, pair{i, j})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			pairs = append(pairs, pair{i, -1})
+			i++
+		default:
+			pairs = append(pairs, pair{-1, j})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		pairs = append(pairs, pair{i, -1})
+	}
+	for ; j < m; j++ {
+		pairs = append(pairs, pair{-1, j})
+	}
+
+	return pairs
+}
@@ -0,0 +1,61 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitSections(t *testing.T) {
+	text := "Intro text.\n\n# Title\n\nBody\n\n## Details ##\n\n```bash\n# not a heading\n```\n\n### Empty"
+
+	sections := SplitSections(text)
+	require.Len(t, sections, 4)
+	assert.Equal(t, Section{Heading: "", Level: 0, Body: "Intro text."}, sections[0])
+	assert.Equal(t, Section{Heading: "Title", Level: 1, Body: "Body"}, sections[1])
+	assert.Equal(t, "Details", sections[2].Heading)
+	assert.Equal(t, "```bash\n# not a heading\n```", sections[2].Body)
+	assert.Equal(t, Section{Heading: "Empty", Level: 3}, sections[3])
+}
+
+func TestHeadingKey(t *testing.T) {
+	assert.Equal(t, headingKey("Trade-offs"), headingKey("2. **Trade-offs**"))
+	assert.Equal(t, headingKey("Best Practices"), headingKey("✅ Best practices:"))
+	assert.NotEqual(t, headingKey("Performance"), headingKey("Pricing"))
+}
+
+func TestSections(t *testing.T) {
+	oldText := "# Overview\n\nGo is fast.\n\n## 1. Setup\n\nRun go mod init.\n\nInstall tools.\n\n## Legacy\n\nUse GOPATH."
+	newText := "# Overview\n\nGo is fast.\n\n## Setup\n\nRun go mod init.\n\nUse go install for tools.\n\n## Workspaces\n\nUse go work."
+
+	result := Sections(oldText, newText)
+
+	require.Len(t, result.Sections, 4)
+	assert.Equal(t, "Overview", result.Sections[0].Heading)
+	assert.False(t, result.Sections[0].Changed())
+
+	setup := result.Sections[1]
+	assert.Equal(t, Equal, setup.Kind, "numbering differences still match")
+	assert.Equal(t, "Setup", setup.Heading)
+	assert.True(t, setup.Changed())
+
+	assert.Equal(t, Delete, result.Sections[2].Kind)
+	assert.Equal(t, "Legacy", result.Sections[2].Heading)
+	assert.Equal(t, Insert, result.Sections[3].Kind)
+	assert.Equal(t, "Workspaces", result.Sections[3].Heading)
+
+	added, removed, edited, unchanged := result.Counts()
+	assert.Equal(t, []int{1, 1, 1, 1}, []int{added, removed, edited, unchanged})
+}
+
+func TestSectionResult_Markdown(t *testing.T) {
+	oldText := "## Setup\n\nRun go mod init.\n\nInstall tools."
+	newText := "## Setup\n\nRun go mod init.\n\nUse go install for tools.\n\n## Extra\n\nNew section."
+
+	md := Sections(oldText, newText).Markdown()
+
+	assert.Contains(t, md, "1 sections added, 0 removed, 1 edited, 0 unchanged")
+	assert.Contains(t, md, "## Setup _(edited)_\n\n```diff\n  … 1 unchanged paragraph(s)\n- Install tools.\n+ Use go install for tools.\n```")
+	assert.Contains(t, md, "## Extra _(added)_\n\n```diff\n+ New section.\n```")
+}
@@ -0,0 +1,178 @@
+package index
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// MaxChunkLines bounds the size of a chunk so retrieved context stays small
+const MaxChunkLines = 40
+
+// supportedExtensions lists the file types the indexer understands
+var supportedExtensions = map[string]bool{
+	".md":       true,
+	".markdown": true,
+	".go":       true,
+	".txt":      true,
+}
+
+// IsSupported reports whether a file can be indexed
+func IsSupported(path string) bool {
+	return supportedExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// rawChunk is a line range of a file before it is stored
+type rawChunk struct {
+	StartLine int // 1-based, inclusive
+	EndLine   int // 1-based, inclusive
+	Content   string
+}
+
+// chunkFile splits file content into chunks at natural boundaries for the
+// file type: headings for markdown, top-level declarations for Go and blank
+// lines for text. Chunks never exceed MaxChunkLines.
+func chunkFile(path, content string) []rawChunk {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	var isBoundary func(i int) bool
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		isBoundary = func(i int) bool {
+			return strings.HasPrefix(lines[i], "#")
+		}
+	case ".go":
+		isBoundary = func(i int) bool {
+			return isGoDeclStart(lines, i)
+		}
+	default:
+		isBoundary = func(i int) bool {
+			return i > 0 && strings.TrimSpace(lines[i-1]) == ""
+		}
+	}
+
+	var chunks []rawChunk
+	start := 0
+	emit := func(end int) {
+		// Trim blank lines at both ends
+		for start < end && strings.TrimSpace(lines[start]) == "" {
+			start++
+		}
+		last := end
+		for last > start && strings.TrimSpace(lines[last-1]) == "" {
+			last--
+		}
+		if last > start {
+			chunks = append(chunks, rawChunk{
+				StartLine: start + 1,
+				EndLine:   last,
+				Content:   strings.Join(lines[start:last], "\n"),
+			})
+		}
+		start = end
+	}
+
+	inFence := false
+	for i := range lines {
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), "```") {
+			inFence = !inFence
+		}
+		if i > start && !inFence && isBoundary(i) && i-start >= minChunkLines(path) {
+			emit(i)
+		} else if i-start >= MaxChunkLines {
+			emit(i)
+		}
+	}
+	emit(len(lines))
+
+	return chunks
+}
+
+// minChunkLines avoids producing a chunk per paragraph for text files
+func minChunkLines(path string) int {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown", ".go":
+		return 1
+	default:
+		return 8
+	}
+}
+
+var goDeclPattern = regexp.MustCompile(`^(func|type|var|const)\b`)
+
+// isGoDecl reports whether a line starts a top-level Go declaration
+func isGoDecl(line string) bool {
+	return goDeclPattern.MatchString(line)
+}
+
+// isGoDeclStart reports whether line i starts a top-level declaration,
+// counting its doc comment as part of the declaration
+func isGoDeclStart(lines []string, i int) bool {
+	if i > 0 && strings.HasPrefix(lines[i-1], "//") {
+		return false
+	}
+	j := i
+	for j < len(lines) && strings.HasPrefix(lines[j], "//") {
+		j++
+	}
+	return j < len(lines) && isGoDecl(lines[j])
+}
+
+// stopWords are ignored when tokenizing
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "do": true, "does": true, "for": true, "from": true,
+	"how": true, "in": true, "is": true, "it": true, "of": true, "on": true,
+	"or": true, "that": true, "the": true, "this": true, "to": true, "was": true,
+	"what": true, "when": true, "where": true, "which": true, "why": true,
+	"with": true, "we": true, "our": true, "i": true, "you": true,
+}
+
+// tokenize splits text into lower-case terms. Identifiers are also split on
+// camelCase and snake_case boundaries so "NewResearchEngine" matches "engine".
+func tokenize(text string) []string {
+	var terms []string
+	add := func(t string) {
+		t = strings.ToLower(t)
+		if len(t) >= 2 && !stopWords[t] {
+			terms = append(terms, t)
+		}
+	}
+
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	for _, word := range words {
+		add(word)
+		parts := splitIdentifier(word)
+		if len(parts) > 1 {
+			for _, part := range parts {
+				add(part)
+			}
+		}
+	}
+
+	return terms
+}
+
+// splitIdentifier splits camelCase, PascalCase and snake_case identifiers
+func splitIdentifier(word string) []string {
+	var parts []string
+	for _, piece := range strings.Split(word, "_") {
+		runes := []rune(piece)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			lowerToUpper := unicode.IsLower(runes[i-1]) && unicode.IsUpper(runes[i])
+			acronymEnd := i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsUpper(runes[i]) && unicode.IsLower(runes[i+1])
+			if lowerToUpper || acronymEnd {
+				parts = append(parts, string(runes[start:i]))
+				start = i
+			}
+		}
+		if start < len(runes) {
+			parts = append(parts, string(runes[start:]))
+		}
+	}
+	return parts
+}
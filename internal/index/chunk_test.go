@@ -0,0 +1,81 @@
+package index
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsSupported(t *testing.T) {
+	assert.True(t, IsSupported("README.md"))
+	assert.True(t, IsSupported("main.GO"))
+	assert.True(t, IsSupported("notes.txt"))
+	assert.False(t, IsSupported("image.png"))
+	assert.False(t, IsSupported("Makefile"))
+}
+
+func TestChunkFile_Markdown(t *testing.T) {
+	content := "# Title\n\nIntro text.\n\n## Section\n\n```bash\n# not a heading\n```\n\n## Another\n\nBody"
+
+	chunks := chunkFile("doc.md", content)
+	require.Len(t, chunks, 3)
+	assert.Equal(t, 1, chunks[0].StartLine)
+	assert.Equal(t, 3, chunks[0].EndLine)
+	assert.Equal(t, "## Section\n\n```bash\n# not a heading\n```", chunks[1].Content)
+	assert.Equal(t, 5, chunks[1].StartLine)
+	assert.Equal(t, "## Another\n\nBody", chunks[2].Content)
+	assert.Equal(t, 13, chunks[2].EndLine)
+}
+
+func TestChunkFile_Go(t *testing.T) {
+	content := strings.Join([]string{
+		"package main",
+		"",
+		"// Foo does things",
+		"// in two lines",
+		"func Foo() {}",
+		"",
+		"type Bar struct{}",
+	}, "\n")
+
+	chunks := chunkFile("main.go", content)
+	require.Len(t, chunks, 3)
+	assert.Equal(t, "package main", chunks[0].Content)
+	assert.Equal(t, 3, chunks[1].StartLine, "doc comment stays with its declaration")
+	assert.Equal(t, 5, chunks[1].EndLine)
+	assert.Equal(t, "type Bar struct{}", chunks[2].Content)
+}
+
+func TestChunkFile_MaxLines(t *testing.T) {
+	var lines []string
+	for i := 0; i < 100; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+
+	chunks := chunkFile("big.txt", strings.Join(lines, "\n"))
+	require.Len(t, chunks, 3)
+	for _, c := range chunks {
+		assert.LessOrEqual(t, c.EndLine-c.StartLine+1, MaxChunkLines)
+	}
+	assert.Equal(t, 100, chunks[2].EndLine)
+}
+
+func TestTokenize(t *testing.T) {
+	terms := tokenize("How does NewResearchEngine handle the_retry_count?")
+	assert.Contains(t, terms, "newresearchengine")
+	assert.Contains(t, terms, "engine")
+	assert.Contains(t, terms, "retry")
+	assert.Contains(t, terms, "handle")
+	assert.NotContains(t, terms, "how")
+	assert.NotContains(t, terms, "the")
+}
+
+func TestSplitIdentifier(t *testing.T) {
+	assert.Equal(t, []string{"New", "Research", "Engine"}, splitIdentifier("NewResearchEngine"))
+	assert.Equal(t, []string{"HTTP", "Client"}, splitIdentifier("HTTPClient"))
+	assert.Equal(t, []string{"snake", "case"}, splitIdentifier("snake_case"))
+	assert.Equal(t, []string{"plain"}, splitIdentifier("plain"))
+}
@@ -0,0 +1,481 @@
+package index
+
+import (
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+)
+
+//go:embed schema.sql
+var schemaSQL string
+
+// BM25 parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// maxFileSize skips files too large to be useful as prompt context
+const maxFileSize = 1 << 20
+
+// Chunk is a scored excerpt of an indexed file
+type Chunk struct {
+	Path      string
+	StartLine int
+	EndLine   int
+	Content   string
+	Score     float64
+}
+
+// UpdateStats summarizes an incremental index update
+type UpdateStats struct {
+	Files     []string // every file in scope after the update
+	Indexed   int      // new or modified files that were (re)indexed
+	Unchanged int      // files skipped because mtime and size matched
+	Removed   int      // files dropped because they no longer exist
+}
+
+// Index is a local full-text index over project files
+type Index struct {
+	db *sql.DB
+	mu sync.Mutex
+}
+
+// Open opens (creating if needed) the index database at path with the
+// default SQLite storage driver
+func Open(path string) (*Index, error) {
+	conn, err := db.OpenSQLite(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open index: %w", err)
+	}
+
+	// Cascading deletes need foreign keys, which are set per connection, and
+	// an in-memory index only exists on a single connection, so the one
+	// connection is kept open
+	conn.SetMaxOpenConns(1)
+	conn.SetConnMaxLifetime(0)
+
+	if _, err := conn.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
+	}
+	if _, err := conn.Exec(schemaSQL); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to initialize index schema: %w", err)
+	}
+
+	return &Index{db: conn}, nil
+}
+
+// Close closes the index database
+func (ix *Index) Close() error {
+	return ix.db.Close()
+}
+
+// ResolvePaths expands paths, directories and glob patterns into the list of
+// supported files they cover. A ** segment in a pattern matches any number
+// of directories.
+func ResolvePaths(patterns []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+
+	add := func(path string) {
+		abs, err := filepath.Abs(path)
+		if err != nil || seen[abs] || !IsSupported(abs) {
+			return
+		}
+		seen[abs] = true
+		files = append(files, abs)
+	}
+
+	for _, pattern := range patterns {
+		glob := filepath.Glob
+		if hasRecursiveSegment(pattern) {
+			glob = globRecursive
+		}
+		matches, err := glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid context pattern %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("context path not found: %s", pattern)
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, fmt.Errorf("failed to stat %s: %w", match, err)
+			}
+			if !info.IsDir() {
+				add(match)
+				continue
+			}
+
+			err = filepath.WalkDir(match, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if d.IsDir() && path != match && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				if !d.IsDir() {
+					add(path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to walk %s: %w", match, err)
+			}
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// hasRecursiveSegment reports whether a pattern has a ** segment
+func hasRecursiveSegment(pattern string) bool {
+	for _, segment := range strings.Split(filepath.ToSlash(pattern), "/") {
+		if segment == "**" {
+			return true
+		}
+	}
+	return false
+}
+
+// globRecursive is filepath.Glob with ** segments matching any number of
+// directories, including none. Hidden directories are not descended into.
+func globRecursive(pattern string) ([]string, error) {
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+	for _, segment := range segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	// The segments before the first ** are an ordinary glob for the roots
+	// to walk
+	first := 0
+	for segments[first] != "**" {
+		first++
+	}
+	root := strings.Join(segments[:first], "/")
+	switch {
+	case root == "" && first > 0:
+		root = "/"
+	case root == "":
+		root = "."
+	}
+	roots, err := filepath.Glob(filepath.FromSlash(root))
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	for _, r := range roots {
+		err := filepath.WalkDir(r, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if p == r {
+				return nil
+			}
+			if d.IsDir() && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			rel, err := filepath.Rel(r, p)
+			if err != nil {
+				return err
+			}
+			if matchSegments(segments[first:], strings.Split(filepath.ToSlash(rel), "/")) {
+				matches = append(matches, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return matches, nil
+}
+
+// matchSegments matches path segments against pattern segments, where **
+// matches any number of segments
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	// Patterns were validated by globRecursive
+	if ok, _ := path.Match(pattern[0], name[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], name[1:])
+}
+
+// Update brings the index up to date for the given paths, directories and
+// glob patterns. Only files whose mtime or size changed are re-chunked.
+func (ix *Index) Update(patterns []string) (*UpdateStats, error) {
+	files, err := ResolvePaths(patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	stats := &UpdateStats{}
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		if info.Size() > maxFileSize {
+			continue
+		}
+		stats.Files = append(stats.Files, path)
+
+		var mtime, size int64
+		err = ix.db.QueryRow("SELECT mtime, size FROM files WHERE path = ?", path).Scan(&mtime, &size)
+		if err == nil && mtime == info.ModTime().UnixNano() && size == info.Size() {
+			stats.Unchanged++
+			continue
+		}
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to check %s: %w", path, err)
+		}
+
+		if err := ix.indexFile(path, info); err != nil {
+			return nil, err
+		}
+		stats.Indexed++
+	}
+
+	removed, err := ix.pruneMissing()
+	if err != nil {
+		return nil, err
+	}
+	stats.Removed = removed
+
+	return stats, nil
+}
+
+// indexFile replaces the chunks and postings for one file
+func (ix *Index) indexFile(path string, info os.FileInfo) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	tx, err := ix.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin index transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM files WHERE path = ?", path); err != nil {
+		return fmt.Errorf("failed to clear %s: %w", path, err)
+	}
+	if _, err := tx.Exec("INSERT INTO files (path, mtime, size) VALUES (?, ?, ?)",
+		path, info.ModTime().UnixNano(), info.Size()); err != nil {
+		return fmt.Errorf("failed to record %s: %w", path, err)
+	}
+
+	for _, c := range chunkFile(path, string(data)) {
+		terms := tokenize(c.Content)
+		res, err := tx.Exec(
+			"INSERT INTO chunks (path, start_line, end_line, content, length) VALUES (?, ?, ?, ?, ?)",
+			path, c.StartLine, c.EndLine, c.Content, len(terms),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to store chunk of %s: %w", path, err)
+		}
+		chunkID, err := res.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get chunk ID: %w", err)
+		}
+
+		tf := make(map[string]int)
+		for _, term := range terms {
+			tf[term]++
+		}
+		for term, count := range tf {
+			if _, err := tx.Exec("INSERT INTO postings (term, chunk_id, tf) VALUES (?, ?, ?)",
+				term, chunkID, count); err != nil {
+				return fmt.Errorf("failed to store postings of %s: %w", path, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit index of %s: %w", path, err)
+	}
+	return nil
+}
+
+// pruneMissing drops files that no longer exist on disk
+func (ix *Index) pruneMissing() (int, error) {
+	rows, err := ix.db.Query("SELECT path FROM files")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list indexed files: %w", err)
+	}
+
+	var missing []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan indexed file: %w", err)
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			missing = append(missing, path)
+		}
+	}
+	rows.Close()
+
+	for _, path := range missing {
+		if _, err := ix.db.Exec("DELETE FROM files WHERE path = ?", path); err != nil {
+			return 0, fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+
+	return len(missing), nil
+}
+
+// Search returns the k chunks that best match query using BM25, limited to
+// chunks from the given files (all indexed files when files is empty)
+func (ix *Index) Search(query string, k int, files []string) ([]Chunk, error) {
+	terms := uniqueTerms(tokenize(query))
+	if len(terms) == 0 || k <= 0 {
+		return nil, nil
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	scope, args := scopeClause(files)
+
+	var n int
+	var avgLen float64
+	err := ix.db.QueryRow("SELECT COUNT(*), COALESCE(AVG(length), 0) FROM chunks WHERE 1=1"+scope, args...).Scan(&n, &avgLen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index statistics: %w", err)
+	}
+	if n == 0 {
+		return nil, nil
+	}
+
+	scores := make(map[int64]float64)
+	for _, term := range terms {
+		rows, err := ix.db.Query(
+			"SELECT p.chunk_id, p.tf, c.length FROM postings p JOIN chunks c ON c.id = p.chunk_id WHERE p.term = ?"+scope,
+			append([]interface{}{term}, args...)...,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search index: %w", err)
+		}
+
+		type posting struct {
+			chunkID int64
+			tf      int
+			length  int
+		}
+		var postings []posting
+		for rows.Next() {
+			var p posting
+			if err := rows.Scan(&p.chunkID, &p.tf, &p.length); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan posting: %w", err)
+			}
+			postings = append(postings, p)
+		}
+		rows.Close()
+
+		df := float64(len(postings))
+		idf := math.Log((float64(n)-df+0.5)/(df+0.5) + 1)
+		for _, p := range postings {
+			scores[p.chunkID] += bm25(float64(p.tf), float64(p.length), avgLen, idf)
+		}
+	}
+
+	ids := make([]int64, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	if len(ids) > k {
+		ids = ids[:k]
+	}
+
+	chunks := make([]Chunk, 0, len(ids))
+	for _, id := range ids {
+		c := Chunk{Score: scores[id]}
+		err := ix.db.QueryRow("SELECT path, start_line, end_line, content FROM chunks WHERE id = ?", id).
+			Scan(&c.Path, &c.StartLine, &c.EndLine, &c.Content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load chunk: %w", err)
+		}
+		chunks = append(chunks, c)
+	}
+
+	return chunks, nil
+}
+
+// bm25 scores one term occurrence in a chunk
+func bm25(tf, length, avgLen, idf float64) float64 {
+	if avgLen == 0 {
+		avgLen = 1
+	}
+	return idf * (tf * (bm25K1 + 1)) / (tf + bm25K1*(1-bm25B+bm25B*length/avgLen))
+}
+
+// scopeClause restricts a chunk query to a set of files
+func scopeClause(files []string) (string, []interface{}) {
+	if len(files) == 0 {
+		return "", nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(files)), ",")
+	args := make([]interface{}, len(files))
+	for i, f := range files {
+		args[i] = f
+	}
+	return " AND path IN (" + placeholders + ")", args
+}
+
+// uniqueTerms removes duplicate query terms
+func uniqueTerms(terms []string) []string {
+	seen := make(map[string]bool)
+	out := terms[:0]
+	for _, t := range terms {
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,180 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupIndex(t *testing.T) *Index {
+	ix, err := Open(filepath.Join(t.TempDir(), "index.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { ix.Close() })
+	return ix
+}
+
+func writeFile(t *testing.T, path, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestResolvePaths(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "docs", "a.md"), "# A")
+	writeFile(t, filepath.Join(dir, "docs", "b.txt"), "b")
+	writeFile(t, filepath.Join(dir, "docs", "image.png"), "png")
+	writeFile(t, filepath.Join(dir, "docs", ".git", "config.txt"), "hidden")
+	writeFile(t, filepath.Join(dir, "main.go"), "package main")
+
+	files, err := ResolvePaths([]string{filepath.Join(dir, "docs"), filepath.Join(dir, "*.go")})
+	require.NoError(t, err)
+
+	var names []string
+	for _, f := range files {
+		names = append(names, filepath.Base(f))
+	}
+	assert.ElementsMatch(t, []string{"a.md", "b.txt", "main.go"}, names)
+
+	_, err = ResolvePaths([]string{filepath.Join(dir, "missing")})
+	assert.Error(t, err)
+}
+
+func TestResolvePaths_Recursive(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "internal", "a.go"), "package a")
+	writeFile(t, filepath.Join(dir, "internal", "retry", "b.go"), "package retry")
+	writeFile(t, filepath.Join(dir, "internal", "retry", "policy", "c.go"), "package policy")
+	writeFile(t, filepath.Join(dir, "internal", "retry", "README.md"), "# Retry")
+	writeFile(t, filepath.Join(dir, "internal", ".cache", "d.go"), "package cache")
+
+	names := func(files []string) []string {
+		var out []string
+		for _, f := range files {
+			out = append(out, filepath.Base(f))
+		}
+		return out
+	}
+
+	files, err := ResolvePaths([]string{filepath.Join(dir, "internal", "**", "*.go")})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a.go", "b.go", "c.go"}, names(files))
+
+	files, err = ResolvePaths([]string{filepath.Join(dir, "internal", "**", "policy", "*.go")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c.go"}, names(files))
+
+	files, err = ResolvePaths([]string{filepath.Join(dir, "*", "**", "*.md")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"README.md"}, names(files))
+
+	_, err = ResolvePaths([]string{filepath.Join(dir, "**", "*.rs")})
+	assert.Error(t, err)
+	_, err = ResolvePaths([]string{filepath.Join(dir, "**", "[")})
+	assert.Error(t, err)
+}
+
+func TestIndex_UpdateIsIncremental(t *testing.T) {
+	ix := setupIndex(t)
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.md")
+	b := filepath.Join(dir, "b.md")
+	writeFile(t, a, "# Alpha\n\nThe retry policy uses exponential backoff.")
+	writeFile(t, b, "# Beta\n\nConnection pooling settings.")
+
+	stats, err := ix.Update([]string{dir})
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Indexed)
+	assert.Equal(t, 0, stats.Unchanged)
+	assert.Len(t, stats.Files, 2)
+
+	stats, err = ix.Update([]string{dir})
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.Indexed)
+	assert.Equal(t, 2, stats.Unchanged)
+
+	// Touch one file with new content and a new mtime
+	writeFile(t, a, "# Alpha\n\nThe retry policy now uses jittered backoff.")
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(a, future, future))
+
+	stats, err = ix.Update([]string{dir})
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Indexed)
+	assert.Equal(t, 1, stats.Unchanged)
+
+	chunks, err := ix.Search("jittered", 5, nil)
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+
+	chunks, err = ix.Search("exponential", 5, nil)
+	require.NoError(t, err)
+	assert.Empty(t, chunks, "stale chunks should be replaced")
+
+	// Deleted files are pruned
+	require.NoError(t, os.Remove(b))
+	stats, err = ix.Update([]string{a})
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Removed)
+
+	chunks, err = ix.Search("pooling", 5, nil)
+	require.NoError(t, err)
+	assert.Empty(t, chunks)
+}
+
+func TestIndex_SearchRanksByBM25(t *testing.T) {
+	ix := setupIndex(t)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "retry.md"), strings.Join([]string{
+		"# Networking",
+		"",
+		"General notes about HTTP clients.",
+		"",
+		"# Retry Policy",
+		"",
+		"Retries use exponential backoff. Each retry doubles the delay; retry at most five times.",
+	}, "\n"))
+	writeFile(t, filepath.Join(dir, "other.md"), "# Logging\n\nWe log one line per retry attempt.")
+
+	_, err := ix.Update([]string{dir})
+	require.NoError(t, err)
+
+	chunks, err := ix.Search("how does retry backoff work?", 2, nil)
+	require.NoError(t, err)
+	require.Len(t, chunks, 2)
+
+	top := chunks[0]
+	assert.Equal(t, "retry.md", filepath.Base(top.Path))
+	assert.Equal(t, 5, top.StartLine)
+	assert.Equal(t, 7, top.EndLine)
+	assert.Contains(t, top.Content, "exponential backoff")
+	assert.Greater(t, top.Score, chunks[1].Score)
+}
+
+func TestIndex_SearchScope(t *testing.T) {
+	ix := setupIndex(t)
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	writeFile(t, a, "shared keyword alpha")
+	writeFile(t, b, "shared keyword beta")
+
+	_, err := ix.Update([]string{dir})
+	require.NoError(t, err)
+
+	absB, err := filepath.Abs(b)
+	require.NoError(t, err)
+
+	chunks, err := ix.Search("keyword", 10, []string{absB})
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+	assert.Equal(t, absB, chunks[0].Path)
+
+	chunks, err = ix.Search("the and of", 10, nil)
+	require.NoError(t, err)
+	assert.Empty(t, chunks, "stop words alone should not match")
+}
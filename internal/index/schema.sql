@@ -0,0 +1,36 @@
+-- Indexed Files Table
+-- Tracks which files are indexed and the mtime/size they were indexed at
+CREATE TABLE IF NOT EXISTS files (
+    path TEXT PRIMARY KEY,
+    mtime INTEGER NOT NULL,
+    size INTEGER NOT NULL,
+    indexed_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Chunks Table
+-- Line ranges of indexed files, the unit of retrieval
+CREATE TABLE IF NOT EXISTS chunks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    path TEXT NOT NULL,
+    start_line INTEGER NOT NULL,
+    end_line INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    length INTEGER NOT NULL,
+    FOREIGN KEY (path) REFERENCES files(path) ON DELETE CASCADE
+);
+
+-- Index for removing a file's chunks
+CREATE INDEX IF NOT EXISTS idx_chunks_path ON chunks(path);
+
+-- Postings Table
+-- Term frequencies per chunk, used for BM25 scoring
+CREATE TABLE IF NOT EXISTS postings (
+    term TEXT NOT NULL,
+    chunk_id INTEGER NOT NULL,
+    tf INTEGER NOT NULL,
+    PRIMARY KEY (term, chunk_id),
+    FOREIGN KEY (chunk_id) REFERENCES chunks(id) ON DELETE CASCADE
+) WITHOUT ROWID;
+
+-- Index for removing a chunk's postings
+CREATE INDEX IF NOT EXISTS idx_postings_chunk ON postings(chunk_id);
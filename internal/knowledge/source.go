@@ -0,0 +1,31 @@
+package knowledge
+
+import "github.com/joelklabo/copilot-research/internal/research"
+
+// SynthesisSource exposes the knowledge base to research synthesis mode
+type SynthesisSource struct {
+	km KnowledgeManagerInterface
+}
+
+// NewSynthesisSource wraps a knowledge manager as a research.KnowledgeSource
+func NewSynthesisSource(km KnowledgeManagerInterface) *SynthesisSource {
+	return &SynthesisSource{km: km}
+}
+
+// Search returns the knowledge entries matching term
+func (s *SynthesisSource) Search(term string) ([]research.KnowledgeNote, error) {
+	entries, err := s.km.Search(term)
+	if err != nil {
+		return nil, err
+	}
+
+	notes := make([]research.KnowledgeNote, 0, len(entries))
+	for _, k := range entries {
+		notes = append(notes, research.KnowledgeNote{
+			Topic:   k.Topic,
+			Content: k.Content,
+			Tags:    k.Tags,
+		})
+	}
+	return notes, nil
+}
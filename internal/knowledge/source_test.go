@@ -0,0 +1,37 @@
+package knowledge
+
+import (
+	"testing"
+
+	"github.com/joelklabo/copilot-research/internal/research"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSynthesisSource_Search(t *testing.T) {
+	km, err := NewKnowledgeManager(t.TempDir())
+	require.NoError(t, err)
+
+	require.NoError(t, km.Add(&Knowledge{
+		Topic:   "swift-concurrency",
+		Content: "Actors isolate mutable state",
+		Source:  "test",
+		Tags:    []string{"swift"},
+	}))
+	require.NoError(t, km.Add(&Knowledge{
+		Topic:   "go-modules",
+		Content: "Use go mod tidy",
+		Source:  "test",
+	}))
+
+	var source research.KnowledgeSource = NewSynthesisSource(km)
+	notes, err := source.Search("actors")
+	require.NoError(t, err)
+
+	require.Len(t, notes, 1)
+	assert.Equal(t, research.KnowledgeNote{
+		Topic:   "swift-concurrency",
+		Content: "Actors isolate mutable state",
+		Tags:    []string{"swift"},
+	}, notes[0])
+}
@@ -212,6 +212,24 @@ func (pm *ProviderManager) Query(ctx context.Context, prompt string, opts QueryO
 	return nil, fmt.Errorf("all providers failed: primary=%s, fallback=%s", pm.primary, pm.fallback)
 }
 
+// QueryProvider queries a specific provider without falling back. An empty
+// name uses the normal primary/fallback selection.
+func (pm *ProviderManager) QueryProvider(ctx context.Context, name, prompt string, opts QueryOptions) (*Response, error) {
+	if name == "" {
+		return pm.Query(ctx, prompt, opts)
+	}
+	
+	provider, err := pm.factory.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if !provider.IsAuthenticated() {
+		return nil, fmt.Errorf("provider '%s' is not authenticated", name)
+	}
+	
+	return provider.Query(ctx, prompt, opts)
+}
+
 // CheckAuthentication returns lists of authenticated and unauthenticated providers
 func (pm *ProviderManager) CheckAuthentication() (authenticated []string, unauthenticated []string) {
 	authenticated = make([]string, 0)
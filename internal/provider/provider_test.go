@@ -256,6 +256,30 @@ func TestProviderManager_PrimarySuccess(t *testing.T) {
 	assert.Equal(t, "primary", resp.Provider)
 }
 
+func TestProviderManager_QueryProvider(t *testing.T) {
+	factory := NewProviderFactory()
+	require.NoError(t, factory.Register("primary", &MockProvider{name: "primary", authenticated: true}))
+	require.NoError(t, factory.Register("other", &MockProvider{name: "other", authenticated: true}))
+	require.NoError(t, factory.Register("unauth", &MockProvider{name: "unauth", authenticated: false}))
+	
+	manager := NewProviderManager(factory, "primary", "", false, false)
+	ctx := context.Background()
+	
+	resp, err := manager.QueryProvider(ctx, "other", "test", QueryOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "other", resp.Provider)
+	
+	resp, err = manager.QueryProvider(ctx, "", "test", QueryOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "primary", resp.Provider, "empty name uses the primary provider")
+	
+	_, err = manager.QueryProvider(ctx, "unauth", "test", QueryOptions{})
+	assert.ErrorContains(t, err, "not authenticated")
+	
+	_, err = manager.QueryProvider(ctx, "missing", "test", QueryOptions{})
+	assert.Error(t, err)
+}
+
 // Test CheckAuthentication
 func TestProviderManager_CheckAuthentication(t *testing.T) {
 	factory := NewProviderFactory()
@@ -0,0 +1,96 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AsciiDoc renders the answer as an AsciiDoc document
+type AsciiDoc struct{}
+
+var asciidocInline = inlineFormat{
+	text:     func(s string) string { return s },
+	code:     func(s string) string { return "`+" + s + "+`" },
+	strong:   func(s string) string { return "*" + s + "*" },
+	emphasis: func(s string) string { return "_" + s + "_" },
+	link:     func(text, url string) string { return url + "[" + text + "]" },
+}
+
+// Name returns "asciidoc"
+func (AsciiDoc) Name() string { return "asciidoc" }
+
+// Extensions returns the AsciiDoc file extensions
+func (AsciiDoc) Extensions() []string { return []string{".adoc", ".asciidoc", ".asc"} }
+
+// Render converts the answer to AsciiDoc, with the metadata as document
+// attributes
+func (AsciiDoc) Render(doc *Document) (string, error) {
+	docTitle, blocks := title(doc, parseBlocks(doc.Content))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "= %s\n", asciidocInline.render(docTitle))
+	if !doc.CreatedAt.IsZero() {
+		fmt.Fprintf(&b, ":revdate: %s\n", doc.CreatedAt.Format("2006-01-02"))
+	}
+	for _, m := range metadata(doc) {
+		if m[0] == "Date" {
+			continue
+		}
+		fmt.Fprintf(&b, ":%s: %s\n", strings.ReplaceAll(strings.ToLower(m[0]), " ", "-"), m[1])
+	}
+
+	for _, blk := range blocks {
+		b.WriteString("\n")
+		writeAsciiDocBlock(&b, blk)
+	}
+
+	if len(doc.Citations) > 0 {
+		b.WriteString("\n== Sources\n\n")
+		for _, c := range doc.Citations {
+			if c.URL != "" {
+				fmt.Fprintf(&b, "* %s[%s]\n", c.URL, citationLabel(c))
+			} else {
+				fmt.Fprintf(&b, "* %s\n", citationLabel(c))
+			}
+		}
+	}
+
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// writeAsciiDocBlock writes one markdown block as AsciiDoc
+func writeAsciiDocBlock(b *strings.Builder, blk block) {
+	switch blk.kind {
+	case blockHeading:
+		// Level 0 (=) is the document title
+		fmt.Fprintf(b, "%s %s\n", strings.Repeat("=", blk.level+1), asciidocInline.render(blk.text))
+	case blockParagraph:
+		fmt.Fprintf(b, "%s\n", asciidocInline.render(blk.text))
+	case blockCode:
+		if blk.lang != "" {
+			fmt.Fprintf(b, "[source,%s]\n", blk.lang)
+		}
+		fmt.Fprintf(b, "----\n%s\n----\n", blk.text)
+	case blockList:
+		marker := "*"
+		if blk.ordered {
+			marker = "."
+		}
+		for _, item := range blk.items {
+			fmt.Fprintf(b, "%s %s\n", marker, asciidocInline.render(item))
+		}
+	case blockQuote:
+		fmt.Fprintf(b, "____\n%s\n____\n", asciidocInline.render(blk.text))
+	case blockTable:
+		fmt.Fprintf(b, "[options=\"header\"]\n|===\n")
+		for _, row := range blk.rows {
+			for _, c := range row {
+				fmt.Fprintf(b, "| %s ", strings.ReplaceAll(asciidocInline.render(c), "|", "\\|"))
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("|===\n")
+	case blockRule:
+		b.WriteString("'''\n")
+	}
+}
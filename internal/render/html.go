@@ -0,0 +1,155 @@
+package render
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+)
+
+// HTML renders a standalone page with embedded CSS
+type HTML struct{}
+
+// htmlStyle is embedded in every page so it renders the same offline
+const htmlStyle = `body { max-width: 52rem; margin: 2rem auto; padding: 0 1rem; font: 16px/1.6 -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; color: #1f2328; }
+h1, h2, h3, h4 { line-height: 1.25; margin-top: 1.6em; }
+h1 { border-bottom: 1px solid #d0d7de; padding-bottom: .3em; }
+a { color: #0969da; }
+code { font: .9em ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; background: #f6f8fa; padding: .15em .35em; border-radius: 4px; }
+pre { background: #f6f8fa; padding: 1em; overflow-x: auto; border-radius: 6px; }
+pre code { background: none; padding: 0; }
+blockquote { margin: 0; padding: 0 1em; color: #59636e; border-left: .25em solid #d0d7de; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #d0d7de; padding: .4em .8em; }
+th { background: #f6f8fa; }
+hr { border: 0; border-top: 1px solid #d0d7de; }
+.meta { color: #59636e; font-size: .9em; }
+.meta dt { font-weight: 600; float: left; clear: left; width: 8em; }
+.meta dd { margin-left: 8em; }
+.flagged { color: #cf222e; }`
+
+var htmlInline = inlineFormat{
+	text:     html.EscapeString,
+	code:     func(s string) string { return "<code>" + html.EscapeString(s) + "</code>" },
+	strong:   func(s string) string { return "<strong>" + s + "</strong>" },
+	emphasis: func(s string) string { return "<em>" + s + "</em>" },
+	link: func(text, url string) string {
+		if !safeHref(url) {
+			return text
+		}
+		return fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(url), text)
+	},
+}
+
+// safeHrefSchemes are the URL schemes allowed in links; relative URLs are
+// allowed too
+var safeHrefSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+// safeHref reports whether a URL is safe to link to. Answers come from a
+// model, so a javascript: or data: link must not become clickable.
+func safeHref(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return u.Scheme == "" || safeHrefSchemes[strings.ToLower(u.Scheme)]
+}
+
+// Name returns "html"
+func (HTML) Name() string { return "html" }
+
+// Extensions returns the HTML file extensions
+func (HTML) Extensions() []string { return []string{".html", ".htm"} }
+
+// Render converts the answer to a complete HTML page
+func (HTML) Render(doc *Document) (string, error) {
+	docTitle, blocks := title(doc, parseBlocks(doc.Content))
+
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	b.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	fmt.Fprintf(&b, "<title>%s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n", html.EscapeString(docTitle), htmlStyle)
+	fmt.Fprintf(&b, "<h1>%s</h1>\n", htmlInline.render(docTitle))
+
+	if meta := metadata(doc); len(meta) > 0 {
+		b.WriteString("<dl class=\"meta\">\n")
+		for _, m := range meta {
+			fmt.Fprintf(&b, "<dt>%s</dt><dd>%s</dd>\n", m[0], html.EscapeString(m[1]))
+		}
+		b.WriteString("</dl>\n")
+	}
+
+	for _, blk := range blocks {
+		writeHTMLBlock(&b, blk)
+	}
+
+	if len(doc.Citations) > 0 {
+		b.WriteString("<h2>Sources</h2>\n<ul class=\"sources\">\n")
+		for _, c := range doc.Citations {
+			b.WriteString(htmlCitation(c))
+		}
+		b.WriteString("</ul>\n")
+	}
+
+	b.WriteString("</body>\n</html>")
+	return b.String(), nil
+}
+
+// writeHTMLBlock writes one markdown block as HTML
+func writeHTMLBlock(b *strings.Builder, blk block) {
+	switch blk.kind {
+	case blockHeading:
+		fmt.Fprintf(b, "<h%d>%s</h%d>\n", blk.level, htmlInline.render(blk.text), blk.level)
+	case blockParagraph:
+		fmt.Fprintf(b, "<p>%s</p>\n", htmlInline.render(blk.text))
+	case blockCode:
+		class := ""
+		if blk.lang != "" {
+			class = fmt.Sprintf(` class="language-%s"`, html.EscapeString(blk.lang))
+		}
+		fmt.Fprintf(b, "<pre><code%s>%s</code></pre>\n", class, html.EscapeString(blk.text))
+	case blockList:
+		tag := "ul"
+		if blk.ordered {
+			tag = "ol"
+		}
+		fmt.Fprintf(b, "<%s>\n", tag)
+		for _, item := range blk.items {
+			fmt.Fprintf(b, "<li>%s</li>\n", htmlInline.render(item))
+		}
+		fmt.Fprintf(b, "</%s>\n", tag)
+	case blockQuote:
+		fmt.Fprintf(b, "<blockquote><p>%s</p></blockquote>\n", htmlInline.render(blk.text))
+	case blockTable:
+		b.WriteString("<table>\n")
+		for i, row := range blk.rows {
+			cell := "td"
+			if i == 0 {
+				cell = "th"
+			}
+			b.WriteString("<tr>")
+			for _, c := range row {
+				fmt.Fprintf(b, "<%s>%s</%s>", cell, htmlInline.render(c), cell)
+			}
+			b.WriteString("</tr>\n")
+		}
+		b.WriteString("</table>\n")
+	case blockRule:
+		b.WriteString("<hr>\n")
+	}
+}
+
+// htmlCitation renders a citation as a list item, linked when it has a
+// safe URL
+func htmlCitation(c db.Citation) string {
+	label := html.EscapeString(citationLabel(c))
+	if c.URL != "" && safeHref(c.URL) {
+		label = fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(c.URL), label)
+	}
+	if c.Flagged() {
+		return fmt.Sprintf("<li class=\"flagged\">%s</li>\n", label)
+	}
+	return fmt.Sprintf("<li>%s</li>\n", label)
+}
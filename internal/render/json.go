@@ -0,0 +1,77 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+)
+
+// JSON renders the answer in an envelope with the run's metadata
+type JSON struct{}
+
+// envelope is the JSON output format
+type envelope struct {
+	Query        string               `json:"query"`
+	RefinedQuery string               `json:"refined_query,omitempty"`
+	Mode         string               `json:"mode"`
+	Provider     string               `json:"provider,omitempty"`
+	Model        string               `json:"model,omitempty"`
+	Tokens       tokens               `json:"tokens"`
+	DurationMS   int64                `json:"duration_ms"`
+	SessionID    int64                `json:"session_id,omitempty"`
+	CreatedAt    string               `json:"created_at,omitempty"`
+	QualityScore *int                 `json:"quality_score,omitempty"`
+	Citations    []db.Citation        `json:"citations"`
+	Matrix       *db.ComparisonMatrix `json:"matrix,omitempty"`
+	Format       string               `json:"format"` // Format of content
+	Content      string               `json:"content"`
+}
+
+type tokens struct {
+	Prompt     int `json:"prompt"`
+	Completion int `json:"completion"`
+	Total      int `json:"total"`
+}
+
+// Name returns "json"
+func (JSON) Name() string { return "json" }
+
+// Extensions returns the JSON file extension
+func (JSON) Extensions() []string { return []string{".json"} }
+
+// Render encodes the document as an indented JSON envelope
+func (JSON) Render(doc *Document) (string, error) {
+	out := envelope{
+		Query:        doc.Query,
+		RefinedQuery: doc.RefinedQuery,
+		Mode:         doc.Mode,
+		Provider:     doc.Provider,
+		Model:        doc.Model,
+		Tokens: tokens{
+			Prompt:     doc.Tokens.Prompt,
+			Completion: doc.Tokens.Completion,
+			Total:      doc.Tokens.Total,
+		},
+		DurationMS:   doc.Duration.Milliseconds(),
+		SessionID:    doc.SessionID,
+		QualityScore: doc.QualityScore,
+		Citations:    doc.Citations,
+		Matrix:       doc.Matrix,
+		Format:       "markdown",
+		Content:      doc.Content,
+	}
+	if !doc.CreatedAt.IsZero() {
+		out.CreatedAt = doc.CreatedAt.Format(time.RFC3339)
+	}
+	if out.Citations == nil {
+		out.Citations = []db.Citation{}
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return string(data), nil
+}
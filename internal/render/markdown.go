@@ -0,0 +1,258 @@
+package render
+
+import (
+	"regexp"
+	"strings"
+)
+
+// blockKind identifies a markdown block
+type blockKind int
+
+const (
+	blockParagraph blockKind = iota
+	blockHeading
+	blockCode
+	blockList
+	blockQuote
+	blockTable
+	blockRule
+)
+
+// block is a markdown block. Renderers for other markup walk the blocks of
+// an answer rather than converting markdown text directly.
+type block struct {
+	kind    blockKind
+	level   int        // Heading level
+	text    string     // Paragraph, heading or quote text; code body
+	lang    string     // Code language
+	ordered bool       // Whether a list is numbered
+	items   []string   // List items
+	rows    [][]string // Table rows, the first being the header
+}
+
+var (
+	mdHeading  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdListItem = regexp.MustCompile(`^\s*([-*+]|\d+[.)])\s+(.*)$`)
+	mdRule     = regexp.MustCompile(`^\s*(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	mdTableSep = regexp.MustCompile(`^\s*\|?\s*:?-{3,}:?\s*(?:\|\s*:?-{3,}:?\s*)*\|?\s*$`)
+	mdQuote    = regexp.MustCompile(`^\s*> ?`)
+)
+
+// parseBlocks splits markdown into blocks. It covers what research answers
+// use: headings, paragraphs, fenced code, flat lists, quotes, tables and
+// rules. Nested lists are flattened.
+func parseBlocks(markdown string) []block {
+	lines := strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n")
+
+	var blocks []block
+	var para []string
+	flush := func() {
+		if len(para) > 0 {
+			blocks = append(blocks, block{kind: blockParagraph, text: strings.Join(para, "\n")})
+			para = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flush()
+
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			flush()
+			fence := trimmed[:3]
+			lang := strings.TrimSpace(strings.TrimLeft(trimmed, fence[:1]))
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			blocks = append(blocks, block{kind: blockCode, lang: lang, text: strings.Join(code, "\n")})
+
+		case mdHeading.MatchString(line):
+			flush()
+			m := mdHeading.FindStringSubmatch(line)
+			blocks = append(blocks, block{kind: blockHeading, level: len(m[1]), text: m[2]})
+
+		case mdRule.MatchString(line):
+			flush()
+			blocks = append(blocks, block{kind: blockRule})
+
+		case mdListItem.MatchString(line):
+			flush()
+			m := mdListItem.FindStringSubmatch(line)
+			list := block{kind: blockList, ordered: m[1][0] >= '0' && m[1][0] <= '9', items: []string{m[2]}}
+			for i+1 < len(lines) {
+				next := lines[i+1]
+				if m := mdListItem.FindStringSubmatch(next); m != nil {
+					list.items = append(list.items, m[2])
+				} else if strings.TrimSpace(next) != "" && (strings.HasPrefix(next, " ") || strings.HasPrefix(next, "\t")) {
+					// An indented line continues the item
+					list.items[len(list.items)-1] += " " + strings.TrimSpace(next)
+				} else {
+					break
+				}
+				i++
+			}
+			blocks = append(blocks, list)
+
+		case strings.Contains(trimmed, "|") && i+1 < len(lines) && mdTableSep.MatchString(lines[i+1]):
+			flush()
+			table := block{kind: blockTable, rows: [][]string{splitRow(trimmed)}}
+			for i += 2; i < len(lines) && strings.Contains(lines[i], "|") && strings.TrimSpace(lines[i]) != ""; i++ {
+				table.rows = append(table.rows, splitRow(lines[i]))
+			}
+			i--
+			blocks = append(blocks, table)
+
+		case mdQuote.MatchString(line):
+			flush()
+			var quote []string
+			for ; i < len(lines) && mdQuote.MatchString(lines[i]); i++ {
+				quote = append(quote, mdQuote.ReplaceAllString(lines[i], ""))
+			}
+			i--
+			blocks = append(blocks, block{kind: blockQuote, text: strings.Join(quote, "\n")})
+
+		default:
+			para = append(para, trimmed)
+		}
+	}
+	flush()
+
+	return blocks
+}
+
+// splitRow splits a markdown table row into trimmed cells
+func splitRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	line = strings.TrimSuffix(line, "|")
+	cells := strings.Split(line, "|")
+	for i, c := range cells {
+		cells[i] = strings.TrimSpace(c)
+	}
+	return cells
+}
+
+// inlineKind identifies a span of inline markdown
+type inlineKind int
+
+const (
+	inlineText inlineKind = iota
+	inlineCode
+	inlineStrong
+	inlineEmphasis
+	inlineLink
+)
+
+// inline is a span of inline markdown. Strong, emphasis and link spans
+// hold their content in children.
+type inline struct {
+	kind     inlineKind
+	text     string
+	url      string
+	children []inline
+}
+
+// parseInline splits a line of markdown into code, strong, emphasis, link
+// and plain text spans. Unmatched markers are kept as text.
+func parseInline(s string) []inline {
+	var spans []inline
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			spans = append(spans, inline{kind: inlineText, text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end >= 0 {
+				flush()
+				spans = append(spans, inline{kind: inlineCode, text: s[i+1 : i+1+end]})
+				i += end + 2
+				continue
+			}
+
+		case strings.HasPrefix(s[i:], "**") || strings.HasPrefix(s[i:], "__"):
+			marker := s[i : i+2]
+			if end := strings.Index(s[i+2:], marker); end > 0 {
+				flush()
+				spans = append(spans, inline{kind: inlineStrong, children: parseInline(s[i+2 : i+2+end])})
+				i += end + 4
+				continue
+			}
+
+		case (s[i] == '*' || s[i] == '_') && i+1 < len(s) && s[i+1] != ' ' && (s[i] == '*' || i == 0 || !isWordByte(s[i-1])):
+			if end := strings.IndexByte(s[i+1:], s[i]); end > 0 {
+				flush()
+				spans = append(spans, inline{kind: inlineEmphasis, children: parseInline(s[i+1 : i+1+end])})
+				i += end + 2
+				continue
+			}
+
+		case s[i] == '[':
+			if close := strings.Index(s[i:], "]("); close > 0 {
+				if end := strings.IndexByte(s[i+close+2:], ')'); end >= 0 {
+					flush()
+					spans = append(spans, inline{
+						kind:     inlineLink,
+						url:      s[i+close+2 : i+close+2+end],
+						children: parseInline(s[i+1 : i+close]),
+					})
+					i += close + end + 3
+					continue
+				}
+			}
+		}
+
+		text.WriteByte(s[i])
+		i++
+	}
+	flush()
+
+	return spans
+}
+
+// isWordByte reports whether b is an ASCII letter or digit
+func isWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+// inlineFormat converts inline spans to a target markup
+type inlineFormat struct {
+	text     func(string) string
+	code     func(string) string
+	strong   func(string) string
+	emphasis func(string) string
+	link     func(text, url string) string
+}
+
+// render converts a line of markdown with the format
+func (f inlineFormat) render(s string) string {
+	return f.spans(parseInline(s))
+}
+
+func (f inlineFormat) spans(spans []inline) string {
+	var b strings.Builder
+	for _, span := range spans {
+		switch span.kind {
+		case inlineText:
+			b.WriteString(f.text(span.text))
+		case inlineCode:
+			b.WriteString(f.code(span.text))
+		case inlineStrong:
+			b.WriteString(f.strong(f.spans(span.children)))
+		case inlineEmphasis:
+			b.WriteString(f.emphasis(f.spans(span.children)))
+		case inlineLink:
+			b.WriteString(f.link(f.spans(span.children), span.url))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,48 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBlocks(t *testing.T) {
+	markdown := "# Title\n\nFirst line\nsecond line.\n\n" +
+		"- one\n- two\n  continued\n\n" +
+		"1. first\n2. second\n\n" +
+		"```go\nfunc main() {}\n# not a heading\n```\n\n" +
+		"> quoted\n> text\n\n" +
+		"| A | B |\n|---|:---:|\n| 1 | 2 |\n\n" +
+		"---\n\n## Next"
+
+	blocks := parseBlocks(markdown)
+	require.Len(t, blocks, 9)
+
+	assert.Equal(t, block{kind: blockHeading, level: 1, text: "Title"}, blocks[0])
+	assert.Equal(t, block{kind: blockParagraph, text: "First line\nsecond line."}, blocks[1])
+	assert.Equal(t, block{kind: blockList, items: []string{"one", "two continued"}}, blocks[2])
+	assert.Equal(t, block{kind: blockList, ordered: true, items: []string{"first", "second"}}, blocks[3])
+	assert.Equal(t, block{kind: blockCode, lang: "go", text: "func main() {}\n# not a heading"}, blocks[4])
+	assert.Equal(t, block{kind: blockQuote, text: "quoted\ntext"}, blocks[5])
+	assert.Equal(t, block{kind: blockTable, rows: [][]string{{"A", "B"}, {"1", "2"}}}, blocks[6])
+	assert.Equal(t, block{kind: blockRule}, blocks[7])
+	assert.Equal(t, block{kind: blockHeading, level: 2, text: "Next"}, blocks[8])
+}
+
+func TestParseInline(t *testing.T) {
+	spans := parseInline("Use `go vet` and **read [the docs](https://go.dev)**, *carefully*; snake_case_names stay")
+
+	require.Len(t, spans, 7)
+	assert.Equal(t, inline{kind: inlineText, text: "Use "}, spans[0])
+	assert.Equal(t, inline{kind: inlineCode, text: "go vet"}, spans[1])
+	assert.Equal(t, inlineStrong, spans[3].kind)
+	assert.Equal(t, inlineLink, spans[3].children[1].kind)
+	assert.Equal(t, "https://go.dev", spans[3].children[1].url)
+	assert.Equal(t, inlineEmphasis, spans[5].kind)
+	assert.Equal(t, inline{kind: inlineText, text: "; snake_case_names stay"}, spans[6])
+}
+
+func TestParseInline_UnmatchedMarkers(t *testing.T) {
+	assert.Equal(t, []inline{{kind: inlineText, text: "2 * 3 and `open"}}, parseInline("2 * 3 and `open"))
+}
@@ -0,0 +1,98 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Org renders the answer as an Org-mode document
+type Org struct{}
+
+var orgInline = inlineFormat{
+	text:     func(s string) string { return s },
+	code:     func(s string) string { return "~" + s + "~" },
+	strong:   func(s string) string { return "*" + s + "*" },
+	emphasis: func(s string) string { return "/" + s + "/" },
+	link:     func(text, url string) string { return "[[" + url + "][" + text + "]]" },
+}
+
+// Name returns "org"
+func (Org) Name() string { return "org" }
+
+// Extensions returns the Org-mode file extension
+func (Org) Extensions() []string { return []string{".org"} }
+
+// Render converts the answer to Org-mode, with the metadata as in-buffer
+// keywords
+func (Org) Render(doc *Document) (string, error) {
+	docTitle, blocks := title(doc, parseBlocks(doc.Content))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "#+TITLE: %s\n", docTitle)
+	if !doc.CreatedAt.IsZero() {
+		fmt.Fprintf(&b, "#+DATE: %s\n", doc.CreatedAt.Format("2006-01-02"))
+	}
+	for _, m := range metadata(doc) {
+		if m[0] == "Date" {
+			continue
+		}
+		fmt.Fprintf(&b, "#+%s: %s\n", strings.ReplaceAll(strings.ToUpper(m[0]), " ", "_"), m[1])
+	}
+
+	for _, blk := range blocks {
+		b.WriteString("\n")
+		writeOrgBlock(&b, blk)
+	}
+
+	if len(doc.Citations) > 0 {
+		b.WriteString("\n* Sources\n\n")
+		for _, c := range doc.Citations {
+			if c.URL != "" {
+				fmt.Fprintf(&b, "- [[%s][%s]]\n", c.URL, citationLabel(c))
+			} else {
+				fmt.Fprintf(&b, "- %s\n", citationLabel(c))
+			}
+		}
+	}
+
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// writeOrgBlock writes one markdown block as Org-mode
+func writeOrgBlock(b *strings.Builder, blk block) {
+	switch blk.kind {
+	case blockHeading:
+		fmt.Fprintf(b, "%s %s\n", strings.Repeat("*", blk.level), orgInline.render(blk.text))
+	case blockParagraph:
+		fmt.Fprintf(b, "%s\n", orgInline.render(blk.text))
+	case blockCode:
+		fmt.Fprintf(b, "#+BEGIN_SRC %s\n%s\n#+END_SRC\n", blk.lang, blk.text)
+	case blockList:
+		for i, item := range blk.items {
+			marker := "-"
+			if blk.ordered {
+				marker = fmt.Sprintf("%d.", i+1)
+			}
+			fmt.Fprintf(b, "%s %s\n", marker, orgInline.render(item))
+		}
+	case blockQuote:
+		fmt.Fprintf(b, "#+BEGIN_QUOTE\n%s\n#+END_QUOTE\n", orgInline.render(blk.text))
+	case blockTable:
+		for i, row := range blk.rows {
+			cells := make([]string, len(row))
+			for j, c := range row {
+				cells[j] = orgInline.render(c)
+			}
+			fmt.Fprintf(b, "| %s |\n", strings.Join(cells, " | "))
+			if i == 0 {
+				seps := make([]string, len(row))
+				for j := range seps {
+					seps[j] = "---"
+				}
+				fmt.Fprintf(b, "|%s|\n", strings.Join(seps, "+"))
+			}
+		}
+	case blockRule:
+		b.WriteString("-----\n")
+	}
+}
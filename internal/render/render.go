@@ -0,0 +1,222 @@
+// Package render turns research results into output documents: markdown,
+// a JSON envelope, standalone HTML, AsciiDoc, Org-mode and text/template
+// report templates such as an ADR skeleton.
+package render
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/joelklabo/copilot-research/internal/provider"
+	"github.com/joelklabo/copilot-research/internal/research"
+)
+
+// Document is a research result with the metadata renderers may include
+type Document struct {
+	Query        string
+	RefinedQuery string
+	Mode         string
+	Content      string // The answer as markdown
+	Provider     string
+	Model        string
+	Tokens       provider.TokenUsage
+	Duration     time.Duration
+	SessionID    int64 // 0 if the result was not stored
+	Citations    []db.Citation
+	Matrix       *db.ComparisonMatrix
+	QualityScore *int
+	CreatedAt    time.Time
+
+	// For report templates; either may be nil
+	Result  *research.ResearchResult
+	Session *db.ResearchSession
+}
+
+// Renderer formats a document
+type Renderer interface {
+	// Name is the value selecting the renderer with --format
+	Name() string
+	// Extensions lists the output file extensions the renderer is inferred
+	// from, with the leading dot
+	Extensions() []string
+	Render(doc *Document) (string, error)
+}
+
+// Registry holds the available renderers
+type Registry struct {
+	renderers map[string]Renderer
+	mu        sync.RWMutex
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		renderers: make(map[string]Renderer),
+	}
+}
+
+// DefaultRegistry returns a registry with the built-in renderers
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+	for _, renderer := range []Renderer{Markdown{}, JSON{}, HTML{}, AsciiDoc{}, Org{}} {
+		// The built-in names are distinct
+		_ = r.Register(renderer)
+	}
+	return r
+}
+
+// Register adds a renderer to the registry
+func (r *Registry) Register(renderer Renderer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := renderer.Name()
+	if _, exists := r.renderers[name]; exists {
+		return fmt.Errorf("renderer '%s' is already registered", name)
+	}
+
+	r.renderers[name] = renderer
+	return nil
+}
+
+// Get retrieves a renderer by name
+func (r *Registry) Get(name string) (Renderer, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	renderer, exists := r.renderers[strings.ToLower(name)]
+	if !exists {
+		return nil, fmt.Errorf("unknown format '%s' (available: %s)", name, strings.Join(r.namesLocked(), ", "))
+	}
+
+	return renderer, nil
+}
+
+// ForPath returns the renderer for an output file's extension
+func (r *Registry) ForPath(path string) (Renderer, bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == "" {
+		return nil, false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, name := range r.namesLocked() {
+		for _, e := range r.renderers[name].Extensions() {
+			if e == ext {
+				return r.renderers[name], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// List returns the registered renderer names, sorted
+func (r *Registry) List() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.namesLocked()
+}
+
+func (r *Registry) namesLocked() []string {
+	names := make([]string, 0, len(r.renderers))
+	for name := range r.renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve picks a renderer: an explicit format wins, then the output file
+// extension, then --json, then markdown
+func (r *Registry) Resolve(format, outputFile string, jsonOutput bool) (Renderer, error) {
+	if format != "" {
+		return r.Get(format)
+	}
+	if renderer, ok := r.ForPath(outputFile); ok {
+		return renderer, nil
+	}
+	if jsonOutput {
+		return r.Get("json")
+	}
+	return r.Get("markdown")
+}
+
+// Markdown renders the answer as written
+type Markdown struct{}
+
+// Name returns "markdown"
+func (Markdown) Name() string { return "markdown" }
+
+// Extensions returns the markdown file extensions
+func (Markdown) Extensions() []string { return []string{".md", ".markdown"} }
+
+// Render returns the content unchanged
+func (Markdown) Render(doc *Document) (string, error) {
+	return doc.Content, nil
+}
+
+// title returns the document title and the blocks after it: a leading
+// level-1 heading if there is one, otherwise the query
+func title(doc *Document, blocks []block) (string, []block) {
+	if len(blocks) > 0 && blocks[0].kind == blockHeading && blocks[0].level == 1 {
+		return blocks[0].text, blocks[1:]
+	}
+	return doc.Query, blocks
+}
+
+// metadata lists the document's metadata as label/value pairs, skipping
+// unknown values
+func metadata(doc *Document) [][2]string {
+	var meta [][2]string
+	add := func(label, value string) {
+		if value != "" {
+			meta = append(meta, [2]string{label, value})
+		}
+	}
+
+	add("Query", doc.Query)
+	add("Refined query", doc.RefinedQuery)
+	add("Mode", doc.Mode)
+	add("Provider", doc.Provider)
+	add("Model", doc.Model)
+	if doc.Tokens.Total > 0 {
+		add("Tokens", fmt.Sprint(doc.Tokens.Total))
+	}
+	if doc.Duration > 0 {
+		add("Duration", doc.Duration.Round(100*time.Millisecond).String())
+	}
+	if doc.QualityScore != nil {
+		add("Quality", fmt.Sprintf("%d/100", *doc.QualityScore))
+	}
+	if doc.SessionID > 0 {
+		add("Session", fmt.Sprintf("#%d", doc.SessionID))
+	}
+	if !doc.CreatedAt.IsZero() {
+		add("Date", doc.CreatedAt.Format("2006-01-02 15:04"))
+	}
+	return meta
+}
+
+// citationLabel is the text shown for a citation in a sources list
+func citationLabel(c db.Citation) string {
+	label := c.Text
+	if label == "" {
+		label = c.URL
+	}
+	if c.Flagged() {
+		label += " (" + c.Status
+		if c.Note != "" {
+			label += ": " + c.Note
+		}
+		label += ")"
+	}
+	return label
+}
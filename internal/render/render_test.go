@@ -0,0 +1,180 @@
+package render
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/joelklabo/copilot-research/internal/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDocument() *Document {
+	score := 82
+	return &Document{
+		Query:     "How do Go contexts work?",
+		Mode:      "quick",
+		Content:   "# Go Contexts\n\nA `context.Context` carries **deadlines** and *cancellation*.\n\n## Usage\n\n- Pass it first\n- Never store it\n\n```go\nctx, cancel := context.WithTimeout(ctx, time.Second)\n```\n\n| API | Use |\n|---|---|\n| WithCancel | manual |\n\nSee [the docs](https://pkg.go.dev/context).",
+		Provider:  "github-copilot",
+		Model:     "gpt-4o",
+		Tokens:    provider.TokenUsage{Prompt: 100, Completion: 200, Total: 300},
+		Duration:  2500 * time.Millisecond,
+		SessionID: 7,
+		Citations: []db.Citation{
+			{Text: "the docs", URL: "https://pkg.go.dev/context", Status: db.CitationOK},
+			{URL: "https://example.com/x", Status: db.CitationSuspicious, Note: "placeholder domain"},
+		},
+		QualityScore: &score,
+		CreatedAt:    time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC),
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := DefaultRegistry()
+	assert.Equal(t, []string{"asciidoc", "html", "json", "markdown", "org"}, r.List())
+
+	renderer, err := r.Get("HTML")
+	require.NoError(t, err)
+	assert.Equal(t, "html", renderer.Name())
+
+	_, err = r.Get("pdf")
+	assert.ErrorContains(t, err, "unknown format 'pdf'")
+
+	assert.Error(t, r.Register(Markdown{}))
+
+	renderer, ok := r.ForPath("notes/report.ADOC")
+	require.True(t, ok)
+	assert.Equal(t, "asciidoc", renderer.Name())
+	_, ok = r.ForPath("report.csv")
+	assert.False(t, ok)
+}
+
+func TestRegistry_Resolve(t *testing.T) {
+	r := DefaultRegistry()
+	tests := []struct {
+		format, output string
+		json           bool
+		want           string
+	}{
+		{"", "", false, "markdown"},
+		{"", "", true, "json"},
+		{"", "report.html", false, "html"},
+		{"", "report.org", true, "org"},
+		{"", "report.txt", true, "json"},
+		{"asciidoc", "report.html", true, "asciidoc"},
+	}
+	for _, tt := range tests {
+		renderer, err := r.Resolve(tt.format, tt.output, tt.json)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, renderer.Name(), "%+v", tt)
+	}
+
+	_, err := r.Resolve("docx", "", false)
+	assert.Error(t, err)
+}
+
+func TestMarkdown_Render(t *testing.T) {
+	doc := testDocument()
+	out, err := Markdown{}.Render(doc)
+	require.NoError(t, err)
+	assert.Equal(t, doc.Content, out)
+}
+
+func TestJSON_Render(t *testing.T) {
+	out, err := JSON{}.Render(testDocument())
+	require.NoError(t, err)
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(out), &decoded))
+	assert.Equal(t, "How do Go contexts work?", decoded["query"])
+	assert.Equal(t, "github-copilot", decoded["provider"])
+	assert.Equal(t, "gpt-4o", decoded["model"])
+	assert.Equal(t, float64(300), decoded["tokens"].(map[string]interface{})["total"])
+	assert.Equal(t, float64(2500), decoded["duration_ms"])
+	assert.Equal(t, float64(7), decoded["session_id"])
+	assert.Equal(t, "2026-03-01T09:30:00Z", decoded["created_at"])
+	assert.Len(t, decoded["citations"], 2)
+	assert.Equal(t, "markdown", decoded["format"])
+	assert.Contains(t, decoded["content"], "# Go Contexts")
+
+	// Citations are always an array
+	out, err = JSON{}.Render(&Document{Query: "q"})
+	require.NoError(t, err)
+	assert.Contains(t, out, `"citations": []`)
+}
+
+func TestHTML_Render(t *testing.T) {
+	out, err := HTML{}.Render(testDocument())
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(out, "<!DOCTYPE html>"))
+	assert.Contains(t, out, "<title>Go Contexts</title>")
+	assert.Contains(t, out, "<style>")
+	assert.Equal(t, 1, strings.Count(out, "<h1>"))
+	assert.Contains(t, out, "<dt>Model</dt><dd>gpt-4o</dd>")
+	assert.Contains(t, out, "<p>A <code>context.Context</code> carries <strong>deadlines</strong> and <em>cancellation</em>.</p>")
+	assert.Contains(t, out, "<ul>\n<li>Pass it first</li>")
+	assert.Contains(t, out, `<pre><code class="language-go">ctx, cancel := context.WithTimeout(ctx, time.Second)</code></pre>`)
+	assert.Contains(t, out, "<tr><th>API</th><th>Use</th></tr>")
+	assert.Contains(t, out, `<a href="https://pkg.go.dev/context">the docs</a>`)
+	assert.Contains(t, out, `<li class="flagged"><a href="https://example.com/x">https://example.com/x (suspicious: placeholder domain)</a></li>`)
+
+	out, err = HTML{}.Render(&Document{Query: "<script>", Content: "a < b"})
+	require.NoError(t, err)
+	assert.Contains(t, out, "<title>&lt;script&gt;</title>")
+	assert.Contains(t, out, "<p>a &lt; b</p>")
+}
+
+func TestHTML_Render_UnsafeLinks(t *testing.T) {
+	out, err := HTML{}.Render(&Document{
+		Query:   "Links",
+		Content: "[run](javascript:alert%281%29) [img](data:text/html;base64,PHNjcmlwdD4=) [mail](mailto:a@example.com) [page](/docs/intro) [site](HTTPS://go.dev)",
+		Citations: []db.Citation{
+			{Text: "bad", URL: "JavaScript:alert(1)"},
+			{Text: "good", URL: "https://go.dev"},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.NotContains(t, out, "javascript:")
+	assert.NotContains(t, out, "JavaScript:")
+	assert.NotContains(t, out, "data:")
+	assert.Contains(t, out, "<p>run img <a href=\"mailto:a@example.com\">mail</a>")
+	assert.Contains(t, out, `<a href="/docs/intro">page</a>`)
+	assert.Contains(t, out, `<a href="HTTPS://go.dev">site</a>`)
+	assert.Contains(t, out, "<li>bad</li>")
+	assert.Contains(t, out, `<li><a href="https://go.dev">good</a></li>`)
+}
+
+func TestAsciiDoc_Render(t *testing.T) {
+	out, err := AsciiDoc{}.Render(testDocument())
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(out, "= Go Contexts\n:revdate: 2026-03-01\n"))
+	assert.Contains(t, out, ":provider: github-copilot")
+	assert.Contains(t, out, "A `+context.Context+` carries *deadlines* and _cancellation_.")
+	assert.Contains(t, out, "== Usage")
+	assert.Contains(t, out, "* Pass it first")
+	assert.Contains(t, out, "[source,go]\n----\nctx, cancel")
+	assert.Contains(t, out, "|===\n| API | Use \n| WithCancel | manual \n|===")
+	assert.Contains(t, out, "https://pkg.go.dev/context[the docs]")
+	assert.Contains(t, out, "== Sources")
+}
+
+func TestOrg_Render(t *testing.T) {
+	out, err := Org{}.Render(testDocument())
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(out, "#+TITLE: Go Contexts\n#+DATE: 2026-03-01\n"))
+	assert.Contains(t, out, "#+QUALITY: 82/100")
+	assert.Contains(t, out, "A ~context.Context~ carries *deadlines* and /cancellation/.")
+	assert.Contains(t, out, "** Usage")
+	assert.Contains(t, out, "- Never store it")
+	assert.Contains(t, out, "#+BEGIN_SRC go\nctx, cancel")
+	assert.Contains(t, out, "| API | Use |\n|---+---|\n| WithCancel | manual |")
+	assert.Contains(t, out, "[[https://pkg.go.dev/context][the docs]]")
+	assert.Contains(t, out, "* Sources")
+}
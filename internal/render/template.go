@@ -0,0 +1,257 @@
+package render
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/joelklabo/copilot-research/internal/diff"
+)
+
+// TemplateExt is the file extension of report templates
+const TemplateExt = ".tmpl"
+
+//go:embed templates/*.tmpl
+var builtinTemplates embed.FS
+
+// ReportTemplate renders documents with a text/template report template,
+// e.g. to turn an answer into an ADR skeleton
+type ReportTemplate struct {
+	name   string
+	source string // File the template was read from, or "built-in"
+	text   string
+	tmpl   *template.Template
+}
+
+// TemplateInfo describes an available report template
+type TemplateInfo struct {
+	Name   string
+	Source string
+}
+
+// TemplateData is what report templates are executed with. The document's
+// fields, including the full Result and the stored Session when there is
+// one, are available directly, e.g. {{.Query}} or {{.Session.PromptUsed}}.
+type TemplateData struct {
+	*Document
+	Title    string         // First level-1 heading of the answer, or the query
+	Body     string         // The answer without its title heading
+	Sections []diff.Section // Body split at its headings
+	Date     time.Time      // When the research ran
+}
+
+// templateFuncs are available to every report template
+var templateFuncs = template.FuncMap{
+	"date": func(layout string, t time.Time) string { return t.Format(layout) },
+	"nest": nestHeadings,
+	"indent": func(n int, s string) string {
+		pad := strings.Repeat(" ", n)
+		return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+	},
+	"join":  func(sep string, items []string) string { return strings.Join(items, sep) },
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+	"default": func(def string, v interface{}) interface{} {
+		if s, ok := v.(string); ok && strings.TrimSpace(s) == "" {
+			return def
+		}
+		if v == nil {
+			return def
+		}
+		return v
+	},
+}
+
+// ParseTemplate compiles a report template
+func ParseTemplate(name, text, source string) (*ReportTemplate, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+	return &ReportTemplate{name: name, source: source, text: text, tmpl: tmpl}, nil
+}
+
+// FindTemplate resolves a template name or path. A name is looked up as
+// <name>.tmpl in each directory, in order, and then among the built-in
+// templates.
+func FindTemplate(nameOrPath string, dirs ...string) (*ReportTemplate, error) {
+	if info, err := os.Stat(nameOrPath); err == nil && !info.IsDir() {
+		return loadTemplateFile(strings.TrimSuffix(filepath.Base(nameOrPath), TemplateExt), nameOrPath)
+	}
+
+	for _, dir := range dirs {
+		path := filepath.Join(dir, nameOrPath+TemplateExt)
+		if _, err := os.Stat(path); err == nil {
+			return loadTemplateFile(nameOrPath, path)
+		}
+	}
+
+	if data, err := builtinTemplates.ReadFile("templates/" + nameOrPath + TemplateExt); err == nil {
+		return ParseTemplate(nameOrPath, string(data), "built-in")
+	}
+
+	var names []string
+	for _, t := range ListTemplates(dirs...) {
+		names = append(names, t.Name)
+	}
+	return nil, fmt.Errorf("template not found: %s (available: %s)", nameOrPath, strings.Join(names, ", "))
+}
+
+// loadTemplateFile reads and compiles a template file
+func loadTemplateFile(name, path string) (*ReportTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+	return ParseTemplate(name, string(data), path)
+}
+
+// ListTemplates returns the templates available by name, sorted. Templates
+// in dirs override built-in templates of the same name.
+func ListTemplates(dirs ...string) []TemplateInfo {
+	found := make(map[string]string)
+
+	entries, _ := fs.ReadDir(builtinTemplates, "templates")
+	for _, e := range entries {
+		found[strings.TrimSuffix(e.Name(), TemplateExt)] = "built-in"
+	}
+
+	// Earlier directories take precedence, as in FindTemplate
+	for i := len(dirs) - 1; i >= 0; i-- {
+		matches, _ := filepath.Glob(filepath.Join(dirs[i], "*"+TemplateExt))
+		for _, path := range matches {
+			found[strings.TrimSuffix(filepath.Base(path), TemplateExt)] = path
+		}
+	}
+
+	templates := make([]TemplateInfo, 0, len(found))
+	for name, source := range found {
+		templates = append(templates, TemplateInfo{Name: name, Source: source})
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates
+}
+
+// Name returns the template name
+func (t *ReportTemplate) Name() string { return t.name }
+
+// Extensions returns nil; templates are only chosen by name
+func (t *ReportTemplate) Extensions() []string { return nil }
+
+// Source returns the file the template was read from, or "built-in"
+func (t *ReportTemplate) Source() string { return t.source }
+
+// Text returns the template source
+func (t *ReportTemplate) Text() string { return t.text }
+
+// Render executes the template with the document
+func (t *ReportTemplate) Render(doc *Document) (string, error) {
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, NewTemplateData(doc)); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %w", t.name, err)
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n", nil
+}
+
+// NewTemplateData prepares a document for a report template
+func NewTemplateData(doc *Document) *TemplateData {
+	data := &TemplateData{
+		Document: doc,
+		Title:    doc.Query,
+		Body:     strings.TrimSpace(doc.Content),
+		Date:     doc.CreatedAt,
+	}
+	if data.Date.IsZero() {
+		data.Date = time.Now()
+	}
+
+	first, rest, _ := strings.Cut(data.Body, "\n")
+	if m := mdHeading.FindStringSubmatch(first); m != nil && len(m[1]) == 1 {
+		data.Title = m[2]
+		data.Body = strings.TrimSpace(rest)
+	}
+	data.Sections = diff.SplitSections(data.Body)
+	return data
+}
+
+// Section returns the text under the first heading containing one of
+// names, ignoring case, including its subsections, or "" if there is none
+func (d *TemplateData) Section(names ...string) string {
+	for _, name := range names {
+		name = strings.ToLower(name)
+		for i, s := range d.Sections {
+			if s.Heading == "" || !strings.Contains(strings.ToLower(s.Heading), name) {
+				continue
+			}
+			parts := []string{s.Body}
+			for _, sub := range d.Sections[i+1:] {
+				if sub.Level <= s.Level {
+					break
+				}
+				parts = append(parts, strings.Repeat("#", sub.Level)+" "+sub.Heading, sub.Body)
+			}
+			return strings.TrimSpace(strings.Join(nonEmpty(parts), "\n\n"))
+		}
+	}
+	return ""
+}
+
+// nonEmpty drops empty strings
+func nonEmpty(items []string) []string {
+	var out []string
+	for _, s := range items {
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Summary returns the first paragraph of the answer
+func (d *TemplateData) Summary() string {
+	for _, s := range d.Sections {
+		for _, para := range diff.SplitParagraphs(s.Body) {
+			if !strings.HasPrefix(para, "```") && !mdListItem.MatchString(para) && !strings.Contains(para, "|") {
+				return para
+			}
+		}
+	}
+	return strings.TrimSpace(d.Content)
+}
+
+// nestHeadings shifts the markdown headings outside code blocks so the
+// shallowest becomes the given level, to nest text under a template's own
+// headings
+func nestHeadings(level int, text string) string {
+	lines := strings.Split(text, "\n")
+	headings := make(map[int]int) // Line index to heading depth
+	shallowest := 7
+	inFence := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+		if inFence || !mdHeading.MatchString(line) {
+			continue
+		}
+		depth := len(line) - len(strings.TrimLeft(line, "#"))
+		headings[i] = depth
+		if depth < shallowest {
+			shallowest = depth
+		}
+	}
+
+	shift := level - shallowest
+	for i, depth := range headings {
+		newDepth := min(max(depth+shift, 1), 6)
+		lines[i] = strings.Repeat("#", newDepth) + lines[i][depth:]
+	}
+	return strings.Join(lines, "\n")
+}
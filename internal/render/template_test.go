@@ -0,0 +1,140 @@
+package render
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/joelklabo/copilot-research/internal/research"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindTemplate_BuiltIn(t *testing.T) {
+	for _, name := range []string{"adr", "rfc"} {
+		tmpl, err := FindTemplate(name, t.TempDir())
+		require.NoError(t, err)
+		assert.Equal(t, name, tmpl.Name())
+		assert.Equal(t, "built-in", tmpl.Source())
+		assert.Nil(t, tmpl.Extensions())
+	}
+
+	_, err := FindTemplate("wiki", t.TempDir())
+	assert.ErrorContains(t, err, "template not found: wiki (available: adr, rfc)")
+}
+
+func TestFindTemplate_UserDirAndPath(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "adr.tmpl"), []byte("custom {{.Query}}"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "wiki.tmpl"), []byte("= {{.Title}} ="), 0644))
+
+	tmpl, err := FindTemplate("adr", dir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "adr.tmpl"), tmpl.Source())
+
+	out, err := tmpl.Render(testDocument())
+	require.NoError(t, err)
+	assert.Equal(t, "custom How do Go contexts work?\n", out)
+
+	tmpl, err = FindTemplate(filepath.Join(dir, "wiki.tmpl"))
+	require.NoError(t, err)
+	assert.Equal(t, "wiki", tmpl.Name())
+
+	assert.Equal(t, []TemplateInfo{
+		{Name: "adr", Source: filepath.Join(dir, "adr.tmpl")},
+		{Name: "rfc", Source: "built-in"},
+		{Name: "wiki", Source: filepath.Join(dir, "wiki.tmpl")},
+	}, ListTemplates(dir))
+}
+
+func TestParseTemplate_Errors(t *testing.T) {
+	_, err := ParseTemplate("bad", "{{.Query", "test")
+	assert.ErrorContains(t, err, "failed to parse template bad")
+
+	tmpl, err := ParseTemplate("missing", "{{.Nope}}", "test")
+	require.NoError(t, err)
+	_, err = tmpl.Render(testDocument())
+	assert.ErrorContains(t, err, "failed to render template missing")
+}
+
+func TestTemplateData(t *testing.T) {
+	doc := testDocument()
+	doc.Content = "# Choosing a Queue\n\nUse **NATS** for most services.\n\n## Background\n\nWe run ten services.\n\n## Trade-offs\n\n- Less durable\n\n### Notes\n\nNested."
+	data := NewTemplateData(doc)
+
+	assert.Equal(t, "Choosing a Queue", data.Title)
+	assert.True(t, strings.HasPrefix(data.Body, "Use **NATS**"))
+	assert.Equal(t, "Use **NATS** for most services.", data.Summary())
+	assert.Equal(t, "We run ten services.", data.Section("context", "background"))
+	assert.Equal(t, "- Less durable\n\n### Notes\n\nNested.", data.Section("TRADE-OFFS"))
+	assert.Equal(t, "", data.Section("decision"))
+	assert.Equal(t, doc.CreatedAt, data.Date)
+
+	doc.Content = "No headings here."
+	data = NewTemplateData(doc)
+	assert.Equal(t, doc.Query, data.Title)
+	assert.Equal(t, "No headings here.", data.Summary())
+}
+
+func TestNestHeadings(t *testing.T) {
+	in := "## Usage\n\n```sh\n# a comment\n```\n\n#### Deep"
+	assert.Equal(t, "### Usage\n\n```sh\n# a comment\n```\n\n##### Deep", nestHeadings(3, in))
+	assert.Equal(t, "# Usage\n\n```sh\n# a comment\n```\n\n### Deep", nestHeadings(1, in))
+	assert.Equal(t, "No headings.", nestHeadings(3, "No headings."))
+}
+
+func TestReportTemplate_ADR(t *testing.T) {
+	doc := testDocument()
+	doc.Content = "# Message Queues\n\nNATS fits our scale.\n\n## Background\n\nWe need pub/sub.\n\n## Recommendation\n\nAdopt NATS.\n\n### Rollout\n\nStart with billing."
+	doc.Matrix = &db.ComparisonMatrix{
+		Criteria: []string{"Speed"},
+		Options: []db.MatrixOption{
+			{Name: "NATS", Summary: "lightweight", Scores: []db.MatrixScore{{Criterion: "Speed", Score: 5}}},
+			{Name: "Kafka", Scores: []db.MatrixScore{{Criterion: "Speed", Score: 4}}},
+		},
+	}
+
+	tmpl, err := FindTemplate("adr")
+	require.NoError(t, err)
+	out, err := tmpl.Render(doc)
+	require.NoError(t, err)
+
+	assert.Contains(t, out, "# ADR: Message Queues\n")
+	assert.Contains(t, out, "- Date: 2026-03-01\n")
+	assert.Contains(t, out, "- Research: session #7 (quick, github-copilot)\n")
+	assert.Contains(t, out, "## Context\n\nWe need pub/sub.\n")
+	assert.Contains(t, out, "- **NATS** (average 5.0/5): lightweight\n- **Kafka** (average 4.0/5)\n")
+	assert.Contains(t, out, "## Decision\n\nAdopt NATS.\n\n### Rollout\n\nStart with billing.\n")
+	assert.Contains(t, out, "_TODO: describe what becomes easier or harder")
+	assert.Contains(t, out, "- [the docs](https://pkg.go.dev/context)\n- [https://example.com/x](https://example.com/x)\n")
+}
+
+func TestReportTemplate_RFC(t *testing.T) {
+	doc := testDocument()
+	doc.Citations = nil
+
+	tmpl, err := FindTemplate("rfc")
+	require.NoError(t, err)
+	out, err := tmpl.Render(doc)
+	require.NoError(t, err)
+
+	assert.Contains(t, out, "# RFC: Go Contexts\n")
+	assert.Contains(t, out, "## Summary\n\nA `context.Context` carries **deadlines** and *cancellation*.\n")
+	assert.Contains(t, out, "## Detailed Design\n\nA `context.Context`")
+	assert.Contains(t, out, "\n### Usage\n")
+	assert.Contains(t, out, "_No sources were cited._")
+}
+
+func TestReportTemplate_ResultAndSession(t *testing.T) {
+	doc := testDocument()
+	doc.Result = &research.ResearchResult{Query: doc.Query, Revised: true}
+	doc.Session = &db.ResearchSession{PromptUsed: "default"}
+
+	tmpl, err := ParseTemplate("meta", "{{if .Result.Revised}}revised{{end}} with {{.Session.PromptUsed}}", "test")
+	require.NoError(t, err)
+	out, err := tmpl.Render(doc)
+	require.NoError(t, err)
+	assert.Equal(t, "revised with default\n", out)
+}
@@ -0,0 +1,426 @@
+package research
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Batch item statuses
+const (
+	BatchStatusPending = "pending"
+	BatchStatusRunning = "running"
+	BatchStatusDone    = "done"
+	BatchStatusFailed  = "failed"
+	BatchStatusSkipped = "skipped"
+)
+
+// DefaultBatchConcurrency is used when neither the manifest nor the caller
+// sets a concurrency limit
+const DefaultBatchConcurrency = 3
+
+// Researcher is anything that can run a single research query.
+// *Engine is the production implementation.
+type Researcher interface {
+	Research(ctx context.Context, opts ResearchOptions, events chan<- Event) (*ResearchResult, error)
+}
+
+// Compile-time check that Engine implements Researcher
+var _ Researcher = (*Engine)(nil)
+
+// BatchItem is a single query in a batch manifest
+type BatchItem struct {
+	ID     string `yaml:"id,omitempty" json:"id"`
+	Query  string `yaml:"query" json:"query"`
+	Mode   string `yaml:"mode,omitempty" json:"mode,omitempty"`
+	Prompt string `yaml:"prompt,omitempty" json:"prompt,omitempty"`
+	Output string `yaml:"output,omitempty" json:"output,omitempty"`
+}
+
+// UnmarshalYAML allows an item to be written as a bare query string
+func (b *BatchItem) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		b.Query = node.Value
+		return nil
+	}
+
+	type plain BatchItem
+	return node.Decode((*plain)(b))
+}
+
+// BatchManifest describes a set of queries to research together
+type BatchManifest struct {
+	Concurrency int         `yaml:"concurrency,omitempty"`
+	Mode        string      `yaml:"mode,omitempty"`
+	Prompt      string      `yaml:"prompt,omitempty"`
+	OutputDir   string      `yaml:"output_dir,omitempty"`
+	Queries     []BatchItem `yaml:"queries"`
+}
+
+// LoadBatchManifest reads and validates a batch manifest file.
+// Per-item defaults are filled in from the manifest and every item gets a
+// stable ID so that batch state survives reordering of the file. Relative
+// output paths are resolved against the manifest's directory.
+func LoadBatchManifest(path string) (*BatchManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch manifest: %w", err)
+	}
+
+	var manifest BatchManifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse batch manifest: %w", err)
+	}
+
+	if len(manifest.Queries) == 0 {
+		return nil, fmt.Errorf("batch manifest has no queries")
+	}
+
+	dir := filepath.Dir(path)
+	if manifest.OutputDir != "" && !filepath.IsAbs(manifest.OutputDir) {
+		manifest.OutputDir = filepath.Join(dir, manifest.OutputDir)
+	}
+
+	seen := make(map[string]int)
+	for i := range manifest.Queries {
+		item := &manifest.Queries[i]
+		item.Query = strings.TrimSpace(item.Query)
+		if item.Query == "" {
+			return nil, fmt.Errorf("batch item %d has an empty query", i+1)
+		}
+		if item.Mode == "" {
+			item.Mode = manifest.Mode
+		}
+		if item.Prompt == "" {
+			item.Prompt = manifest.Prompt
+		}
+		if item.Output == "" && manifest.OutputDir != "" {
+			item.Output = filepath.Join(manifest.OutputDir, slugify(item.Query)+".md")
+		} else if item.Output != "" && !filepath.IsAbs(item.Output) {
+			item.Output = filepath.Join(dir, item.Output)
+		}
+		if item.ID == "" {
+			item.ID = batchItemID(item)
+		}
+		if prev, exists := seen[item.ID]; exists {
+			return nil, fmt.Errorf("batch items %d and %d have the same id %q", prev+1, i+1, item.ID)
+		}
+		seen[item.ID] = i
+	}
+
+	return &manifest, nil
+}
+
+// batchItemID derives a stable identifier from the item's query settings
+func batchItemID(item *BatchItem) string {
+	hash := sha256.Sum256([]byte(item.Query + "\x00" + item.Mode + "\x00" + item.Prompt))
+	return hex.EncodeToString(hash[:])[:12]
+}
+
+var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slugify turns a query into a short file-name friendly string
+func slugify(s string) string {
+	slug := strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(s), "-"), "-")
+	if len(slug) > 60 {
+		slug = strings.TrimRight(slug[:60], "-")
+	}
+	if slug == "" {
+		slug = "query"
+	}
+	return slug
+}
+
+// BatchItemState records the outcome of one batch item
+type BatchItemState struct {
+	Status      string        `json:"status"`
+	SessionID   int64         `json:"session_id,omitempty"`
+	Output      string        `json:"output,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	Duration    time.Duration `json:"duration,omitempty"`
+	CompletedAt time.Time     `json:"completed_at,omitempty"`
+}
+
+// BatchState is the persisted progress of a batch run
+type BatchState struct {
+	Manifest  string                     `json:"manifest"`
+	StartedAt time.Time                  `json:"started_at"`
+	UpdatedAt time.Time                  `json:"updated_at"`
+	Items     map[string]*BatchItemState `json:"items"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// DefaultBatchStatePath returns the state file used for a manifest
+func DefaultBatchStatePath(manifestPath string) string {
+	return manifestPath + ".state.json"
+}
+
+// LoadBatchState loads batch state from path, or returns fresh state if the
+// file does not exist yet
+func LoadBatchState(path, manifestPath string) (*BatchState, error) {
+	state := &BatchState{
+		Manifest:  manifestPath,
+		StartedAt: time.Now(),
+		Items:     make(map[string]*BatchItemState),
+		path:      path,
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("failed to read batch state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse batch state: %w", err)
+	}
+	if state.Items == nil {
+		state.Items = make(map[string]*BatchItemState)
+	}
+
+	return state, nil
+}
+
+// Get returns a copy of the state for an item
+func (s *BatchState) Get(id string) BatchItemState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if item, ok := s.Items[id]; ok {
+		return *item
+	}
+	return BatchItemState{Status: BatchStatusPending}
+}
+
+// Set records the state for an item and persists the whole batch state
+func (s *BatchState) Set(id string, item BatchItemState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Items[id] = &item
+	s.UpdatedAt = time.Now()
+	return s.saveLocked()
+}
+
+// Save persists the batch state
+func (s *BatchState) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saveLocked()
+}
+
+// saveLocked writes the state atomically so an interrupted run never leaves
+// a truncated file behind
+func (s *BatchState) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch state: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write batch state: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write batch state: %w", err)
+	}
+
+	return nil
+}
+
+// BatchUpdate reports progress for one batch item
+type BatchUpdate struct {
+	Index   int
+	Item    BatchItem
+	Status  string
+	Message string
+	Result  *ResearchResult
+	Err     error
+}
+
+// BatchSummary totals the outcome of a batch run
+type BatchSummary struct {
+	Total     int
+	Completed int
+	Skipped   int
+	Failed    int
+	Duration  time.Duration
+}
+
+// BatchRunner executes batch manifests with bounded concurrency
+type BatchRunner struct {
+	researcher  Researcher
+	concurrency int
+	noStore     bool
+}
+
+// NewBatchRunner creates a batch runner. A concurrency below one falls back
+// to DefaultBatchConcurrency.
+func NewBatchRunner(researcher Researcher, concurrency int, noStore bool) *BatchRunner {
+	if concurrency < 1 {
+		concurrency = DefaultBatchConcurrency
+	}
+	return &BatchRunner{
+		researcher:  researcher,
+		concurrency: concurrency,
+		noStore:     noStore,
+	}
+}
+
+// Run researches every item in the manifest that is not already done in
+// state. Updates are sent on the (optional) updates channel; the caller owns
+// and closes it. Cancelling ctx stops dispatching new items and leaves the
+// interrupted ones pending so the next run picks them up.
+func (b *BatchRunner) Run(ctx context.Context, manifest *BatchManifest, state *BatchState, updates chan<- BatchUpdate) (*BatchSummary, error) {
+	start := time.Now()
+	summary := &BatchSummary{Total: len(manifest.Queries)}
+
+	send := func(u BatchUpdate) {
+		if updates != nil {
+			updates <- u
+		}
+	}
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, b.concurrency)
+	)
+
+	for i, item := range manifest.Queries {
+		if state.Get(item.ID).Status == BatchStatusDone {
+			mu.Lock()
+			summary.Skipped++
+			mu.Unlock()
+			send(BatchUpdate{Index: i, Item: item, Status: BatchStatusSkipped, Message: "Already completed"})
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case sem <- struct{}{}:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, item BatchItem) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			status := b.runItem(ctx, i, item, state, send)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch status {
+			case BatchStatusDone:
+				summary.Completed++
+			case BatchStatusFailed:
+				summary.Failed++
+			}
+		}(i, item)
+	}
+
+	wg.Wait()
+	summary.Duration = time.Since(start)
+
+	if err := state.Save(); err != nil {
+		return summary, err
+	}
+
+	return summary, ctx.Err()
+}
+
+// runItem researches a single item and records its outcome
+func (b *BatchRunner) runItem(ctx context.Context, index int, item BatchItem, state *BatchState, send func(BatchUpdate)) string {
+	start := time.Now()
+
+	if err := state.Set(item.ID, BatchItemState{Status: BatchStatusRunning}); err != nil {
+		send(BatchUpdate{Index: index, Item: item, Status: BatchStatusRunning, Message: fmt.Sprintf("Warning: %v", err)})
+	}
+	send(BatchUpdate{Index: index, Item: item, Status: BatchStatusRunning, Message: "Starting..."})
+
+	progress := make(chan Event, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event := range progress {
+			send(BatchUpdate{Index: index, Item: item, Status: BatchStatusRunning, Message: event.String()})
+		}
+	}()
+
+	result, err := b.researcher.Research(ctx, ResearchOptions{
+		Query:      item.Query,
+		Mode:       item.Mode,
+		PromptName: item.Prompt,
+		NoStore:    b.noStore,
+	}, progress)
+	close(progress)
+	<-done
+
+	if err == nil && item.Output != "" {
+		err = writeBatchOutput(item.Output, result.Content)
+	}
+
+	if err != nil {
+		// An interrupted item is not a failure; leave it for the next run
+		if ctx.Err() != nil {
+			_ = state.Set(item.ID, BatchItemState{Status: BatchStatusPending})
+			send(BatchUpdate{Index: index, Item: item, Status: BatchStatusPending, Message: "Interrupted", Err: ctx.Err()})
+			return BatchStatusPending
+		}
+
+		_ = state.Set(item.ID, BatchItemState{
+			Status:   BatchStatusFailed,
+			Error:    err.Error(),
+			Duration: time.Since(start),
+		})
+		send(BatchUpdate{Index: index, Item: item, Status: BatchStatusFailed, Message: err.Error(), Err: err})
+		return BatchStatusFailed
+	}
+
+	itemState := BatchItemState{
+		Status:      BatchStatusDone,
+		SessionID:   result.SessionID,
+		Output:      item.Output,
+		Duration:    time.Since(start),
+		CompletedAt: time.Now(),
+	}
+	if err := state.Set(item.ID, itemState); err != nil {
+		send(BatchUpdate{Index: index, Item: item, Status: BatchStatusDone, Message: fmt.Sprintf("Warning: %v", err), Result: result})
+		return BatchStatusDone
+	}
+
+	send(BatchUpdate{Index: index, Item: item, Status: BatchStatusDone, Message: "Complete!", Result: result})
+	return BatchStatusDone
+}
+
+// writeBatchOutput writes an item's report, creating parent directories
+func writeBatchOutput(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
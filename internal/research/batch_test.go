@@ -0,0 +1,276 @@
+package research
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubResearcher records calls and answers with canned content
+type stubResearcher struct {
+	mu       sync.Mutex
+	calls    []ResearchOptions
+	failOn   map[string]error
+	delay    time.Duration
+	inFlight int32
+	maxSeen  int32
+}
+
+func (s *stubResearcher) Research(ctx context.Context, opts ResearchOptions, events chan<- Event) (*ResearchResult, error) {
+	n := atomic.AddInt32(&s.inFlight, 1)
+	defer atomic.AddInt32(&s.inFlight, -1)
+	for {
+		max := atomic.LoadInt32(&s.maxSeen)
+		if n <= max || atomic.CompareAndSwapInt32(&s.maxSeen, max, n) {
+			break
+		}
+	}
+
+	s.mu.Lock()
+	s.calls = append(s.calls, opts)
+	s.mu.Unlock()
+
+	if events != nil {
+		events <- Event{Stage: StageQuery, Status: EventStarted, Message: "Querying AI provider..."}
+	}
+
+	if s.delay > 0 {
+		select {
+		case <-time.After(s.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if err, ok := s.failOn[opts.Query]; ok {
+		return nil, err
+	}
+
+	return &ResearchResult{
+		Query:     opts.Query,
+		Mode:      opts.Mode,
+		Content:   "Answer: " + opts.Query,
+		SessionID: 1,
+	}, nil
+}
+
+func writeManifest(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "queries.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadBatchManifest(t *testing.T) {
+	path := writeManifest(t, `
+concurrency: 2
+mode: deep
+output_dir: reports
+queries:
+  - "Evaluate cobra"
+  - query: Evaluate urfave/cli
+    mode: quick
+    prompt: compare
+    output: cli.md
+`)
+
+	manifest, err := LoadBatchManifest(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, manifest.Concurrency)
+	require.Len(t, manifest.Queries, 2)
+
+	first := manifest.Queries[0]
+	assert.Equal(t, "Evaluate cobra", first.Query)
+	assert.Equal(t, "deep", first.Mode)
+	dir := filepath.Dir(path)
+	assert.Equal(t, filepath.Join(dir, "reports", "evaluate-cobra.md"), first.Output)
+	assert.Len(t, first.ID, 12)
+
+	second := manifest.Queries[1]
+	assert.Equal(t, "quick", second.Mode)
+	assert.Equal(t, "compare", second.Prompt)
+	assert.Equal(t, filepath.Join(dir, "cli.md"), second.Output)
+	assert.NotEqual(t, first.ID, second.ID)
+}
+
+func TestLoadBatchManifest_PathsRelativeToManifest(t *testing.T) {
+	path := writeManifest(t, `
+output_dir: reports
+queries:
+  - "Evaluate cobra"
+  - query: Evaluate urfave/cli
+    output: /tmp/cli.md
+`)
+
+	// The working directory does not change where output goes
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(wd)
+
+	manifest, err := LoadBatchManifest(path)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(filepath.Dir(path), "reports"), manifest.OutputDir)
+	assert.Equal(t, filepath.Join(filepath.Dir(path), "reports", "evaluate-cobra.md"), manifest.Queries[0].Output)
+	assert.Equal(t, "/tmp/cli.md", manifest.Queries[1].Output, "absolute paths are kept")
+}
+
+func TestLoadBatchManifest_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"no queries", "concurrency: 2\n"},
+		{"empty query", "queries:\n  - query: \"  \"\n"},
+		{"duplicate ids", "queries:\n  - id: a\n    query: one\n  - id: a\n    query: two\n"},
+		{"invalid yaml", "queries: [\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadBatchManifest(writeManifest(t, tt.content))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestBatchItemID_Stable(t *testing.T) {
+	a := BatchItem{Query: "q", Mode: "quick"}
+	b := BatchItem{Query: "q", Mode: "quick"}
+	c := BatchItem{Query: "q", Mode: "deep"}
+
+	assert.Equal(t, batchItemID(&a), batchItemID(&b))
+	assert.NotEqual(t, batchItemID(&a), batchItemID(&c))
+}
+
+func TestSlugify(t *testing.T) {
+	assert.Equal(t, "how-do-swift-actors-work", slugify("How do Swift actors work?"))
+	assert.Equal(t, "query", slugify("???"))
+	assert.LessOrEqual(t, len(slugify(string(make([]byte, 200)))), 60)
+}
+
+func TestBatchState_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	state, err := LoadBatchState(path, "queries.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, BatchStatusPending, state.Get("abc").Status)
+
+	require.NoError(t, state.Set("abc", BatchItemState{Status: BatchStatusDone, SessionID: 42}))
+
+	reloaded, err := LoadBatchState(path, "queries.yaml")
+	require.NoError(t, err)
+	item := reloaded.Get("abc")
+	assert.Equal(t, BatchStatusDone, item.Status)
+	assert.Equal(t, int64(42), item.SessionID)
+}
+
+func TestBatchRunner_Run(t *testing.T) {
+	dir := t.TempDir()
+	manifest := &BatchManifest{Queries: []BatchItem{
+		{ID: "a", Query: "alpha", Output: filepath.Join(dir, "out", "alpha.md")},
+		{ID: "b", Query: "beta"},
+		{ID: "c", Query: "gamma"},
+	}}
+	state, err := LoadBatchState(filepath.Join(dir, "state.json"), "m.yaml")
+	require.NoError(t, err)
+
+	stub := &stubResearcher{failOn: map[string]error{"gamma": fmt.Errorf("boom")}}
+	runner := NewBatchRunner(stub, 2, false)
+
+	updates := make(chan BatchUpdate, 100)
+	summary, err := runner.Run(context.Background(), manifest, state, updates)
+	close(updates)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, summary.Total)
+	assert.Equal(t, 2, summary.Completed)
+	assert.Equal(t, 1, summary.Failed)
+	assert.Equal(t, 0, summary.Skipped)
+
+	data, err := os.ReadFile(filepath.Join(dir, "out", "alpha.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "Answer: alpha", string(data))
+
+	assert.Equal(t, BatchStatusDone, state.Get("a").Status)
+	assert.Equal(t, BatchStatusFailed, state.Get("c").Status)
+	assert.Equal(t, "boom", state.Get("c").Error)
+
+	var statuses []string
+	for u := range updates {
+		if u.Item.ID == "b" {
+			statuses = append(statuses, u.Status)
+		}
+	}
+	assert.Equal(t, BatchStatusRunning, statuses[0])
+	assert.Equal(t, BatchStatusDone, statuses[len(statuses)-1])
+}
+
+func TestBatchRunner_ResumeSkipsCompleted(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "state.json")
+	manifest := &BatchManifest{Queries: []BatchItem{
+		{ID: "a", Query: "alpha"},
+		{ID: "b", Query: "beta"},
+	}}
+
+	state, err := LoadBatchState(statePath, "m.yaml")
+	require.NoError(t, err)
+	require.NoError(t, state.Set("a", BatchItemState{Status: BatchStatusDone}))
+	require.NoError(t, state.Set("b", BatchItemState{Status: BatchStatusFailed}))
+
+	stub := &stubResearcher{}
+	summary, err := NewBatchRunner(stub, 1, true).Run(context.Background(), manifest, state, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, summary.Skipped)
+	assert.Equal(t, 1, summary.Completed)
+	require.Len(t, stub.calls, 1)
+	assert.Equal(t, "beta", stub.calls[0].Query)
+	assert.True(t, stub.calls[0].NoStore)
+}
+
+func TestBatchRunner_BoundedConcurrency(t *testing.T) {
+	var queries []BatchItem
+	for i := 0; i < 8; i++ {
+		queries = append(queries, BatchItem{ID: fmt.Sprint(i), Query: fmt.Sprintf("q%d", i)})
+	}
+	state, err := LoadBatchState(filepath.Join(t.TempDir(), "state.json"), "m.yaml")
+	require.NoError(t, err)
+
+	stub := &stubResearcher{delay: 20 * time.Millisecond}
+	summary, err := NewBatchRunner(stub, 3, false).Run(context.Background(), &BatchManifest{Queries: queries}, state, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, 8, summary.Completed)
+	assert.LessOrEqual(t, atomic.LoadInt32(&stub.maxSeen), int32(3))
+}
+
+func TestBatchRunner_CancelLeavesItemsPending(t *testing.T) {
+	manifest := &BatchManifest{Queries: []BatchItem{
+		{ID: "a", Query: "alpha"},
+		{ID: "b", Query: "beta"},
+	}}
+	state, err := LoadBatchState(filepath.Join(t.TempDir(), "state.json"), "m.yaml")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stub := &stubResearcher{delay: time.Second}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err = NewBatchRunner(stub, 1, false).Run(ctx, manifest, state, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, BatchStatusPending, state.Get("a").Status)
+	assert.Equal(t, BatchStatusPending, state.Get("b").Status)
+}
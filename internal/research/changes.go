@@ -0,0 +1,38 @@
+package research
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/joelklabo/copilot-research/internal/provider"
+)
+
+// fallbackChangesTemplate is used when diff-summary.md cannot be loaded
+const fallbackChangesTemplate = "Two answers to \"{{query}}\" were produced by different runs. " +
+	"Summarize what changed in meaning between the old and the new answer: new or dropped recommendations, " +
+	"changed facts, versions or numbers, and contradictions. Ignore rewording and formatting. " +
+	"Reply with a short bulleted list, or \"No meaningful changes.\"\n\n" +
+	"Old answer:\n\n{{old}}\n\nNew answer:\n\n{{new}}"
+
+// SummarizeChanges asks the provider to describe the semantic differences
+// between two answers to the same query
+func (e *Engine) SummarizeChanges(ctx context.Context, query, oldText, newText string) (string, error) {
+	prompt := loadTemplate(e.promptLoader, "diff-summary", fallbackChangesTemplate)
+	rendered := e.promptLoader.Render(prompt, map[string]string{
+		"query": query,
+		"old":   oldText,
+		"new":   newText,
+	})
+
+	response, err := e.providerManager.Query(ctx, rendered, provider.QueryOptions{})
+	if err != nil {
+		return "", fmt.Errorf("provider query failed: %w", err)
+	}
+
+	summary := strings.TrimSpace(response.Content)
+	if summary == "" {
+		return "", fmt.Errorf("provider returned an empty summary")
+	}
+	return summary, nil
+}
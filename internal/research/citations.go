@@ -0,0 +1,269 @@
+package research
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+)
+
+// DefaultVerifyConcurrency is how many links are checked at once
+const DefaultVerifyConcurrency = 4
+
+var (
+	// [text](https://url "optional title")
+	markdownLinkPattern = regexp.MustCompile(`\[([^\]]*)\]\((https?://[^\s)]+)(?:\s+"[^"]*")?\)`)
+	// [label]: https://url "optional title"
+	referenceDefPattern = regexp.MustCompile(`(?m)^\s*\[([^\]]+)\]:\s*<?(https?://[^\s>]+)>?`)
+	// <https://url>
+	autolinkPattern = regexp.MustCompile(`<(https?://[^\s>]+)>`)
+	// https://url anywhere else
+	bareURLPattern = regexp.MustCompile(`https?://[^\s<>()\[\]"'` + "`" + `]+`)
+	// [path/to/file.go:10-20] as produced for local context
+	fileCitationPattern = regexp.MustCompile(`\[([\w./\\-]+\.\w+:\d+-\d+)\]`)
+	// [session #12] and [knowledge: topic] as produced by synthesis mode
+	priorCitationPattern = regexp.MustCompile(`\[(session #\d+|knowledge: [^\]\n]+)\]`)
+	// `code`
+	inlineCodePattern = regexp.MustCompile("`[^`\n]*`")
+)
+
+// ExtractCitations finds the sources referenced in a markdown report: inline
+// links, reference definitions, autolinks, bare URLs, local file citations
+// and references to past sessions and knowledge topics. Each source appears
+// once, in order of first appearance.
+func ExtractCitations(markdown string) []db.Citation {
+	var citations []db.Citation
+	seen := make(map[string]int)
+
+	add := func(text, rawURL string) {
+		key := normalizeURL(rawURL)
+		if i, ok := seen[key]; ok {
+			if citations[i].Text == "" {
+				citations[i].Text = text
+			}
+			return
+		}
+		seen[key] = len(citations)
+		citation := db.Citation{Text: text, URL: key, Status: db.CitationUnchecked}
+		if note := suspiciousReason(key); note != "" {
+			citation.Status = db.CitationSuspicious
+			citation.Note = note
+		}
+		citations = append(citations, citation)
+	}
+
+	// Code blocks contain examples, not sources
+	text := stripCodeBlocks(markdown)
+	fileText := text
+
+	for _, m := range markdownLinkPattern.FindAllStringSubmatch(text, -1) {
+		add(strings.TrimSpace(m[1]), m[2])
+	}
+	for _, m := range referenceDefPattern.FindAllStringSubmatch(text, -1) {
+		add(strings.TrimSpace(m[1]), m[2])
+	}
+
+	// Remove what was matched so bare URL matching does not see it again
+	text = markdownLinkPattern.ReplaceAllString(text, "")
+	text = referenceDefPattern.ReplaceAllString(text, "")
+
+	for _, m := range autolinkPattern.FindAllStringSubmatch(text, -1) {
+		add("", m[1])
+	}
+	text = autolinkPattern.ReplaceAllString(text, "")
+	for _, m := range bareURLPattern.FindAllString(text, -1) {
+		add("", m)
+	}
+
+	for _, pattern := range []*regexp.Regexp{fileCitationPattern, priorCitationPattern} {
+		for _, m := range pattern.FindAllStringSubmatch(fileText, -1) {
+			if _, ok := seen[m[1]]; ok {
+				continue
+			}
+			seen[m[1]] = len(citations)
+			citations = append(citations, db.Citation{Text: m[1], Status: db.CitationUnchecked})
+		}
+	}
+
+	return citations
+}
+
+// normalizeURL trims punctuation that commonly trails a URL in prose
+func normalizeURL(raw string) string {
+	return strings.TrimRight(raw, ".,;:!?*_")
+}
+
+// stripCodeBlocks removes fenced code blocks and inline code spans
+func stripCodeBlocks(markdown string) string {
+	var b strings.Builder
+	inFence := false
+	for _, line := range strings.Split(markdown, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if !inFence {
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+	}
+	return inlineCodePattern.ReplaceAllString(b.String(), "")
+}
+
+// placeholderHosts are domains models tend to invent when they lack a source
+var placeholderHosts = []string{"example.com", "example.org", "example.net", "localhost"}
+
+// placeholderSuffixes are reserved top-level domains that never resolve
+var placeholderSuffixes = []string{".example", ".invalid", ".test", ".localhost"}
+
+// suspiciousReason returns why a URL looks untrustworthy without fetching it,
+// or "" if it looks fine
+func suspiciousReason(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "malformed URL"
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, h := range placeholderHosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return "placeholder domain"
+		}
+	}
+	for _, suffix := range placeholderSuffixes {
+		if strings.HasSuffix(host, suffix) {
+			return "reserved domain"
+		}
+	}
+	if net.ParseIP(host) != nil {
+		return "raw IP address"
+	}
+	if !strings.Contains(host, ".") {
+		return "host has no domain"
+	}
+
+	return ""
+}
+
+var (
+	errHostNotFound = errors.New("host not found")
+	errUnreachable  = errors.New("unreachable")
+)
+
+// HTTPClient is the subset of *http.Client used to verify links
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// LinkVerifier checks that cited URLs resolve
+type LinkVerifier struct {
+	client      HTTPClient
+	timeout     time.Duration
+	concurrency int
+}
+
+// NewLinkVerifier creates a verifier using client, or a default HTTP client
+// if client is nil
+func NewLinkVerifier(client HTTPClient, timeout time.Duration) *LinkVerifier {
+	if client == nil {
+		client = &http.Client{}
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &LinkVerifier{
+		client:      client,
+		timeout:     timeout,
+		concurrency: DefaultVerifyConcurrency,
+	}
+}
+
+// Verify checks every URL citation and updates its status in place.
+// Citations without a URL and ones already flagged as suspicious are skipped.
+func (v *LinkVerifier) Verify(ctx context.Context, citations []db.Citation) {
+	sem := make(chan struct{}, v.concurrency)
+	var wg sync.WaitGroup
+
+	for i := range citations {
+		if citations[i].URL == "" || citations[i].Status == db.CitationSuspicious {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(c *db.Citation) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			v.check(ctx, c)
+		}(&citations[i])
+	}
+
+	wg.Wait()
+}
+
+// check requests a single URL, falling back to GET for servers that reject HEAD
+func (v *LinkVerifier) check(ctx context.Context, c *db.Citation) {
+	code, err := v.request(ctx, http.MethodHead, c.URL)
+	if err == nil && (code == http.StatusMethodNotAllowed || code == http.StatusNotImplemented || code == http.StatusForbidden) {
+		code, err = v.request(ctx, http.MethodGet, c.URL)
+	}
+
+	if ctx.Err() != nil {
+		// Cancelled, leave the citation unchecked
+		return
+	}
+	if err != nil {
+		c.Note = err.Error()
+		c.Status = db.CitationSuspicious
+		if errors.Is(err, errHostNotFound) {
+			c.Status = db.CitationDead
+		}
+		return
+	}
+
+	c.StatusCode = code
+	switch {
+	case code < 400:
+		c.Status = db.CitationOK
+		c.Note = ""
+	case code == http.StatusNotFound || code == http.StatusGone:
+		c.Status = db.CitationDead
+		c.Note = http.StatusText(code)
+	default:
+		// Rate limits, auth walls and server errors don't prove the link is wrong
+		c.Status = db.CitationSuspicious
+		c.Note = fmt.Sprintf("HTTP %d", code)
+	}
+}
+
+// request performs one request and returns the status code
+func (v *LinkVerifier) request(ctx context.Context, method, rawURL string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, v.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("invalid URL: %w", err)
+	}
+	req.Header.Set("User-Agent", "copilot-research link checker")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return 0, errHostNotFound
+		}
+		return 0, errUnreachable
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
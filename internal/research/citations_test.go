@@ -0,0 +1,131 @@
+package research
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractCitations(t *testing.T) {
+	markdown := strings.Join([]string{
+		"Actors are described in [the Swift book](https://docs.swift.org/actors \"Actors\").",
+		"See also https://swift.org/blog/swift-6, and <https://github.com/apple/swift>.",
+		"Repeated: [Swift book](https://docs.swift.org/actors).",
+		"Our notes agree [docs/actors.md:3-9].",
+		"",
+		"```go",
+		"http.Get(\"https://api.internal.test/v1\")",
+		"```",
+		"Use `curl https://ignored.dev` to try it.",
+		"",
+		"[ref]: https://developer.apple.com/documentation/swift",
+	}, "\n")
+
+	citations := ExtractCitations(markdown)
+	require.Len(t, citations, 5)
+
+	assert.Equal(t, "the Swift book", citations[0].Text)
+	assert.Equal(t, "https://docs.swift.org/actors", citations[0].URL)
+	assert.Equal(t, db.CitationUnchecked, citations[0].Status)
+
+	assert.Equal(t, "ref", citations[1].Text)
+	assert.Equal(t, "https://developer.apple.com/documentation/swift", citations[1].URL)
+
+	assert.Equal(t, "https://github.com/apple/swift", citations[2].URL)
+	assert.Equal(t, "https://swift.org/blog/swift-6", citations[3].URL, "trailing punctuation is trimmed")
+
+	assert.Equal(t, "docs/actors.md:3-9", citations[4].Text)
+	assert.Empty(t, citations[4].URL)
+}
+
+func TestExtractCitations_FlagsSuspicious(t *testing.T) {
+	citations := ExtractCitations("Sources: https://example.com/paper, http://192.168.1.4/x and https://docs.invalid/a")
+	require.Len(t, citations, 3)
+	for _, c := range citations {
+		assert.Equal(t, db.CitationSuspicious, c.Status, c.URL)
+		assert.NotEmpty(t, c.Note)
+		assert.True(t, c.Flagged())
+	}
+}
+
+func TestExtractCitations_None(t *testing.T) {
+	assert.Empty(t, ExtractCitations("No sources here."))
+}
+
+// stubHTTPClient answers requests from a table of URL to status code
+type stubHTTPClient struct {
+	mu       sync.Mutex
+	statuses map[string]int
+	errs     map[string]error
+	methods  []string
+}
+
+func (c *stubHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	c.methods = append(c.methods, req.Method+" "+req.URL.String())
+	c.mu.Unlock()
+
+	if err, ok := c.errs[req.URL.String()]; ok {
+		return nil, err
+	}
+	code := c.statuses[req.URL.String()]
+	if req.Method == http.MethodGet {
+		if getCode, ok := c.statuses["GET "+req.URL.String()]; ok {
+			code = getCode
+		}
+	}
+	return &http.Response{StatusCode: code, Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+func TestLinkVerifier_Verify(t *testing.T) {
+	client := &stubHTTPClient{
+		statuses: map[string]int{
+			"https://ok.dev":          200,
+			"https://gone.dev":        404,
+			"https://nohead.dev":      405,
+			"GET https://nohead.dev":  200,
+			"https://ratelimited.dev": 429,
+		},
+		errs: map[string]error{
+			"https://nxdomain.dev": &net.DNSError{Err: "no such host", Name: "nxdomain.dev", IsNotFound: true},
+			"https://timeout.dev":  errors.New("i/o timeout"),
+		},
+	}
+
+	citations := []db.Citation{
+		{URL: "https://ok.dev", Status: db.CitationUnchecked},
+		{URL: "https://gone.dev", Status: db.CitationUnchecked},
+		{URL: "https://nohead.dev", Status: db.CitationUnchecked},
+		{URL: "https://ratelimited.dev", Status: db.CitationUnchecked},
+		{URL: "https://nxdomain.dev", Status: db.CitationUnchecked},
+		{URL: "https://timeout.dev", Status: db.CitationUnchecked},
+		{URL: "https://example.com", Status: db.CitationSuspicious, Note: "placeholder domain"},
+		{Text: "docs/a.md:1-2", Status: db.CitationUnchecked},
+	}
+
+	NewLinkVerifier(client, time.Second).Verify(context.Background(), citations)
+
+	assert.Equal(t, db.CitationOK, citations[0].Status)
+	assert.Equal(t, 200, citations[0].StatusCode)
+	assert.Equal(t, db.CitationDead, citations[1].Status)
+	assert.Equal(t, db.CitationOK, citations[2].Status, "falls back to GET when HEAD is rejected")
+	assert.Equal(t, db.CitationSuspicious, citations[3].Status)
+	assert.Equal(t, "HTTP 429", citations[3].Note)
+	assert.Equal(t, db.CitationDead, citations[4].Status)
+	assert.Equal(t, "host not found", citations[4].Note)
+	assert.Equal(t, db.CitationSuspicious, citations[5].Status)
+	assert.Equal(t, "placeholder domain", citations[6].Note, "statically flagged links are not fetched")
+	assert.Equal(t, db.CitationUnchecked, citations[7].Status, "file citations are not fetched")
+
+	assert.NotContains(t, client.methods, "HEAD https://example.com")
+}
@@ -0,0 +1,451 @@
+package research
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/joelklabo/copilot-research/internal/provider"
+)
+
+// StageOption is the stage reported as each compared option is researched
+const StageOption Stage = "option"
+
+// DefaultCompareCriteria are used when no criteria are given
+var DefaultCompareCriteria = []string{"Performance", "Ease of use", "Maturity", "Ecosystem", "Learning curve"}
+
+// Matrix output formats
+const (
+	MatrixMarkdown = "markdown"
+	MatrixJSON     = "json"
+	MatrixCSV      = "csv"
+)
+
+// fallbackCompareOptionTemplate is used when compare-option.md cannot be loaded
+const fallbackCompareOptionTemplate = "Evaluate **{{option}}** as one of the options in this comparison: {{options}}.\n\n" +
+	"Question: {{query}}\n\n" +
+	"Score {{option}} from 1 (poor) to 5 (excellent) on each criterion:\n\n{{criteria}}\n\n" +
+	"Reply with `SUMMARY: <one paragraph>`, then one line per criterion as `Criterion: N/5 - short justification`."
+
+// fallbackCompareOptionsTemplate is used when compare-options.md cannot be loaded
+const fallbackCompareOptionsTemplate = "List the options this question compares, one per line, giving only their names. " +
+	"Reply with NONE if it does not compare specific options.\n\nQuestion: {{query}}"
+
+// maxOptionWords is the most words an option can have; longer ones are
+// pieces of a question rather than names
+const maxOptionWords = 4
+
+var (
+	// "A vs B", "A vs. B", "A versus B"
+	versusPattern = regexp.MustCompile(`(?i)\s+(?:vs\.?|versus)\s+`)
+	// "A, B and C", "A or B"
+	listSeparatorPattern = regexp.MustCompile(`(?i)\s*,\s*(?:(?:and|or)\s+)?|\s+(?:and|or)\s+`)
+	// "A or B" without a lead-in
+	orPattern = regexp.MustCompile(`(?i)\s+or\s+`)
+	// Leading phrases after which the rest of the query lists the options
+	compareLeadInPattern = regexp.MustCompile(`(?i)^(?:compare|comparing|comparison of|contrast|choose|choosing|(?:should|would|do) (?:i|we|you) (?:use|pick|choose)|which is better|which (?:one )?should (?:i|we) (?:use|pick|choose))\b[:,]?\s*`)
+	// "... between A and B"
+	betweenPattern = regexp.MustCompile(`(?i)\bbetween\s+`)
+	// Trailing context such as "for a CLI tool"
+	compareContextPattern = regexp.MustCompile(`(?i)\s+(?:for|when)\s+`)
+	// Words that start a question rather than an option
+	questionWordPattern = regexp.MustCompile(`(?i)^(?:what|what's|how|why|which|who|when|where|is|are|do|does|did|can|could|should|would|will)\b`)
+	// Bullets and numbers in front of listed options
+	listMarkerPattern = regexp.MustCompile(`^(?:[-*•]|\d+[.)])\s*`)
+
+	optionSummaryPattern = regexp.MustCompile(`(?im)^[ \t*_#-]*summary[ \t*_]*:[ \t*_]*(.+)$`)
+	// "Criterion: 4/5 - note" or a "| Criterion | 4/5 | note |" table row
+	optionScorePattern = regexp.MustCompile(`(?im)^[ \t*_|-]*([a-z][a-z0-9 /&-]*?)[ \t*_]*[:|][ \t*_|]*([0-5])[ \t]*/[ \t]*5[ \t*_|]*(?:[-–—:|][ \t]*(.*?))?[ \t|]*$`)
+)
+
+// ParseOptions extracts the options being compared from a query such as
+// "React vs Vue for dashboards", "Compare Postgres, MySQL and SQLite" or
+// "Trade-offs between REST and GraphQL". Lists joined by "and" are only
+// recognized after a colon, "between" or a verb like "compare", since
+// ordinary questions join other words with "and". It returns nil if fewer
+// than two options are found, or any looks like part of a question.
+func ParseOptions(query string) []string {
+	q := strings.TrimSpace(strings.TrimRight(strings.TrimSpace(query), "?.!"))
+
+	listed := true
+	switch {
+	case strings.Contains(q, ":"):
+		q = q[strings.Index(q, ":")+1:]
+	case betweenPattern.MatchString(q):
+		q = q[betweenPattern.FindStringIndex(q)[1]:]
+	case compareLeadInPattern.MatchString(q):
+		q = compareLeadInPattern.ReplaceAllString(q, "")
+	default:
+		listed = false
+	}
+	q = strings.TrimSpace(q)
+
+	// Drop trailing context; it belongs to the question, not the last option
+	if loc := compareContextPattern.FindStringIndex(q); loc != nil {
+		q = q[:loc[0]]
+	}
+
+	parts := versusPattern.Split(q, -1)
+	if len(parts) < 2 {
+		if !listed && !orPattern.MatchString(q) {
+			return nil
+		}
+		parts = listSeparatorPattern.Split(q, -1)
+	}
+	return cleanOptions(parts)
+}
+
+// parseListedOptions reads the options from a response to the
+// compare-options prompt, one per line
+func parseListedOptions(text string) []string {
+	var parts []string
+	for _, line := range strings.Split(text, "\n") {
+		line = listMarkerPattern.ReplaceAllString(strings.TrimSpace(line), "")
+		if strings.EqualFold(strings.Trim(line, ".*"), "none") {
+			return nil
+		}
+		parts = append(parts, line)
+	}
+	return cleanOptions(parts)
+}
+
+// cleanOptions trims and deduplicates options. It returns nil if fewer than
+// two remain, or any is too long or starts like a question.
+func cleanOptions(parts []string) []string {
+	var options []string
+	seen := make(map[string]bool)
+	for _, part := range parts {
+		option := strings.Trim(strings.TrimSpace(part), `"'*`+"`")
+		key := strings.ToLower(option)
+		if option == "" || seen[key] {
+			continue
+		}
+		if len(strings.Fields(option)) > maxOptionWords || questionWordPattern.MatchString(option) {
+			return nil
+		}
+		seen[key] = true
+		options = append(options, option)
+	}
+
+	if len(options) < 2 {
+		return nil
+	}
+	return options
+}
+
+// identifyOptions asks the provider which options a query compares, for
+// queries ParseOptions cannot read them from
+func (e *Engine) identifyOptions(ctx context.Context, r *run, em *emitter) ([]string, error) {
+	em.started(StageQuery, "Identifying the options to compare...")
+
+	prompt := loadTemplate(e.promptLoader, "compare-options", fallbackCompareOptionsTemplate)
+	rendered := e.promptLoader.Render(prompt, map[string]string{
+		"query": r.opts.Query,
+		"mode":  "compare",
+	})
+	response, err := e.providerManager.Query(ctx, rendered, provider.QueryOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("provider query failed: %w", err)
+	}
+	em.used(response)
+
+	return parseListedOptions(response.Content), nil
+}
+
+// ParseOptionScores extracts the summary and per-criterion scores from a
+// response to the compare-option prompt. Criteria the response does not
+// score are returned with a score of 0.
+func ParseOptionScores(text string, criteria []string) db.MatrixOption {
+	var option db.MatrixOption
+
+	if m := optionSummaryPattern.FindStringSubmatch(text); m != nil {
+		option.Summary = strings.TrimSpace(m[1])
+	}
+
+	found := make(map[string]db.MatrixScore)
+	for _, m := range optionScorePattern.FindAllStringSubmatch(text, -1) {
+		name := strings.TrimSpace(m[1])
+		for _, c := range criteria {
+			if strings.EqualFold(name, c) {
+				score, _ := strconv.Atoi(m[2])
+				found[c] = db.MatrixScore{Criterion: c, Score: score, Note: strings.TrimSpace(m[3])}
+			}
+		}
+	}
+
+	for _, c := range criteria {
+		score, ok := found[c]
+		if !ok {
+			score = db.MatrixScore{Criterion: c}
+		}
+		option.Scores = append(option.Scores, score)
+	}
+
+	return option
+}
+
+// compareOptions researches each option separately and scores it against
+// the shared criteria. Options already scored in the checkpoint are kept,
+// and the checkpoint is updated as each option is scored.
+func (e *Engine) compareOptions(ctx context.Context, r *run, em *emitter) (*db.ComparisonMatrix, error) {
+	options, criteria := r.cp.Options, r.cp.Criteria
+	prompt := loadTemplate(e.promptLoader, "compare-option", fallbackCompareOptionTemplate)
+
+	var criteriaList strings.Builder
+	for _, c := range criteria {
+		fmt.Fprintf(&criteriaList, "- %s\n", c)
+	}
+
+	matrix := &db.ComparisonMatrix{Criteria: criteria, Options: make([]db.MatrixOption, len(options))}
+	done := 0
+	if previous := r.cp.Matrix; previous != nil && len(previous.Options) == len(options) {
+		for i, o := range previous.Options {
+			if o.Name != "" {
+				matrix.Options[i] = o
+				done++
+			}
+		}
+	}
+
+	em.started(StageQuery, fmt.Sprintf("Researching %d options...", len(options)-done))
+
+	type optionResult struct {
+		index    int
+		response *provider.Response
+		err      error
+	}
+	results := make(chan optionResult, len(options))
+	sem := make(chan struct{}, DefaultBatchConcurrency)
+	var wg sync.WaitGroup
+
+	for i, option := range options {
+		if matrix.Options[i].Name != "" {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, option string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			rendered := e.promptLoader.Render(prompt, map[string]string{
+				"query":    r.opts.Query,
+				"mode":     "compare",
+				"option":   option,
+				"options":  strings.Join(options, ", "),
+				"criteria": strings.TrimRight(criteriaList.String(), "\n"),
+				"context":  r.cp.Context,
+			})
+			if r.cp.Context != "" && !strings.Contains(prompt.Template, "{{context}}") {
+				rendered += "\n\n" + r.cp.Context
+			}
+
+			response, err := e.providerManager.Query(ctx, rendered, provider.QueryOptions{})
+			results <- optionResult{index: i, response: response, err: err}
+		}(i, option)
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var tokens provider.TokenUsage
+	var firstErr error
+	lastProvider := ""
+
+	for res := range results {
+		if res.err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to research %s: %w", options[res.index], res.err)
+			}
+			continue
+		}
+
+		option := ParseOptionScores(res.response.Content, criteria)
+		option.Name = options[res.index]
+		matrix.Options[res.index] = option
+
+		done++
+		em.used(res.response)
+		tokens.Prompt += res.response.TokensUsed.Prompt
+		tokens.Completion += res.response.TokensUsed.Completion
+		tokens.Total += res.response.TokensUsed.Total
+		lastProvider = res.response.Provider
+
+		em.send(Event{
+			Stage:    StageOption,
+			Status:   EventCompleted,
+			Percent:  stagePercent[StageQuery] + done*(stagePercent[StageCritique]-stagePercent[StageQuery])/len(options),
+			Message:  fmt.Sprintf("Scored %s (%d/%d)", option.Name, done, len(options)),
+			Provider: res.response.Provider,
+			Tokens:   res.response.TokensUsed,
+		})
+
+		r.cp.Matrix = matrix
+		e.checkpoint(r, em)
+	}
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	em.completed(Event{
+		Stage:    StageQuery,
+		Message:  fmt.Sprintf("Compared %d options", len(options)),
+		Provider: lastProvider,
+		Tokens:   tokens,
+	})
+
+	return matrix, nil
+}
+
+// RenderMatrix formats a comparison matrix as markdown, JSON or CSV
+func RenderMatrix(m *db.ComparisonMatrix, format string) (string, error) {
+	switch format {
+	case "", MatrixMarkdown:
+		return renderMatrixMarkdown(m), nil
+	case MatrixJSON:
+		data, err := json.MarshalIndent(m, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to encode matrix: %w", err)
+		}
+		return string(data), nil
+	case MatrixCSV:
+		return renderMatrixCSV(m)
+	default:
+		return "", fmt.Errorf("unknown matrix format: %s (valid formats: markdown, json, csv)", format)
+	}
+}
+
+// renderMatrixMarkdown renders a table of criteria by option, followed by
+// each option's summary and score justifications
+func renderMatrixMarkdown(m *db.ComparisonMatrix) string {
+	var b strings.Builder
+
+	b.WriteString("## Comparison Matrix\n\n| Criteria |")
+	for _, o := range m.Options {
+		fmt.Fprintf(&b, " %s |", escapeTableCell(o.Name))
+	}
+	b.WriteString("\n|---|")
+	for range m.Options {
+		b.WriteString("---|")
+	}
+	b.WriteString("\n")
+
+	for i, c := range m.Criteria {
+		fmt.Fprintf(&b, "| %s |", escapeTableCell(c))
+		for _, o := range m.Options {
+			fmt.Fprintf(&b, " %s |", formatScore(o, i))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("| **Average** |")
+	for _, o := range m.Options {
+		if avg := o.Average(); avg > 0 {
+			fmt.Fprintf(&b, " **%.1f** |", avg)
+		} else {
+			b.WriteString(" – |")
+		}
+	}
+	b.WriteString("\n")
+
+	if best := bestOption(m); best != nil {
+		fmt.Fprintf(&b, "\n**Highest overall:** %s (%.1f/5)\n", best.Name, best.Average())
+	}
+
+	for _, o := range m.Options {
+		fmt.Fprintf(&b, "\n### %s\n\n", o.Name)
+		if o.Summary != "" {
+			fmt.Fprintf(&b, "%s\n\n", o.Summary)
+		}
+		for _, s := range o.Scores {
+			if s.Note != "" {
+				fmt.Fprintf(&b, "- **%s** (%s): %s\n", s.Criterion, scoreText(s.Score), s.Note)
+			}
+		}
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// renderMatrixCSV renders one row per criterion with a score column per
+// option; unknown scores are left empty
+func renderMatrixCSV(m *db.ComparisonMatrix) (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	header := []string{"Criterion"}
+	for _, o := range m.Options {
+		header = append(header, o.Name)
+	}
+	rows := [][]string{header}
+
+	for i, c := range m.Criteria {
+		row := []string{c}
+		for _, o := range m.Options {
+			cell := ""
+			if i < len(o.Scores) && o.Scores[i].Score > 0 {
+				cell = strconv.Itoa(o.Scores[i].Score)
+			}
+			row = append(row, cell)
+		}
+		rows = append(rows, row)
+	}
+
+	average := []string{"Average"}
+	for _, o := range m.Options {
+		cell := ""
+		if avg := o.Average(); avg > 0 {
+			cell = strconv.FormatFloat(avg, 'f', 1, 64)
+		}
+		average = append(average, cell)
+	}
+	rows = append(rows, average)
+
+	if err := w.WriteAll(rows); err != nil {
+		return "", fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return strings.TrimRight(buf.String(), "\n"), nil
+}
+
+// formatScore renders the score of option o for the i-th criterion
+func formatScore(o db.MatrixOption, i int) string {
+	if i >= len(o.Scores) {
+		return "–"
+	}
+	return scoreText(o.Scores[i].Score)
+}
+
+// scoreText renders a 1-5 score as stars, or a dash when unknown
+func scoreText(score int) string {
+	if score <= 0 {
+		return "–"
+	}
+	return strings.Repeat("★", score) + strings.Repeat("☆", 5-score)
+}
+
+// bestOption returns the option with the highest average score, or nil if
+// nothing was scored
+func bestOption(m *db.ComparisonMatrix) *db.MatrixOption {
+	var best *db.MatrixOption
+	for i := range m.Options {
+		if avg := m.Options[i].Average(); avg > 0 && (best == nil || avg > best.Average()) {
+			best = &m.Options[i]
+		}
+	}
+	return best
+}
+
+// escapeTableCell keeps pipes in names from breaking the table
+func escapeTableCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
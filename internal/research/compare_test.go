@@ -0,0 +1,221 @@
+package research
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/joelklabo/copilot-research/internal/prompts"
+	"github.com/joelklabo/copilot-research/internal/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"React vs Vue", []string{"React", "Vue"}},
+		{"React vs. Vue vs Svelte for dashboards?", []string{"React", "Vue", "Svelte"}},
+		{"Postgres versus MySQL", []string{"Postgres", "MySQL"}},
+		{"Compare Postgres, MySQL and SQLite", []string{"Postgres", "MySQL", "SQLite"}},
+		{"Compare React and Vue", []string{"React", "Vue"}},
+		{"Differences between gRPC and REST when building internal APIs", []string{"gRPC", "REST"}},
+		{"Should I use Kafka or RabbitMQ?", []string{"Kafka", "RabbitMQ"}},
+		{"React vs react", nil},
+		{"How do Swift actors work?", nil},
+		{"What are the trade-offs between REST and GraphQL?", []string{"REST", "GraphQL"}},
+		{"How do Postgres and MySQL handle replication?", nil},
+		{"Which is better for web apps: Django or Rails", []string{"Django", "Rails"}},
+		{"Which is better: the one we built last year or something off the shelf", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParseOptions(tt.query))
+		})
+	}
+}
+
+func TestParseListedOptions(t *testing.T) {
+	assert.Equal(t, []string{"Postgres", "MySQL"}, parseListedOptions("1. Postgres\n2. MySQL\n"))
+	assert.Equal(t, []string{"Postgres", "MySQL"}, parseListedOptions("- **Postgres**\n- MySQL"))
+	assert.Nil(t, parseListedOptions("NONE"))
+	assert.Nil(t, parseListedOptions("Postgres"))
+}
+
+func TestParseOptionScores(t *testing.T) {
+	response := `SUMMARY: A mature relational database.
+
+- **Performance**: 4/5 - fast for mixed workloads
+Maturity: 5/5
+| Ecosystem | 3/5 | fewer hosted options |
+Cost: 2/5 - not a requested criterion`
+
+	option := ParseOptionScores(response, []string{"Performance", "Maturity", "Ecosystem", "Learning curve"})
+
+	assert.Equal(t, "A mature relational database.", option.Summary)
+	assert.Equal(t, []db.MatrixScore{
+		{Criterion: "Performance", Score: 4, Note: "fast for mixed workloads"},
+		{Criterion: "Maturity", Score: 5},
+		{Criterion: "Ecosystem", Score: 3, Note: "fewer hosted options"},
+		{Criterion: "Learning curve", Score: 0},
+	}, option.Scores)
+}
+
+func testMatrix() *db.ComparisonMatrix {
+	return &db.ComparisonMatrix{
+		Criteria: []string{"Speed", "Docs"},
+		Options: []db.MatrixOption{
+			{Name: "Alpha", Summary: "The first one.", Scores: []db.MatrixScore{{Criterion: "Speed", Score: 5, Note: "very fast"}, {Criterion: "Docs", Score: 3}}},
+			{Name: "Beta, Inc", Scores: []db.MatrixScore{{Criterion: "Speed", Score: 2}, {Criterion: "Docs"}}},
+		},
+	}
+}
+
+func TestRenderMatrix_Markdown(t *testing.T) {
+	out, err := RenderMatrix(testMatrix(), MatrixMarkdown)
+	require.NoError(t, err)
+
+	assert.Contains(t, out, "| Criteria | Alpha | Beta, Inc |")
+	assert.Contains(t, out, "| Speed | ★★★★★ | ★★☆☆☆ |")
+	assert.Contains(t, out, "| Docs | ★★★☆☆ | – |")
+	assert.Contains(t, out, "| **Average** | **4.0** | **2.0** |")
+	assert.Contains(t, out, "**Highest overall:** Alpha (4.0/5)")
+	assert.Contains(t, out, "### Alpha\n\nThe first one.\n\n- **Speed** (★★★★★): very fast")
+}
+
+func TestRenderMatrix_CSV(t *testing.T) {
+	out, err := RenderMatrix(testMatrix(), MatrixCSV)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Criterion,Alpha,\"Beta, Inc\"\nSpeed,5,2\nDocs,3,\nAverage,4.0,2.0", out)
+}
+
+func TestRenderMatrix_JSON(t *testing.T) {
+	out, err := RenderMatrix(testMatrix(), MatrixJSON)
+	require.NoError(t, err)
+
+	var decoded db.ComparisonMatrix
+	require.NoError(t, json.Unmarshal([]byte(out), &decoded))
+	assert.Equal(t, *testMatrix(), decoded)
+}
+
+func TestRenderMatrix_UnknownFormat(t *testing.T) {
+	_, err := RenderMatrix(testMatrix(), "xml")
+	assert.ErrorContains(t, err, "unknown matrix format")
+}
+
+func TestEngine_Research_CompareMatrix(t *testing.T) {
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	defer database.Close()
+
+	mock := &funcProvider{MockProvider: MockProvider{name: "test", authenticated: true}, fn: func(prompt string) string {
+		if strings.Contains(prompt, "**Kafka**") {
+			return "SUMMARY: A distributed log.\nThroughput: 5/5 - built for it\nSimplicity: 2/5"
+		}
+		return "SUMMARY: A message broker.\nThroughput: 3/5\nSimplicity: 4/5"
+	}}
+	factory := provider.NewProviderFactory()
+	require.NoError(t, factory.Register("test", mock))
+	engine := NewEngine(database, prompts.NewPromptLoader("../../prompts"), provider.NewProviderManager(factory, "test", "", false, false))
+
+	events := make(chan Event, 50)
+	result, err := engine.Research(context.Background(), ResearchOptions{
+		Query:    "Kafka vs RabbitMQ for event sourcing",
+		Mode:     "compare",
+		Criteria: []string{"Throughput", "Simplicity"},
+	}, events)
+	close(events)
+	require.NoError(t, err)
+
+	require.NotNil(t, result.Matrix)
+	require.Len(t, result.Matrix.Options, 2)
+	assert.Equal(t, "Kafka", result.Matrix.Options[0].Name)
+	assert.Equal(t, 5, result.Matrix.Options[0].Scores[0].Score)
+	assert.Equal(t, "RabbitMQ", result.Matrix.Options[1].Name)
+	assert.Equal(t, 4, result.Matrix.Options[1].Scores[1].Score)
+	assert.Contains(t, result.Content, "| Criteria | Kafka | RabbitMQ |")
+
+	session, err := database.GetSession(result.SessionID)
+	require.NoError(t, err)
+	assert.Equal(t, result.Matrix, session.Matrix)
+
+	scored := 0
+	var queryDone Event
+	for e := range events {
+		if e.Stage == StageOption {
+			scored++
+		}
+		if e.Stage == StageQuery && e.Status == EventCompleted {
+			queryDone = e
+		}
+	}
+	assert.Equal(t, 2, scored)
+	assert.Equal(t, 20, queryDone.Tokens.Total)
+	assert.Equal(t, 20, result.Tokens.Total)
+}
+
+func TestEngine_Research_CompareAsksForOptions(t *testing.T) {
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	defer database.Close()
+
+	mock := &funcProvider{MockProvider: MockProvider{name: "test", authenticated: true}, fn: func(prompt string) string {
+		if strings.Contains(prompt, "list the options it compares") {
+			return "Postgres\nMySQL"
+		}
+		return "SUMMARY: A relational database.\nReplication: 4/5"
+	}}
+	factory := provider.NewProviderFactory()
+	require.NoError(t, factory.Register("test", mock))
+	engine := NewEngine(database, prompts.NewPromptLoader("../../prompts"), provider.NewProviderManager(factory, "test", "", false, false))
+
+	result, err := engine.Research(context.Background(), ResearchOptions{
+		Query:    "How do Postgres and MySQL handle replication?",
+		Mode:     "compare",
+		Criteria: []string{"Replication"},
+	}, nil)
+	require.NoError(t, err)
+
+	require.NotNil(t, result.Matrix)
+	require.Len(t, result.Matrix.Options, 2)
+	assert.Equal(t, "Postgres", result.Matrix.Options[0].Name)
+	assert.Equal(t, "MySQL", result.Matrix.Options[1].Name)
+}
+
+func TestEngine_Research_CompareFallsBackWithoutOptions(t *testing.T) {
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	defer database.Close()
+
+	mock := &funcProvider{MockProvider: MockProvider{name: "test", authenticated: true}, fn: func(prompt string) string {
+		return "A single comparison"
+	}}
+	factory := provider.NewProviderFactory()
+	require.NoError(t, factory.Register("test", mock))
+	engine := NewEngine(database, prompts.NewPromptLoader("../../prompts"), provider.NewProviderManager(factory, "test", "", false, false))
+
+	events := make(chan Event, 50)
+	result, err := engine.Research(context.Background(), ResearchOptions{Query: "Best web framework", Mode: "compare"}, events)
+	close(events)
+	require.NoError(t, err)
+
+	assert.Nil(t, result.Matrix)
+	assert.Equal(t, "A single comparison", result.Content)
+
+	warned := false
+	for e := range events {
+		if e.IsWarning() && e.Stage == StageQuery {
+			warned = true
+		}
+	}
+	assert.True(t, warned)
+
+	_, err = engine.Research(context.Background(), ResearchOptions{Query: "x", Mode: "compare", Options: []string{"only"}}, nil)
+	assert.ErrorContains(t, err, "at least two options")
+}
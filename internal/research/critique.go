@@ -0,0 +1,143 @@
+package research
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/joelklabo/copilot-research/internal/prompts"
+)
+
+// DefaultCritiqueThreshold is the score below which an answer is revised
+const DefaultCritiqueThreshold = 70
+
+// Fallback templates used when critique.md or revise.md cannot be loaded
+const (
+	fallbackCritiqueTemplate = "Grade the following answer to \"{{query}}\" ({{mode}} research).\n\n" +
+		"Score each criterion from 0 to 10:\n\n{{rubric}}\n\n" +
+		"Reply with one line per criterion as `Criterion: N/10`, then `OVERALL: N` (0-100), then a short critique.\n\n" +
+		"Answer:\n\n{{answer}}"
+	fallbackReviseTemplate = "Revise the following answer to \"{{query}}\" ({{mode}} research) to address the critique. " +
+		"Reply with the complete revised answer only.\n\nAnswer:\n\n{{answer}}\n\nCritique:\n\n{{critique}}"
+)
+
+// RubricCriterion is one aspect an answer is graded on
+type RubricCriterion struct {
+	Name        string
+	Description string
+	Weight      int // Relative weight; weights in a rubric sum to 100
+}
+
+// rubrics define what a good answer looks like for each mode
+var rubrics = map[string][]RubricCriterion{
+	"quick": {
+		{"Accuracy", "Claims are correct and uncertainty is hedged rather than stated as fact", 40},
+		{"Structure", "Follows the TL;DR / key points format and stays brief", 30},
+		{"Examples", "Includes one clear, correct example where applicable", 15},
+		{"Citations", "Links to 2-3 authoritative sources", 15},
+	},
+	"deep": {
+		{"Accuracy", "Claims are correct and uncertainty is hedged rather than stated as fact", 35},
+		{"Structure", "Covers background, details, trade-offs and best practices in clear sections", 20},
+		{"Examples", "Includes several realistic, correct code examples", 25},
+		{"Citations", "Supports key claims with authoritative sources", 20},
+	},
+	"compare": {
+		{"Accuracy", "Claims about each option are correct and hedged where uncertain", 35},
+		{"Structure", "Compares options on the same criteria, ideally in a table, and ends with a recommendation", 30},
+		{"Examples", "Shows representative usage of each option", 15},
+		{"Citations", "Links to the official sources of each option", 20},
+	},
+	"synthesis": {
+		{"Accuracy", "Combined claims are consistent and conflicts between sources are called out", 35},
+		{"Structure", "Organizes findings into themes rather than repeating sources one by one", 30},
+		{"Examples", "Illustrates the synthesized findings with concrete examples", 10},
+		{"Citations", "Attributes each finding to its sources", 25},
+	},
+}
+
+// RubricFor returns the rubric for a mode, falling back to the quick rubric
+func RubricFor(mode string) []RubricCriterion {
+	if rubric, ok := rubrics[mode]; ok {
+		return rubric
+	}
+	return rubrics["quick"]
+}
+
+// renderRubric formats a rubric as a markdown list for the critique prompt
+func renderRubric(rubric []RubricCriterion) string {
+	var b strings.Builder
+	for _, c := range rubric {
+		fmt.Fprintf(&b, "- **%s** (weight %d%%): %s\n", c.Name, c.Weight, c.Description)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Critique is a graded review of an answer
+type Critique struct {
+	Score  int            // Overall score from 0 to 100
+	Scores map[string]int // Per-criterion scores from 0 to 10
+	Text   string         // The reviewer's full response
+}
+
+var (
+	overallPattern   = regexp.MustCompile(`(?im)^[\s*_#-]*overall[\s*_]*:[\s*_]*(\d{1,3})`)
+	criterionPattern = regexp.MustCompile(`(?im)^[\s*_-]*([a-z][a-z /&-]*?)[\s*_]*:[\s*_]*(\d{1,2})\s*/\s*10`)
+)
+
+// ParseCritique extracts scores from a reviewer's response. The overall score
+// is taken from the OVERALL line, or computed from the weighted criterion
+// scores when that line is missing.
+func ParseCritique(text string, rubric []RubricCriterion) (*Critique, error) {
+	critique := &Critique{
+		Scores: make(map[string]int),
+		Text:   strings.TrimSpace(text),
+	}
+
+	for _, m := range criterionPattern.FindAllStringSubmatch(text, -1) {
+		score, _ := strconv.Atoi(m[2])
+		if score > 10 {
+			score = 10
+		}
+		for _, c := range rubric {
+			if strings.EqualFold(strings.TrimSpace(m[1]), c.Name) {
+				critique.Scores[c.Name] = score
+			}
+		}
+	}
+
+	if m := overallPattern.FindStringSubmatch(text); m != nil {
+		score, _ := strconv.Atoi(m[1])
+		if score > 100 {
+			score = 100
+		}
+		critique.Score = score
+		return critique, nil
+	}
+
+	if len(critique.Scores) == 0 {
+		return nil, fmt.Errorf("critique contained no scores")
+	}
+
+	total, weights := 0, 0
+	for _, c := range rubric {
+		if score, ok := critique.Scores[c.Name]; ok {
+			total += score * c.Weight
+			weights += c.Weight
+		}
+	}
+	critique.Score = total * 10 / weights
+
+	return critique, nil
+}
+
+// loadTemplate loads a prompt template by name, or returns fallback if the
+// prompt is not available
+func loadTemplate(loader *prompts.PromptLoader, name, fallback string) *prompts.Prompt {
+	prompt, err := loader.Load(name)
+	if err != nil {
+		return &prompts.Prompt{Name: name, Template: fallback}
+	}
+	return prompt
+}
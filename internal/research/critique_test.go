@@ -0,0 +1,174 @@
+package research
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/joelklabo/copilot-research/internal/prompts"
+	"github.com/joelklabo/copilot-research/internal/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRubricFor(t *testing.T) {
+	for mode, rubric := range rubrics {
+		total := 0
+		for _, c := range rubric {
+			total += c.Weight
+		}
+		assert.Equal(t, 100, total, "weights for %s should sum to 100", mode)
+	}
+
+	assert.Equal(t, rubrics["deep"], RubricFor("deep"))
+	assert.Equal(t, rubrics["quick"], RubricFor("unknown"))
+}
+
+func TestRenderRubric(t *testing.T) {
+	rendered := renderRubric(RubricFor("compare"))
+	assert.Contains(t, rendered, "- **Accuracy** (weight 35%)")
+	assert.Contains(t, rendered, "- **Citations** (weight 20%)")
+}
+
+func TestParseCritique(t *testing.T) {
+	rubric := RubricFor("quick")
+
+	t.Run("overall line", func(t *testing.T) {
+		text := "Accuracy: 8/10\n**Structure**: 6/10\n- Examples: 5 / 10\nCitations: 2/10\nOVERALL: 64\n\nAdd real sources."
+		critique, err := ParseCritique(text, rubric)
+		require.NoError(t, err)
+		assert.Equal(t, 64, critique.Score)
+		assert.Equal(t, map[string]int{"Accuracy": 8, "Structure": 6, "Examples": 5, "Citations": 2}, critique.Scores)
+		assert.Contains(t, critique.Text, "Add real sources.")
+	})
+
+	t.Run("weighted fallback", func(t *testing.T) {
+		text := "Accuracy: 10/10\nStructure: 5/10\nExamples: 10/10\nCitations: 0/10"
+		critique, err := ParseCritique(text, rubric)
+		require.NoError(t, err)
+		// (10*40 + 5*30 + 10*15 + 0*15) * 10 / 100
+		assert.Equal(t, 70, critique.Score)
+	})
+
+	t.Run("clamped", func(t *testing.T) {
+		critique, err := ParseCritique("Overall: 250", rubric)
+		require.NoError(t, err)
+		assert.Equal(t, 100, critique.Score)
+	})
+
+	t.Run("no scores", func(t *testing.T) {
+		_, err := ParseCritique("Looks good to me!", rubric)
+		assert.Error(t, err)
+	})
+}
+
+// scriptedProvider answers queries with a fixed sequence of responses
+type scriptedProvider struct {
+	MockProvider
+	mu        sync.Mutex
+	responses []string
+	prompts   []string
+}
+
+func (p *scriptedProvider) Query(ctx context.Context, prompt string, opts provider.QueryOptions) (*provider.Response, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.prompts = append(p.prompts, prompt)
+	if len(p.responses) == 0 {
+		return nil, fmt.Errorf("no more responses")
+	}
+	content := p.responses[0]
+	p.responses = p.responses[1:]
+	return &provider.Response{Content: content}, nil
+}
+
+func newScriptedEngine(t *testing.T, responses ...string) (*Engine, *scriptedProvider, db.DB) {
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { database.Close() })
+
+	scripted := &scriptedProvider{
+		MockProvider: MockProvider{name: "test", authenticated: true},
+		responses:    responses,
+	}
+	factory := provider.NewProviderFactory()
+	require.NoError(t, factory.Register("test", scripted))
+	providerMgr := provider.NewProviderManager(factory, "test", "", false, false)
+
+	engine := NewEngine(database, prompts.NewPromptLoader("../../prompts"), providerMgr)
+	return engine, scripted, database
+}
+
+func TestEngine_Research_CritiqueAboveThreshold(t *testing.T) {
+	engine, scripted, database := newScriptedEngine(t,
+		"Good answer",
+		"Accuracy: 9/10\nStructure: 8/10\nExamples: 8/10\nCitations: 7/10\nOVERALL: 85\nSolid.",
+	)
+
+	opts := ResearchOptions{Query: "What are actors?", Mode: "quick", PromptName: "default", Critique: true}
+	result, err := engine.Research(context.Background(), opts, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Good answer", result.Content)
+	require.NotNil(t, result.QualityScore)
+	assert.Equal(t, 85, *result.QualityScore)
+	assert.False(t, result.Revised)
+	require.Len(t, scripted.prompts, 2)
+	assert.Contains(t, scripted.prompts[1], "**Accuracy** (weight 40%)")
+	assert.Contains(t, scripted.prompts[1], "Good answer")
+
+	session, err := database.GetSession(result.SessionID)
+	require.NoError(t, err)
+	require.NotNil(t, session.QualityScore)
+	assert.Equal(t, 85, *session.QualityScore)
+	assert.Contains(t, session.Critique, "Solid.")
+}
+
+func TestEngine_Research_CritiqueRevisesLowScore(t *testing.T) {
+	engine, scripted, _ := newScriptedEngine(t,
+		"Weak answer",
+		"OVERALL: 40\nNo sources and no example.",
+		"Improved answer with an example",
+		"OVERALL: 78\nMuch better.",
+	)
+
+	opts := ResearchOptions{Query: "What are actors?", Mode: "quick", PromptName: "default", Critique: true, NoStore: true}
+	result, err := engine.Research(context.Background(), opts, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Improved answer with an example", result.Content)
+	assert.True(t, result.Revised)
+	require.NotNil(t, result.QualityScore)
+	assert.Equal(t, 78, *result.QualityScore)
+	assert.Equal(t, "OVERALL: 78\nMuch better.", result.Critique)
+
+	require.Len(t, scripted.prompts, 4)
+	assert.Contains(t, scripted.prompts[2], "Weak answer")
+	assert.Contains(t, scripted.prompts[2], "No sources and no example.")
+}
+
+func TestEngine_Research_CritiqueFailureKeepsAnswer(t *testing.T) {
+	engine, _, _ := newScriptedEngine(t, "Only answer")
+
+	progress := make(chan Event, 20)
+	opts := ResearchOptions{Query: "q", Mode: "quick", PromptName: "default", Critique: true, NoStore: true}
+	result, err := engine.Research(context.Background(), opts, progress)
+	close(progress)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Only answer", result.Content)
+	assert.Nil(t, result.QualityScore)
+
+	var warned bool
+	for event := range progress {
+		if event.IsWarning() && event.Stage == StageCritique {
+			assert.True(t, strings.HasPrefix(event.String(), "Warning: Critique failed"))
+			warned = true
+		}
+	}
+	assert.True(t, warned)
+}
@@ -3,6 +3,7 @@ package research
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/joelklabo/copilot-research/internal/db"
@@ -16,15 +17,50 @@ type ResearchOptions struct {
 	Mode       string
 	PromptName string
 	NoStore    bool
+	Context    []ContextSnippet // Local file excerpts to ground the answer in
+
+	Critique          bool // Grade the answer against the mode's rubric
+	CritiqueThreshold int  // Revise answers scoring below this; 0 uses DefaultCritiqueThreshold
+
+	Options  []string // Options to compare in compare mode; parsed from the query if empty
+	Criteria []string // Criteria to score options on; DefaultCompareCriteria if empty
+
+	Refine  bool      // Expand the query into a research brief before the main query
+	Clarify Clarifier // Asks the user the model's clarifying questions; skipped if nil
+}
+
+// ContextSnippet is an excerpt of a local file injected into the prompt
+type ContextSnippet struct {
+	Path      string
+	StartLine int
+	EndLine   int
+	Content   string
+}
+
+// Citation returns the file/line reference used to cite the snippet
+func (c ContextSnippet) Citation() string {
+	return fmt.Sprintf("%s:%d-%d", c.Path, c.StartLine, c.EndLine)
 }
 
 // ResearchResult contains the result of a research query
 type ResearchResult struct {
-	Query     string
-	Mode      string
-	Content   string
-	Duration  time.Duration
-	SessionID int64
+	Query        string
+	RefinedQuery string // Research brief the query was expanded into, if refined
+	Mode         string
+	Content      string
+	Duration     time.Duration
+	SessionID    int64
+	Citations    []db.Citation
+	Matrix       *db.ComparisonMatrix // Set when compare mode scored the options separately
+	Prior        *PriorResearch       // Past sessions and knowledge a synthesis drew on
+
+	Provider string              // Provider of the last answer
+	Model    string              // Model of the last answer, when the provider reports it
+	Tokens   provider.TokenUsage // Tokens used by every provider call of the run
+
+	QualityScore *int   // Rubric score from 0 to 100, set when critique is enabled
+	Critique     string // Reviewer feedback behind QualityScore
+	Revised      bool   // Whether the answer was revised after critique
 }
 
 // Engine coordinates the research process
@@ -32,6 +68,8 @@ type Engine struct {
 	db              db.DB
 	promptLoader    *prompts.PromptLoader
 	providerManager *provider.ProviderManager
+	verifier        *LinkVerifier
+	knowledge       KnowledgeSource
 }
 
 // NewEngine creates a new research engine
@@ -43,102 +81,623 @@ func NewEngine(database db.DB, loader *prompts.PromptLoader, providerMgr *provid
 	}
 }
 
-// Research executes a research query
-func (e *Engine) Research(ctx context.Context, opts ResearchOptions, progress chan<- string) (*ResearchResult, error) {
-	start := time.Now()
+// SetLinkVerifier enables checking that cited links resolve. A nil verifier
+// disables checking; citations are still extracted.
+func (e *Engine) SetLinkVerifier(verifier *LinkVerifier) {
+	e.verifier = verifier
+}
+
+// SetKnowledgeSource lets synthesis mode draw on the knowledge base in
+// addition to past sessions
+func (e *Engine) SetKnowledgeSource(source KnowledgeSource) {
+	e.knowledge = source
+}
+
+// CheckpointError is returned when a run fails after it was checkpointed.
+// The run can be continued with Engine.Resume.
+type CheckpointError struct {
+	SessionID int64
+	Err       error
+}
+
+func (e *CheckpointError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CheckpointError) Unwrap() error {
+	return e.Err
+}
+
+// run is the state of a research run, new or resumed
+type run struct {
+	opts       ResearchOptions
+	mode       string
+	promptName string
+	cp         *db.Checkpoint
+	session    *db.ResearchSession // The checkpointed session, nil until the first checkpoint
+	prior      *PriorResearch
+	refined    string // Research brief from the refine stage
 
+	qualityScore *int
+	critique     string
+	revised      bool
+
+	checkpointFailed bool
+}
+
+// done reports whether stage was completed before the last checkpoint
+func (r *run) done(stage Stage) bool {
+	return stageIndex(stage) <= stageIndex(Stage(r.cp.Stage))
+}
+
+// brief returns the query the research is about: the refined brief if
+// there is one, otherwise the query as written
+func (r *run) brief() string {
+	if r.refined != "" {
+		return r.refined
+	}
+	return r.opts.Query
+}
+
+// Research executes a research query, reporting progress on events if it
+// is not nil. Unless NoStore is set, progress is checkpointed to an
+// in-progress session after every completed step.
+func (e *Engine) Research(ctx context.Context, opts ResearchOptions, events chan<- Event) (*ResearchResult, error) {
 	// Check context first
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
 
-	// Send progress: Loading prompt
-	if progress != nil {
-		progress <- "Loading prompt..."
+	r := &run{
+		opts:       opts,
+		mode:       opts.Mode,
+		promptName: opts.PromptName,
+		cp: &db.Checkpoint{
+			Critique:          opts.Critique,
+			CritiqueThreshold: opts.CritiqueThreshold,
+			Options:           opts.Options,
+			Criteria:          opts.Criteria,
+			Snippets:          checkpointSnippets(opts.Context),
+		},
 	}
-
-	// Load the prompt template
-	promptName := opts.PromptName
-	if promptName == "" {
-		promptName = "default"
+	if r.mode == "" {
+		r.mode = "quick"
+	}
+	if r.promptName == "" {
+		r.promptName = "default"
 	}
 
-	prompt, err := e.promptLoader.Load(promptName)
+	return e.execute(ctx, r, newEmitter(events))
+}
+
+// Resume continues an interrupted session from its last checkpoint
+func (e *Engine) Resume(ctx context.Context, id int64, events chan<- Event) (*ResearchResult, error) {
+	session, err := e.db.GetSession(id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load prompt: %w", err)
+		return nil, err
+	}
+	if !session.InProgress() && !session.Cancelled() {
+		return nil, fmt.Errorf("session #%d is already complete", id)
+	}
+	if session.Checkpoint == nil {
+		return nil, fmt.Errorf("session #%d has no checkpoint to resume from", id)
 	}
 
-	// Render the prompt with variables
-	mode := opts.Mode
-	if mode == "" {
-		mode = "quick"
+	cp := session.Checkpoint
+	r := &run{
+		opts: ResearchOptions{
+			Query:             session.Query,
+			Mode:              session.Mode,
+			PromptName:        session.PromptUsed,
+			Critique:          cp.Critique,
+			CritiqueThreshold: cp.CritiqueThreshold,
+			Options:           cp.Options,
+			Criteria:          cp.Criteria,
+			Context:           restoreSnippets(cp.Snippets),
+		},
+		mode:         session.Mode,
+		promptName:   session.PromptUsed,
+		cp:           cp,
+		session:      session,
+		refined:      session.RefinedQuery,
+		qualityScore: session.QualityScore,
+		critique:     session.Critique,
 	}
 
-	renderedPrompt := e.promptLoader.Render(prompt, map[string]string{
-		"query": opts.Query,
-		"mode":  mode,
-	})
+	// Usage so far carries over, so the session totals the whole run
+	em := newEmitter(events)
+	em.provider, em.model, em.tokens = session.Provider, session.Model, provider.TokenUsage(session.Tokens)
+	em.send(Event{Stage: Stage(cp.Stage), Status: EventCompleted, Message: fmt.Sprintf("Resuming session #%d after %s", id, cp.Stage)})
+
+	return e.execute(ctx, r, em)
+}
+
+// execute runs the pipeline, marking errors from checkpointed runs as
+// resumable. A run stopped by its context is stored as cancelled, with the
+// output it had so far.
+func (e *Engine) execute(ctx context.Context, r *run, em *emitter) (*ResearchResult, error) {
+	result, err := e.pipeline(ctx, r, em)
+	if err != nil && ctx.Err() != nil {
+		e.save(r, em, db.SessionCancelled)
+	}
+	e.logSearch(ctx, r, em, result, err)
+	if err != nil && r.session != nil {
+		return nil, &CheckpointError{SessionID: r.session.ID, Err: err}
+	}
+	return result, err
+}
+
+// pipeline runs every stage not completed before the last checkpoint
+func (e *Engine) pipeline(ctx context.Context, r *run, em *emitter) (*ResearchResult, error) {
+	// Expand the query into a research brief, asking the user if needed
+	if r.opts.Refine && !r.done(StageRefine) {
+		if err := e.refine(ctx, r, em); err != nil {
+			return nil, err
+		}
+		r.cp.Stage = string(StageRefine)
+		e.checkpoint(r, em)
+	}
+
+	if !r.done(StageLoadPrompt) {
+		if err := e.preparePrompt(r, em); err != nil {
+			return nil, err
+		}
+		r.cp.Stage = string(StageLoadPrompt)
+		e.checkpoint(r, em)
+	}
 
 	// Check context again
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
 
-	// Send progress: Querying provider
-	if progress != nil {
-		progress <- "Querying AI provider..."
+	if !r.done(StageQuery) {
+		if err := e.query(ctx, r, em); err != nil {
+			return nil, err
+		}
+		r.cp.Stage = string(StageQuery)
+		e.checkpoint(r, em)
 	}
 
-	// Query the provider
-	response, err := e.providerManager.Query(ctx, renderedPrompt, provider.QueryOptions{})
-	if err != nil {
-		return nil, fmt.Errorf("provider query failed: %w", err)
-	}
+	content := r.cp.Content
 
-	// Send progress: Processing results
-	if progress != nil {
-		progress <- "Processing results..."
+	// Grade the answer and revise it once if it scores too low
+	if r.opts.Critique && !r.done(StageCritique) {
+		// Grade against the brief, which says what the answer should cover
+		opts := r.opts
+		opts.Query = r.brief()
+		critique, revisedContent, revised := e.critiqueAndRevise(ctx, opts, r.mode, content, em)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		content = revisedContent
+		r.revised = revised
+		r.qualityScore, r.critique = nil, ""
+		if critique != nil {
+			score := critique.Score
+			r.qualityScore = &score
+			r.critique = critique.Text
+		}
+
+		r.cp.Content = content
+		r.cp.Stage = string(StageCritique)
+		e.checkpoint(r, em)
 	}
 
-	duration := time.Since(start)
+	// List the prior research a synthesis drew on
+	content = appendSources(content, r.cp.Sources)
+
+	// Extract and optionally verify cited sources
+	citations := ExtractCitations(content)
+	if e.verifier != nil && len(citations) > 0 {
+		em.started(StageVerify, fmt.Sprintf("Verifying %d citations...", len(citations)))
+		e.verifier.Verify(ctx, citations)
+
+		flagged := 0
+		for _, c := range citations {
+			if c.Flagged() {
+				flagged++
+			}
+		}
+		em.completed(Event{Stage: StageVerify, Message: fmt.Sprintf("Verified %d citations, %d flagged", len(citations), flagged)})
+	}
 
 	// Create result
 	result := &ResearchResult{
-		Query:    opts.Query,
-		Mode:     mode,
-		Content:  response.Content,
-		Duration: duration,
+		Query:        r.opts.Query,
+		RefinedQuery: r.refined,
+		Mode:         r.mode,
+		Content:      content,
+		Duration:     time.Since(em.start),
+		Citations:    citations,
+		Matrix:       r.cp.Matrix,
+		Prior:        r.prior,
+		Provider:     em.provider,
+		Model:        em.model,
+		Tokens:       em.tokens,
+		QualityScore: r.qualityScore,
+		Critique:     r.critique,
+		Revised:      r.revised,
 	}
 
 	// Store in database if not disabled
-	if !opts.NoStore {
-		if progress != nil {
-			progress <- "Storing in database..."
-		}
+	if !r.opts.NoStore {
+		em.started(StageStore, "Storing in database...")
 
-		session := &db.ResearchSession{
-			Query:      opts.Query,
-			Mode:       mode,
-			PromptUsed: promptName,
-			Result:     response.Content,
-			CreatedAt:  time.Now(),
+		session := r.session
+		if session == nil {
+			session = &db.ResearchSession{CreatedAt: time.Now()}
 		}
+		session.Query = r.opts.Query
+		session.RefinedQuery = r.refined
+		session.Mode = r.mode
+		session.PromptUsed = r.promptName
+		session.Result = content
+		session.QualityScore = result.QualityScore
+		session.Critique = result.Critique
+		session.Citations = citations
+		session.Matrix = result.Matrix
+		session.Provider = result.Provider
+		session.Model = result.Model
+		session.Tokens = db.TokenCount(result.Tokens)
+		session.Status = db.SessionComplete
+		session.Checkpoint = nil
 
-		if err := e.db.SaveSession(session); err != nil {
+		var err error
+		if session.ID == 0 {
+			err = e.db.SaveSession(session)
+		} else {
+			err = e.db.UpdateSession(session)
+		}
+		if err != nil {
 			// Don't fail the entire operation if storage fails
-			// Just log and continue
-			if progress != nil {
-				progress <- fmt.Sprintf("Warning: Failed to store session: %v", err)
-			}
+			em.warn(StageStore, "Failed to store session: %v", err)
 		} else {
 			result.SessionID = session.ID
+			em.completed(Event{Stage: StageStore, Message: fmt.Sprintf("Stored session #%d", session.ID)})
 		}
 	}
 
-	// Send completion progress
-	if progress != nil {
-		progress <- "Complete!"
-	}
+	em.completed(Event{Stage: StageComplete, Message: "Complete!"})
 
 	return result, nil
 }
+
+// preparePrompt loads the prompt template, gathers what it should be
+// grounded in and renders it into the checkpoint
+func (e *Engine) preparePrompt(r *run, em *emitter) error {
+	em.started(StageLoadPrompt, "Loading prompt...")
+
+	// Load the prompt template
+	prompt, err := e.promptLoader.Load(r.promptName)
+	if err != nil {
+		return fmt.Errorf("failed to load prompt: %w", err)
+	}
+
+	// Synthesis mode builds on past sessions and the knowledge base
+	if r.mode == "synthesis" {
+		em.started(StageGather, "Gathering prior research...")
+		prior, err := e.gatherPrior(r.brief())
+		switch {
+		case err != nil:
+			em.warn(StageGather, "Failed to gather prior research: %v", err)
+		case prior.Empty():
+			em.warn(StageGather, "No related sessions or knowledge found, synthesizing from scratch")
+		default:
+			r.prior = prior
+			em.completed(Event{Stage: StageGather, Message: fmt.Sprintf("Found %d related sessions and %d knowledge topics", len(prior.Sessions), len(prior.Knowledge))})
+		}
+	}
+
+	// Render the prompt with variables
+	contextBlock := renderContext(r.opts.Context)
+	priorBlock := renderPrior(r.prior)
+	renderedPrompt := e.promptLoader.Render(prompt, map[string]string{
+		"query":   r.brief(),
+		"mode":    r.mode,
+		"context": contextBlock,
+		"prior":   priorBlock,
+	})
+	if contextBlock != "" && !strings.Contains(prompt.Template, "{{context}}") {
+		renderedPrompt += "\n\n" + contextBlock
+	}
+	if priorBlock != "" && !strings.Contains(prompt.Template, "{{prior}}") {
+		renderedPrompt += "\n\n" + priorBlock
+	}
+
+	r.cp.Prompt = renderedPrompt
+	r.cp.Context = contextBlock
+	r.cp.Sources = renderPriorSources(r.prior)
+
+	em.completed(Event{Stage: StageLoadPrompt, Message: fmt.Sprintf("Loaded prompt %s", r.promptName)})
+	return nil
+}
+
+// query produces the answer into the checkpoint. Compare mode researches
+// each option separately when it can tell what the options are, from the
+// query or by asking the provider.
+func (e *Engine) query(ctx context.Context, r *run, em *emitter) error {
+	if r.mode == "compare" {
+		options := r.cp.Options
+		if len(options) == 0 {
+			options = ParseOptions(r.opts.Query)
+		}
+		if len(options) == 0 {
+			var err error
+			if options, err = e.identifyOptions(ctx, r, em); err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case len(options) >= 2:
+			r.cp.Options = options
+			if len(r.cp.Criteria) == 0 {
+				r.cp.Criteria = DefaultCompareCriteria
+			}
+			matrix, err := e.compareOptions(ctx, r, em)
+			if err != nil {
+				return err
+			}
+			r.cp.Matrix = matrix
+			r.cp.Content = renderMatrixMarkdown(matrix)
+			return nil
+		case len(options) == 1:
+			return fmt.Errorf("compare mode needs at least two options")
+		default:
+			em.warn(StageQuery, "Could not find the options to compare in the query, falling back to a single answer")
+		}
+	}
+
+	em.started(StageQuery, "Querying AI provider...")
+
+	// Query the provider
+	response, err := e.providerManager.Query(ctx, r.cp.Prompt, provider.QueryOptions{})
+	if err != nil {
+		return fmt.Errorf("provider query failed: %w", err)
+	}
+	em.used(response)
+
+	em.completed(Event{
+		Stage:    StageQuery,
+		Message:  "Received answer",
+		Provider: response.Provider,
+		Tokens:   response.TokensUsed,
+		Partial:  response.Content,
+	})
+
+	r.cp.Content = response.Content
+	return nil
+}
+
+// logSearch records the run, whatever its outcome, in the search log.
+// Runs with NoStore are not logged, and logging failures are only reported.
+// Callers that store the result themselves, such as watches, log it too.
+func (e *Engine) logSearch(ctx context.Context, r *run, em *emitter, result *ResearchResult, err error) {
+	if r.opts.NoStore {
+		return
+	}
+
+	entry := &db.SearchHistory{
+		Query:      r.opts.Query,
+		Mode:       r.mode,
+		PromptUsed: r.promptName,
+		Provider:   em.provider,
+		Latency:    time.Since(em.start),
+	}
+	entry.Outcome, entry.Error = RunOutcome(ctx, err)
+	if result != nil {
+		entry.SessionID = result.SessionID
+	} else if r.session != nil {
+		entry.SessionID = r.session.ID
+	}
+
+	if err := e.db.LogSearch(entry); err != nil {
+		em.warn(StageStore, "Failed to log search: %v", err)
+	}
+}
+
+// RunOutcome classifies a run that ended with err for the search log, and
+// returns the error message to log with it. A run whose context ended is
+// cancelled rather than failed.
+func RunOutcome(ctx context.Context, err error) (outcome, message string) {
+	switch {
+	case err == nil:
+		return db.OutcomeSuccess, ""
+	case ctx.Err() != nil:
+		return db.OutcomeCancelled, err.Error()
+	default:
+		return db.OutcomeFailed, err.Error()
+	}
+}
+
+// checkpoint saves the run as an in-progress session. Checkpointing is best
+// effort: after the first failure the run continues without it.
+func (e *Engine) checkpoint(r *run, em *emitter) {
+	e.save(r, em, db.SessionInProgress)
+}
+
+// save stores the run's progress as a session with the given status
+func (e *Engine) save(r *run, em *emitter, status string) {
+	if r.opts.NoStore || r.checkpointFailed {
+		return
+	}
+
+	session := r.session
+	if session == nil {
+		session = &db.ResearchSession{
+			Query:      r.opts.Query,
+			Mode:       r.mode,
+			PromptUsed: r.promptName,
+			CreatedAt:  time.Now(),
+		}
+	}
+	session.RefinedQuery = r.refined
+	session.Status = status
+	session.Checkpoint = r.cp
+	session.Result = r.cp.Content
+	session.Matrix = r.cp.Matrix
+	session.QualityScore = r.qualityScore
+	session.Critique = r.critique
+	session.Provider = em.provider
+	session.Model = em.model
+	session.Tokens = db.TokenCount(em.tokens)
+
+	var err error
+	if session.ID == 0 {
+		err = e.db.SaveSession(session)
+	} else {
+		err = e.db.UpdateSession(session)
+	}
+	if err != nil {
+		r.checkpointFailed = true
+		em.warn(StageStore, "Failed to checkpoint session, it will not be resumable: %v", err)
+		return
+	}
+	r.session = session
+	if status == db.SessionCancelled {
+		em.warn(StageStore, "Cancelled, saved the partial output as session #%d", session.ID)
+	}
+}
+
+// critiqueAndRevise grades content and, when it scores below the threshold,
+// runs one revision round and grades the revision. Failures are reported as
+// warnings and leave the answer as it was.
+func (e *Engine) critiqueAndRevise(ctx context.Context, opts ResearchOptions, mode, content string, em *emitter) (*Critique, string, bool) {
+	threshold := opts.CritiqueThreshold
+	if threshold <= 0 {
+		threshold = DefaultCritiqueThreshold
+	}
+
+	em.started(StageCritique, "Critiquing answer...")
+	critique, err := e.critique(ctx, opts.Query, mode, content, em)
+	if err != nil {
+		if ctx.Err() == nil {
+			em.warn(StageCritique, "Critique failed: %v", err)
+		}
+		return nil, content, false
+	}
+	if critique.Score >= threshold {
+		return critique, content, false
+	}
+
+	em.started(StageRevise, fmt.Sprintf("Scored %d/100 (below %d), revising answer...", critique.Score, threshold))
+	revised, err := e.revise(ctx, opts.Query, mode, content, critique.Text, em)
+	if err != nil {
+		if ctx.Err() == nil {
+			em.warn(StageRevise, "Revision failed: %v", err)
+		}
+		return critique, content, false
+	}
+
+	em.started(StageCritique, "Critiquing revised answer...")
+	revisedCritique, err := e.critique(ctx, opts.Query, mode, revised, em)
+	if err != nil {
+		// The original critique no longer describes the answer
+		if ctx.Err() == nil {
+			em.warn(StageCritique, "Critique of revision failed: %v", err)
+		}
+		return nil, revised, true
+	}
+
+	return revisedCritique, revised, true
+}
+
+// critique asks the provider to grade an answer against the mode's rubric
+func (e *Engine) critique(ctx context.Context, query, mode, answer string, em *emitter) (*Critique, error) {
+	rubric := RubricFor(mode)
+	prompt := loadTemplate(e.promptLoader, "critique", fallbackCritiqueTemplate)
+	rendered := e.promptLoader.Render(prompt, map[string]string{
+		"query":  query,
+		"mode":   mode,
+		"rubric": renderRubric(rubric),
+		"answer": answer,
+	})
+
+	response, err := e.providerManager.Query(ctx, rendered, provider.QueryOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("provider query failed: %w", err)
+	}
+	em.used(response)
+
+	critique, err := ParseCritique(response.Content, rubric)
+	if err != nil {
+		return nil, err
+	}
+
+	em.completed(Event{
+		Stage:    StageCritique,
+		Message:  fmt.Sprintf("Scored %d/100", critique.Score),
+		Provider: response.Provider,
+		Tokens:   response.TokensUsed,
+	})
+	return critique, nil
+}
+
+// revise asks the provider to rewrite an answer to address a critique
+func (e *Engine) revise(ctx context.Context, query, mode, answer, critique string, em *emitter) (string, error) {
+	prompt := loadTemplate(e.promptLoader, "revise", fallbackReviseTemplate)
+	rendered := e.promptLoader.Render(prompt, map[string]string{
+		"query":    query,
+		"mode":     mode,
+		"answer":   answer,
+		"critique": critique,
+	})
+
+	response, err := e.providerManager.Query(ctx, rendered, provider.QueryOptions{})
+	if err != nil {
+		return "", fmt.Errorf("provider query failed: %w", err)
+	}
+	em.used(response)
+	if strings.TrimSpace(response.Content) == "" {
+		return "", fmt.Errorf("provider returned an empty revision")
+	}
+
+	em.completed(Event{
+		Stage:    StageRevise,
+		Message:  "Revised answer",
+		Provider: response.Provider,
+		Tokens:   response.TokensUsed,
+		Partial:  response.Content,
+	})
+	return response.Content, nil
+}
+
+// checkpointSnippets converts context snippets for storing in a checkpoint
+func checkpointSnippets(snippets []ContextSnippet) []db.ContextSnippet {
+	var stored []db.ContextSnippet
+	for _, s := range snippets {
+		stored = append(stored, db.ContextSnippet(s))
+	}
+	return stored
+}
+
+// restoreSnippets converts checkpointed context snippets back, so a resumed
+// run is grounded in the same excerpts as the original
+func restoreSnippets(stored []db.ContextSnippet) []ContextSnippet {
+	var snippets []ContextSnippet
+	for _, s := range stored {
+		snippets = append(snippets, ContextSnippet(s))
+	}
+	return snippets
+}
+
+// renderContext formats local file excerpts for inclusion in a prompt.
+// Templates can place it with {{context}}; otherwise it is appended.
+func renderContext(snippets []ContextSnippet) string {
+	if len(snippets) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("## Local Context\n\n")
+	b.WriteString("The following excerpts come from the user's own files. Prefer them over general knowledge ")
+	b.WriteString("when they are relevant, and cite them inline as [path:start-end].\n")
+
+	for _, snippet := range snippets {
+		fmt.Fprintf(&b, "\n### [%s]\n\n```\n%s\n```\n", snippet.Citation(), snippet.Content)
+	}
+
+	return b.String()
+}
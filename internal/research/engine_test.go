@@ -112,7 +112,7 @@ func TestEngine_Research_FullFlow(t *testing.T) {
 	}
 
 	// Progress channel
-	progress := make(chan string, 10)
+	progress := make(chan Event, 10)
 	go func() {
 		// Drain progress channel
 		for range progress {
@@ -141,6 +141,17 @@ func TestEngine_Research_FullFlow(t *testing.T) {
 	assert.Equal(t, opts.Query, session.Query)
 	assert.Equal(t, opts.Mode, session.Mode)
 	assert.Equal(t, "Test response about Swift actors", session.Result)
+	assert.Equal(t, "test", session.Provider)
+	assert.Equal(t, "test-model", session.Model)
+
+	// Verify the run was logged
+	searches, err := database.ListSearches(db.SearchFilter{})
+	require.NoError(t, err)
+	require.Len(t, searches, 1)
+	assert.Equal(t, db.OutcomeSuccess, searches[0].Outcome)
+	assert.Equal(t, result.SessionID, searches[0].SessionID)
+	assert.Equal(t, "test", searches[0].Provider)
+	assert.Equal(t, "default", searches[0].PromptUsed)
 
 	close(progress)
 }
@@ -182,7 +193,7 @@ func TestEngine_Research_NoStore(t *testing.T) {
 	}
 
 	// Progress channel
-	progress := make(chan string, 10)
+	progress := make(chan Event, 10)
 	go func() {
 		for range progress {
 		}
@@ -202,6 +213,11 @@ func TestEngine_Research_NoStore(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, 0, len(sessions))
 
+	// Nor logged
+	searches, err := database.ListSearches(db.SearchFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, searches)
+
 	close(progress)
 }
 
@@ -242,8 +258,8 @@ func TestEngine_Research_ProgressEvents(t *testing.T) {
 	}
 
 	// Collect progress events
-	progress := make(chan string, 10)
-	var events []string
+	progress := make(chan Event, 10)
+	var events []Event
 	done := make(chan struct{})
 	go func() {
 		for msg := range progress {
@@ -266,15 +282,35 @@ func TestEngine_Research_ProgressEvents(t *testing.T) {
 	hasLoadingPrompt := false
 	hasQuerying := false
 	for _, event := range events {
-		if event == "Loading prompt..." {
+		if event.Message == "Loading prompt..." {
 			hasLoadingPrompt = true
 		}
-		if event == "Querying AI provider..." {
+		if event.Message == "Querying AI provider..." {
 			hasQuerying = true
 		}
 	}
 	assert.True(t, hasLoadingPrompt, "Expected 'Loading prompt...' event")
 	assert.True(t, hasQuerying, "Expected 'Querying AI provider...' event")
+
+	// Stages are typed and timed
+	var queryDone *Event
+	for i := range events {
+		if events[i].Stage == StageQuery && events[i].Status == EventCompleted {
+			queryDone = &events[i]
+		}
+	}
+	require.NotNil(t, queryDone)
+	assert.Equal(t, "test", queryDone.Provider)
+	assert.Equal(t, "Test response", queryDone.Partial)
+	assert.GreaterOrEqual(t, queryDone.Duration, time.Duration(0))
+
+	last := events[len(events)-1]
+	assert.Equal(t, StageComplete, last.Stage)
+	assert.Equal(t, 100, last.Percent)
+	for i := 1; i < len(events); i++ {
+		assert.GreaterOrEqual(t, events[i].Percent, events[i-1].Percent, "progress never goes backwards")
+		assert.False(t, events[i].IsWarning())
+	}
 }
 
 func TestEngine_Research_ContextCancellation(t *testing.T) {
@@ -309,7 +345,7 @@ func TestEngine_Research_ContextCancellation(t *testing.T) {
 	}
 
 	// Progress channel
-	progress := make(chan string, 10)
+	progress := make(chan Event, 10)
 	go func() {
 		for range progress {
 		}
@@ -361,7 +397,7 @@ func TestEngine_Research_ProviderError(t *testing.T) {
 	}
 
 	// Progress channel
-	progress := make(chan string, 10)
+	progress := make(chan Event, 10)
 	go func() {
 		for range progress {
 		}
@@ -375,5 +411,132 @@ func TestEngine_Research_ProviderError(t *testing.T) {
 	assert.Error(t, err)
 	assert.Nil(t, result)
 
+	// Verify the failure was logged
+	searches, err := database.ListSearches(db.SearchFilter{Outcome: db.OutcomeFailed})
+	require.NoError(t, err)
+	require.Len(t, searches, 1)
+	assert.Equal(t, "Test query", searches[0].Query)
+	assert.Contains(t, searches[0].Error, "provider query failed")
+
 	close(progress)
 }
+
+// promptCapturingProvider records the prompt it was sent
+type promptCapturingProvider struct {
+	MockProvider
+	prompt string
+}
+
+func (p *promptCapturingProvider) Query(ctx context.Context, prompt string, opts provider.QueryOptions) (*provider.Response, error) {
+	p.prompt = prompt
+	return p.MockProvider.Query(ctx, prompt, opts)
+}
+
+func TestEngine_Research_InjectsContext(t *testing.T) {
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	defer database.Close()
+
+	loader := prompts.NewPromptLoader("../../prompts")
+
+	factory := provider.NewProviderFactory()
+	mockProvider := &promptCapturingProvider{MockProvider: MockProvider{
+		name:          "test",
+		authenticated: true,
+		queryResponse: &provider.Response{Content: "Grounded answer"},
+	}}
+	require.NoError(t, factory.Register("test", mockProvider))
+	providerMgr := provider.NewProviderManager(factory, "test", "", false, false)
+
+	engine := NewEngine(database, loader, providerMgr)
+
+	opts := ResearchOptions{
+		Query:      "How do we retry requests?",
+		Mode:       "quick",
+		PromptName: "default",
+		NoStore:    true,
+		Context: []ContextSnippet{
+			{Path: "docs/retry.md", StartLine: 10, EndLine: 24, Content: "Retries use exponential backoff."},
+		},
+	}
+
+	_, err = engine.Research(context.Background(), opts, nil)
+	require.NoError(t, err)
+
+	assert.Contains(t, mockProvider.prompt, "How do we retry requests?")
+	assert.Contains(t, mockProvider.prompt, "## Local Context")
+	assert.Contains(t, mockProvider.prompt, "[docs/retry.md:10-24]")
+	assert.Contains(t, mockProvider.prompt, "Retries use exponential backoff.")
+}
+
+func TestRenderContext(t *testing.T) {
+	assert.Equal(t, "", renderContext(nil))
+
+	block := renderContext([]ContextSnippet{
+		{Path: "a.go", StartLine: 1, EndLine: 3, Content: "package a"},
+		{Path: "b.md", StartLine: 5, EndLine: 5, Content: "# B"},
+	})
+	assert.Contains(t, block, "### [a.go:1-3]")
+	assert.Contains(t, block, "### [b.md:5-5]")
+	assert.Contains(t, block, "cite them inline")
+}
+
+func TestEngine_Research_Citations(t *testing.T) {
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	defer database.Close()
+
+	loader := prompts.NewPromptLoader("../../prompts")
+
+	factory := provider.NewProviderFactory()
+	mockProvider := &MockProvider{
+		name:          "test",
+		authenticated: true,
+		queryResponse: &provider.Response{
+			Content: "See [Go docs](https://go.dev/doc) and [this](https://go.dev/missing).",
+		},
+	}
+	require.NoError(t, factory.Register("test", mockProvider))
+	providerMgr := provider.NewProviderManager(factory, "test", "", false, false)
+
+	engine := NewEngine(database, loader, providerMgr)
+	engine.SetLinkVerifier(NewLinkVerifier(&stubHTTPClient{statuses: map[string]int{
+		"https://go.dev/doc":     200,
+		"https://go.dev/missing": 404,
+	}}, time.Second))
+
+	opts := ResearchOptions{Query: "Go docs", Mode: "quick", PromptName: "default"}
+	result, err := engine.Research(context.Background(), opts, nil)
+	require.NoError(t, err)
+
+	require.Len(t, result.Citations, 2)
+	assert.Equal(t, db.CitationOK, result.Citations[0].Status)
+	assert.Equal(t, db.CitationDead, result.Citations[1].Status)
+
+	session, err := database.GetSession(result.SessionID)
+	require.NoError(t, err)
+	assert.Equal(t, result.Citations, session.Citations)
+}
+
+func TestEngine_SummarizeChanges(t *testing.T) {
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	defer database.Close()
+
+	mock := &promptCapturingProvider{MockProvider: MockProvider{
+		name:          "test",
+		authenticated: true,
+		queryResponse: &provider.Response{Content: "- Now recommends v2\n", Provider: "test"},
+	}}
+	factory := provider.NewProviderFactory()
+	require.NoError(t, factory.Register("test", mock))
+	engine := NewEngine(database, prompts.NewPromptLoader("../../prompts"), provider.NewProviderManager(factory, "test", "", false, false))
+
+	summary, err := engine.SummarizeChanges(context.Background(), "Which API version?", "Use v1.", "Use v2.")
+	require.NoError(t, err)
+
+	assert.Equal(t, "- Now recommends v2", summary)
+	assert.Contains(t, mock.prompt, "Which API version?")
+	assert.Contains(t, mock.prompt, "## Old Answer\n\nUse v1.")
+	assert.Contains(t, mock.prompt, "## New Answer\n\nUse v2.")
+}
@@ -0,0 +1,214 @@
+package research
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/joelklabo/copilot-research/internal/provider"
+)
+
+// Stage identifies a step of the research pipeline
+type Stage string
+
+// Research pipeline stages, in order
+const (
+	StageLoadPrompt Stage = "load_prompt"
+	StageQuery      Stage = "query"
+	StageCritique   Stage = "critique"
+	StageRevise     Stage = "revise"
+	StageVerify     Stage = "verify"
+	StageStore      Stage = "store"
+	StageComplete   Stage = "complete"
+)
+
+// stagePercent is the approximate share of the pipeline finished when a
+// stage starts
+var stagePercent = map[Stage]int{
+	StageRefine:     0,
+	StageLoadPrompt: 0,
+	StageQuery:      10,
+	StageCritique:   60,
+	StageRevise:     70,
+	StageVerify:     85,
+	StageStore:      95,
+	StageComplete:   100,
+}
+
+// EventStatus describes what happened in a stage
+type EventStatus string
+
+// Event statuses
+const (
+	EventStarted   EventStatus = "started"
+	EventCompleted EventStatus = "completed"
+	EventWarning   EventStatus = "warning"
+)
+
+// Event is a structured progress update from the research pipeline
+type Event struct {
+	Stage    Stage
+	Status   EventStatus
+	Message  string              // Human-readable description
+	Time     time.Time           // When the event was emitted
+	Elapsed  time.Duration       // Time since the research started
+	Duration time.Duration       // How long the stage took, set on completed events
+	Percent  int                 // Approximate overall progress from 0 to 100
+	Provider string              // Provider that answered, set on completed query events
+	Tokens   provider.TokenUsage // Tokens used by the stage, when the provider reports them
+	Partial  string              // Content produced so far, e.g. the draft before revision
+}
+
+// IsWarning reports whether the event is a non-fatal problem
+func (e Event) IsWarning() bool {
+	return e.Status == EventWarning
+}
+
+// String formats the event as a single progress line
+func (e Event) String() string {
+	if e.IsWarning() {
+		return "Warning: " + e.Message
+	}
+	return e.Message
+}
+
+// eventJSON is the wire format of an Event
+type eventJSON struct {
+	Stage      Stage       `json:"stage"`
+	Status     EventStatus `json:"status"`
+	Message    string      `json:"message,omitempty"`
+	Time       time.Time   `json:"time"`
+	ElapsedMS  int64       `json:"elapsed_ms"`
+	DurationMS int64       `json:"duration_ms,omitempty"`
+	Percent    int         `json:"percent"`
+	Provider   string      `json:"provider,omitempty"`
+	Tokens     *tokensJSON `json:"tokens,omitempty"`
+	Partial    string      `json:"partial,omitempty"`
+}
+
+type tokensJSON struct {
+	Prompt     int `json:"prompt"`
+	Completion int `json:"completion"`
+	Total      int `json:"total"`
+}
+
+// MarshalJSON encodes the event with millisecond durations, as written by
+// --progress-json
+func (e Event) MarshalJSON() ([]byte, error) {
+	out := eventJSON{
+		Stage:      e.Stage,
+		Status:     e.Status,
+		Message:    e.Message,
+		Time:       e.Time,
+		ElapsedMS:  e.Elapsed.Milliseconds(),
+		DurationMS: e.Duration.Milliseconds(),
+		Percent:    e.Percent,
+		Provider:   e.Provider,
+		Partial:    e.Partial,
+	}
+	if e.Tokens.Total > 0 || e.Tokens.Prompt > 0 || e.Tokens.Completion > 0 {
+		out.Tokens = &tokensJSON{
+			Prompt:     e.Tokens.Prompt,
+			Completion: e.Tokens.Completion,
+			Total:      e.Tokens.Total,
+		}
+	}
+	return json.Marshal(out)
+}
+
+// emitter stamps and sends events. A nil channel discards them. It also
+// tallies the provider calls of the run.
+type emitter struct {
+	events      chan<- Event
+	start       time.Time
+	stageStarts map[Stage]time.Time
+
+	provider string
+	model    string
+	tokens   provider.TokenUsage
+}
+
+func newEmitter(events chan<- Event) *emitter {
+	return &emitter{
+		events:      events,
+		start:       time.Now(),
+		stageStarts: make(map[Stage]time.Time),
+	}
+}
+
+// send fills in timing and progress and delivers the event
+func (em *emitter) send(e Event) {
+	now := time.Now()
+	e.Time = now
+	e.Elapsed = now.Sub(em.start)
+	if e.Percent == 0 {
+		e.Percent = stagePercent[e.Stage]
+	}
+
+	switch e.Status {
+	case EventStarted:
+		em.stageStarts[e.Stage] = now
+	case EventCompleted:
+		if started, ok := em.stageStarts[e.Stage]; ok {
+			e.Duration = now.Sub(started)
+		}
+		if next := nextStage(e.Stage); next != "" {
+			e.Percent = stagePercent[next]
+		}
+	}
+
+	if em.events != nil {
+		em.events <- e
+	}
+}
+
+// used records a provider response in the run's usage totals
+func (em *emitter) used(response *provider.Response) {
+	em.provider = response.Provider
+	if response.Model != "" {
+		em.model = response.Model
+	}
+	em.tokens.Prompt += response.TokensUsed.Prompt
+	em.tokens.Completion += response.TokensUsed.Completion
+	em.tokens.Total += response.TokensUsed.Total
+}
+
+// started reports the start of a stage
+func (em *emitter) started(stage Stage, message string) {
+	em.send(Event{Stage: stage, Status: EventStarted, Message: message})
+}
+
+// completed reports the end of a stage
+func (em *emitter) completed(e Event) {
+	e.Status = EventCompleted
+	em.send(e)
+}
+
+// warn reports a non-fatal problem in a stage
+func (em *emitter) warn(stage Stage, format string, args ...interface{}) {
+	em.send(Event{Stage: stage, Status: EventWarning, Message: fmt.Sprintf(format, args...)})
+}
+
+// stageOrder lists the stages in pipeline order
+var stageOrder = []Stage{StageRefine, StageLoadPrompt, StageQuery, StageCritique, StageRevise, StageVerify, StageStore, StageComplete}
+
+// stageIndex returns the position of s in the pipeline, or -1 for stages
+// outside it
+func stageIndex(s Stage) int {
+	for i, stage := range stageOrder {
+		if stage == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// nextStage returns the stage that follows s, or "" for the last stage
+func nextStage(s Stage) Stage {
+	for i, stage := range stageOrder {
+		if stage == s && i+1 < len(stageOrder) {
+			return stageOrder[i+1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,73 @@
+package research
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/joelklabo/copilot-research/internal/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvent_String(t *testing.T) {
+	assert.Equal(t, "Loading prompt...", Event{Status: EventStarted, Message: "Loading prompt..."}.String())
+	assert.Equal(t, "Warning: disk full", Event{Status: EventWarning, Message: "disk full"}.String())
+}
+
+func TestEvent_MarshalJSON(t *testing.T) {
+	event := Event{
+		Stage:    StageQuery,
+		Status:   EventCompleted,
+		Message:  "Received answer",
+		Time:     time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Elapsed:  1500 * time.Millisecond,
+		Duration: 1200 * time.Millisecond,
+		Percent:  60,
+		Provider: "github-copilot",
+		Tokens:   provider.TokenUsage{Prompt: 10, Completion: 20, Total: 30},
+	}
+
+	data, err := json.Marshal(event)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"stage": "query",
+		"status": "completed",
+		"message": "Received answer",
+		"time": "2025-01-02T03:04:05Z",
+		"elapsed_ms": 1500,
+		"duration_ms": 1200,
+		"percent": 60,
+		"provider": "github-copilot",
+		"tokens": {"prompt": 10, "completion": 20, "total": 30}
+	}`, string(data))
+
+	data, err = json.Marshal(Event{Stage: StageLoadPrompt, Status: EventStarted})
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "tokens")
+	assert.NotContains(t, string(data), "duration_ms")
+}
+
+func TestEmitter(t *testing.T) {
+	events := make(chan Event, 10)
+	em := newEmitter(events)
+
+	em.started(StageQuery, "Querying...")
+	em.completed(Event{Stage: StageQuery, Message: "Done"})
+	em.warn(StageStore, "failed: %s", "disk full")
+	close(events)
+
+	var got []Event
+	for e := range events {
+		got = append(got, e)
+	}
+	require.Len(t, got, 3)
+	assert.Equal(t, 10, got[0].Percent)
+	assert.Equal(t, EventCompleted, got[1].Status)
+	assert.Equal(t, 60, got[1].Percent, "completed stages report the start of the next stage")
+	assert.Equal(t, "failed: disk full", got[2].Message)
+	assert.Equal(t, 95, got[2].Percent)
+
+	// A nil channel discards events
+	newEmitter(nil).started(StageQuery, "ignored")
+}
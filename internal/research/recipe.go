@@ -0,0 +1,464 @@
+package research
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/joelklabo/copilot-research/internal/prompts"
+	"github.com/joelklabo/copilot-research/internal/provider"
+	"gopkg.in/yaml.v3"
+)
+
+// StageStep is the stage reported for each recipe step
+const StageStep Stage = "step"
+
+// Recipe is a user-defined, multi-step research pipeline
+type Recipe struct {
+	Name        string            `yaml:"name"`
+	Description string            `yaml:"description,omitempty"`
+	Vars        map[string]string `yaml:"vars,omitempty"` // Defaults; an empty default makes the variable required
+	Steps       []RecipeStep      `yaml:"steps"`
+}
+
+// RecipeStep is one step of a recipe. Its prompt is rendered with the recipe
+// variables, the outputs of the steps listed in Inputs as {{<step id>}}, and
+// {{item}} when fanning out.
+type RecipeStep struct {
+	ID       string   `yaml:"id"`
+	Prompt   string   `yaml:"prompt,omitempty"`   // Named prompt template
+	Template string   `yaml:"template,omitempty"` // Inline prompt template, instead of Prompt
+	Query    string   `yaml:"query,omitempty"`    // Passed to the prompt as {{query}}
+	Mode     string   `yaml:"mode,omitempty"`     // Passed to the prompt as {{mode}}
+	Provider string   `yaml:"provider,omitempty"` // Provider to use; empty uses the configured primary
+	Model    string   `yaml:"model,omitempty"`    // Model to request from the provider
+	Inputs   []string `yaml:"inputs,omitempty"`   // Earlier steps whose output the prompt uses
+	ForEach  string   `yaml:"for_each,omitempty"` // Earlier step or variable holding a list to run the step once per item of
+}
+
+var (
+	stepIDPattern      = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+	placeholderPattern = regexp.MustCompile(`{{([\w-]+)}}`)
+	listItemPattern    = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+(.+)$`)
+)
+
+// LoadRecipe reads and validates a recipe file
+func LoadRecipe(path string) (*Recipe, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recipe: %w", err)
+	}
+
+	var recipe Recipe
+	if err := yaml.Unmarshal(data, &recipe); err != nil {
+		return nil, fmt.Errorf("failed to parse recipe: %w", err)
+	}
+	if recipe.Name == "" {
+		recipe.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	if err := recipe.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid recipe %s: %w", recipe.Name, err)
+	}
+
+	return &recipe, nil
+}
+
+// FindRecipe resolves a recipe name or path. A name is looked up as
+// <name>.yaml or <name>.yml in each directory, in order.
+func FindRecipe(nameOrPath string, dirs ...string) (string, error) {
+	if _, err := os.Stat(nameOrPath); err == nil {
+		return nameOrPath, nil
+	}
+
+	for _, dir := range dirs {
+		for _, ext := range []string{".yaml", ".yml"} {
+			path := filepath.Join(dir, nameOrPath+ext)
+			if _, err := os.Stat(path); err == nil {
+				return path, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("recipe not found: %s (searched %s)", nameOrPath, strings.Join(dirs, ", "))
+}
+
+// Validate checks that steps are well-formed and only reference variables
+// and steps that exist before them
+func (r *Recipe) Validate() error {
+	if len(r.Steps) == 0 {
+		return fmt.Errorf("recipe has no steps")
+	}
+
+	earlier := make(map[string]bool)
+	for i, step := range r.Steps {
+		if !stepIDPattern.MatchString(step.ID) {
+			return fmt.Errorf("step %d: id %q must be lower-case letters, digits, '-' or '_'", i+1, step.ID)
+		}
+		if earlier[step.ID] {
+			return fmt.Errorf("step %s: duplicate id", step.ID)
+		}
+		if _, clash := r.Vars[step.ID]; clash {
+			return fmt.Errorf("step %s: id clashes with a variable", step.ID)
+		}
+		if (step.Prompt == "") == (step.Template == "") {
+			return fmt.Errorf("step %s: set exactly one of prompt or template", step.ID)
+		}
+
+		known := map[string]bool{"query": true, "mode": true}
+		for name := range r.Vars {
+			known[name] = true
+		}
+		for _, input := range step.Inputs {
+			if !earlier[input] {
+				return fmt.Errorf("step %s: input %q is not an earlier step", step.ID, input)
+			}
+			known[input] = true
+		}
+		if step.ForEach != "" {
+			if _, isVar := r.Vars[step.ForEach]; !isVar && !earlier[step.ForEach] {
+				return fmt.Errorf("step %s: for_each %q is not an earlier step or variable", step.ID, step.ForEach)
+			}
+			known["item"] = true
+		}
+
+		for _, text := range []string{step.Query, step.Template} {
+			for _, m := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+				if !known[m[1]] {
+					return fmt.Errorf("step %s: unknown variable {{%s}} (add it to vars or inputs)", step.ID, m[1])
+				}
+			}
+		}
+
+		earlier[step.ID] = true
+	}
+
+	return nil
+}
+
+// ResolveVars merges values over the recipe defaults and reports required
+// variables that are still missing
+func (r *Recipe) ResolveVars(values map[string]string) (map[string]string, error) {
+	vars := make(map[string]string, len(r.Vars))
+	for name, def := range r.Vars {
+		vars[name] = def
+	}
+	for name, value := range values {
+		if _, ok := r.Vars[name]; !ok {
+			return nil, fmt.Errorf("unknown variable %q for recipe %s", name, r.Name)
+		}
+		vars[name] = value
+	}
+
+	var missing []string
+	for name, value := range vars {
+		if value == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("missing required variables: %s (use --var name=value)", strings.Join(missing, ", "))
+	}
+
+	return vars, nil
+}
+
+// ParseList splits step output into list items. Markdown bullets and
+// numbered items are used when present, otherwise every non-empty line.
+func ParseList(text string) []string {
+	var bullets, lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if m := listItemPattern.FindStringSubmatch(line); m != nil {
+			bullets = append(bullets, strings.TrimSpace(m[1]))
+		}
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			lines = append(lines, trimmed)
+		}
+	}
+	if len(bullets) > 0 {
+		return bullets
+	}
+	return lines
+}
+
+// StepResult is the outcome of one recipe step
+type StepResult struct {
+	ID       string
+	Items    []string // Fan-out items, in order
+	Outputs  []string // One output per item, or a single output
+	Output   string   // Combined output available to later steps
+	Provider string
+	Model    string
+	Tokens   provider.TokenUsage
+	Duration time.Duration
+}
+
+// RecipeResult is the outcome of running a recipe
+type RecipeResult struct {
+	Recipe    string
+	Vars      map[string]string
+	Steps     []StepResult
+	Output    string              // Output of the last step
+	Tokens    provider.TokenUsage // Summed over every step
+	Duration  time.Duration
+	SessionID int64
+}
+
+// RecipeOptions contains options for running a recipe
+type RecipeOptions struct {
+	Vars    map[string]string
+	NoStore bool
+}
+
+// RecipeRunner executes recipes step by step
+type RecipeRunner struct {
+	db              db.DB
+	promptLoader    *prompts.PromptLoader
+	providerManager *provider.ProviderManager
+	concurrency     int
+}
+
+// NewRecipeRunner creates a recipe runner
+func NewRecipeRunner(database db.DB, loader *prompts.PromptLoader, providerMgr *provider.ProviderManager) *RecipeRunner {
+	return &RecipeRunner{
+		db:              database,
+		promptLoader:    loader,
+		providerManager: providerMgr,
+		concurrency:     DefaultBatchConcurrency,
+	}
+}
+
+// Run executes every step of the recipe in order. Fan-out steps run their
+// items concurrently. The final output is stored as a research session,
+// with the last step's provider and model and the tokens of every step,
+// unless NoStore is set.
+func (r *RecipeRunner) Run(ctx context.Context, recipe *Recipe, opts RecipeOptions, events chan<- Event) (_ *RecipeResult, err error) {
+	em := newEmitter(events)
+
+	vars, err := recipe.ResolveVars(opts.Vars)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RecipeResult{Recipe: recipe.Name, Vars: vars}
+	if !opts.NoStore {
+		defer func() { r.logRun(ctx, em, result, err) }()
+	}
+	outputs := make(map[string]*StepResult)
+
+	for i, step := range recipe.Steps {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		percent := i * 95 / len(recipe.Steps)
+		em.send(Event{Stage: StageStep, Status: EventStarted, Percent: percent,
+			Message: fmt.Sprintf("Step %d/%d: %s...", i+1, len(recipe.Steps), step.ID)})
+
+		stepResult, err := r.runStep(ctx, recipe, step, vars, outputs)
+		if err != nil {
+			return nil, fmt.Errorf("step %s failed: %w", step.ID, err)
+		}
+
+		em.send(Event{
+			Stage:    StageStep,
+			Status:   EventCompleted,
+			Percent:  (i + 1) * 95 / len(recipe.Steps),
+			Message:  fmt.Sprintf("Finished %s", step.ID),
+			Provider: stepResult.Provider,
+			Tokens:   stepResult.Tokens,
+			Partial:  stepResult.Output,
+		})
+
+		result.Steps = append(result.Steps, *stepResult)
+		outputs[step.ID] = stepResult
+		result.Tokens.Prompt += stepResult.Tokens.Prompt
+		result.Tokens.Completion += stepResult.Tokens.Completion
+		result.Tokens.Total += stepResult.Tokens.Total
+	}
+
+	final := result.Steps[len(result.Steps)-1]
+	result.Output = final.Output
+	result.Duration = time.Since(em.start)
+
+	if !opts.NoStore {
+		em.started(StageStore, "Storing in database...")
+		session := &db.ResearchSession{
+			Query:      recipeQuery(recipe.Name, vars),
+			Mode:       "recipe",
+			PromptUsed: recipe.Name,
+			Result:     result.Output,
+			Citations:  ExtractCitations(result.Output),
+			Provider:   final.Provider,
+			Model:      final.Model,
+			Tokens:     db.TokenCount(result.Tokens),
+			CreatedAt:  time.Now(),
+		}
+		if err := r.db.SaveSession(session); err != nil {
+			em.warn(StageStore, "Failed to store session: %v", err)
+		} else {
+			result.SessionID = session.ID
+			em.completed(Event{Stage: StageStore, Message: fmt.Sprintf("Stored session #%d", session.ID)})
+		}
+	}
+
+	em.completed(Event{Stage: StageComplete, Message: "Complete!"})
+
+	return result, nil
+}
+
+// logRun records the recipe run, whatever its outcome, in the search log.
+// The provider is the one that ran the last step to finish.
+func (r *RecipeRunner) logRun(ctx context.Context, em *emitter, result *RecipeResult, err error) {
+	entry := &db.SearchHistory{
+		SessionID:  result.SessionID,
+		Query:      recipeQuery(result.Recipe, result.Vars),
+		Mode:       "recipe",
+		PromptUsed: result.Recipe,
+		Latency:    time.Since(em.start),
+	}
+	entry.Outcome, entry.Error = RunOutcome(ctx, err)
+	if len(result.Steps) > 0 {
+		entry.Provider = result.Steps[len(result.Steps)-1].Provider
+	}
+
+	if err := r.db.LogSearch(entry); err != nil {
+		em.warn(StageStore, "Failed to log search: %v", err)
+	}
+}
+
+// runStep runs a single step, once or once per fan-out item
+func (r *RecipeRunner) runStep(ctx context.Context, recipe *Recipe, step RecipeStep, vars map[string]string, outputs map[string]*StepResult) (*StepResult, error) {
+	start := time.Now()
+
+	base := make(map[string]string, len(vars)+len(step.Inputs)+2)
+	for name, value := range vars {
+		base[name] = value
+	}
+	for _, input := range step.Inputs {
+		base[input] = outputs[input].Output
+	}
+
+	if step.ForEach == "" {
+		out, err := r.query(ctx, step, base)
+		if err != nil {
+			return nil, err
+		}
+		return &StepResult{
+			ID:       step.ID,
+			Outputs:  []string{out.Content},
+			Output:   out.Content,
+			Provider: out.Provider,
+			Model:    out.Model,
+			Tokens:   out.TokensUsed,
+			Duration: time.Since(start),
+		}, nil
+	}
+
+	var items []string
+	if source, ok := outputs[step.ForEach]; ok {
+		items = ParseList(source.Output)
+	} else {
+		for _, item := range strings.Split(vars[step.ForEach], ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("for_each %s produced no items", step.ForEach)
+	}
+
+	responses := make([]*provider.Response, len(items))
+	errs := make([]error, len(items))
+	sem := make(chan struct{}, r.concurrency)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, item string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			itemVars := make(map[string]string, len(base)+1)
+			for name, value := range base {
+				itemVars[name] = value
+			}
+			itemVars["item"] = item
+			responses[i], errs[i] = r.query(ctx, step, itemVars)
+		}(i, item)
+	}
+	wg.Wait()
+
+	result := &StepResult{ID: step.ID, Items: items}
+	var combined []string
+	for i, item := range items {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("item %q: %w", item, errs[i])
+		}
+		result.Outputs = append(result.Outputs, responses[i].Content)
+		combined = append(combined, fmt.Sprintf("## %s\n\n%s", item, strings.TrimSpace(responses[i].Content)))
+		result.Provider = responses[i].Provider
+		result.Model = responses[i].Model
+		result.Tokens.Prompt += responses[i].TokensUsed.Prompt
+		result.Tokens.Completion += responses[i].TokensUsed.Completion
+		result.Tokens.Total += responses[i].TokensUsed.Total
+	}
+	result.Output = strings.Join(combined, "\n\n")
+	result.Duration = time.Since(start)
+
+	return result, nil
+}
+
+// query renders the step's prompt with vars and sends it to the step's provider
+func (r *RecipeRunner) query(ctx context.Context, step RecipeStep, vars map[string]string) (*provider.Response, error) {
+	prompt := &prompts.Prompt{Name: step.ID, Template: step.Template}
+	if step.Prompt != "" {
+		loaded, err := r.promptLoader.Load(step.Prompt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load prompt: %w", err)
+		}
+		prompt = loaded
+	}
+
+	all := make(map[string]string, len(vars)+2)
+	for name, value := range vars {
+		all[name] = value
+	}
+	all["query"] = r.promptLoader.Render(&prompts.Prompt{Template: step.Query}, vars)
+	all["mode"] = step.Mode
+	if all["mode"] == "" {
+		all["mode"] = "quick"
+	}
+
+	rendered := r.promptLoader.Render(prompt, all)
+	response, err := r.providerManager.QueryProvider(ctx, step.Provider, rendered, provider.QueryOptions{Model: step.Model})
+	if err != nil {
+		return nil, fmt.Errorf("provider query failed: %w", err)
+	}
+
+	return response, nil
+}
+
+// recipeQuery describes a recipe run for the history list
+func recipeQuery(name string, vars map[string]string) string {
+	names := make([]string, 0, len(vars))
+	for n := range vars {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	parts := []string{name}
+	for _, n := range names {
+		parts = append(parts, fmt.Sprintf("%s=%s", n, vars[n]))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,270 @@
+package research
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/joelklabo/copilot-research/internal/prompts"
+	"github.com/joelklabo/copilot-research/internal/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeRecipe(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "recipe.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadRecipe(t *testing.T) {
+	path := writeRecipe(t, `
+vars:
+  topic: ""
+steps:
+  - id: survey
+    prompt: quick
+    query: "Options for {{topic}}"
+  - id: evaluate
+    for_each: survey
+    template: "Evaluate {{item}}"
+`)
+
+	recipe, err := LoadRecipe(path)
+	require.NoError(t, err)
+	assert.Equal(t, "recipe", recipe.Name, "name defaults to the file name")
+	require.Len(t, recipe.Steps, 2)
+	assert.Equal(t, "survey", recipe.Steps[1].ForEach)
+}
+
+func TestLoadRecipe_BundledRecipes(t *testing.T) {
+	paths, err := filepath.Glob("../../recipes/*.yaml")
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+
+	for _, path := range paths {
+		_, err := LoadRecipe(path)
+		assert.NoError(t, err, path)
+	}
+}
+
+func TestRecipe_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		recipe  Recipe
+		wantErr string
+	}{
+		{"no steps", Recipe{}, "no steps"},
+		{"bad id", Recipe{Steps: []RecipeStep{{ID: "Step 1", Template: "x"}}}, "must be lower-case"},
+		{"duplicate id", Recipe{Steps: []RecipeStep{{ID: "a", Template: "x"}, {ID: "a", Template: "y"}}}, "duplicate id"},
+		{"prompt and template", Recipe{Steps: []RecipeStep{{ID: "a", Prompt: "quick", Template: "x"}}}, "exactly one"},
+		{"neither prompt nor template", Recipe{Steps: []RecipeStep{{ID: "a"}}}, "exactly one"},
+		{"future input", Recipe{Steps: []RecipeStep{{ID: "a", Template: "x", Inputs: []string{"b"}}, {ID: "b", Template: "y"}}}, "not an earlier step"},
+		{"unknown for_each", Recipe{Steps: []RecipeStep{{ID: "a", Template: "{{item}}", ForEach: "list"}}}, "for_each"},
+		{"unknown variable", Recipe{Steps: []RecipeStep{{ID: "a", Template: "{{topic}}"}}}, "unknown variable {{topic}}"},
+		{"output without input", Recipe{Steps: []RecipeStep{{ID: "a", Template: "x"}, {ID: "b", Template: "{{a}}"}}}, "unknown variable {{a}}"},
+		{"item without for_each", Recipe{Steps: []RecipeStep{{ID: "a", Template: "{{item}}"}}}, "unknown variable {{item}}"},
+		{"id clashes with var", Recipe{Vars: map[string]string{"a": "1"}, Steps: []RecipeStep{{ID: "a", Template: "x"}}}, "clashes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.recipe.Validate()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestRecipe_ResolveVars(t *testing.T) {
+	recipe := &Recipe{Name: "adr", Vars: map[string]string{"topic": "", "audience": "engineers"}}
+
+	vars, err := recipe.ResolveVars(map[string]string{"topic": "logging"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"topic": "logging", "audience": "engineers"}, vars)
+
+	_, err = recipe.ResolveVars(nil)
+	assert.ErrorContains(t, err, "missing required variables: topic")
+
+	_, err = recipe.ResolveVars(map[string]string{"topic": "x", "typo": "y"})
+	assert.ErrorContains(t, err, `unknown variable "typo"`)
+}
+
+func TestParseList(t *testing.T) {
+	assert.Equal(t, []string{"cobra", "urfave/cli", "kong"}, ParseList("Top picks:\n\n- cobra\n* urfave/cli\n1. kong\n"))
+	assert.Equal(t, []string{"cobra", "kong"}, ParseList("cobra\n\n kong \n"))
+	assert.Empty(t, ParseList("\n \n"))
+}
+
+func TestFindRecipe(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "adr.yml")
+	require.NoError(t, os.WriteFile(path, []byte("steps: []"), 0644))
+
+	found, err := FindRecipe("adr", filepath.Join(dir, "missing"), dir)
+	require.NoError(t, err)
+	assert.Equal(t, path, found)
+
+	found, err = FindRecipe(path)
+	require.NoError(t, err)
+	assert.Equal(t, path, found)
+
+	_, err = FindRecipe("nope", dir)
+	assert.ErrorContains(t, err, "recipe not found")
+}
+
+// funcProvider answers each prompt with the result of a function
+type funcProvider struct {
+	MockProvider
+	mu     sync.Mutex
+	fn     func(prompt string) string
+	models []string
+}
+
+func (p *funcProvider) Query(ctx context.Context, prompt string, opts provider.QueryOptions) (*provider.Response, error) {
+	p.mu.Lock()
+	p.models = append(p.models, opts.Model)
+	p.mu.Unlock()
+	return &provider.Response{
+		Content:    p.fn(prompt),
+		Provider:   p.name,
+		Model:      opts.Model,
+		TokensUsed: provider.TokenUsage{Total: 10},
+	}, nil
+}
+
+func TestRecipeRunner_Run(t *testing.T) {
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	defer database.Close()
+
+	primary := &funcProvider{MockProvider: MockProvider{name: "primary", authenticated: true}, fn: func(prompt string) string {
+		switch {
+		case strings.HasPrefix(prompt, "List"):
+			return "- alpha\n- beta"
+		case strings.HasPrefix(prompt, "Evaluate"):
+			return "verdict on " + strings.TrimPrefix(prompt, "Evaluate ")
+		default:
+			return "unexpected"
+		}
+	}}
+	other := &funcProvider{MockProvider: MockProvider{name: "other", authenticated: true}, fn: func(prompt string) string {
+		return "ADR based on:\n" + prompt
+	}}
+
+	factory := provider.NewProviderFactory()
+	require.NoError(t, factory.Register("primary", primary))
+	require.NoError(t, factory.Register("other", other))
+	providerMgr := provider.NewProviderManager(factory, "primary", "", false, false)
+
+	recipe := &Recipe{
+		Name: "adr",
+		Vars: map[string]string{"topic": ""},
+		Steps: []RecipeStep{
+			{ID: "survey", Template: "List options for {{topic}}"},
+			{ID: "evaluate", ForEach: "survey", Template: "Evaluate {{item}} for {{topic}}"},
+			{ID: "adr", Inputs: []string{"evaluate"}, Template: "Write ADR\n{{evaluate}}", Provider: "other", Model: "big-model"},
+		},
+	}
+	require.NoError(t, recipe.Validate())
+
+	runner := NewRecipeRunner(database, prompts.NewPromptLoader("../../prompts"), providerMgr)
+
+	events := make(chan Event, 50)
+	result, err := runner.Run(context.Background(), recipe, RecipeOptions{Vars: map[string]string{"topic": "queues"}}, events)
+	close(events)
+	require.NoError(t, err)
+
+	require.Len(t, result.Steps, 3)
+	assert.Equal(t, []string{"alpha", "beta"}, result.Steps[1].Items)
+	assert.Equal(t, []string{"verdict on alpha for queues", "verdict on beta for queues"}, result.Steps[1].Outputs)
+	assert.Equal(t, 20, result.Steps[1].Tokens.Total)
+	assert.Equal(t, "## alpha\n\nverdict on alpha for queues\n\n## beta\n\nverdict on beta for queues", result.Steps[1].Output)
+
+	assert.Equal(t, "other", result.Steps[2].Provider)
+	assert.Equal(t, []string{"big-model"}, other.models)
+	assert.Contains(t, result.Output, "ADR based on:\nWrite ADR\n## alpha")
+
+	session, err := database.GetSession(result.SessionID)
+	require.NoError(t, err)
+	assert.Equal(t, "recipe", session.Mode)
+	assert.Equal(t, "adr", session.PromptUsed)
+	assert.Equal(t, "adr topic=queues", session.Query)
+	assert.Equal(t, result.Output, session.Result)
+	assert.Equal(t, "other", session.Provider)
+	assert.Equal(t, "big-model", session.Model)
+	assert.Equal(t, 40, session.Tokens.Total, "every step and fan-out item counts")
+
+	searches, err := database.ListSearches(db.SearchFilter{})
+	require.NoError(t, err)
+	require.Len(t, searches, 1)
+	assert.Equal(t, result.SessionID, searches[0].SessionID)
+	assert.Equal(t, "adr topic=queues", searches[0].Query)
+	assert.Equal(t, "recipe", searches[0].Mode)
+	assert.Equal(t, "other", searches[0].Provider)
+	assert.Equal(t, db.OutcomeSuccess, searches[0].Outcome)
+
+	var steps []string
+	var last Event
+	for e := range events {
+		if e.Stage == StageStep && e.Status == EventStarted {
+			steps = append(steps, e.Message)
+		}
+		last = e
+	}
+	assert.Equal(t, []string{"Step 1/3: survey...", "Step 2/3: evaluate...", "Step 3/3: adr..."}, steps)
+	assert.Equal(t, StageComplete, last.Stage)
+}
+
+func TestRecipeRunner_StepFailure(t *testing.T) {
+	factory := provider.NewProviderFactory()
+	require.NoError(t, factory.Register("primary", &MockProvider{name: "primary", authenticated: true, queryResponse: &provider.Response{Content: "ok"}}))
+	providerMgr := provider.NewProviderManager(factory, "primary", "", false, false)
+
+	recipe := &Recipe{Steps: []RecipeStep{
+		{ID: "a", Template: "x"},
+		{ID: "b", Template: "y", Provider: "missing"},
+	}}
+
+	runner := NewRecipeRunner(nil, prompts.NewPromptLoader("../../prompts"), providerMgr)
+	_, err := runner.Run(context.Background(), recipe, RecipeOptions{NoStore: true}, nil)
+	assert.ErrorContains(t, err, "step b failed")
+}
+
+func TestRecipeRunner_LogsFailedRun(t *testing.T) {
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	defer database.Close()
+
+	factory := provider.NewProviderFactory()
+	require.NoError(t, factory.Register("primary", &MockProvider{name: "primary", authenticated: true, queryResponse: &provider.Response{Content: "ok", Provider: "primary"}}))
+	providerMgr := provider.NewProviderManager(factory, "primary", "", false, false)
+
+	recipe := &Recipe{Name: "broken", Steps: []RecipeStep{
+		{ID: "a", Template: "x"},
+		{ID: "b", Template: "y", Provider: "missing"},
+	}}
+
+	runner := NewRecipeRunner(database, prompts.NewPromptLoader("../../prompts"), providerMgr)
+	_, err = runner.Run(context.Background(), recipe, RecipeOptions{}, nil)
+	require.Error(t, err)
+
+	searches, err := database.ListSearches(db.SearchFilter{})
+	require.NoError(t, err)
+	require.Len(t, searches, 1)
+	assert.Equal(t, "broken", searches[0].Query)
+	assert.Equal(t, db.OutcomeFailed, searches[0].Outcome)
+	assert.Contains(t, searches[0].Error, "step b failed")
+	assert.Equal(t, "primary", searches[0].Provider)
+	assert.Zero(t, searches[0].SessionID)
+
+	_, err = runner.Run(context.Background(), recipe, RecipeOptions{NoStore: true}, nil)
+	require.Error(t, err)
+	searches, err = database.ListSearches(db.SearchFilter{})
+	require.NoError(t, err)
+	assert.Len(t, searches, 1, "runs with NoStore are not logged")
+}
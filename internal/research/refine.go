@@ -0,0 +1,163 @@
+package research
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/joelklabo/copilot-research/internal/provider"
+)
+
+// StageRefine is the optional stage that expands the query into a research
+// brief before the main query runs
+const StageRefine Stage = "refine"
+
+// MaxClarifyingQuestions caps how many questions are put to the user
+const MaxClarifyingQuestions = 3
+
+// fallbackRefineTemplate is used when refine.md cannot be loaded
+const fallbackRefineTemplate = "Rewrite the following {{mode}} research query into a precise research brief: " +
+	"state the subject, scope, constraints and what the answer should cover. " +
+	"If the query is ambiguous, also list up to three short clarifying questions.\n\n" +
+	"Reply with `BRIEF:` followed by the brief, then `QUESTIONS:` followed by a bulleted list, or `QUESTIONS: none`.\n\n" +
+	"Query: {{query}}\n\n{{clarifications}}"
+
+// Clarifier puts clarifying questions to the user and returns the answers in
+// the same order. An empty answer means the question was skipped.
+type Clarifier func(ctx context.Context, questions []string) ([]string, error)
+
+// Refinement is the model's rewrite of a query
+type Refinement struct {
+	Brief     string
+	Questions []string
+}
+
+var (
+	briefHeaderPattern     = regexp.MustCompile(`(?im)^[ \t]*\**BRIEF\**[ \t]*:\**[ \t]*`)
+	questionsHeaderPattern = regexp.MustCompile(`(?im)^[ \t]*\**QUESTIONS\**[ \t]*:\**[ \t]*`)
+)
+
+// ParseRefinement reads a BRIEF: section and an optional QUESTIONS: list
+// from a refine response. Without a BRIEF: marker the text before the
+// questions is taken as the brief.
+func ParseRefinement(text string) Refinement {
+	text = strings.TrimSpace(text)
+
+	briefPart, questionsPart := text, ""
+	if loc := questionsHeaderPattern.FindStringIndex(text); loc != nil {
+		briefPart, questionsPart = text[:loc[0]], text[loc[1]:]
+	}
+	if loc := briefHeaderPattern.FindStringIndex(briefPart); loc != nil {
+		briefPart = briefPart[loc[1]:]
+	}
+
+	var refinement Refinement
+	refinement.Brief = strings.TrimSpace(briefPart)
+
+	for _, line := range strings.Split(questionsPart, "\n") {
+		if m := listItemPattern.FindStringSubmatch(line); m != nil {
+			line = m[1]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.EqualFold(strings.Trim(line, "."), "none") {
+			continue
+		}
+		refinement.Questions = append(refinement.Questions, line)
+		if len(refinement.Questions) == MaxClarifyingQuestions {
+			break
+		}
+	}
+
+	return refinement
+}
+
+// renderClarifications formats the user's answers for the second refine
+// request, skipping unanswered questions
+func renderClarifications(questions, answers []string) string {
+	var b strings.Builder
+	for i, q := range questions {
+		if i >= len(answers) || strings.TrimSpace(answers[i]) == "" {
+			continue
+		}
+		if b.Len() == 0 {
+			b.WriteString("## Clarifications\n\nThe user answered these questions. Fold the answers into the brief and reply with `QUESTIONS: none`.\n")
+		}
+		fmt.Fprintf(&b, "\n- Q: %s\n  A: %s", q, strings.TrimSpace(answers[i]))
+	}
+	return b.String()
+}
+
+// refine expands the query into a research brief, asking the user the
+// model's clarifying questions when a Clarifier is set. Failures are
+// reported as warnings and leave the query as it was.
+func (e *Engine) refine(ctx context.Context, r *run, em *emitter) error {
+	em.started(StageRefine, "Refining query...")
+
+	refinement, response, err := e.requestRefinement(ctx, r, "")
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		em.warn(StageRefine, "Query refinement failed, using the query as written: %v", err)
+		return nil
+	}
+	em.used(response)
+
+	if len(refinement.Questions) > 0 && r.opts.Clarify != nil {
+		em.started(StageRefine, fmt.Sprintf("Waiting for answers to %d clarifying questions...", len(refinement.Questions)))
+		answers, err := r.opts.Clarify(ctx, refinement.Questions)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			em.warn(StageRefine, "Clarifying questions failed: %v", err)
+		} else if clarifications := renderClarifications(refinement.Questions, answers); clarifications != "" {
+			clarified, clarifiedResponse, err := e.requestRefinement(ctx, r, clarifications)
+			switch {
+			case err != nil && ctx.Err() != nil:
+				return ctx.Err()
+			case err != nil:
+				em.warn(StageRefine, "Refinement with answers failed, using the first brief: %v", err)
+			default:
+				em.used(clarifiedResponse)
+				if clarified.Brief != "" {
+					refinement, response = clarified, clarifiedResponse
+				}
+			}
+		}
+	}
+
+	if refinement.Brief == "" {
+		em.warn(StageRefine, "The model returned no research brief, using the query as written")
+		return nil
+	}
+
+	r.refined = refinement.Brief
+	em.completed(Event{
+		Stage:    StageRefine,
+		Message:  "Refined query into a research brief",
+		Provider: response.Provider,
+		Tokens:   response.TokensUsed,
+		Partial:  refinement.Brief,
+	})
+	return nil
+}
+
+// requestRefinement asks the provider to rewrite the query, with the user's
+// answers to earlier questions if there are any
+func (e *Engine) requestRefinement(ctx context.Context, r *run, clarifications string) (Refinement, *provider.Response, error) {
+	prompt := loadTemplate(e.promptLoader, "refine", fallbackRefineTemplate)
+	rendered := e.promptLoader.Render(prompt, map[string]string{
+		"query":          r.opts.Query,
+		"mode":           r.mode,
+		"clarifications": clarifications,
+	})
+
+	response, err := e.providerManager.Query(ctx, rendered, provider.QueryOptions{})
+	if err != nil {
+		return Refinement{}, nil, fmt.Errorf("provider query failed: %w", err)
+	}
+
+	return ParseRefinement(response.Content), response, nil
+}
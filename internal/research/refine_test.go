@@ -0,0 +1,153 @@
+package research
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/joelklabo/copilot-research/internal/prompts"
+	"github.com/joelklabo/copilot-research/internal/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRefinement(t *testing.T) {
+	tests := []struct {
+		name      string
+		response  string
+		brief     string
+		questions []string
+	}{
+		{
+			name:     "brief and questions",
+			response: "BRIEF:\nCompare Go logging libraries.\nCover performance.\n\nQUESTIONS:\n- Which Go version?\n2. Is JSON output required?",
+			brief:    "Compare Go logging libraries.\nCover performance.",
+			questions: []string{
+				"Which Go version?",
+				"Is JSON output required?",
+			},
+		},
+		{
+			name:     "no questions",
+			response: "**BRIEF:** Explain Swift actors in Swift 6.\n\n**QUESTIONS:** none",
+			brief:    "Explain Swift actors in Swift 6.",
+		},
+		{
+			name:     "no markers",
+			response: "Explain how the Go garbage collector works.",
+			brief:    "Explain how the Go garbage collector works.",
+		},
+		{
+			name:      "questions capped",
+			response:  "BRIEF: x\nQUESTIONS:\n- a?\n- b?\n- c?\n- d?",
+			brief:     "x",
+			questions: []string{"a?", "b?", "c?"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refinement := ParseRefinement(tt.response)
+			assert.Equal(t, tt.brief, refinement.Brief)
+			assert.Equal(t, tt.questions, refinement.Questions)
+		})
+	}
+}
+
+func TestRenderClarifications(t *testing.T) {
+	assert.Empty(t, renderClarifications([]string{"a?"}, []string{" "}))
+
+	out := renderClarifications([]string{"Which version?", "Skipped?", "Scale?"}, []string{"1.22", "", "10k rps"})
+	assert.Contains(t, out, "- Q: Which version?\n  A: 1.22")
+	assert.Contains(t, out, "- Q: Scale?\n  A: 10k rps")
+	assert.NotContains(t, out, "Skipped?")
+}
+
+// newRefineEngine returns an engine whose provider answers refine prompts
+// with refine and everything else by echoing the prompt
+func newRefineEngine(t *testing.T, refine func(prompt string) string) (*Engine, db.DB) {
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { database.Close() })
+
+	mock := &funcProvider{MockProvider: MockProvider{name: "test", authenticated: true}, fn: func(prompt string) string {
+		if strings.Contains(prompt, "research brief") {
+			return refine(prompt)
+		}
+		return "ANSWER TO: " + prompt
+	}}
+	factory := provider.NewProviderFactory()
+	require.NoError(t, factory.Register("test", mock))
+	return NewEngine(database, prompts.NewPromptLoader("../../prompts"), provider.NewProviderManager(factory, "test", "", false, false)), database
+}
+
+func TestEngine_Research_Refine(t *testing.T) {
+	engine, database := newRefineEngine(t, func(prompt string) string {
+		return "BRIEF:\nCompare slog, zap and zerolog for Go services.\n\nQUESTIONS: none"
+	})
+
+	result, err := engine.Research(context.Background(), ResearchOptions{Query: "go logging", Refine: true}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "go logging", result.Query)
+	assert.Equal(t, "Compare slog, zap and zerolog for Go services.", result.RefinedQuery)
+	assert.Contains(t, result.Content, "Compare slog, zap and zerolog")
+
+	// The refine and main queries are both counted
+	assert.Equal(t, "test", result.Provider)
+	assert.Equal(t, 20, result.Tokens.Total)
+
+	session, err := database.GetSession(result.SessionID)
+	require.NoError(t, err)
+	assert.Equal(t, "go logging", session.Query)
+	assert.Equal(t, result.RefinedQuery, session.RefinedQuery)
+}
+
+func TestEngine_Research_RefineWithClarification(t *testing.T) {
+	engine, _ := newRefineEngine(t, func(prompt string) string {
+		if strings.Contains(prompt, "A: Go 1.22") {
+			return "BRIEF: Structured logging in Go 1.22 services.\nQUESTIONS: none"
+		}
+		return "BRIEF: Structured logging in Go.\nQUESTIONS:\n- Which Go version?"
+	})
+
+	var asked []string
+	clarify := func(ctx context.Context, questions []string) ([]string, error) {
+		asked = questions
+		return []string{"Go 1.22"}, nil
+	}
+
+	result, err := engine.Research(context.Background(), ResearchOptions{Query: "go logging", Refine: true, Clarify: clarify}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Which Go version?"}, asked)
+	assert.Equal(t, "Structured logging in Go 1.22 services.", result.RefinedQuery)
+
+	// Without a clarifier the first brief is used
+	result, err = engine.Research(context.Background(), ResearchOptions{Query: "go logging", Refine: true, NoStore: true}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Structured logging in Go.", result.RefinedQuery)
+}
+
+func TestEngine_Research_RefineFallsBack(t *testing.T) {
+	engine, _ := newRefineEngine(t, func(prompt string) string {
+		return "QUESTIONS:\n- What do you mean?"
+	})
+
+	events := make(chan Event, 50)
+	result, err := engine.Research(context.Background(), ResearchOptions{Query: "go logging", Refine: true, NoStore: true}, events)
+	close(events)
+	require.NoError(t, err)
+
+	assert.Empty(t, result.RefinedQuery)
+	assert.Contains(t, result.Content, "go logging")
+
+	warned := false
+	for e := range events {
+		if e.IsWarning() && e.Stage == StageRefine {
+			warned = true
+		}
+	}
+	assert.True(t, warned)
+}
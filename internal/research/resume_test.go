@@ -0,0 +1,247 @@
+package research
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/joelklabo/copilot-research/internal/prompts"
+	"github.com/joelklabo/copilot-research/internal/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyProvider fails prompts matching failOn until it is fixed
+type flakyProvider struct {
+	MockProvider
+	mu      sync.Mutex
+	failOn  string
+	prompts []string
+}
+
+func (p *flakyProvider) Query(ctx context.Context, prompt string, opts provider.QueryOptions) (*provider.Response, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prompts = append(p.prompts, prompt)
+	if p.failOn != "" && strings.Contains(prompt, p.failOn) {
+		return nil, errors.New("connection reset")
+	}
+	content := "Answer"
+	if strings.Contains(prompt, "SUMMARY:") {
+		content = "SUMMARY: ok\nSpeed: 4/5"
+	}
+	return &provider.Response{Content: content, Provider: p.name}, nil
+}
+
+func (p *flakyProvider) fix() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failOn = ""
+	p.prompts = nil
+}
+
+func newFlakyEngine(t *testing.T, failOn string) (*Engine, *flakyProvider, db.DB) {
+	t.Helper()
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { database.Close() })
+
+	mock := &flakyProvider{MockProvider: MockProvider{name: "test", authenticated: true}, failOn: failOn}
+	factory := provider.NewProviderFactory()
+	require.NoError(t, factory.Register("test", mock))
+	engine := NewEngine(database, prompts.NewPromptLoader("../../prompts"), provider.NewProviderManager(factory, "test", "", false, false))
+	return engine, mock, database
+}
+
+func TestEngine_Research_CheckpointsAndResumes(t *testing.T) {
+	engine, mock, database := newFlakyEngine(t, "Test query")
+
+	_, err := engine.Research(context.Background(), ResearchOptions{Query: "Test query", Mode: "deep", PromptName: "default"}, nil)
+	require.Error(t, err)
+
+	var checkpointed *CheckpointError
+	require.True(t, errors.As(err, &checkpointed))
+	assert.Contains(t, err.Error(), "provider query failed")
+
+	session, err := database.GetSession(checkpointed.SessionID)
+	require.NoError(t, err)
+	assert.True(t, session.InProgress())
+	require.NotNil(t, session.Checkpoint)
+	assert.Equal(t, string(StageLoadPrompt), session.Checkpoint.Stage)
+	assert.Contains(t, session.Checkpoint.Prompt, "Test query")
+
+	mock.fix()
+	result, err := engine.Resume(context.Background(), checkpointed.SessionID, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Answer", result.Content)
+	assert.Equal(t, checkpointed.SessionID, result.SessionID, "resuming finishes the same session")
+	assert.Equal(t, []string{session.Checkpoint.Prompt}, mock.prompts, "the saved prompt is reused")
+
+	session, err = database.GetSession(result.SessionID)
+	require.NoError(t, err)
+	assert.False(t, session.InProgress())
+	assert.Nil(t, session.Checkpoint)
+	assert.Equal(t, "Answer", session.Result)
+
+	_, err = engine.Resume(context.Background(), result.SessionID, nil)
+	assert.ErrorContains(t, err, "already complete")
+}
+
+func TestEngine_Resume_SkipsScoredOptions(t *testing.T) {
+	engine, mock, database := newFlakyEngine(t, "**Beta**")
+
+	_, err := engine.Research(context.Background(), ResearchOptions{
+		Query:    "Alpha vs Beta",
+		Mode:     "compare",
+		Criteria: []string{"Speed"},
+	}, nil)
+	var checkpointed *CheckpointError
+	require.True(t, errors.As(err, &checkpointed))
+
+	session, err := database.GetSession(checkpointed.SessionID)
+	require.NoError(t, err)
+	require.NotNil(t, session.Checkpoint.Matrix)
+	assert.Equal(t, "Alpha", session.Checkpoint.Matrix.Options[0].Name)
+	assert.Empty(t, session.Checkpoint.Matrix.Options[1].Name)
+
+	mock.fix()
+	result, err := engine.Resume(context.Background(), checkpointed.SessionID, nil)
+	require.NoError(t, err)
+
+	require.Len(t, mock.prompts, 1, "only the missing option is researched")
+	assert.Contains(t, mock.prompts[0], "**Beta**")
+	assert.Equal(t, []string{"Alpha", "Beta"}, []string{result.Matrix.Options[0].Name, result.Matrix.Options[1].Name})
+	assert.Equal(t, 4, result.Matrix.Options[1].Scores[0].Score)
+}
+
+func TestEngine_Resume_RestoresContext(t *testing.T) {
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	defer database.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cancelled while refining, before the prompt with the context is built
+	mock := &cancellingProvider{
+		flakyProvider: flakyProvider{MockProvider: MockProvider{name: "test", authenticated: true}},
+		cancelOn:      "precise research brief",
+		cancel:        cancel,
+	}
+	factory := provider.NewProviderFactory()
+	require.NoError(t, factory.Register("test", mock))
+	engine := NewEngine(database, prompts.NewPromptLoader("../../prompts"), provider.NewProviderManager(factory, "test", "", false, false))
+
+	snippets := []ContextSnippet{{Path: "internal/auth/token.go", StartLine: 10, EndLine: 12, Content: "func rotateToken() {}"}}
+	_, err = engine.Research(ctx, ResearchOptions{Query: "How are tokens rotated?", Refine: true, Context: snippets}, nil)
+	var checkpointed *CheckpointError
+	require.True(t, errors.As(err, &checkpointed))
+
+	session, err := database.GetSession(checkpointed.SessionID)
+	require.NoError(t, err)
+	assert.Empty(t, session.Checkpoint.Prompt)
+	require.Len(t, session.Checkpoint.Snippets, 1)
+
+	mock.cancelOn = ""
+	mock.fix()
+	_, err = engine.Resume(context.Background(), checkpointed.SessionID, nil)
+	require.NoError(t, err)
+
+	require.NotEmpty(t, mock.prompts)
+	last := mock.prompts[len(mock.prompts)-1]
+	assert.Contains(t, last, "internal/auth/token.go:10-12")
+	assert.Contains(t, last, "func rotateToken() {}")
+}
+
+func TestEngine_Research_NoStoreIsNotResumable(t *testing.T) {
+	engine, _, database := newFlakyEngine(t, "Test query")
+
+	_, err := engine.Research(context.Background(), ResearchOptions{Query: "Test query", NoStore: true}, nil)
+	require.Error(t, err)
+
+	var checkpointed *CheckpointError
+	assert.False(t, errors.As(err, &checkpointed))
+
+	total, err := database.GetTotalSessions()
+	require.NoError(t, err)
+	assert.Equal(t, 0, total)
+}
+
+// cancellingProvider cancels the run when it sees a prompt containing
+// cancelOn, as Ctrl-C or a timeout would
+type cancellingProvider struct {
+	flakyProvider
+	cancelOn string
+	cancel   context.CancelFunc
+}
+
+func (p *cancellingProvider) Query(ctx context.Context, prompt string, opts provider.QueryOptions) (*provider.Response, error) {
+	if p.cancelOn != "" && strings.Contains(prompt, p.cancelOn) {
+		p.cancel()
+		return nil, ctx.Err()
+	}
+	return p.flakyProvider.Query(ctx, prompt, opts)
+}
+
+func TestEngine_Research_CancelledKeepsPartialOutput(t *testing.T) {
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	defer database.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mock := &cancellingProvider{
+		flakyProvider: flakyProvider{MockProvider: MockProvider{name: "test", authenticated: true}},
+		cancelOn:      "strict reviewer",
+		cancel:        cancel,
+	}
+	factory := provider.NewProviderFactory()
+	require.NoError(t, factory.Register("test", mock))
+	engine := NewEngine(database, prompts.NewPromptLoader("../../prompts"), provider.NewProviderManager(factory, "test", "", false, false))
+
+	events := make(chan Event, 50)
+	_, err = engine.Research(ctx, ResearchOptions{Query: "Test query", Mode: "deep", Critique: true}, events)
+	close(events)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	var checkpointed *CheckpointError
+	require.True(t, errors.As(err, &checkpointed))
+
+	session, err := database.GetSession(checkpointed.SessionID)
+	require.NoError(t, err)
+	assert.True(t, session.Cancelled())
+	assert.True(t, session.Resumable())
+	assert.Equal(t, "Answer", session.Result, "the answer before the critique is kept")
+	assert.Equal(t, string(StageQuery), session.Checkpoint.Stage)
+
+	var warned bool
+	for e := range events {
+		if e.IsWarning() && strings.Contains(e.Message, "Cancelled") {
+			warned = true
+		}
+	}
+	assert.True(t, warned)
+
+	mock.cancelOn = ""
+	result, err := engine.Resume(context.Background(), checkpointed.SessionID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, checkpointed.SessionID, result.SessionID)
+
+	session, err = database.GetSession(result.SessionID)
+	require.NoError(t, err)
+	assert.Equal(t, db.SessionComplete, session.Status)
+
+	// Both runs are logged against the session
+	searches, err := database.ListSearches(db.SearchFilter{})
+	require.NoError(t, err)
+	require.Len(t, searches, 2)
+	assert.Equal(t, db.OutcomeSuccess, searches[0].Outcome)
+	assert.Equal(t, db.OutcomeCancelled, searches[1].Outcome)
+	assert.Equal(t, checkpointed.SessionID, searches[1].SessionID)
+}
@@ -0,0 +1,268 @@
+package research
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+)
+
+// StageGather is the stage reported while synthesis mode collects prior
+// sessions and knowledge
+const StageGather Stage = "gather"
+
+// Limits on how much prior research synthesis mode feeds to the model
+const (
+	DefaultSynthesisSessions  = 5
+	DefaultSynthesisKnowledge = 5
+	priorSummaryLength        = 800
+)
+
+// KnowledgeNote is a knowledge base entry offered to synthesis mode
+type KnowledgeNote struct {
+	Topic   string
+	Content string
+	Tags    []string
+}
+
+// KnowledgeSource finds knowledge base entries matching a search term. It is
+// implemented outside this package so research does not depend on the
+// knowledge base.
+type KnowledgeSource interface {
+	Search(term string) ([]KnowledgeNote, error)
+}
+
+// PriorResearch is what synthesis mode drew on
+type PriorResearch struct {
+	Sessions  []*db.ResearchSession
+	Knowledge []KnowledgeNote
+}
+
+// Empty reports whether nothing related was found
+func (p *PriorResearch) Empty() bool {
+	return p == nil || (len(p.Sessions) == 0 && len(p.Knowledge) == 0)
+}
+
+// SessionIDs returns the IDs of the sessions drawn on
+func (p *PriorResearch) SessionIDs() []int64 {
+	if p == nil {
+		return nil
+	}
+	ids := make([]int64, 0, len(p.Sessions))
+	for _, s := range p.Sessions {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+// Topics returns the knowledge topics drawn on
+func (p *PriorResearch) Topics() []string {
+	if p == nil {
+		return nil
+	}
+	topics := make([]string, 0, len(p.Knowledge))
+	for _, k := range p.Knowledge {
+		topics = append(topics, k.Topic)
+	}
+	return topics
+}
+
+// synthesisStopWords are too common to find related research by
+var synthesisStopWords = map[string]bool{
+	"about": true, "and": true, "are": true, "between": true, "can": true,
+	"does": true, "for": true, "from": true, "how": true, "into": true,
+	"our": true, "should": true, "that": true, "the": true, "this": true,
+	"using": true, "what": true, "when": true, "where": true, "which": true,
+	"why": true, "with": true, "work": true, "works": true, "you": true,
+	"synthesize": true, "synthesis": true, "summarize": true, "everything": true,
+	"know": true, "learned": true, "have": true,
+}
+
+// searchTerms returns the distinct words of query worth searching for
+func searchTerms(query string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '+' && r != '#'
+	})
+	for _, w := range words {
+		w = strings.Trim(w, "-")
+		if len(w) < 3 || synthesisStopWords[w] || seen[w] {
+			continue
+		}
+		seen[w] = true
+		terms = append(terms, w)
+	}
+	return terms
+}
+
+// termHits counts how many of terms occur in text
+func termHits(text string, terms []string) int {
+	text = strings.ToLower(text)
+	hits := 0
+	for _, t := range terms {
+		if strings.Contains(text, t) {
+			hits++
+		}
+	}
+	return hits
+}
+
+// gatherPrior finds past sessions and knowledge entries related to query,
+// ranked by how many of the query's terms they mention
+func (e *Engine) gatherPrior(query string) (*PriorResearch, error) {
+	terms := searchTerms(query)
+	prior := &PriorResearch{}
+	if len(terms) == 0 {
+		return prior, nil
+	}
+
+	type scoredSession struct {
+		session *db.ResearchSession
+		score   int
+	}
+	sessions := make(map[int64]*scoredSession)
+	for _, term := range terms {
+		found, err := e.db.SearchSessions(term)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search sessions: %w", err)
+		}
+		for _, s := range found {
+			if _, ok := sessions[s.ID]; ok || s.InProgress() || s.Cancelled() || strings.TrimSpace(s.Result) == "" {
+				continue
+			}
+			// Matches in the query count double; the result mentions many things
+			score := 2*termHits(s.Query, terms) + termHits(s.Result, terms)
+			sessions[s.ID] = &scoredSession{session: s, score: score}
+		}
+	}
+
+	ranked := make([]*scoredSession, 0, len(sessions))
+	for _, s := range sessions {
+		ranked = append(ranked, s)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].session.CreatedAt.After(ranked[j].session.CreatedAt)
+	})
+	for i := 0; i < len(ranked) && i < DefaultSynthesisSessions; i++ {
+		prior.Sessions = append(prior.Sessions, ranked[i].session)
+	}
+
+	if e.knowledge == nil {
+		return prior, nil
+	}
+
+	type scoredNote struct {
+		note  KnowledgeNote
+		score int
+	}
+	notes := make(map[string]*scoredNote)
+	for _, term := range terms {
+		found, err := e.knowledge.Search(term)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search knowledge: %w", err)
+		}
+		for _, k := range found {
+			if _, ok := notes[k.Topic]; ok {
+				continue
+			}
+			score := 2*termHits(k.Topic+" "+strings.Join(k.Tags, " "), terms) + termHits(k.Content, terms)
+			notes[k.Topic] = &scoredNote{note: k, score: score}
+		}
+	}
+
+	rankedNotes := make([]*scoredNote, 0, len(notes))
+	for _, n := range notes {
+		rankedNotes = append(rankedNotes, n)
+	}
+	sort.Slice(rankedNotes, func(i, j int) bool {
+		if rankedNotes[i].score != rankedNotes[j].score {
+			return rankedNotes[i].score > rankedNotes[j].score
+		}
+		return rankedNotes[i].note.Topic < rankedNotes[j].note.Topic
+	})
+	for i := 0; i < len(rankedNotes) && i < DefaultSynthesisKnowledge; i++ {
+		prior.Knowledge = append(prior.Knowledge, rankedNotes[i].note)
+	}
+
+	return prior, nil
+}
+
+// summarize shortens text to about limit characters, cutting at a word
+// boundary
+func summarize(text string, limit int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) <= limit {
+		return text
+	}
+	cut := strings.LastIndex(text[:limit], " ")
+	if cut <= 0 {
+		cut = limit
+	}
+	return text[:cut] + " …"
+}
+
+// sessionRef is how a report cites a past session
+func sessionRef(id int64) string {
+	return fmt.Sprintf("session #%d", id)
+}
+
+// knowledgeRef is how a report cites a knowledge topic
+func knowledgeRef(topic string) string {
+	return "knowledge: " + topic
+}
+
+// renderPrior formats prior research for inclusion in a prompt. Templates
+// can place it with {{prior}}; otherwise it is appended.
+func renderPrior(prior *PriorResearch) string {
+	if prior.Empty() {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("## Prior Research\n\n")
+	b.WriteString("Synthesize across the following earlier findings rather than starting from scratch. ")
+	b.WriteString("Call out where they agree, conflict or are out of date, and cite them inline as ")
+	b.WriteString("[session #N] or [knowledge: topic].\n")
+
+	for _, s := range prior.Sessions {
+		fmt.Fprintf(&b, "\n### [%s] %s\n\n_%s research, %s_\n\n%s\n",
+			sessionRef(s.ID), s.Query, s.Mode, s.CreatedAt.Format("2006-01-02"), summarize(s.Result, priorSummaryLength))
+	}
+	for _, k := range prior.Knowledge {
+		fmt.Fprintf(&b, "\n### [%s]\n\n%s\n", knowledgeRef(k.Topic), summarize(k.Content, priorSummaryLength))
+	}
+
+	return b.String()
+}
+
+// renderPriorSources lists the prior research a synthesis drew on, for the
+// end of the report
+func renderPriorSources(prior *PriorResearch) string {
+	if prior.Empty() {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("## Prior Research Used\n\n")
+	for _, s := range prior.Sessions {
+		fmt.Fprintf(&b, "- [%s] %s (%s, %s)\n", sessionRef(s.ID), s.Query, s.Mode, s.CreatedAt.Format("2006-01-02"))
+	}
+	for _, k := range prior.Knowledge {
+		fmt.Fprintf(&b, "- [%s]\n", knowledgeRef(k.Topic))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// appendSources adds a sources section to the end of a report
+func appendSources(content, sources string) string {
+	if sources == "" {
+		return content
+	}
+	return strings.TrimRight(content, "\n") + "\n\n" + sources
+}
@@ -0,0 +1,162 @@
+package research
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/joelklabo/copilot-research/internal/prompts"
+	"github.com/joelklabo/copilot-research/internal/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubKnowledge serves fixed notes, matching terms against topic and content
+type stubKnowledge struct {
+	notes []KnowledgeNote
+	err   error
+}
+
+func (s *stubKnowledge) Search(term string) ([]KnowledgeNote, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	var found []KnowledgeNote
+	for _, n := range s.notes {
+		if strings.Contains(strings.ToLower(n.Topic+" "+n.Content), term) {
+			found = append(found, n)
+		}
+	}
+	return found, nil
+}
+
+func TestSearchTerms(t *testing.T) {
+	assert.Equal(t, []string{"swift", "actors", "c++", "interop"}, searchTerms("Synthesize what we know about Swift actors and C++ interop, actors?"))
+	assert.Empty(t, searchTerms("how does it work"))
+}
+
+func TestSummarize(t *testing.T) {
+	assert.Equal(t, "short text", summarize("short\n\n  text", 100))
+	assert.Equal(t, "one two …", summarize("one two three", 9))
+}
+
+func saveTestSession(t *testing.T, database db.DB, query, result string, age time.Duration) *db.ResearchSession {
+	t.Helper()
+	session := &db.ResearchSession{
+		Query:      query,
+		Mode:       "quick",
+		PromptUsed: "default",
+		Result:     result,
+		CreatedAt:  time.Now().Add(-age),
+	}
+	require.NoError(t, database.SaveSession(session))
+	return session
+}
+
+func TestEngine_GatherPrior(t *testing.T) {
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	defer database.Close()
+
+	both := saveTestSession(t, database, "Swift actors and isolation", "Actors serialize access.", 2*time.Hour)
+	one := saveTestSession(t, database, "Swift packages", "SwiftPM basics.", time.Hour)
+	saveTestSession(t, database, "Go generics", "Type parameters.", time.Hour)
+	saveTestSession(t, database, "Swift macros", "", time.Hour) // Failed sessions are skipped
+
+	engine := NewEngine(database, prompts.NewPromptLoader("../../prompts"), nil)
+	engine.SetKnowledgeSource(&stubKnowledge{notes: []KnowledgeNote{
+		{Topic: "swift-concurrency", Content: "Actors isolate state"},
+		{Topic: "go-modules", Content: "go mod tidy"},
+	}})
+
+	prior, err := engine.gatherPrior("Synthesize Swift actors")
+	require.NoError(t, err)
+
+	assert.Equal(t, []int64{both.ID, one.ID}, prior.SessionIDs())
+	assert.Equal(t, []string{"swift-concurrency"}, prior.Topics())
+}
+
+func TestEngine_GatherPrior_KnowledgeError(t *testing.T) {
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	defer database.Close()
+
+	engine := NewEngine(database, prompts.NewPromptLoader("../../prompts"), nil)
+	engine.SetKnowledgeSource(&stubKnowledge{err: fmt.Errorf("broken")})
+
+	_, err = engine.gatherPrior("Swift actors")
+	assert.ErrorContains(t, err, "failed to search knowledge")
+}
+
+func TestEngine_Research_SynthesisDrawsOnPrior(t *testing.T) {
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	defer database.Close()
+
+	past := saveTestSession(t, database, "Swift actors", "Actors serialize access to their state.", time.Hour)
+
+	var mu sync.Mutex
+	var prompt string
+	mock := &funcProvider{MockProvider: MockProvider{name: "test", authenticated: true}, fn: func(p string) string {
+		mu.Lock()
+		prompt = p
+		mu.Unlock()
+		return fmt.Sprintf("Actors are safe [session #%d].", past.ID)
+	}}
+	factory := provider.NewProviderFactory()
+	require.NoError(t, factory.Register("test", mock))
+	engine := NewEngine(database, prompts.NewPromptLoader("../../prompts"), provider.NewProviderManager(factory, "test", "", false, false))
+	engine.SetKnowledgeSource(&stubKnowledge{notes: []KnowledgeNote{{Topic: "swift-concurrency", Content: "Actors isolate state"}}})
+
+	result, err := engine.Research(context.Background(), ResearchOptions{
+		Query:      "Swift actors",
+		Mode:       "synthesis",
+		PromptName: "synthesis",
+	}, nil)
+	require.NoError(t, err)
+
+	assert.Contains(t, prompt, fmt.Sprintf("### [session #%d] Swift actors", past.ID))
+	assert.Contains(t, prompt, "### [knowledge: swift-concurrency]")
+	assert.Equal(t, 1, strings.Count(prompt, "## Prior Research"), "placed by {{prior}}, not appended again")
+
+	assert.Contains(t, result.Content, "## Prior Research Used")
+	assert.Contains(t, result.Content, "- [knowledge: swift-concurrency]")
+	assert.Equal(t, []int64{past.ID}, result.Prior.SessionIDs())
+
+	var refs []string
+	for _, c := range result.Citations {
+		refs = append(refs, c.Text)
+	}
+	assert.Equal(t, []string{fmt.Sprintf("session #%d", past.ID), "knowledge: swift-concurrency"}, refs)
+}
+
+func TestEngine_Research_SynthesisWithoutPrior(t *testing.T) {
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	defer database.Close()
+
+	mock := &funcProvider{MockProvider: MockProvider{name: "test", authenticated: true}, fn: func(p string) string {
+		return "Fresh synthesis"
+	}}
+	factory := provider.NewProviderFactory()
+	require.NoError(t, factory.Register("test", mock))
+	engine := NewEngine(database, prompts.NewPromptLoader("../../prompts"), provider.NewProviderManager(factory, "test", "", false, false))
+
+	events := make(chan Event, 20)
+	result, err := engine.Research(context.Background(), ResearchOptions{Query: "Swift actors", Mode: "synthesis", NoStore: true}, events)
+	close(events)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Fresh synthesis", result.Content)
+	warned := false
+	for e := range events {
+		if e.Stage == StageGather && e.IsWarning() {
+			warned = true
+		}
+	}
+	assert.True(t, warned)
+}
@@ -0,0 +1,231 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/joelklabo/copilot-research/internal/research"
+)
+
+// batchRow is the display state of one batch item
+type batchRow struct {
+	query   string
+	status  string
+	message string
+}
+
+// BatchModel is the Bubble Tea model for batch research runs
+type BatchModel struct {
+	rows    []batchRow
+	spinner *SpinnerModel
+	summary *research.BatchSummary
+	err     error
+	done    bool
+	height  int
+	styles  Styles
+}
+
+// BatchUpdateMsg carries progress for one batch item
+type BatchUpdateMsg research.BatchUpdate
+
+// BatchDoneMsg is sent when the batch run finishes
+type BatchDoneMsg struct {
+	Summary *research.BatchSummary
+	Err     error
+}
+
+// NewBatchModel creates a batch model for the manifest's queries
+func NewBatchModel(items []research.BatchItem) BatchModel {
+	rows := make([]batchRow, len(items))
+	for i, item := range items {
+		rows[i] = batchRow{query: item.Query, status: research.BatchStatusPending}
+	}
+
+	return BatchModel{
+		rows:    rows,
+		spinner: NewSpinner(),
+		styles:  DefaultStyles(),
+	}
+}
+
+// Init initializes the model
+func (m BatchModel) Init() tea.Cmd {
+	return m.spinner.Init()
+}
+
+// Update handles messages
+func (m BatchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.Type {
+		case tea.KeyCtrlC:
+			return m, tea.Quit
+		case tea.KeyRunes:
+			if m.done && len(msg.Runes) > 0 && msg.Runes[0] == 'q' {
+				return m, tea.Quit
+			}
+		}
+
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+		return m, nil
+
+	case BatchUpdateMsg:
+		if msg.Index >= 0 && msg.Index < len(m.rows) {
+			row := &m.rows[msg.Index]
+			row.status = msg.Status
+			row.message = msg.Message
+		}
+		return m, nil
+
+	case BatchDoneMsg:
+		m.done = true
+		m.summary = msg.Summary
+		m.err = msg.Err
+		return m, nil
+	}
+
+	if !m.done {
+		var cmd tea.Cmd
+		spinnerModel, cmd := m.spinner.Update(msg)
+		m.spinner = spinnerModel.(*SpinnerModel)
+		return m, cmd
+	}
+
+	return m, nil
+}
+
+// View renders the model
+func (m BatchModel) View() string {
+	var b strings.Builder
+
+	finished, failed := m.counts()
+	title := "📚 Batch Research"
+	if m.done {
+		title = "✓ Batch Complete"
+	}
+	b.WriteString(m.styles.TitleStyle.Render(title))
+	b.WriteString("\n\n")
+	b.WriteString(m.styles.MessageStyle.Render(fmt.Sprintf("%s %d/%d done, %d failed",
+		progressBar(finished, len(m.rows), 30), finished, len(m.rows), failed)))
+	b.WriteString("\n\n")
+
+	start, end := m.visibleRange()
+	if start > 0 {
+		b.WriteString(m.styles.MessageStyle.Render(fmt.Sprintf("… %d more above", start)))
+		b.WriteString("\n")
+	}
+	for _, row := range m.rows[start:end] {
+		b.WriteString(m.renderRow(row))
+		b.WriteString("\n")
+	}
+	if end < len(m.rows) {
+		b.WriteString(m.styles.MessageStyle.Render(fmt.Sprintf("… %d more below", len(m.rows)-end)))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	if m.done {
+		if m.err != nil {
+			b.WriteString(m.styles.ErrorStyle.Render(fmt.Sprintf("Stopped: %v", m.err)))
+			b.WriteString("\n")
+		}
+		if m.summary != nil {
+			b.WriteString(fmt.Sprintf("Completed: %d • Skipped: %d • Failed: %d • Duration: %v\n",
+				m.summary.Completed, m.summary.Skipped, m.summary.Failed, m.summary.Duration.Round(1e9)))
+		}
+		b.WriteString("Press q to quit")
+	} else {
+		b.WriteString("Press Ctrl+C to stop (progress is saved)")
+	}
+
+	return b.String()
+}
+
+// renderRow renders one batch item line
+func (m BatchModel) renderRow(row batchRow) string {
+	var icon string
+	switch row.status {
+	case research.BatchStatusRunning:
+		icon = m.spinner.spinner.View()
+	case research.BatchStatusDone:
+		icon = m.styles.SuccessStyle.Render("✓")
+	case research.BatchStatusSkipped:
+		icon = m.styles.SuccessStyle.Render("↷")
+	case research.BatchStatusFailed:
+		icon = m.styles.ErrorStyle.Render("✗")
+	default:
+		icon = "·"
+	}
+
+	line := fmt.Sprintf("%s %-50s", icon, truncate(row.query, 50))
+	if row.message != "" && row.status != research.BatchStatusPending {
+		line += " " + m.styles.MessageStyle.Render(truncate(row.message, 40))
+	}
+	return line
+}
+
+// counts returns how many items have finished and how many failed
+func (m BatchModel) counts() (finished, failed int) {
+	for _, row := range m.rows {
+		switch row.status {
+		case research.BatchStatusDone, research.BatchStatusSkipped:
+			finished++
+		case research.BatchStatusFailed:
+			finished++
+			failed++
+		}
+	}
+	return finished, failed
+}
+
+// visibleRange picks the rows that fit the terminal, keeping the first
+// unfinished item in view
+func (m BatchModel) visibleRange() (int, int) {
+	maxRows := m.height - 10
+	if m.height == 0 || maxRows >= len(m.rows) {
+		return 0, len(m.rows)
+	}
+	if maxRows < 1 {
+		maxRows = 1
+	}
+
+	first := 0
+	for i, row := range m.rows {
+		if row.status == research.BatchStatusRunning || row.status == research.BatchStatusPending {
+			first = i
+			break
+		}
+	}
+
+	start := first - 2
+	if start < 0 {
+		start = 0
+	}
+	if start+maxRows > len(m.rows) {
+		start = len(m.rows) - maxRows
+	}
+	return start, start + maxRows
+}
+
+// progressBar renders a fixed-width text progress bar
+func progressBar(done, total, width int) string {
+	filled := 0
+	if total > 0 {
+		filled = done * width / total
+	}
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("░", width-filled) + "]"
+}
+
+// truncate shortens s to maxLen runes, adding an ellipsis
+func truncate(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+	if maxLen <= 1 {
+		return string(runes[:maxLen])
+	}
+	return string(runes[:maxLen-1]) + "…"
+}
@@ -0,0 +1,100 @@
+package ui
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/joelklabo/copilot-research/internal/research"
+	"github.com/stretchr/testify/assert"
+)
+
+func testBatchItems(n int) []research.BatchItem {
+	items := make([]research.BatchItem, n)
+	for i := range items {
+		items[i] = research.BatchItem{ID: fmt.Sprint(i), Query: fmt.Sprintf("query %d", i)}
+	}
+	return items
+}
+
+func TestNewBatchModel(t *testing.T) {
+	model := NewBatchModel(testBatchItems(3))
+	assert.Len(t, model.rows, 3)
+	assert.Equal(t, research.BatchStatusPending, model.rows[0].status)
+	assert.NotNil(t, model.Init())
+}
+
+func TestBatchModel_UpdateRows(t *testing.T) {
+	model := NewBatchModel(testBatchItems(3))
+
+	newModel, _ := model.Update(BatchUpdateMsg{Index: 1, Status: research.BatchStatusRunning, Message: "Querying AI provider..."})
+	model = newModel.(BatchModel)
+	assert.Equal(t, research.BatchStatusRunning, model.rows[1].status)
+	assert.Equal(t, "Querying AI provider...", model.rows[1].message)
+
+	newModel, _ = model.Update(BatchUpdateMsg{Index: 0, Status: research.BatchStatusFailed, Message: "boom"})
+	model = newModel.(BatchModel)
+	newModel, _ = model.Update(BatchUpdateMsg{Index: 2, Status: research.BatchStatusDone})
+	model = newModel.(BatchModel)
+
+	finished, failed := model.counts()
+	assert.Equal(t, 2, finished)
+	assert.Equal(t, 1, failed)
+
+	// Out of range updates are ignored
+	newModel, _ = model.Update(BatchUpdateMsg{Index: 10, Status: research.BatchStatusDone})
+	assert.Len(t, newModel.(BatchModel).rows, 3)
+}
+
+func TestBatchModel_View(t *testing.T) {
+	model := NewBatchModel(testBatchItems(2))
+	model.rows[0].status = research.BatchStatusDone
+	model.rows[1].status = research.BatchStatusFailed
+	model.rows[1].message = "provider query failed"
+
+	view := model.View()
+	assert.Contains(t, view, "Batch Research")
+	assert.Contains(t, view, "query 0")
+	assert.Contains(t, view, "provider query failed")
+	assert.Contains(t, view, "2/2 done, 1 failed")
+}
+
+func TestBatchModel_Done(t *testing.T) {
+	model := NewBatchModel(testBatchItems(1))
+
+	newModel, _ := model.Update(BatchDoneMsg{Summary: &research.BatchSummary{Total: 1, Completed: 1, Duration: time.Second}})
+	model = newModel.(BatchModel)
+	assert.True(t, model.done)
+	assert.Contains(t, model.View(), "Batch Complete")
+	assert.Contains(t, model.View(), "Completed: 1")
+
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}})
+	assert.NotNil(t, cmd)
+}
+
+func TestBatchModel_VisibleRange(t *testing.T) {
+	model := NewBatchModel(testBatchItems(40))
+	for i := 0; i < 20; i++ {
+		model.rows[i].status = research.BatchStatusDone
+	}
+
+	newModel, _ := model.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	model = newModel.(BatchModel)
+
+	start, end := model.visibleRange()
+	assert.Equal(t, 10, end-start)
+	assert.LessOrEqual(t, start, 20)
+	assert.Greater(t, end, 20)
+	assert.Contains(t, model.View(), "more above")
+}
+
+func TestProgressBar(t *testing.T) {
+	assert.Equal(t, "[█████░░░░░]", progressBar(1, 2, 10))
+	assert.Equal(t, "[░░░░]", progressBar(0, 0, 4))
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", truncate("short", 10))
+	assert.Equal(t, "abcd…", truncate("abcdefgh", 5))
+}
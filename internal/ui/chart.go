@@ -0,0 +1,65 @@
+package ui
+
+import (
+	"math"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// sparkTicks are the characters of a sparkline, lowest first
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders values as a line of block characters scaled to the
+// largest. Zero values use the lowest block, and any other value a higher
+// one.
+func Sparkline(values []float64) string {
+	peak := 0.0
+	for _, v := range values {
+		peak = math.Max(peak, v)
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		tick := 0
+		if v > 0 && peak > 0 {
+			tick = 1 + int(math.Round(v/peak*float64(len(sparkTicks)-2)))
+		}
+		b.WriteRune(sparkTicks[tick])
+	}
+	return b.String()
+}
+
+// Bar is a row of a bar chart
+type Bar struct {
+	Label string
+	Value float64
+	Text  string // Shown after the bar
+}
+
+// BarChart renders bars as rows of a label and a horizontal bar, scaled so
+// the longest is width cells, drawn in style. Any value above zero gets at
+// least one cell.
+func BarChart(bars []Bar, width int, style lipgloss.Style) string {
+	labelWidth := 0
+	peak := 0.0
+	for _, bar := range bars {
+		labelWidth = max(labelWidth, lipgloss.Width(bar.Label))
+		peak = math.Max(peak, bar.Value)
+	}
+
+	var b strings.Builder
+	for _, bar := range bars {
+		cells := 0
+		if bar.Value > 0 && peak > 0 {
+			cells = max(1, int(math.Round(bar.Value/peak*float64(width))))
+		}
+		b.WriteString(bar.Label)
+		b.WriteString(strings.Repeat(" ", labelWidth-lipgloss.Width(bar.Label)+1))
+		b.WriteString(style.Render(strings.Repeat("█", cells)))
+		b.WriteString(strings.Repeat(" ", width-cells+1))
+		b.WriteString(bar.Text)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,30 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSparkline(t *testing.T) {
+	assert.Equal(t, "▁▂▅█", Sparkline([]float64{0, 0.1, 5, 10}))
+	assert.Equal(t, "▁▁", Sparkline([]float64{0, 0}))
+	assert.Empty(t, Sparkline(nil))
+}
+
+func TestBarChart(t *testing.T) {
+	chart := BarChart([]Bar{
+		{Label: "openai", Value: 10, Text: "10 runs"},
+		{Label: "anthropic", Value: 1, Text: "1 run"},
+		{Label: "none", Value: 0, Text: "0 runs"},
+	}, 10, lipgloss.NewStyle())
+
+	lines := strings.Split(strings.TrimSuffix(chart, "\n"), "\n")
+	assert.Equal(t, []string{
+		"openai    ██████████ 10 runs",
+		"anthropic █          1 run",
+		"none                 0 runs",
+	}, lines)
+}
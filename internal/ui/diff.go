@@ -0,0 +1,205 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/joelklabo/copilot-research/internal/diff"
+)
+
+// diffChrome is the number of lines used by the title, column labels and help
+const diffChrome = 5
+
+// DiffModel is the Bubble Tea model for a side-by-side view of a section diff
+type DiffModel struct {
+	oldLabel string
+	newLabel string
+	result   *diff.SectionResult
+	summary  string
+	viewport viewport.Model
+	ready    bool
+	width    int
+	changes  []int // Line offsets of the changed sections
+	styles   Styles
+}
+
+// NewDiffModel creates a side-by-side view of result, with the old document
+// on the left. summary, if set, is shown above the sections.
+func NewDiffModel(oldLabel, newLabel string, result *diff.SectionResult, summary string) DiffModel {
+	return DiffModel{
+		oldLabel: oldLabel,
+		newLabel: newLabel,
+		result:   result,
+		summary:  summary,
+		styles:   DefaultStyles(),
+	}
+}
+
+// Init initializes the model
+func (m DiffModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles messages
+func (m DiffModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "q", "esc":
+			return m, tea.Quit
+		case "n":
+			m.jump(1)
+			return m, nil
+		case "p":
+			m.jump(-1)
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		return m, cmd
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		content, changes := m.render(msg.Width)
+		m.changes = changes
+		height := msg.Height - diffChrome
+		if height < 1 {
+			height = 1
+		}
+		if !m.ready {
+			m.viewport = viewport.New(msg.Width, height)
+			m.ready = true
+		} else {
+			m.viewport.Width = msg.Width
+			m.viewport.Height = height
+		}
+		m.viewport.SetContent(content)
+	}
+
+	return m, nil
+}
+
+// jump scrolls to the next (dir 1) or previous (dir -1) changed section
+func (m *DiffModel) jump(dir int) {
+	if !m.ready {
+		return
+	}
+	offset := m.viewport.YOffset
+	if dir > 0 {
+		for _, line := range m.changes {
+			if line > offset {
+				m.viewport.SetYOffset(line)
+				return
+			}
+		}
+		return
+	}
+	for i := len(m.changes) - 1; i >= 0; i-- {
+		if m.changes[i] < offset {
+			m.viewport.SetYOffset(m.changes[i])
+			return
+		}
+	}
+}
+
+// View renders the UI
+func (m DiffModel) View() string {
+	var b strings.Builder
+
+	added, removed, edited, unchanged := m.result.Counts()
+	b.WriteString(m.styles.TitleStyle.Render(fmt.Sprintf("🔀 %s → %s", m.oldLabel, m.newLabel)))
+	b.WriteString("\n")
+	b.WriteString(m.styles.MessageStyle.Render(fmt.Sprintf("Similarity %.0f%% · %d added · %d removed · %d edited · %d unchanged",
+		m.result.Similarity*100, added, removed, edited, unchanged)))
+	b.WriteString("\n")
+
+	if !m.ready {
+		return b.String()
+	}
+
+	b.WriteString(m.viewport.View())
+	b.WriteString("\n")
+	b.WriteString(m.styles.MessageStyle.Render("n/p: next/previous change • ↑/↓: scroll • q: quit"))
+
+	return b.String()
+}
+
+// render lays out the sections in two columns for the given width, returning
+// the content and the line offsets of the changed sections
+func (m DiffModel) render(width int) (string, []int) {
+	column := (width - 3) / 2
+	if column < 10 {
+		column = 10
+	}
+
+	var rows []string
+	var changes []int
+	lines := 0
+	add := func(row string) {
+		rows = append(rows, row)
+		lines += lipgloss.Height(row)
+	}
+
+	if m.summary != "" {
+		add(m.styles.HeaderStyle.Render("Semantic changes"))
+		add(lipgloss.NewStyle().Width(width).Render(m.summary))
+		add("")
+	}
+
+	add(m.columns(column, m.styles.HeaderStyle.Render(m.oldLabel), m.styles.HeaderStyle.Render(m.newLabel)))
+
+	for _, s := range m.result.Sections {
+		heading := s.Heading
+		if heading == "" {
+			heading = "(introduction)"
+		}
+
+		if !s.Changed() {
+			add(m.styles.MessageStyle.Render(fmt.Sprintf("  %s (unchanged)", heading)))
+			continue
+		}
+
+		changes = append(changes, lines)
+		add("")
+		switch s.Kind {
+		case diff.Insert:
+			add(m.columns(column, "", m.styles.AddedStyle.Bold(true).Render("+ "+heading)))
+		case diff.Delete:
+			add(m.columns(column, m.styles.RemovedStyle.Bold(true).Render("- "+heading), ""))
+		default:
+			add(m.styles.WarningStyle.Render("~ " + heading))
+		}
+
+		for _, op := range s.Ops {
+			switch op.Kind {
+			case diff.Equal:
+				add(m.columns(column, op.Old, op.New))
+			case diff.Delete:
+				add(m.columns(column, m.styles.RemovedStyle.Render(op.Old), ""))
+			case diff.Insert:
+				add(m.columns(column, "", m.styles.AddedStyle.Render(op.New)))
+			}
+		}
+	}
+
+	return strings.Join(rows, "\n"), changes
+}
+
+// columns places left and right side by side, wrapped to width and padded to
+// the same height
+func (m DiffModel) columns(width int, left, right string) string {
+	cell := lipgloss.NewStyle().Width(width)
+	l := cell.Render(left)
+	r := cell.Render(right)
+
+	height := lipgloss.Height(l)
+	if h := lipgloss.Height(r); h > height {
+		height = h
+	}
+	separator := strings.TrimSuffix(strings.Repeat(" │ \n", height), "\n")
+
+	return lipgloss.JoinHorizontal(lipgloss.Top, l, separator, r)
+}
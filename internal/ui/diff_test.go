@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/joelklabo/copilot-research/internal/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+func testSectionDiff() *diff.SectionResult {
+	return diff.Sections(
+		"# Overview\n\nGo is fast.\n\n# Setup\n\nRun go install.\n\n# Legacy\n\nUse dep.",
+		"# Overview\n\nGo is fast.\n\n# Setup\n\nRun go get.\n\n# Modules\n\nUse go mod.",
+	)
+}
+
+func TestDiffModel_View(t *testing.T) {
+	model := NewDiffModel("session #1", "session #2", testSectionDiff(), "- Switched from dep to modules")
+
+	// Before the window size is known only the header is shown
+	assert.Contains(t, model.View(), "session #1 → session #2")
+
+	updated, _ := model.Update(tea.WindowSizeMsg{Width: 100, Height: 60})
+	dm := updated.(DiffModel)
+	view := dm.View()
+
+	assert.Contains(t, view, "Overview (unchanged)")
+	assert.Contains(t, view, "~ Setup")
+	assert.Contains(t, view, "- Legacy")
+	assert.Contains(t, view, "+ Modules")
+	assert.Contains(t, view, "Switched from dep to modules")
+	assert.Len(t, dm.changes, 3)
+
+	// Old and new paragraphs of an edited section share a row
+	for _, line := range strings.Split(view, "\n") {
+		if strings.Contains(line, "Run go install.") {
+			assert.Contains(t, line, "│")
+		}
+	}
+}
+
+func TestDiffModel_Jump(t *testing.T) {
+	model := NewDiffModel("a", "b", testSectionDiff(), "")
+	updated, _ := model.Update(tea.WindowSizeMsg{Width: 80, Height: diffChrome + 3})
+	dm := updated.(DiffModel)
+
+	updated, _ = dm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	dm = updated.(DiffModel)
+	assert.Equal(t, dm.changes[0], dm.viewport.YOffset)
+
+	updated, _ = dm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	dm = updated.(DiffModel)
+	assert.Equal(t, dm.changes[1], dm.viewport.YOffset)
+
+	updated, _ = dm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	dm = updated.(DiffModel)
+	assert.Equal(t, dm.changes[0], dm.viewport.YOffset)
+}
+
+func TestDiffModel_Quit(t *testing.T) {
+	model := NewDiffModel("a", "b", testSectionDiff(), "")
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	assert.NotNil(t, cmd)
+}
@@ -1,17 +1,22 @@
 package ui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/joelklabo/copilot-research/internal/db"
 	"github.com/joelklabo/copilot-research/internal/research"
 )
 
 // States for the research UI
 const (
 	stateResearching = "researching"
+	stateClarifying  = "clarifying"
 	stateComplete    = "complete"
 	stateError       = "error"
 )
@@ -24,16 +29,35 @@ type ResearchModel struct {
 	
 	spinner  *SpinnerModel
 	status   string
+	percent  int
+	elapsed  time.Duration
+	warnings []string
+	provider string
+	tokens   int
 	result   *research.ResearchResult
 	err      error
 	
+	questions []string
+	answers   []string
+	input     string
+	reply     chan<- []string
+	
+	rate    Rater
+	rating  int
+	rateErr error
+	
 	viewport viewport.Model
 	ready    bool
 	styles   Styles
 }
 
-// ProgressMsg is sent when research progress updates
-type ProgressMsg string
+// Rater records the user's rating, from 1 to 5, of a stored session
+type Rater func(sessionID int64, rating int) error
+
+// ProgressMsg is sent when the research pipeline reports an event
+type ProgressMsg struct {
+	Event research.Event
+}
 
 // CompleteMsg is sent when research completes
 type CompleteMsg struct {
@@ -45,6 +69,34 @@ type ErrorMsg struct {
 	Err error
 }
 
+// RatedMsg is sent when a rating has been recorded
+type RatedMsg struct {
+	Rating int
+	Err    error
+}
+
+// ClarifyMsg asks the user clarifying questions; the answers are sent on
+// Reply
+type ClarifyMsg struct {
+	Questions []string
+	Reply     chan<- []string
+}
+
+// NewClarifier returns a research.Clarifier that asks its questions in the
+// program's research view
+func NewClarifier(p *tea.Program) research.Clarifier {
+	return func(ctx context.Context, questions []string) ([]string, error) {
+		reply := make(chan []string, 1)
+		p.Send(ClarifyMsg{Questions: questions, Reply: reply})
+		select {
+		case answers := <-reply:
+			return answers, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
 // NewResearchModel creates a new research model
 func NewResearchModel(query, mode string) ResearchModel {
 	spinner := NewSpinner()
@@ -59,6 +111,13 @@ func NewResearchModel(query, mode string) ResearchModel {
 	}
 }
 
+// WithRater returns the model with number keys 1 to 5 rating the session
+// once research completes
+func (m ResearchModel) WithRater(rate Rater) ResearchModel {
+	m.rate = rate
+	return m
+}
+
 // Init initializes the model
 func (m ResearchModel) Init() tea.Cmd {
 	return m.spinner.Init()
@@ -68,6 +127,11 @@ func (m ResearchModel) Init() tea.Cmd {
 func (m ResearchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.state == stateClarifying && msg.Type != tea.KeyCtrlC {
+			m.handleClarifyKey(msg)
+			return m, nil
+		}
+		
 		switch msg.Type {
 		case tea.KeyCtrlC:
 			return m, tea.Quit
@@ -76,6 +140,9 @@ func (m ResearchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if (m.state == stateComplete || m.state == stateError) && len(msg.Runes) > 0 && msg.Runes[0] == 'q' {
 				return m, tea.Quit
 			}
+			if m.canRate() && len(msg.Runes) == 1 && msg.Runes[0] >= '1' && msg.Runes[0] <= '5' {
+				return m, m.rateCmd(int(msg.Runes[0] - '0'))
+			}
 		}
 		
 		// Pass key events to viewport when in complete state
@@ -95,8 +162,7 @@ func (m ResearchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case ProgressMsg:
-		m.status = string(msg)
-		m.spinner.SetMessage(m.status)
+		m.applyEvent(msg.Event)
 		return m, nil
 
 	case CompleteMsg:
@@ -105,14 +171,29 @@ func (m ResearchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.ready = false // Reset viewport ready state
 		return m, nil
 
+	case RatedMsg:
+		m.rating, m.rateErr = msg.Rating, msg.Err
+		if msg.Err != nil {
+			m.rating = 0
+		}
+		return m, nil
+
 	case ErrorMsg:
 		m.state = stateError
 		m.err = msg.Err
 		return m, nil
+
+	case ClarifyMsg:
+		m.state = stateClarifying
+		m.questions = msg.Questions
+		m.answers = nil
+		m.input = ""
+		m.reply = msg.Reply
+		return m, nil
 	}
 
-	// Update spinner in researching state
-	if m.state == stateResearching {
+	// Keep the spinner running while researching or waiting for answers
+	if m.state == stateResearching || m.state == stateClarifying {
 		var cmd tea.Cmd
 		spinnerModel, cmd := m.spinner.Update(msg)
 		m.spinner = spinnerModel.(*SpinnerModel)
@@ -122,11 +203,80 @@ func (m ResearchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// canRate reports whether the completed session can be rated
+func (m ResearchModel) canRate() bool {
+	return m.state == stateComplete && m.rate != nil && m.result != nil && m.result.SessionID != 0
+}
+
+// rateCmd records a rating in the background
+func (m ResearchModel) rateCmd(rating int) tea.Cmd {
+	rate, id := m.rate, m.result.SessionID
+	return func() tea.Msg {
+		return RatedMsg{Rating: rating, Err: rate(id, rating)}
+	}
+}
+
+// handleClarifyKey edits the answer to the current question. Enter moves to
+// the next question and Esc skips the remaining ones.
+func (m *ResearchModel) handleClarifyKey(msg tea.KeyMsg) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.answers = append(m.answers, strings.TrimSpace(m.input))
+		m.input = ""
+		if len(m.answers) == len(m.questions) {
+			m.finishClarifying()
+		}
+	case tea.KeyEsc:
+		m.finishClarifying()
+	case tea.KeyBackspace:
+		if runes := []rune(m.input); len(runes) > 0 {
+			m.input = string(runes[:len(runes)-1])
+		}
+	case tea.KeySpace:
+		m.input += " "
+	case tea.KeyRunes:
+		m.input += string(msg.Runes)
+	}
+}
+
+// finishClarifying sends the answers, leaving skipped questions empty, and
+// returns to the research view
+func (m *ResearchModel) finishClarifying() {
+	answers := make([]string, len(m.questions))
+	copy(answers, m.answers)
+	if m.reply != nil {
+		m.reply <- answers
+	}
+	m.state = stateResearching
+	m.questions, m.answers, m.input, m.reply = nil, nil, "", nil
+}
+
+// applyEvent records a pipeline event
+func (m *ResearchModel) applyEvent(event research.Event) {
+	m.elapsed = event.Elapsed
+	if event.Percent > m.percent {
+		m.percent = event.Percent
+	}
+	if event.Provider != "" {
+		m.provider = event.Provider
+	}
+	m.tokens += event.Tokens.Total
+	
+	if event.IsWarning() {
+		m.warnings = append(m.warnings, event.Message)
+		return
+	}
+	m.status = event.Message
+	m.spinner.SetMessage(m.status)
+}
+
 // View renders the model
 func (m ResearchModel) View() string {
 	switch m.state {
 	case stateResearching:
 		return m.viewResearching()
+	case stateClarifying:
+		return m.viewClarifying()
 	case stateComplete:
 		return m.viewComplete()
 	case stateError:
@@ -149,16 +299,50 @@ func (m ResearchModel) viewResearching() string {
 	
 	// Show spinner with status
 	if m.status != "" {
-		m.spinner.SetMessage(m.status)
+		m.spinner.SetMessage(fmt.Sprintf("[%3d%%] %s (%s)", m.percent, m.status, m.elapsed.Round(100*time.Millisecond)))
 	}
 	b.WriteString(m.spinner.View())
+	b.WriteString("\n")
+	b.WriteString(m.viewWarnings())
 	
-	b.WriteString("\n\n")
+	b.WriteString("\n")
 	b.WriteString("Press Ctrl+C to cancel")
 	
 	return b.String()
 }
 
+// viewClarifying renders the clarifying questions and the answers so far
+func (m ResearchModel) viewClarifying() string {
+	var b strings.Builder
+	
+	b.WriteString(m.styles.TitleStyle.Render("❓ A few questions first"))
+	b.WriteString("\n\n")
+	b.WriteString(m.styles.MessageStyle.Render(fmt.Sprintf("Query: %s", m.query)))
+	b.WriteString("\n\n")
+	
+	for i, q := range m.questions {
+		if i > len(m.answers) {
+			break
+		}
+		b.WriteString(m.styles.HeaderStyle.Render(fmt.Sprintf("%d. %s", i+1, q)))
+		b.WriteString("\n")
+		if i < len(m.answers) {
+			answer := m.answers[i]
+			if answer == "" {
+				answer = "(skipped)"
+			}
+			b.WriteString(m.styles.MessageStyle.Render(answer))
+		} else {
+			b.WriteString("> " + m.input + "█")
+		}
+		b.WriteString("\n\n")
+	}
+	
+	b.WriteString("Enter: answer (empty to skip) • Esc: skip the rest • Ctrl+C: cancel")
+	
+	return b.String()
+}
+
 // viewComplete renders the complete state
 func (m ResearchModel) viewComplete() string {
 	var b strings.Builder
@@ -167,23 +351,76 @@ func (m ResearchModel) viewComplete() string {
 	b.WriteString("\n\n")
 	b.WriteString(m.styles.MessageStyle.Render(fmt.Sprintf("Query: %s", m.query)))
 	b.WriteString("\n")
+	if m.result.RefinedQuery != "" {
+		b.WriteString(m.styles.MessageStyle.Render(fmt.Sprintf("Refined: %s", m.result.RefinedQuery)))
+		b.WriteString("\n")
+	}
 	b.WriteString(m.styles.MessageStyle.Render(fmt.Sprintf("Mode: %s | Duration: %v", m.mode, m.result.Duration)))
-	b.WriteString("\n\n")
+	b.WriteString("\n")
+	if m.provider != "" {
+		usage := fmt.Sprintf("Provider: %s", m.provider)
+		if m.tokens > 0 {
+			usage += fmt.Sprintf(" | Tokens: %d", m.tokens)
+		}
+		b.WriteString(m.styles.MessageStyle.Render(usage))
+		b.WriteString("\n")
+	}
+	if m.result.QualityScore != nil {
+		quality := fmt.Sprintf("Quality: %d/100", *m.result.QualityScore)
+		if m.result.Revised {
+			quality += " (revised after critique)"
+		}
+		b.WriteString(m.styles.MessageStyle.Render(quality))
+		b.WriteString("\n")
+	}
+	if flagged := countFlagged(m.result.Citations); flagged > 0 {
+		b.WriteString(m.styles.WarningStyle.Render(fmt.Sprintf("⚠ %d of %d citations look dead or suspicious", flagged, len(m.result.Citations))))
+		b.WriteString("\n")
+	}
+	b.WriteString(m.viewWarnings())
+	if m.rating > 0 {
+		b.WriteString(m.styles.SuccessStyle.Render(fmt.Sprintf("Rated %s", RatingStars(m.rating))))
+		b.WriteString("\n")
+	}
+	if m.rateErr != nil {
+		b.WriteString(m.styles.ErrorStyle.Render(fmt.Sprintf("✗ Failed to save rating: %v", m.rateErr)))
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
 	
+	rateHelp := ""
+	if m.canRate() {
+		rateHelp = "1-5: Rate • "
+	}
 	if m.ready {
 		b.WriteString(m.viewport.View())
 		b.WriteString("\n\n")
-		b.WriteString("↑/↓: Scroll • q: Quit")
+		b.WriteString(rateHelp + "↑/↓: Scroll • q: Quit")
 	} else {
 		// Before viewport is ready, show result directly
-		b.WriteString(m.styles.ResultStyle.Render(m.result.Content))
+		b.WriteString(m.styles.ResultStyle.Render(m.formatResult()))
 		b.WriteString("\n\n")
-		b.WriteString("Press q to quit")
+		b.WriteString(rateHelp + "Press q to quit")
 	}
 	
 	return b.String()
 }
 
+// RatingStars renders a rating as filled and empty stars
+func RatingStars(rating int) string {
+	return strings.Repeat("★", rating) + strings.Repeat("☆", db.MaxRating-rating)
+}
+
+// viewWarnings renders warnings reported during research
+func (m ResearchModel) viewWarnings() string {
+	var b strings.Builder
+	for _, warning := range m.warnings {
+		b.WriteString(m.styles.WarningStyle.Render("⚠ " + warning))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
 // viewError renders the error state
 func (m ResearchModel) viewError() string {
 	var b strings.Builder
@@ -194,6 +431,11 @@ func (m ResearchModel) viewError() string {
 	b.WriteString("\n\n")
 	b.WriteString(fmt.Sprintf("Error: %v", m.err))
 	b.WriteString("\n\n")
+	var checkpointed *research.CheckpointError
+	if errors.As(m.err, &checkpointed) {
+		b.WriteString(m.styles.MessageStyle.Render(fmt.Sprintf("Progress was saved. Resume with: copilot-research resume %d", checkpointed.SessionID)))
+		b.WriteString("\n\n")
+	}
 	b.WriteString("Press q to quit")
 	
 	return b.String()
@@ -204,5 +446,65 @@ func (m ResearchModel) formatResult() string {
 	if m.result == nil {
 		return ""
 	}
-	return m.result.Content
+	if len(m.result.Citations) == 0 {
+		return m.result.Content
+	}
+	return m.result.Content + "\n\n" + m.formatCitations()
+}
+
+// formatCitations renders the sources list with dead and suspicious links flagged
+func (m ResearchModel) formatCitations() string {
+	var b strings.Builder
+	b.WriteString(m.styles.HeaderStyle.Render("Sources"))
+	b.WriteString("\n")
+	
+	for _, c := range m.result.Citations {
+		ref := c.URL
+		if ref == "" {
+			ref = c.Text
+		} else if c.Text != "" {
+			ref = fmt.Sprintf("%s (%s)", c.Text, c.URL)
+		}
+		
+		line := fmt.Sprintf("%s %s", citationMarker(c.Status), ref)
+		if c.Note != "" {
+			line += " — " + c.Note
+		}
+		
+		switch c.Status {
+		case db.CitationDead:
+			line = m.styles.ErrorStyle.Render(line)
+		case db.CitationSuspicious:
+			line = m.styles.WarningStyle.Render(line)
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// citationMarker returns the symbol shown next to a citation
+func citationMarker(status string) string {
+	switch status {
+	case db.CitationOK:
+		return "✓"
+	case db.CitationDead:
+		return "✗"
+	case db.CitationSuspicious:
+		return "⚠"
+	default:
+		return "•"
+	}
+}
+
+// countFlagged counts dead and suspicious citations
+func countFlagged(citations []db.Citation) int {
+	count := 0
+	for _, c := range citations {
+		if c.Flagged() {
+			count++
+		}
+	}
+	return count
 }
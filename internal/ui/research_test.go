@@ -5,6 +5,8 @@ import (
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/joelklabo/copilot-research/internal/provider"
 	"github.com/joelklabo/copilot-research/internal/research"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -48,7 +50,7 @@ func TestResearchModel_ProgressMessage(t *testing.T) {
 	model := NewResearchModel("test", "quick")
 
 	// Send progress message
-	msg := ProgressMsg("Loading prompt...")
+	msg := ProgressMsg{Event: research.Event{Stage: research.StageLoadPrompt, Status: research.EventStarted, Message: "Loading prompt..."}}
 	newModel, _ := model.Update(msg)
 	
 	rm := newModel.(ResearchModel)
@@ -56,6 +58,33 @@ func TestResearchModel_ProgressMessage(t *testing.T) {
 	assert.Equal(t, stateResearching, rm.state)
 }
 
+func TestResearchModel_ProgressEvents(t *testing.T) {
+	model := NewResearchModel("test", "quick")
+
+	events := []research.Event{
+		{Stage: research.StageQuery, Status: research.EventStarted, Message: "Querying AI provider...", Percent: 10, Elapsed: time.Second},
+		{Stage: research.StageQuery, Status: research.EventCompleted, Message: "Received answer", Percent: 60, Provider: "github-copilot", Tokens: provider.TokenUsage{Total: 120}},
+		{Stage: research.StageStore, Status: research.EventWarning, Message: "Failed to store session: disk full", Percent: 95},
+	}
+	for _, event := range events {
+		newModel, _ := model.Update(ProgressMsg{Event: event})
+		model = newModel.(ResearchModel)
+	}
+
+	assert.Equal(t, "Received answer", model.status, "warnings don't replace the current status")
+	assert.Equal(t, 95, model.percent)
+	assert.Equal(t, []string{"Failed to store session: disk full"}, model.warnings)
+
+	view := model.View()
+	assert.Contains(t, view, "95%")
+	assert.Contains(t, view, "⚠ Failed to store session: disk full")
+
+	newModel, _ := model.Update(CompleteMsg{Result: &research.ResearchResult{Content: "Done"}})
+	view = newModel.(ResearchModel).View()
+	assert.Contains(t, view, "Provider: github-copilot | Tokens: 120")
+	assert.Contains(t, view, "⚠ Failed to store session: disk full")
+}
+
 func TestResearchModel_CompleteMessage(t *testing.T) {
 	model := NewResearchModel("test", "quick")
 
@@ -137,6 +166,43 @@ func TestResearchModel_ViewComplete(t *testing.T) {
 	assert.Contains(t, view, "Test result")
 }
 
+func TestResearchModel_ViewComplete_Citations(t *testing.T) {
+	model := NewResearchModel("test", "quick")
+	model.state = stateComplete
+	model.result = &research.ResearchResult{
+		Query:   "test",
+		Mode:    "quick",
+		Content: "Test result",
+		Citations: []db.Citation{
+			{Text: "Go", URL: "https://go.dev", Status: db.CitationOK},
+			{URL: "https://go.dev/missing", Status: db.CitationDead, Note: "Not Found"},
+			{URL: "https://example.com", Status: db.CitationSuspicious, Note: "placeholder domain"},
+		},
+	}
+
+	view := model.View()
+	
+	assert.Contains(t, view, "2 of 3 citations look dead or suspicious")
+	assert.Contains(t, view, "Sources")
+	assert.Contains(t, view, "✓ Go (https://go.dev)")
+	assert.Contains(t, view, "✗ https://go.dev/missing — Not Found")
+	assert.Contains(t, view, "⚠ https://example.com — placeholder domain")
+}
+
+func TestResearchModel_ViewComplete_QualityScore(t *testing.T) {
+	score := 78
+	model := NewResearchModel("test", "deep")
+	model.state = stateComplete
+	model.result = &research.ResearchResult{
+		Content:      "Revised result",
+		QualityScore: &score,
+		Revised:      true,
+	}
+
+	view := model.View()
+	assert.Contains(t, view, "Quality: 78/100 (revised after critique)")
+}
+
 func TestResearchModel_ViewError(t *testing.T) {
 	model := NewResearchModel("test", "quick")
 	model.state = stateError
@@ -150,6 +216,15 @@ func TestResearchModel_ViewError(t *testing.T) {
 	assert.Contains(t, view, "Error") // Should be case-insensitive
 }
 
+func TestResearchModel_ViewError_Resumable(t *testing.T) {
+	model := NewResearchModel("test", "deep")
+	model.state = stateError
+	model.err = &research.CheckpointError{SessionID: 42, Err: assert.AnError}
+
+	view := model.View()
+	assert.Contains(t, view, "Resume with: copilot-research resume 42")
+}
+
 func TestResearchModel_ViewportIntegration(t *testing.T) {
 	model := NewResearchModel("test", "quick")
 	model.state = stateComplete
@@ -206,7 +281,7 @@ func TestResearchModel_MultipleProgressUpdates(t *testing.T) {
 	}
 
 	for _, msg := range progressMessages {
-		newModel, _ := model.Update(ProgressMsg(msg))
+		newModel, _ := model.Update(ProgressMsg{Event: research.Event{Status: research.EventStarted, Message: msg}})
 		model = newModel.(ResearchModel)
 		assert.Equal(t, msg, model.status)
 		assert.Equal(t, stateResearching, model.state)
@@ -214,8 +289,8 @@ func TestResearchModel_MultipleProgressUpdates(t *testing.T) {
 }
 
 func TestProgressMsg(t *testing.T) {
-	msg := ProgressMsg("test")
-	assert.Equal(t, ProgressMsg("test"), msg)
+	msg := ProgressMsg{Event: research.Event{Message: "test"}}
+	assert.Equal(t, "test", msg.Event.Message)
 }
 
 func TestCompleteMsg(t *testing.T) {
@@ -231,3 +306,89 @@ func TestErrorMsg(t *testing.T) {
 	msg := ErrorMsg{Err: assert.AnError}
 	assert.Error(t, msg.Err)
 }
+
+func TestResearchModel_Clarify(t *testing.T) {
+	model := NewResearchModel("go logging", "quick")
+	reply := make(chan []string, 1)
+
+	updated, _ := model.Update(ClarifyMsg{Questions: []string{"Which Go version?", "JSON output?", "Scale?"}, Reply: reply})
+	rm := updated.(ResearchModel)
+	assert.Equal(t, stateClarifying, rm.state)
+	assert.Contains(t, rm.View(), "Which Go version?")
+	assert.NotContains(t, rm.View(), "JSON output?")
+
+	for _, key := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("Go")},
+		{Type: tea.KeySpace},
+		{Type: tea.KeyRunes, Runes: []rune("1.22x")},
+		{Type: tea.KeyBackspace},
+		{Type: tea.KeyEnter},
+		{Type: tea.KeyEnter},
+	} {
+		updated, _ = rm.Update(key)
+		rm = updated.(ResearchModel)
+	}
+	assert.Contains(t, rm.View(), "(skipped)")
+	assert.Contains(t, rm.View(), "Scale?")
+
+	// Esc skips the remaining questions
+	updated, _ = rm.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	rm = updated.(ResearchModel)
+	assert.Equal(t, stateResearching, rm.state)
+
+	select {
+	case answers := <-reply:
+		assert.Equal(t, []string{"Go 1.22", "", ""}, answers)
+	default:
+		t.Fatal("answers were not sent")
+	}
+}
+
+func TestResearchModel_CompleteShowsRefinedQuery(t *testing.T) {
+	model := NewResearchModel("go logging", "quick")
+	updated, _ := model.Update(CompleteMsg{Result: &research.ResearchResult{Content: "answer", RefinedQuery: "Compare Go logging libraries"}})
+	assert.Contains(t, updated.View(), "Refined: Compare Go logging libraries")
+}
+
+func TestResearchModel_Rate(t *testing.T) {
+	var ratedID int64
+	var rated int
+	model := NewResearchModel("test", "quick").WithRater(func(id int64, rating int) error {
+		ratedID, rated = id, rating
+		return nil
+	})
+	keys := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'4'}}
+
+	// Nothing to rate while researching
+	_, cmd := model.Update(keys)
+	assert.Nil(t, cmd)
+
+	newModel, _ := model.Update(CompleteMsg{Result: &research.ResearchResult{Content: "Answer", SessionID: 12}})
+	model = newModel.(ResearchModel)
+	assert.Contains(t, model.View(), "1-5: Rate")
+
+	_, cmd = model.Update(keys)
+	require.NotNil(t, cmd)
+	msg := cmd()
+	assert.Equal(t, RatedMsg{Rating: 4}, msg)
+	assert.Equal(t, int64(12), ratedID)
+	assert.Equal(t, 4, rated)
+
+	newModel, _ = model.Update(msg)
+	model = newModel.(ResearchModel)
+	assert.Contains(t, model.View(), "Rated ★★★★☆")
+
+	newModel, _ = model.Update(RatedMsg{Rating: 2, Err: assert.AnError})
+	assert.Contains(t, newModel.View(), "Failed to save rating")
+	assert.NotContains(t, newModel.View(), "Rated ★")
+}
+
+func TestResearchModel_RateNeedsStoredSession(t *testing.T) {
+	model := NewResearchModel("test", "quick").WithRater(func(int64, int) error { return nil })
+	newModel, _ := model.Update(CompleteMsg{Result: &research.ResearchResult{Content: "Answer"}})
+	model = newModel.(ResearchModel)
+
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'5'}})
+	assert.Nil(t, cmd, "--no-store sessions can't be rated")
+	assert.NotContains(t, model.View(), "1-5: Rate")
+}
@@ -10,7 +10,11 @@ type Styles struct {
 	ResultStyle  lipgloss.Style
 	ErrorStyle   lipgloss.Style
 	SuccessStyle lipgloss.Style
+	WarningStyle lipgloss.Style
 	HeaderStyle  lipgloss.Style
+	AddedStyle   lipgloss.Style
+	RemovedStyle lipgloss.Style
+	ChartStyle   lipgloss.Style
 }
 
 // DefaultStyles returns the default style configuration
@@ -42,8 +46,21 @@ func DefaultStyles() Styles {
 			Bold(true).
 			Foreground(lipgloss.Color("42")),
 
+		WarningStyle: lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("214")),
+
 		HeaderStyle: lipgloss.NewStyle().
 			Bold(true).
 			Foreground(lipgloss.Color("86")),
+
+		AddedStyle: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("42")),
+
+		RemovedStyle: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")),
+
+		ChartStyle: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("69")),
 	}
 }
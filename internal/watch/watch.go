@@ -0,0 +1,171 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/joelklabo/copilot-research/internal/diff"
+	"github.com/joelklabo/copilot-research/internal/research"
+)
+
+// DefaultThreshold is the similarity below which a new answer counts as a
+// material change
+const DefaultThreshold = 0.85
+
+// Report describes the outcome of re-running one watched query
+type Report struct {
+	Watch             *db.WatchedQuery
+	Changed           bool
+	FirstRun          bool
+	Diff              *diff.Result
+	SessionID         int64
+	PreviousSessionID int64
+	Err               error
+}
+
+// Runner re-executes watched queries that are due and detects changes
+type Runner struct {
+	db         db.DB
+	researcher research.Researcher
+	threshold  float64
+	now        func() time.Time
+}
+
+// NewRunner creates a watch runner. A threshold outside (0, 1] falls back to
+// DefaultThreshold.
+func NewRunner(database db.DB, researcher research.Researcher, threshold float64) *Runner {
+	if threshold <= 0 || threshold > 1 {
+		threshold = DefaultThreshold
+	}
+	return &Runner{
+		db:         database,
+		researcher: researcher,
+		threshold:  threshold,
+		now:        time.Now,
+	}
+}
+
+// RunDue re-runs every watch that is due (or all of them when force is set)
+// and returns one report per executed watch. A failing watch is reported in
+// its Report and does not stop the others.
+func (r *Runner) RunDue(ctx context.Context, force bool) ([]*Report, error) {
+	watches, err := r.db.ListWatches()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list watches: %w", err)
+	}
+
+	var reports []*Report
+	for _, w := range watches {
+		if ctx.Err() != nil {
+			return reports, ctx.Err()
+		}
+		if !force && !w.IsDue(r.now()) {
+			continue
+		}
+		reports = append(reports, r.run(ctx, w))
+	}
+
+	return reports, nil
+}
+
+// run re-executes a single watch and stores the answer if it changed
+func (r *Runner) run(ctx context.Context, w *db.WatchedQuery) *Report {
+	report := &Report{Watch: w}
+
+	previous, err := r.previousSession(w)
+	if err != nil {
+		report.Err = err
+		return report
+	}
+
+	start := time.Now()
+	result, err := r.researcher.Research(ctx, research.ResearchOptions{
+		Query:      w.Query,
+		Mode:       w.Mode,
+		PromptName: w.PromptUsed,
+		NoStore:    true,
+	}, nil)
+	latency := time.Since(start)
+	defer r.logRun(ctx, w, report, result, err, latency)
+	if err != nil {
+		report.Err = fmt.Errorf("research failed: %w", err)
+		return report
+	}
+
+	runAt := r.now()
+
+	if previous == nil {
+		report.FirstRun = true
+		report.Changed = true
+	} else {
+		report.PreviousSessionID = previous.ID
+		report.SessionID = previous.ID
+		report.Diff = diff.Paragraphs(previous.Result, result.Content)
+		report.Changed = report.Diff.Material(r.threshold)
+	}
+
+	if report.Changed {
+		session := &db.ResearchSession{
+			Query:      w.Query,
+			Mode:       w.Mode,
+			PromptUsed: w.PromptUsed,
+			Result:     result.Content,
+			Provider:   result.Provider,
+			Model:      result.Model,
+			Tokens:     db.TokenCount(result.Tokens),
+			CreatedAt:  runAt,
+		}
+		if previous != nil {
+			session.ParentID = previous.ID
+		}
+		if err := r.db.SaveSession(session); err != nil {
+			report.Err = fmt.Errorf("failed to store session: %w", err)
+			return report
+		}
+		report.SessionID = session.ID
+	}
+
+	if err := r.db.UpdateWatchRun(w.ID, runAt, report.SessionID); err != nil {
+		report.Err = fmt.Errorf("failed to record watch run: %w", err)
+	}
+
+	return report
+}
+
+// logRun records a watch run in the search log with the session it left
+// the watch on. The research runs without storing, so the engine does not
+// log it. A failure to log does not fail the watch.
+func (r *Runner) logRun(ctx context.Context, w *db.WatchedQuery, report *Report, result *research.ResearchResult, err error, latency time.Duration) {
+	entry := &db.SearchHistory{
+		SessionID:  report.SessionID,
+		Query:      w.Query,
+		Mode:       w.Mode,
+		PromptUsed: w.PromptUsed,
+		Latency:    latency,
+		CreatedAt:  r.now(),
+	}
+	entry.Outcome, entry.Error = research.RunOutcome(ctx, err)
+	if result != nil {
+		entry.Provider = result.Provider
+	}
+	r.db.LogSearch(entry)
+}
+
+// previousSession finds the last stored answer for a watch
+func (r *Runner) previousSession(w *db.WatchedQuery) (*db.ResearchSession, error) {
+	if w.LastSessionID != nil {
+		session, err := r.db.GetSession(*w.LastSessionID)
+		if err == nil {
+			return session, nil
+		}
+		// The session may have been deleted; fall back to the latest one
+	}
+
+	session, err := r.db.GetLatestSession(w.Query, w.Mode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load previous session: %w", err)
+	}
+	return session, nil
+}
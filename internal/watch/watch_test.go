@@ -0,0 +1,183 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/joelklabo/copilot-research/internal/db"
+	"github.com/joelklabo/copilot-research/internal/research"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResearcher answers every query with a fixed response
+type fakeResearcher struct {
+	content string
+	err     error
+	calls   int
+}
+
+func (f *fakeResearcher) Research(ctx context.Context, opts research.ResearchOptions, events chan<- research.Event) (*research.ResearchResult, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &research.ResearchResult{Query: opts.Query, Mode: opts.Mode, Content: f.content, Provider: "github-copilot"}, nil
+}
+
+func setupDB(t *testing.T) db.DB {
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { database.Close() })
+	return database
+}
+
+func addWatch(t *testing.T, database db.DB, query string) *db.WatchedQuery {
+	w := &db.WatchedQuery{Query: query, Mode: "quick", PromptUsed: "default", Interval: time.Hour}
+	require.NoError(t, database.SaveWatch(w))
+	return w
+}
+
+func TestRunner_FirstRunStoresSession(t *testing.T) {
+	database := setupDB(t)
+	addWatch(t, database, "Stripe API")
+
+	fake := &fakeResearcher{content: "# Stripe\n\nUse the v1 API."}
+	reports, err := NewRunner(database, fake, 0).RunDue(context.Background(), false)
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+
+	report := reports[0]
+	require.NoError(t, report.Err)
+	assert.True(t, report.FirstRun)
+	assert.True(t, report.Changed)
+	assert.Greater(t, report.SessionID, int64(0))
+
+	session, err := database.GetSession(report.SessionID)
+	require.NoError(t, err)
+	assert.Equal(t, fake.content, session.Result)
+
+	// The watch is no longer due
+	reports, err = NewRunner(database, fake, 0).RunDue(context.Background(), false)
+	require.NoError(t, err)
+	assert.Empty(t, reports)
+	assert.Equal(t, 1, fake.calls)
+}
+
+func TestRunner_UnchangedAnswerIsNotStored(t *testing.T) {
+	database := setupDB(t)
+	addWatch(t, database, "Stripe API")
+
+	fake := &fakeResearcher{content: "# Stripe\n\nUse the v1 API for payments."}
+	runner := NewRunner(database, fake, 0)
+	first, err := runner.RunDue(context.Background(), false)
+	require.NoError(t, err)
+
+	second, err := runner.RunDue(context.Background(), true)
+	require.NoError(t, err)
+	require.Len(t, second, 1)
+	assert.False(t, second[0].Changed)
+	assert.Equal(t, first[0].SessionID, second[0].SessionID)
+
+	total, err := database.GetTotalSessions()
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+}
+
+func TestRunner_MaterialChangeIsStored(t *testing.T) {
+	database := setupDB(t)
+	w := addWatch(t, database, "Stripe API")
+
+	old := &db.ResearchSession{
+		Query: w.Query, Mode: "quick", PromptUsed: "default",
+		Result:    "# Stripe\n\nUse the v1 charges API for payments.",
+		CreatedAt: time.Now().Add(-48 * time.Hour),
+	}
+	require.NoError(t, database.SaveSession(old))
+
+	fake := &fakeResearcher{content: "# Stripe\n\nPaymentIntents replaced charges; migrate before the deprecation deadline.\n\nWebhooks now require signature verification."}
+	now := time.Now()
+	runner := NewRunner(database, fake, 0.9)
+	runner.now = func() time.Time { return now }
+
+	reports, err := runner.RunDue(context.Background(), false)
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+
+	report := reports[0]
+	require.NoError(t, report.Err)
+	assert.False(t, report.FirstRun)
+	assert.True(t, report.Changed)
+	assert.Equal(t, old.ID, report.PreviousSessionID)
+	assert.NotEqual(t, old.ID, report.SessionID)
+	require.NotNil(t, report.Diff)
+	assert.NotEmpty(t, report.Diff.Added)
+
+	watches, err := database.ListWatches()
+	require.NoError(t, err)
+	require.NotNil(t, watches[0].LastSessionID)
+	assert.Equal(t, report.SessionID, *watches[0].LastSessionID)
+
+	session, err := database.GetSession(report.SessionID)
+	require.NoError(t, err)
+	assert.Equal(t, old.ID, session.ParentID, "the new answer follows up on the previous one")
+}
+
+func TestRunner_ErrorsAreReportedPerWatch(t *testing.T) {
+	database := setupDB(t)
+	addWatch(t, database, "one")
+	addWatch(t, database, "two")
+
+	fake := &fakeResearcher{err: fmt.Errorf("provider down")}
+	reports, err := NewRunner(database, fake, 0).RunDue(context.Background(), false)
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+	for _, r := range reports {
+		assert.Error(t, r.Err)
+	}
+
+	// Failed watches stay due
+	watches, err := database.ListWatches()
+	require.NoError(t, err)
+	assert.Nil(t, watches[0].LastRunAt)
+
+	failed, err := database.ListSearches(db.SearchFilter{Outcome: db.OutcomeFailed})
+	require.NoError(t, err)
+	require.Len(t, failed, 2)
+	assert.Equal(t, "provider down", failed[0].Error)
+}
+
+func TestRunner_LogsEveryRun(t *testing.T) {
+	database := setupDB(t)
+	addWatch(t, database, "Stripe API")
+
+	fake := &fakeResearcher{content: "# Stripe\n\nUse the v1 API."}
+	runner := NewRunner(database, fake, 0)
+	first, err := runner.RunDue(context.Background(), true)
+	require.NoError(t, err)
+	_, err = runner.RunDue(context.Background(), true)
+	require.NoError(t, err)
+
+	searches, err := database.ListSearches(db.SearchFilter{})
+	require.NoError(t, err)
+	require.Len(t, searches, 2, "unchanged answers are logged too")
+	for _, s := range searches {
+		assert.Equal(t, "Stripe API", s.Query)
+		assert.Equal(t, db.OutcomeSuccess, s.Outcome)
+		assert.Equal(t, "github-copilot", s.Provider)
+		assert.Equal(t, first[0].SessionID, s.SessionID)
+	}
+
+	top, err := database.GetTopQueries(5)
+	require.NoError(t, err)
+	require.Len(t, top, 1)
+	assert.Equal(t, 2, top[0].Count)
+}
+
+func TestNewRunner_DefaultThreshold(t *testing.T) {
+	assert.Equal(t, DefaultThreshold, NewRunner(nil, nil, 0).threshold)
+	assert.Equal(t, DefaultThreshold, NewRunner(nil, nil, 1.5).threshold)
+	assert.Equal(t, 0.5, NewRunner(nil, nil, 0.5).threshold)
+}